@@ -0,0 +1,452 @@
+package backend
+
+import (
+  "bytes"
+  "errors"
+  "fmt"
+  "os"
+  "strconv"
+  "strings"
+)
+
+import (
+  apev2 "github.com/elias-boemeke/taggo/format/apev2"
+  id3v1 "github.com/elias-boemeke/taggo/format/id3v1"
+  mac "github.com/elias-boemeke/taggo/format/mac"
+  musepack "github.com/elias-boemeke/taggo/format/musepack"
+  wavpack "github.com/elias-boemeke/taggo/format/wavpack"
+)
+
+
+
+// native backend for Monkey's Audio, WavPack and Musepack files,
+// which are tagged with apev2
+type apeBackend struct{}
+
+type apeFile struct {
+  path  string
+  tag   *apev2.Tag
+  props Properties
+  size  int64
+  // size of a trailing id3v1 tag
+  trailer int64
+}
+
+// apev2 item keys of the fields, track and disc hold n/total
+var apeKeys = map[string]string{
+  "album":           "Album",
+  "albumartist":     "Album Artist",
+  "albumartistsort": "ALBUMARTISTSORT",
+  "albumgain":       "REPLAYGAIN_ALBUM_GAIN",
+  "albumpeak":       "REPLAYGAIN_ALBUM_PEAK",
+  "albumsort":       "ALBUMSORT",
+  "artist":          "Artist",
+  "artistsort":      "ARTISTSORT",
+  "bpm":             "BPM",
+  "catalog":         "CatalogNumber",
+  "comment":         "Comment",
+  "compilation":     "Compilation",
+  "composer":        "Composer",
+  "composersort":    "COMPOSERSORT",
+  "conductor":       "Conductor",
+  "copyright":       "Copyright",
+  "date":            "Year",
+  "disc":            "Disc",
+  "disctotal":       "Disc",
+  "encodedby":       "EncodedBy",
+  "genre":           "Genre",
+  "grouping":        "Grouping",
+  "isrc":            "ISRC",
+  "lyricist":        "Lyricist",
+  "originaldate":    "ORIGINALDATE",
+  "publisher":       "Label",
+  "title":           "Title",
+  "titlesort":       "TITLESORT",
+  "track":           "Track",
+  "trackgain":       "REPLAYGAIN_TRACK_GAIN",
+  "trackpeak":       "REPLAYGAIN_TRACK_PEAK",
+  "tracktotal":      "Track",
+  "year":            "Year",
+}
+
+// keys read when the one of apeKeys is missing
+var apeFallbacks = map[string]string{
+  "albumartist": "AlbumArtist",
+  "publisher":   "Publisher",
+}
+
+func init() {
+  Register(apeBackend{}, 10)
+}
+
+func (apeBackend) Name() string {
+  return "ape"
+}
+
+func (apeBackend) Capabilities() Capabilities {
+  return Capabilities{
+    Formats: []string{"ape", "wavpack", "musepack"},
+    Fields:  AllFields,
+    Write:   true,
+  }
+}
+
+func (apeBackend) Open(path string, config Config) (File, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+  info, err := file.Stat()
+  if err != nil {
+    return nil, err
+  }
+
+  f := &apeFile{path: path, size: info.Size()}
+  end := info.Size()
+  if _, err := id3v1.Read(file, end); err == nil {
+    f.trailer = id3v1.Size
+    end -= id3v1.Size
+  }
+  f.tag, err = apev2.Read(file, end)
+  if err == apev2.ErrNoTag {
+    f.tag = nil
+  } else if err != nil {
+    return nil, err
+  }
+
+  if f.props, err = streamProperties(file); err != nil {
+    return nil, err
+  }
+  audio := end
+  if f.tag != nil {
+    audio = f.tag.Offset
+  }
+  if f.props.Length > 0 {
+    f.props.Bitrate = int(float64(audio) * 8 / f.props.Length.Seconds() / 1000)
+  }
+  return f, nil
+}
+
+// streamProperties reads the header of a Monkey's Audio, WavPack or Musepack stream
+func streamProperties(file *os.File) (Properties, error) {
+  if p, err := mac.ReadProperties(file); err == nil {
+    return Properties{
+      Length:     p.Length,
+      Samplerate: p.Samplerate,
+      Channels:   p.Channels,
+      Technical:  []Property{
+        {"Codec", fmt.Sprintf("Monkey's Audio %d.%02d", p.Version / 1000, p.Version % 1000 / 10)},
+        {"Compression", p.CompressionName()},
+        {"Bits per sample", strconv.Itoa(p.BitsPerSample)},
+      },
+    }, nil
+  }
+  if p, err := wavpack.ReadProperties(file); err == nil {
+    mode := "lossy"
+    if p.Lossless {
+      mode = "lossless"
+    }
+    return Properties{
+      Length:     p.Length,
+      Samplerate: p.Samplerate,
+      Channels:   p.Channels,
+      Technical:  []Property{
+        {"Codec", fmt.Sprintf("WavPack (stream version 0x%x)", p.Version)},
+        {"Mode", mode},
+        {"Bits per sample", strconv.Itoa(p.BitsPerSample)},
+      },
+    }, nil
+  }
+  if p, err := musepack.ReadProperties(file); err == nil {
+    return Properties{
+      Length:     p.Length,
+      Samplerate: p.Samplerate,
+      Channels:   p.Channels,
+      Technical:  []Property{
+        {"Codec", fmt.Sprintf("Musepack SV%d", p.StreamVersion)},
+      },
+    }, nil
+  }
+  return Properties{}, errors.New("unknown stream, expected Monkey's Audio, WavPack or Musepack")
+}
+
+func (f *apeFile) Fields() map[string]string {
+  return joinValues(f.FieldValues())
+}
+
+func (f *apeFile) FieldValues() map[string][]string {
+  if f.tag == nil {
+    return make(map[string][]string)
+  }
+  return apeFieldValues(f.tag)
+}
+
+func (f *apeFile) SetField(key string, value string) error {
+  return f.SetFieldValues(key, valuesOf(value))
+}
+
+func (f *apeFile) SetFieldValues(key string, values []string) error {
+  if !isField(key) {
+    return errors.New(fmt.Sprintf("field '%s' is not supported by backend 'ape'", key))
+  }
+  if f.tag == nil {
+    f.tag = apev2.NewTag()
+  }
+  setAPEValues(f.tag, key, values)
+  return nil
+}
+
+func (f *apeFile) Namespaces() []string {
+  return []string{"ape"}
+}
+
+func (f *apeFile) RawFields() []RawField {
+  if f.tag == nil {
+    return nil
+  }
+  return apeRawFields(f.tag)
+}
+
+func (f *apeFile) RawValues(namespace string, key string) ([]string, error) {
+  if f.tag == nil {
+    return nil, nil
+  }
+  return apeRawValues(f.tag, key), nil
+}
+
+func (f *apeFile) SetRaw(namespace string, key string, values []string) error {
+  if f.tag == nil {
+    f.tag = apev2.NewTag()
+  }
+  return setAPERaw(f.tag, key, values)
+}
+
+func (f *apeFile) Pictures() []Picture {
+  if f.tag == nil {
+    return nil
+  }
+  return apePictures(f.tag)
+}
+
+func (f *apeFile) SetPictures(pictures []Picture) error {
+  if f.tag == nil {
+    f.tag = apev2.NewTag()
+  }
+  return setAPEPictures(f.tag, pictures)
+}
+
+func (f *apeFile) Properties() Properties {
+  props := f.props
+  if f.tag != nil {
+    props.Technical = append(props.Technical[:len(props.Technical):len(props.Technical)],
+      Property{"APE", apeVersion(f.tag)})
+  }
+  return props
+}
+
+// Structure lists the audio stream and the trailing tags
+func (f *apeFile) Structure() []Element {
+  end := f.size - f.trailer
+  audio := end
+  if f.tag != nil {
+    audio = f.tag.Offset
+  }
+  elements := []Element{{Name: "audio", Offset: 0, Size: audio}}
+  if f.tag != nil {
+    elements = append(elements, apeElement(f.tag))
+  }
+  if f.trailer > 0 {
+    elements = append(elements, Element{Name: "ID3v1", Offset: end, Size: f.trailer})
+  }
+  return elements
+}
+
+func (f *apeFile) Save() error {
+  if f.tag == nil {
+    return nil
+  }
+  if err := apev2.WriteFile(f.path, f.tag); err != nil {
+    return err
+  }
+  // read back to pick up the new position
+  tag, err := apev2.ReadFile(f.path)
+  if err != nil {
+    return err
+  }
+  f.tag = tag
+  if info, err := os.Stat(f.path); err == nil {
+    f.size = info.Size()
+  }
+  return nil
+}
+
+func (f *apeFile) Close() error {
+  return nil
+}
+
+func apeFields(t *apev2.Tag) map[string]string {
+  return joinValues(apeFieldValues(t))
+}
+
+// apeFieldValues returns the values of the fields, text items hold
+// several values separated by zero bytes
+func apeFieldValues(t *apev2.Tag) map[string][]string {
+  values := make(map[string][]string)
+  for key, name := range apeKeys {
+    values[key] = apeTexts(t, name)
+    if fallback, ok := apeFallbacks[key]; ok && len(values[key]) == 0 {
+      values[key] = apeTexts(t, fallback)
+    }
+  }
+  for key, pair := range pairFields {
+    value := strings.Join(values[key], ValueSeparator)
+    if key == pair[0] {
+      values[key] = valuesOf(numberOf(value))
+    } else {
+      values[key] = valuesOf(totalOf(value))
+    }
+  }
+  values["year"] = valuesOf(yearOf(strings.Join(values["year"], ValueSeparator)))
+  values["date"] = valuesOf(strings.Join(values["date"], ValueSeparator))
+  return values
+}
+
+// apeTexts returns the values of a text item
+func apeTexts(t *apev2.Tag, name string) []string {
+  if item := t.Get(name); item != nil && item.Type() == apev2.TypeText {
+    return item.Values()
+  }
+  return nil
+}
+
+func setAPEValues(t *apev2.Tag, key string, values []string) {
+  name := apeKeys[key]
+  if _, ok := pairFields[key]; ok {
+    // a plain number keeps the total of an existing n/total value
+    values = valuesOf(setPairPart(t.Text(name), key, strings.Join(values, ValueSeparator)))
+  }
+  if fallback, ok := apeFallbacks[key]; ok {
+    t.Remove(fallback)
+  }
+  t.SetText(name, values...)
+}
+
+func apeVersion(t *apev2.Tag) string {
+  return fmt.Sprintf("%d.%d, %d item(s)", t.Version / 1000, t.Version % 1000 / 100,
+    len(t.Items))
+}
+
+// apeElement lists the items of an apev2 tag as children
+func apeElement(t *apev2.Tag) Element {
+  e := Element{
+    Name:   "APEv2",
+    Offset: t.Offset,
+    Size:   t.Size,
+    Info:   fmt.Sprintf("version %d, %d item(s)", t.Version, len(t.Items)),
+  }
+  for _, item := range t.Items {
+    e.Children = append(e.Children, item.Key + "=" + item.String())
+  }
+  return e
+}
+
+// apeRawFields lists the values of all items, binary items are summarised
+func apeRawFields(t *apev2.Tag) []RawField {
+  var fields []RawField
+  for _, item := range t.Items {
+    for _, v := range apeRawValues(t, item.Key) {
+      fields = append(fields, RawField{"ape", item.Key, v})
+    }
+  }
+  return fields
+}
+
+func apeRawValues(t *apev2.Tag, key string) []string {
+  item := t.Get(key)
+  switch {
+  case item == nil:
+    return nil
+  case item.Type() == apev2.TypeText || item.Type() == apev2.TypeLocator:
+    return item.Values()
+  }
+  return []string{item.String()}
+}
+
+// setAPERaw sets the text values of item key, binary items can only be removed
+func setAPERaw(t *apev2.Tag, key string, values []string) error {
+  if len(key) < 2 || len(key) > 255 {
+    return errors.New(fmt.Sprintf("apev2 key '%s' must have 2 to 255 characters", key))
+  }
+  for _, r := range key {
+    if r < 0x20 || r > 0x7e {
+      return errors.New(fmt.Sprintf("apev2 key '%s' must be printable ascii", key))
+    }
+  }
+  item := t.Get(key)
+  if len(values) == 0 {
+    t.Remove(key)
+    return nil
+  }
+  if item != nil && item.Type() == apev2.TypeBinary {
+    return errors.New(fmt.Sprintf("apev2 item '%s' is binary and can only be removed", key))
+  }
+  t.SetText(key, values...)
+  return nil
+}
+
+// keys of the cover art items in the order of the picture types
+var apeCoverKeys = []string{"Cover Art (Other)", "Cover Art (Png Icon)",
+  "Cover Art (Icon)", "Cover Art (Front)", "Cover Art (Back)", "Cover Art (Leaflet)",
+  "Cover Art (Media)", "Cover Art (Lead Artist)", "Cover Art (Artist)",
+  "Cover Art (Conductor)", "Cover Art (Band)", "Cover Art (Composer)",
+  "Cover Art (Lyricist)", "Cover Art (Recording Location)", "Cover Art (During Recording)",
+  "Cover Art (During Performance)", "Cover Art (Video Capture)", "Cover Art (Fish)",
+  "Cover Art (Illustration)", "Cover Art (Band Logotype)", "Cover Art (Publisher Logotype)"}
+
+// apePictures returns the cover art items, whose value is a zero
+// terminated file name followed by the image
+func apePictures(t *apev2.Tag) []Picture {
+  var pictures []Picture
+  for i, key := range apeCoverKeys {
+    item := t.Get(key)
+    if item == nil || item.Type() != apev2.TypeBinary {
+      continue
+    }
+    name, data := "", item.Value
+    if n := bytes.IndexByte(item.Value, 0); n >= 0 {
+      name, data = string(item.Value[:n]), item.Value[n + 1:]
+    }
+    pictures = append(pictures, Picture{i, mimeOf(data), name, data})
+  }
+  return pictures
+}
+
+// setAPEPictures replaces the cover art items, there is one per type;
+// the description is stored as file name, cover.jpg and the like if empty
+func setAPEPictures(t *apev2.Tag, pictures []Picture) error {
+  byType := make(map[int]Picture)
+  for _, p := range pictures {
+    if p.Type < 0 || p.Type >= len(apeCoverKeys) {
+      return errors.New(fmt.Sprintf("picture type %d is not supported by apev2", p.Type))
+    }
+    if _, ok := byType[p.Type]; ok {
+      return errors.New(fmt.Sprintf("apev2 holds one picture per type, '%s' is given twice",
+        apeCoverKeys[p.Type]))
+    }
+    byType[p.Type] = p
+  }
+  for i, key := range apeCoverKeys {
+    p, ok := byType[i]
+    if !ok {
+      t.Remove(key)
+      continue
+    }
+    name := p.Description
+    if name == "" {
+      name = "cover" + ExtensionOf(p.MIME)
+    }
+    t.SetBinary(key, append(append([]byte(name), 0), p.Data...))
+  }
+  return nil
+}
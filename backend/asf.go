@@ -0,0 +1,336 @@
+package backend
+
+import (
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "strconv"
+  "strings"
+)
+
+import (
+  asf "github.com/elias-boemeke/taggo/format/asf"
+)
+
+
+
+// native backend for the content descriptions of asf files (wma)
+type asfBackend struct{}
+
+type asfFile struct {
+  path    string
+  config  Config
+  file    *asf.File
+  content *asf.Content
+}
+
+// fields of the content description object
+var asfFields = map[string]int{
+  "artist":    asf.Author,
+  "comment":   asf.Description,
+  "copyright": asf.Copyright,
+  "title":     asf.Title,
+}
+
+// names of the fields of the content description object in order
+var asfFieldNames = []string{"Title", "Author", "Copyright", "Description", "Rating"}
+
+// extended content descriptors of the other fields
+var asfAttributes = map[string]string{
+  "album":           "WM/AlbumTitle",
+  "albumartist":     "WM/AlbumArtist",
+  "albumartistsort": "WM/AlbumArtistSortOrder",
+  "albumgain":       "replaygain_album_gain",
+  "albumpeak":       "replaygain_album_peak",
+  "albumsort":       "WM/AlbumSortOrder",
+  "artistsort":      "WM/ArtistSortOrder",
+  "bpm":             "WM/BeatsPerMinute",
+  "catalog":         "WM/CatalogNo",
+  "compilation":     "WM/IsCompilation",
+  "composer":        "WM/Composer",
+  "composersort":    "WM/ComposerSortOrder",
+  "conductor":       "WM/Conductor",
+  "date":            "WM/Year",
+  "disc":            "WM/PartOfSet",
+  "disctotal":       "WM/PartOfSet",
+  "encodedby":       "WM/EncodedBy",
+  "genre":           "WM/Genre",
+  "grouping":        "WM/ContentGroupDescription",
+  "isrc":            "WM/ISRC",
+  "lyricist":        "WM/Writer",
+  "originaldate":    "WM/OriginalReleaseTime",
+  "publisher":       "WM/Publisher",
+  "titlesort":       "WM/TitleSortOrder",
+  "track":           "WM/TrackNumber",
+  "trackgain":       "replaygain_track_gain",
+  "trackpeak":       "replaygain_track_peak",
+  "tracktotal":      "TotalTracks",
+  "year":            "WM/Year",
+}
+
+func init() {
+  Register(asfBackend{}, 10)
+}
+
+func (asfBackend) Name() string {
+  return "asf"
+}
+
+func (asfBackend) Capabilities() Capabilities {
+  return Capabilities{
+    Formats: []string{"asf"},
+    Fields:  AllFields,
+    Write:   true,
+  }
+}
+
+func (asfBackend) Open(path string, config Config) (File, error) {
+  file, err := asf.ReadFile(path)
+  if err != nil {
+    return nil, err
+  }
+  content, err := file.Content()
+  if err != nil {
+    return nil, err
+  }
+  return &asfFile{path: path, config: config, file: file, content: content}, nil
+}
+
+func (f *asfFile) Fields() map[string]string {
+  return joinValues(f.FieldValues())
+}
+
+// FieldValues returns the values of the fields, attributes hold several
+// values as repeated descriptors
+func (f *asfFile) FieldValues() map[string][]string {
+  values := make(map[string][]string)
+  for key, i := range asfFields {
+    values[key] = splitValues(f.content.Fields[i])
+  }
+  for key, name := range asfAttributes {
+    values[key] = f.content.Texts(name)
+  }
+  // WM/Track is the zero based predecessor of WM/TrackNumber
+  if len(values["track"]) == 0 {
+    if n, err := strconv.Atoi(f.content.Text("WM/Track")); err == nil {
+      values["track"] = []string{strconv.Itoa(n + 1)}
+    }
+  }
+  switch FirstOf(values["compilation"]) {
+  case "true":
+    values["compilation"] = []string{"1"}
+  case "false":
+    values["compilation"] = []string{"0"}
+  }
+  values["date"] = valuesOf(FirstOf(values["date"]))
+  values["year"] = valuesOf(yearOf(FirstOf(values["year"])))
+  values["track"] = valuesOf(numberOf(FirstOf(values["track"])))
+  values["disc"] = valuesOf(numberOf(FirstOf(values["disc"])))
+  values["disctotal"] = valuesOf(totalOf(FirstOf(values["disctotal"])))
+  return values
+}
+
+func (f *asfFile) SetField(key string, value string) error {
+  return f.SetFieldValues(key, valuesOf(value))
+}
+
+// SetFieldValues writes attributes as one descriptor per value,
+// the fields of the content description get the values joined
+func (f *asfFile) SetFieldValues(key string, values []string) error {
+  value := strings.Join(values, ValueSeparator)
+  if i, ok := asfFields[key]; ok {
+    f.content.Fields[i] = value
+    return nil
+  }
+  name, ok := asfAttributes[key]
+  if !ok {
+    return errors.New(fmt.Sprintf("field '%s' is not supported by backend 'asf'", key))
+  }
+  switch key {
+  case "track", "bpm":
+    if key == "track" {
+      f.content.Remove("WM/Track")
+    }
+    if n, err := strconv.ParseUint(value, 10, 32); err == nil {
+      f.content.SetDWord(name, uint32(n))
+      return nil
+    }
+  case "compilation":
+    if value != "" {
+      f.content.SetBool(name, value != "0")
+      return nil
+    }
+  case "disc", "disctotal":
+    f.content.SetText(name, setPairPart(f.content.Text(name), key, value))
+    return nil
+  }
+  f.content.SetTexts(name, values...)
+  return nil
+}
+
+func (f *asfFile) Namespaces() []string {
+  return []string{"asf"}
+}
+
+// RawFields lists the fields of the content description object by
+// name, followed by the extended content descriptors
+func (f *asfFile) RawFields() []RawField {
+  var fields []RawField
+  for i, v := range f.content.Fields {
+    if v != "" {
+      fields = append(fields, RawField{"asf", asfFieldNames[i], v})
+    }
+  }
+  for _, d := range f.content.Descriptors {
+    fields = append(fields, RawField{"asf", d.Name, d.String()})
+  }
+  return fields
+}
+
+func (f *asfFile) RawValues(namespace string, key string) ([]string, error) {
+  if i := asfFieldIndex(key); i >= 0 {
+    if v := f.content.Fields[i]; v != "" {
+      return []string{v}, nil
+    }
+    return nil, nil
+  }
+  if d := f.content.Get(key); d != nil {
+    return []string{d.String()}, nil
+  }
+  return nil, nil
+}
+
+// SetRaw sets a field of the content description or a descriptor, which
+// keeps the type of an existing one; binary descriptors can only be removed
+func (f *asfFile) SetRaw(namespace string, key string, values []string) error {
+  if len(values) > 1 {
+    return errSingleValue("asf", key)
+  }
+  value := ""
+  if len(values) > 0 {
+    value = values[0]
+  }
+  if i := asfFieldIndex(key); i >= 0 {
+    f.content.Fields[i] = value
+    return nil
+  }
+  d := f.content.Get(key)
+  if value == "" || d == nil || d.Type == asf.TypeUnicode {
+    f.content.SetText(key, value)
+    return nil
+  }
+
+  if d.Type == asf.TypeBool {
+    b, err := strconv.ParseBool(value)
+    if err != nil {
+      return errors.New(fmt.Sprintf("asf descriptor '%s' needs true or false, got '%s'", key, value))
+    }
+    f.content.SetBool(d.Name, b)
+    return nil
+  }
+  sizes := map[uint16]int{asf.TypeWord: 2, asf.TypeDWord: 4, asf.TypeQWord: 8}
+  size, ok := sizes[d.Type]
+  if !ok {
+    return errors.New(fmt.Sprintf("asf descriptor '%s' is binary and can only be removed", key))
+  }
+  n, err := strconv.ParseUint(value, 10, size * 8)
+  if err != nil {
+    return errors.New(fmt.Sprintf("asf descriptor '%s' needs a %d bit number, got '%s'",
+      key, size * 8, value))
+  }
+  b := make([]byte, 8)
+  binary.LittleEndian.PutUint64(b, n)
+  f.content.Set(&asf.Descriptor{Name: d.Name, Type: d.Type, Value: b[:size]})
+  return nil
+}
+
+func (f *asfFile) Pictures() []Picture {
+  var pictures []Picture
+  for _, p := range f.content.Pictures() {
+    pictures = append(pictures, Picture{int(p.Type), p.MIME, p.Description, p.Data})
+  }
+  return pictures
+}
+
+// SetPictures writes WM/Picture attributes, which are limited to 64 kB
+func (f *asfFile) SetPictures(pictures []Picture) error {
+  var list []*asf.Picture
+  for _, p := range pictures {
+    list = append(list, &asf.Picture{
+      Type:        byte(p.Type),
+      MIME:        p.MIME,
+      Description: p.Description,
+      Data:        p.Data,
+    })
+  }
+  f.content.SetPictures(list)
+  return nil
+}
+
+// asfFieldIndex returns the index of a field of the content description, -1 for others
+func asfFieldIndex(name string) int {
+  for i, n := range asfFieldNames {
+    if strings.EqualFold(n, name) {
+      return i
+    }
+  }
+  return -1
+}
+
+func (f *asfFile) Properties() Properties {
+  p := f.file.Properties()
+  return Properties{
+    Length:     p.Length,
+    Bitrate:    p.Bitrate,
+    Samplerate: p.Samplerate,
+    Channels:   p.Channels,
+    Technical:  []Property{
+      {"Codec", p.Codec()},
+      {"Bits per sample", strconv.Itoa(p.BitsPerSample)},
+    },
+  }
+}
+
+// Structure lists the objects of the header, the content
+// descriptions with their fields and attributes
+func (f *asfFile) Structure() []Element {
+  elements := []Element{{
+    Name:   "Header",
+    Offset: 0,
+    Size:   f.file.HeaderSize,
+    Info:   fmt.Sprintf("%d object(s)", len(f.file.Objects)),
+  }}
+  for _, o := range f.file.Objects {
+    e := Element{Name: "  " + o.GUID.Name(), Offset: o.Offset, Size: o.Size()}
+    switch o.GUID {
+    case asf.ContentDescriptionGUID:
+      for i, v := range f.content.Fields {
+        if v != "" {
+          e.Children = append(e.Children, asfFieldNames[i] + "=" + v)
+        }
+      }
+    case asf.ExtendedContentDescriptionGUID:
+      for _, d := range f.content.Descriptors {
+        e.Children = append(e.Children, d.Name + "=" + d.String())
+      }
+    }
+    elements = append(elements, e)
+  }
+  return append(elements, Element{
+    Name:   "Data",
+    Offset: f.file.HeaderSize,
+    Size:   f.file.Size - f.file.HeaderSize,
+    Info:   "data and index objects",
+  })
+}
+
+func (f *asfFile) Save() error {
+  if err := f.file.SetContent(f.content); err != nil {
+    return err
+  }
+  return asf.WriteFile(f.path, f.file, f.config.Padding, f.config.Compact)
+}
+
+func (f *asfFile) Close() error {
+  return nil
+}
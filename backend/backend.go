@@ -0,0 +1,322 @@
+package backend
+
+import (
+  "errors"
+  "fmt"
+  "sort"
+  "strings"
+  "time"
+)
+
+import (
+  chapters "github.com/elias-boemeke/taggo/chapters"
+  detect "github.com/elias-boemeke/taggo/detect"
+)
+
+
+
+// Backend opens files of the formats it is capable of
+type Backend interface {
+  Name() string
+  Capabilities() Capabilities
+  Open(path string, config Config) (File, error)
+}
+
+// Config holds the settings of a run that influence how backends write
+type Config struct {
+  // id3v2 major version written, zero keeps the version of the file
+  ID3v2Version byte
+  // padding reserved for future edits, negative selects the format default
+  Padding int
+  // which id3 tag versions are written to mp3 files (ID3Auto, ID3V2, ...)
+  ID3Mode string
+  // give back the space of shrinking tags beyond the padding
+  Compact bool
+}
+
+// File is an opened audio file; fields are keyed by the long tag
+// names of package parse (album, artist, ...), an empty value
+// means the field is not present
+type File interface {
+  Fields() map[string]string
+  SetField(key string, value string) error
+  Properties() Properties
+  Save() error
+  Close() error
+}
+
+// MultiValued is implemented by files whose text fields can hold several
+// values, which are stored the way the format intends (repeated keys, one
+// data atom per value, ...); Fields joins them by ValueSeparator
+type MultiValued interface {
+  FieldValues() map[string][]string
+  SetFieldValues(key string, values []string) error
+}
+
+// joins multiple values where the format has room for one only
+const ValueSeparator = "; "
+
+// Layered is implemented by files carrying more than one tag,
+// each layer holds the fields of one of them
+type Layered interface {
+  Layers() []Layer
+}
+
+type Layer struct {
+  Name   string
+  Fields map[string]string
+}
+
+// Structured is implemented by files able to list the elements of their
+// container (blocks, chunks, atoms, ...) in the order of the file
+type Structured interface {
+  Structure() []Element
+}
+
+type Element struct {
+  Name   string
+  Offset int64
+  // size in bytes including headers
+  Size int64
+  Info string
+  // further details shown below the element
+  Children []string
+}
+
+// Pictured is implemented by files able to embed pictures,
+// see Picture for the values
+type Pictured interface {
+  Pictures() []Picture
+  SetPictures(pictures []Picture) error
+}
+
+// Lyrical is implemented by files able to hold lyrics
+type Lyrical interface {
+  Lyrics() []Lyrics
+  SetLyrics(lyrics []Lyrics) error
+}
+
+// Chaptered is implemented by files able to hold chapter marks; the
+// chapters given to SetChapters are sorted and carry their end
+type Chaptered interface {
+  Chapters() ([]chapters.Chapter, error)
+  SetChapters(list []chapters.Chapter) error
+}
+
+// Raw is implemented by files giving access to the native keys of their
+// tags, e.g. id3v2 frames or vorbis comments; namespaces name the tag
+// formats of the file, the first one is used for keys without namespace
+type Raw interface {
+  Namespaces() []string
+  RawFields() []RawField
+  RawValues(namespace string, key string) ([]string, error)
+  // no values remove the key
+  SetRaw(namespace string, key string, values []string) error
+}
+
+type RawField struct {
+  Namespace string
+  Key       string
+  Value     string
+}
+
+// namespaces of all tag formats, keys are prefixed by them and a colon
+var RawNamespaces = []string{"id3v2", "ape", "vorbis", "mp4", "info", "aiff",
+  "matroska", "asf"}
+
+// SplitRawKey splits key into namespace and native key; a key without one
+// of RawNamespaces in front belongs to the first namespace of the file
+func SplitRawKey(r Raw, key string) (string, string, error) {
+  i := strings.Index(key, ":")
+  if i < 0 {
+    return r.Namespaces()[0], key, nil
+  }
+  prefix := strings.ToLower(key[:i])
+  for _, known := range RawNamespaces {
+    if prefix != known {
+      continue
+    }
+    for _, ns := range r.Namespaces() {
+      if ns == prefix {
+        return ns, key[i + 1:], nil
+      }
+    }
+    return "", "", errors.New(fmt.Sprintf("namespace '%s' is not available, the file has [%s]",
+      prefix, strings.Join(r.Namespaces(), ", ")))
+  }
+  return r.Namespaces()[0], key, nil
+}
+
+// joinValues joins the values of each field by ValueSeparator
+func joinValues(values map[string][]string) map[string]string {
+  joined := make(map[string]string)
+  for k, v := range values {
+    joined[k] = strings.Join(v, ValueSeparator)
+  }
+  return joined
+}
+
+// valuesOf returns value as list, an empty value as empty list
+func valuesOf(value string) []string {
+  if value == "" {
+    return nil
+  }
+  return []string{value}
+}
+
+// splitValues splits a value joined by ValueSeparator
+// where the format has room for one only
+func splitValues(value string) []string {
+  if value == "" {
+    return nil
+  }
+  return strings.Split(value, ValueSeparator)
+}
+
+// FirstOf returns the first of values or an empty string
+func FirstOf(values []string) string {
+  if len(values) > 0 {
+    return values[0]
+  }
+  return ""
+}
+
+// errSingleValue is returned by formats whose keys hold one value only
+func errSingleValue(namespace string, key string) error {
+  return errors.New(fmt.Sprintf("%s key '%s' holds a single value", namespace, key))
+}
+
+type Capabilities struct {
+  // names of the formats (see package detect) handled by the backend,
+  // AnyFormat lets the backend act as a fallback for all formats
+  Formats []string
+  // fields the backend can write
+  Fields []string
+  Write bool
+}
+
+const AnyFormat = "*"
+
+// the fields every backend is expected to support
+var BasicFields = []string{"album", "artist", "comment", "genre", "title", "track", "year"}
+
+// the fields beyond the basic ones supported by the native backends
+var ExtendedFields = []string{"albumartist", "albumartistsort", "albumgain", "albumpeak",
+  "albumsort", "artistsort", "bpm", "catalog", "compilation", "composer", "composersort",
+  "conductor", "copyright", "date", "disc", "disctotal", "encodedby", "grouping", "isrc",
+  "lyricist", "originaldate", "publisher", "titlesort", "trackgain", "trackpeak", "tracktotal"}
+
+var AllFields = append(append([]string{}, BasicFields...), ExtendedFields...)
+
+type Properties struct {
+  Length     time.Duration
+  Bitrate    int
+  Samplerate int
+  Channels   int
+  // format specific properties listed in technical mode
+  Technical []Property
+}
+
+type Property struct {
+  Name  string
+  Value string
+}
+
+type registration struct {
+  backend  Backend
+  priority int
+}
+
+var registry []registration
+
+// Register makes a backend available to Open; when several backends
+// handle the same format, the one with the lowest priority is chosen
+func Register(b Backend, priority int) {
+  registry = append(registry, registration{b, priority})
+  sort.SliceStable(registry, func(i, j int) bool {
+    return registry[i].priority < registry[j].priority
+  })
+}
+
+// Names returns the names of all registered backends in order of priority
+func Names() []string {
+  var names []string
+  for _, r := range registry {
+    names = append(names, r.backend.Name())
+  }
+  return names
+}
+
+// Lookup returns the registered backend called name
+func Lookup(name string) (Backend, error) {
+  for _, r := range registry {
+    if r.backend.Name() == name {
+      return r.backend, nil
+    }
+  }
+  return nil, errors.New(fmt.Sprintf("unknown backend '%s', available are [%s]",
+    name, strings.Join(Names(), ", ")))
+}
+
+// Select picks a backend for the file at path by its content,
+// or by its extension if the content is not recognised
+func Select(path string) (Backend, error) {
+  format, err := detect.Sniff(path)
+  if err != nil {
+    return nil, err
+  }
+  if format == "" {
+    format = detect.ByExtension(path)
+  }
+
+  for _, r := range registry {
+    if handles(r.backend, format) {
+      return r.backend, nil
+    }
+  }
+  if format == "" {
+    return nil, errors.New("unknown file format")
+  }
+  return nil, errors.New(fmt.Sprintf("no backend available for format '%s'", format))
+}
+
+// Choose returns the backend called name, an empty name selects the
+// backend for the file at path automatically
+func Choose(path string, name string) (Backend, error) {
+  if name == "" {
+    return Select(path)
+  }
+  return Lookup(name)
+}
+
+// Open opens the file at path with the backend called name,
+// an empty name selects the backend automatically
+func Open(path string, name string, config Config) (File, error) {
+  b, err := Choose(path, name)
+  if err != nil {
+    return nil, err
+  }
+  return b.Open(path, config)
+}
+
+func handles(b Backend, format string) bool {
+  for _, f := range b.Capabilities().Formats {
+    if f == AnyFormat || (f == format && format != "") {
+      return true
+    }
+  }
+  return false
+}
+
+// CanWrite reports whether the backend is able to write field key
+func (c Capabilities) CanWrite(key string) bool {
+  if !c.Write {
+    return false
+  }
+  for _, f := range c.Fields {
+    if f == key {
+      return true
+    }
+  }
+  return false
+}
@@ -0,0 +1,115 @@
+package backend
+
+import (
+  "fmt"
+  "regexp"
+  "strings"
+  "time"
+)
+
+import (
+  chapters "github.com/elias-boemeke/taggo/chapters"
+  id3v2 "github.com/elias-boemeke/taggo/format/id3v2"
+  mp4 "github.com/elias-boemeke/taggo/format/mp4"
+  vorbis "github.com/elias-boemeke/taggo/format/vorbis"
+)
+
+
+
+// id3v2Chapters reads the CHAP frames, the title is taken from TIT2
+func id3v2Chapters(tag *id3v2.Tag) []chapters.Chapter {
+  var list []chapters.Chapter
+  for _, c := range tag.Chapters() {
+    list = append(list, chapters.Chapter{
+      Start: time.Duration(c.Start) * time.Millisecond,
+      End:   time.Duration(c.End) * time.Millisecond,
+      Title: c.Title(),
+    })
+  }
+  return list
+}
+
+// setID3v2Chapters writes a CHAP frame per chapter and a top level
+// CTOC frame listing them in order; other CTOC frames are removed
+func setID3v2Chapters(tag *id3v2.Tag, list []chapters.Chapter) error {
+  var frames []id3v2.Chapter
+  toc := id3v2.TableOfContents{ID: "toc", Flags: id3v2.TOCTopLevel | id3v2.TOCOrdered}
+  for i, c := range list {
+    chapter := id3v2.Chapter{
+      ID:          fmt.Sprintf("chp%d", i),
+      Start:       uint32(c.Start / time.Millisecond),
+      End:         uint32(c.End / time.Millisecond),
+      StartOffset: id3v2.NoOffset,
+      EndOffset:   id3v2.NoOffset,
+    }
+    if c.Title != "" {
+      chapter.SetTitle(tag.Version, c.Title)
+    }
+    frames = append(frames, chapter)
+    toc.Children = append(toc.Children, chapter.ID)
+  }
+  var tocs []id3v2.TableOfContents
+  if len(list) > 0 {
+    tocs = append(tocs, toc)
+  }
+  if err := tag.SetTablesOfContents(tocs); err != nil {
+    return err
+  }
+  return tag.SetChapters(frames)
+}
+
+// chapter starts and names of vorbis comments like CHAPTER001=00:01:02.000
+// and CHAPTER001NAME=Title
+var vorbisChapterKey = regexp.MustCompile(`^CHAPTER(\d+)(NAME|URL)?$`)
+
+// vorbisChapters reads the chapters of a comment in order of their number,
+// those with a broken start are skipped
+func vorbisChapters(c *vorbis.Comment) []chapters.Chapter {
+  var list []chapters.Chapter
+  for _, key := range c.Keys() {
+    m := vorbisChapterKey.FindStringSubmatch(key)
+    if m == nil || m[2] != "" {
+      continue
+    }
+    start, err := chapters.ParseTime(c.First(key))
+    if err != nil {
+      continue
+    }
+    list = append(list, chapters.Chapter{Start: start, Title: c.First(key + "NAME")})
+  }
+  return list
+}
+
+// setVorbisChapters replaces the chapter comments, numbered from 000
+func setVorbisChapters(c *vorbis.Comment, list []chapters.Chapter) {
+  for _, key := range c.Keys() {
+    if vorbisChapterKey.MatchString(key) {
+      c.Remove(key)
+    }
+  }
+  for i, ch := range list {
+    key := fmt.Sprintf("CHAPTER%03d", i)
+    c.Add(key, chapters.FormatTime(ch.Start))
+    if ch.Title != "" {
+      c.Add(key + "NAME", ch.Title)
+    }
+  }
+}
+
+// mp4Chapters converts chapters of the mp4 package
+func mp4Chapters(list []mp4.Chapter) []chapters.Chapter {
+  var result []chapters.Chapter
+  for _, c := range list {
+    result = append(result, chapters.Chapter{Start: c.Start, End: c.End,
+      Title: strings.TrimRight(c.Title, "\x00")})
+  }
+  return result
+}
+
+func mp4ChaptersOf(list []chapters.Chapter) []mp4.Chapter {
+  var result []mp4.Chapter
+  for _, c := range list {
+    result = append(result, mp4.Chapter{Start: c.Start, End: c.End, Title: c.Title})
+  }
+  return result
+}
@@ -0,0 +1,197 @@
+package backend
+
+import (
+  "errors"
+  "fmt"
+  "strconv"
+  "time"
+)
+
+import (
+  chapters "github.com/elias-boemeke/taggo/chapters"
+  flac "github.com/elias-boemeke/taggo/format/flac"
+  vorbis "github.com/elias-boemeke/taggo/format/vorbis"
+)
+
+
+
+// native backend for flac files and their metadata blocks
+type flacBackend struct{}
+
+type flacFile struct {
+  path    string
+  config  Config
+  meta    *flac.Metadata
+  comment *vorbis.Comment
+}
+
+func init() {
+  Register(flacBackend{}, 10)
+}
+
+func (flacBackend) Name() string {
+  return "flac"
+}
+
+func (flacBackend) Capabilities() Capabilities {
+  return Capabilities{
+    Formats: []string{"flac"},
+    Fields:  AllFields,
+    Write:   true,
+  }
+}
+
+func (flacBackend) Open(path string, config Config) (File, error) {
+  meta, err := flac.ReadFile(path)
+  if err != nil {
+    return nil, err
+  }
+  comment, err := meta.Comment()
+  if err != nil {
+    return nil, err
+  }
+  return &flacFile{path: path, config: config, meta: meta, comment: comment}, nil
+}
+
+func (f *flacFile) Fields() map[string]string {
+  return vorbisFields(f.comment)
+}
+
+func (f *flacFile) FieldValues() map[string][]string {
+  return vorbisFieldValues(f.comment)
+}
+
+func (f *flacFile) SetField(key string, value string) error {
+  return f.SetFieldValues(key, valuesOf(value))
+}
+
+func (f *flacFile) SetFieldValues(key string, values []string) error {
+  if !setVorbisValues(f.comment, key, values, false) {
+    return errors.New(fmt.Sprintf("field '%s' is not supported by backend 'flac'", key))
+  }
+  return nil
+}
+
+func (f *flacFile) Namespaces() []string {
+  return []string{"vorbis"}
+}
+
+func (f *flacFile) RawFields() []RawField {
+  return vorbisRawFields(f.comment)
+}
+
+func (f *flacFile) RawValues(namespace string, key string) ([]string, error) {
+  return f.comment.Get(key), nil
+}
+
+func (f *flacFile) SetRaw(namespace string, key string, values []string) error {
+  return setVorbisRaw(f.comment, key, values)
+}
+
+func (f *flacFile) Pictures() []Picture {
+  return flacPictures(f.meta.Pictures())
+}
+
+func (f *flacFile) SetPictures(pictures []Picture) error {
+  var blocks []*flac.PictureBlock
+  for _, p := range pictures {
+    blocks = append(blocks, flacBlock(p))
+  }
+  f.meta.SetPictures(blocks)
+  return nil
+}
+
+func (f *flacFile) Lyrics() []Lyrics {
+  return vorbisLyrics(f.comment)
+}
+
+func (f *flacFile) SetLyrics(lyrics []Lyrics) error {
+  setVorbisLyrics(f.comment, lyrics)
+  return nil
+}
+
+func (f *flacFile) Chapters() ([]chapters.Chapter, error) {
+  return vorbisChapters(f.comment), nil
+}
+
+func (f *flacFile) SetChapters(list []chapters.Chapter) error {
+  setVorbisChapters(f.comment, list)
+  return nil
+}
+
+func (f *flacFile) Properties() Properties {
+  info := f.meta.Info
+  props := Properties{
+    Samplerate: info.Samplerate,
+    Channels:   info.Channels,
+  }
+  if info.Samplerate > 0 {
+    props.Length = time.Duration(float64(info.TotalSamples) / float64(info.Samplerate) *
+      float64(time.Second))
+  }
+  if props.Length > 0 {
+    audio := f.meta.FileSize - f.meta.AudioStart
+    props.Bitrate = int(float64(audio) * 8 / props.Length.Seconds() / 1000)
+  }
+  samples := "unknown"
+  if info.TotalSamples > 0 {
+    samples = strconv.FormatInt(info.TotalSamples, 10)
+  }
+  props.Technical = []Property{
+    {"Codec", "FLAC"},
+    {"Bits per sample", strconv.Itoa(info.BitsPerSample)},
+    {"Samples", samples},
+    {"MD5", info.MD5String()},
+    {"Vendor", f.comment.Vendor},
+  }
+  return props
+}
+
+// Structure lists the metadata blocks
+func (f *flacFile) Structure() []Element {
+  var elements []Element
+  offset := f.meta.Offset + 4
+  for _, b := range f.meta.Blocks {
+    e := Element{Name: b.Type.String(), Offset: offset, Size: int64(4 + len(b.Data))}
+    switch b.Type {
+    case flac.StreamInfo:
+      i := f.meta.Info
+      e.Info = fmt.Sprintf("%d Hz, %d bit, %d channel(s), %d samples, block size %d-%d",
+        i.Samplerate, i.BitsPerSample, i.Channels, i.TotalSamples,
+        i.MinBlockSize, i.MaxBlockSize)
+    case flac.VorbisComment:
+      if c, _, err := vorbis.Decode(b.Data); err == nil {
+        e.Info = fmt.Sprintf("vendor '%s', %d comment(s)", c.Vendor, len(c.Fields))
+        e.Children = vorbisChildren(c)
+      }
+    case flac.SeekTable:
+      e.Info = fmt.Sprintf("%d seek point(s)", len(b.Data) / 18)
+    case flac.Application:
+      if len(b.Data) >= 4 {
+        e.Info = fmt.Sprintf("application id '%s'", string(b.Data[0:4]))
+      }
+    case flac.Picture:
+      if p, err := flac.DecodePicture(b.Data); err == nil {
+        e.Info = fmt.Sprintf("type %d, %s, %dx%d, %d bytes", p.Type, p.MIME,
+          p.Width, p.Height, len(p.Data))
+      }
+    }
+    elements = append(elements, e)
+    offset += e.Size
+  }
+  elements = append(elements, Element{
+    Name:   "audio frames",
+    Offset: f.meta.AudioStart,
+    Size:   f.meta.FileSize - f.meta.AudioStart,
+  })
+  return elements
+}
+
+func (f *flacFile) Save() error {
+  f.meta.SetComment(f.comment)
+  return flac.WriteFile(f.path, f.meta, f.config.Padding, f.config.Compact)
+}
+
+func (f *flacFile) Close() error {
+  return nil
+}
@@ -0,0 +1,256 @@
+package backend
+
+import (
+  "errors"
+  "fmt"
+  "regexp"
+  "strings"
+)
+
+import (
+  id3v2 "github.com/elias-boemeke/taggo/format/id3v2"
+)
+
+
+
+// id3v2 handling shared by mp3 and the id3 chunks of wav and aiff files
+
+// text frames of the fields, track and disc are handled separately
+var id3v2TextFrames = map[string]string{
+  "album":           "TALB",
+  "albumartist":     "TPE2",
+  "albumartistsort": "TSO2",
+  "albumsort":       "TSOA",
+  "artist":          "TPE1",
+  "artistsort":      "TSOP",
+  "bpm":             "TBPM",
+  "compilation":     "TCMP",
+  "composer":        "TCOM",
+  "composersort":    "TSOC",
+  "conductor":       "TPE3",
+  "copyright":       "TCOP",
+  "encodedby":       "TENC",
+  "genre":           "TCON",
+  "grouping":        "TIT1",
+  "isrc":            "TSRC",
+  "lyricist":        "TEXT",
+  "publisher":       "TPUB",
+  "title":           "TIT2",
+  "titlesort":       "TSOT",
+}
+
+// frames holding the number and total of track and disc
+var id3v2PairFrames = map[string]string{
+  "track":      "TRCK",
+  "tracktotal": "TRCK",
+  "disc":       "TPOS",
+  "disctotal":  "TPOS",
+}
+
+// user defined text frames of the fields without a frame of their own
+var id3v2UserTexts = map[string]string{
+  "albumgain": "REPLAYGAIN_ALBUM_GAIN",
+  "albumpeak": "REPLAYGAIN_ALBUM_PEAK",
+  "catalog":   "CATALOGNUMBER",
+  "trackgain": "REPLAYGAIN_TRACK_GAIN",
+  "trackpeak": "REPLAYGAIN_TRACK_PEAK",
+}
+
+// id3v2Fields returns the fields of an id3v2 tag
+func id3v2Fields(tag *id3v2.Tag) map[string]string {
+  return joinValues(id3v2FieldValues(tag))
+}
+
+// id3v2FieldValues returns the values of the fields of an id3v2 tag,
+// text frames of version 4 hold several values separated by zero bytes
+func id3v2FieldValues(tag *id3v2.Tag) map[string][]string {
+  values := make(map[string][]string)
+  for key, id := range id3v2TextFrames {
+    values[key] = tag.TextValues(id)
+  }
+  for key, description := range id3v2UserTexts {
+    values[key] = tag.UserText(description)
+  }
+  for key, id := range id3v2PairFrames {
+    if key == pairFields[key][0] {
+      values[key] = valuesOf(numberOf(tag.Text(id)))
+    } else {
+      values[key] = valuesOf(totalOf(tag.Text(id)))
+    }
+  }
+  values["comment"] = valuesOf(tag.Comment(""))
+  values["date"] = valuesOf(tag.Date())
+  values["year"] = valuesOf(yearOf(tag.Date()))
+  values["originaldate"] = valuesOf(tag.OriginalDate())
+  for i, genre := range values["genre"] {
+    values["genre"][i] = resolveGenre(genre)
+  }
+  return values
+}
+
+func setID3v2Field(tag *id3v2.Tag, key string, value string) {
+  setID3v2Values(tag, key, valuesOf(value))
+}
+
+// setID3v2Values sets the values of a field, version 3 joins
+// the values of a frame by a slash; numbers and the comment
+// take the values joined by ValueSeparator
+func setID3v2Values(tag *id3v2.Tag, key string, values []string) {
+  if id, ok := id3v2TextFrames[key]; ok {
+    tag.SetText(id, values...)
+    return
+  }
+  if description, ok := id3v2UserTexts[key]; ok {
+    tag.SetUserText(description, values...)
+    return
+  }
+  value := strings.Join(values, ValueSeparator)
+  if id, ok := id3v2PairFrames[key]; ok {
+    tag.SetText(id, setPairPart(tag.Text(id), key, value))
+    return
+  }
+  switch key {
+  case "comment":
+    tag.SetComment("eng", "", value)
+  case "date", "year":
+    tag.SetDate(value)
+  case "originaldate":
+    tag.SetOriginalDate(value)
+  }
+}
+
+// id3v2Pictures returns the pictures of the APIC frames
+func id3v2Pictures(tag *id3v2.Tag) []Picture {
+  var pictures []Picture
+  for _, p := range tag.Pictures() {
+    pictures = append(pictures, Picture{int(p.Type), p.MIME, p.Description, p.Data})
+  }
+  return pictures
+}
+
+func setID3v2Pictures(tag *id3v2.Tag, pictures []Picture) {
+  var frames []id3v2.Picture
+  for _, p := range pictures {
+    frames = append(frames, id3v2.Picture{
+      Type:        byte(p.Type),
+      MIME:        p.MIME,
+      Description: p.Description,
+      Data:        p.Data,
+    })
+  }
+  tag.SetPictures(frames)
+}
+
+var frameID = regexp.MustCompile(`^[A-Z0-9]{4}$`)
+
+// id3v2RawFields lists the frames of a tag; TXXX, WXXX and COMM frames are
+// keyed by id and description like TXXX:SOURCE, frames which are neither
+// text nor url are summarised
+func id3v2RawFields(tag *id3v2.Tag) []RawField {
+  var fields []RawField
+  for _, f := range tag.Frames {
+    add := func(key string, values ...string) {
+      for _, v := range values {
+        fields = append(fields, RawField{"id3v2", key, v})
+      }
+    }
+    switch {
+    case f.Encrypted:
+      add(f.ID, fmt.Sprintf("<encrypted, %d bytes>", len(f.Data)))
+    case len(f.Data) == 0:
+      add(f.ID, "")
+    case f.ID == "TXXX" || f.ID == "WXXX":
+      desc, rest := id3v2.SplitString(f.Data[0], f.Data[1:])
+      key := f.ID + ":" + id3v2.DecodeString(f.Data[0], desc)
+      if f.ID == "TXXX" {
+        add(key, id3v2.DecodeStrings(f.Data[0], rest)...)
+      } else {
+        add(key, id3v2.DecodeString(id3v2.EncodingISO88591, rest))
+      }
+    case strings.HasPrefix(f.ID, "T"):
+      add(f.ID, tag.DecodeValues(f.ID, f.Data[0], f.Data[1:])...)
+    case strings.HasPrefix(f.ID, "W"):
+      add(f.ID, id3v2.DecodeString(id3v2.EncodingISO88591, f.Data))
+    case f.ID == "COMM":
+      if _, desc, text, ok := id3v2.DecodeLanguageText(f.Data); ok {
+        add(commentKey(desc), text)
+      }
+    default:
+      add(f.ID, fmt.Sprintf("<binary, %d bytes>", len(f.Data)))
+    }
+  }
+  return fields
+}
+
+func commentKey(description string) string {
+  if description == "" {
+    return "COMM"
+  }
+  return "COMM:" + description
+}
+
+// setID3v2Raw sets text, url and comment frames; other
+// frames can only be removed
+func setID3v2Raw(tag *id3v2.Tag, key string, values []string) error {
+  id, desc := key, ""
+  if i := strings.Index(key, ":"); i >= 0 {
+    id, desc = key[:i], key[i + 1:]
+  }
+  if !frameID.MatchString(id) {
+    return errors.New(fmt.Sprintf("invalid id3v2 frame id '%s'", id))
+  }
+  if desc != "" && id != "TXXX" && id != "WXXX" && id != "COMM" {
+    return errors.New(fmt.Sprintf("id3v2 frame '%s' has no description", id))
+  }
+  if len(values) > 1 && !strings.HasPrefix(id, "T") {
+    return errSingleValue("id3v2", key)
+  }
+  value := ""
+  if len(values) > 0 {
+    value = values[0]
+  }
+
+  switch {
+  case id == "TXXX":
+    tag.SetUserText(desc, values...)
+  case id == "COMM":
+    tag.SetComment("eng", desc, value)
+  case id == "WXXX":
+    tag.RemoveFrames(func(f *id3v2.Frame) bool {
+      if f.ID != id || f.Encrypted || len(f.Data) == 0 {
+        return false
+      }
+      d, _ := id3v2.SplitString(f.Data[0], f.Data[1:])
+      return id3v2.DecodeString(f.Data[0], d) == desc
+    })
+    if value != "" {
+      enc := id3v2.BestEncoding(tag.Version, desc)
+      data := append([]byte{enc}, id3v2.EncodeString(enc, desc)...)
+      data = append(data, id3v2.Terminator(enc)...)
+      tag.AddFrame(id, append(data, id3v2.EncodeString(id3v2.EncodingISO88591, value)...))
+    }
+  case strings.HasPrefix(id, "T"):
+    tag.SetText(id, values...)
+  case strings.HasPrefix(id, "W"):
+    tag.RemoveID(id)
+    if value != "" {
+      tag.AddFrame(id, id3v2.EncodeString(id3v2.EncodingISO88591, value))
+    }
+  case len(values) == 0:
+    tag.RemoveID(id)
+  default:
+    return errors.New(fmt.Sprintf("id3v2 frame '%s' can only be removed", id))
+  }
+  return nil
+}
+
+// rawValues returns the values of key among fields
+func rawValues(fields []RawField, key string) []string {
+  var values []string
+  for _, f := range fields {
+    if f.Key == key {
+      values = append(values, f.Value)
+    }
+  }
+  return values
+}
@@ -0,0 +1,132 @@
+package backend
+
+import (
+  "strings"
+  "time"
+)
+
+import (
+  id3v2 "github.com/elias-boemeke/taggo/format/id3v2"
+  lyrics "github.com/elias-boemeke/taggo/lyrics"
+)
+
+
+
+type Lyrics struct {
+  // three letter language and content descriptor of id3v2,
+  // empty for formats without them
+  Language    string
+  Description string
+  // unsynchronised text
+  Text string
+  // synchronised lines, none if the lyrics have no timing
+  Lines []lyrics.Line
+}
+
+// id3v2Lyrics pairs the USLT and SYLT frames of the same language and
+// description, SYLT frames of other content or in mpeg frames are left out
+func id3v2Lyrics(tag *id3v2.Tag) []Lyrics {
+  var list []Lyrics
+  for _, c := range tag.UnsyncedLyrics() {
+    list = append(list, Lyrics{Language: c.Language, Description: c.Description, Text: c.Text})
+  }
+  for _, s := range tag.SyncedTexts() {
+    if !isSyncedLyrics(s) {
+      continue
+    }
+    var lines []lyrics.Line
+    for _, e := range s.Events {
+      // lines often start with the line break
+      lines = append(lines, lyrics.Line{
+        Time: time.Duration(e.Time) * time.Millisecond,
+        Text: strings.TrimLeft(e.Text, "\r\n"),
+      })
+    }
+    found := false
+    for i := range list {
+      if strings.EqualFold(list[i].Language, s.Language) && list[i].Description == s.Description &&
+        list[i].Lines == nil {
+        list[i].Lines, found = lines, true
+        break
+      }
+    }
+    if !found {
+      list = append(list, Lyrics{Language: s.Language, Description: s.Description,
+        Text: lyrics.Plain(lines), Lines: lines})
+    }
+  }
+  return list
+}
+
+func isSyncedLyrics(s id3v2.SyncedText) bool {
+  return s.TimeFormat == id3v2.TimeMilliseconds && s.ContentType == id3v2.ContentLyrics
+}
+
+// setID3v2Lyrics writes a USLT frame per lyrics, which takes the text of the
+// lines if there is none, and a SYLT frame for synchronised lines; SYLT frames
+// of other content are kept
+func setID3v2Lyrics(tag *id3v2.Tag, list []Lyrics) {
+  var unsynced []id3v2.Comment
+  var synced []id3v2.SyncedText
+  for _, s := range tag.SyncedTexts() {
+    if !isSyncedLyrics(s) {
+      synced = append(synced, s)
+    }
+  }
+  for _, l := range list {
+    text := l.Text
+    if text == "" {
+      text = lyrics.Plain(l.Lines)
+    }
+    unsynced = append(unsynced, id3v2.Comment{Language: l.Language, Description: l.Description,
+      Text: text})
+    if len(l.Lines) == 0 {
+      continue
+    }
+    s := id3v2.SyncedText{
+      Language:    l.Language,
+      Description: l.Description,
+      TimeFormat:  id3v2.TimeMilliseconds,
+      ContentType: id3v2.ContentLyrics,
+    }
+    for _, line := range l.Lines {
+      s.Events = append(s.Events, id3v2.SyncEvent{
+        Time: uint32(line.Time / time.Millisecond),
+        Text: line.Text,
+      })
+    }
+    synced = append(synced, s)
+  }
+  tag.SetUnsyncedLyrics(unsynced)
+  tag.SetSyncedTexts(synced)
+}
+
+// textLyrics reads lyrics of formats keeping them as text,
+// text with timestamps is taken as lrc
+func textLyrics(values []string) []Lyrics {
+  var list []Lyrics
+  for _, v := range values {
+    if !lyrics.IsLRC(v) {
+      list = append(list, Lyrics{Text: v})
+      continue
+    }
+    lrc := lyrics.Parse(v)
+    list = append(list, Lyrics{Text: lyrics.Plain(lrc.Lines), Lines: lrc.Lines})
+  }
+  return list
+}
+
+// lyricsTexts formats lyrics for formats keeping them as text,
+// synchronised lines are written as lrc
+func lyricsTexts(list []Lyrics) []string {
+  var values []string
+  for _, l := range list {
+    if len(l.Lines) > 0 {
+      lrc := &lyrics.LRC{Lines: l.Lines}
+      values = append(values, strings.TrimSuffix(lrc.String(), "\n"))
+    } else if l.Text != "" {
+      values = append(values, l.Text)
+    }
+  }
+  return values
+}
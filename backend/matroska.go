@@ -0,0 +1,270 @@
+package backend
+
+import (
+  "errors"
+  "fmt"
+  "strconv"
+  "strings"
+)
+
+import (
+  matroska "github.com/elias-boemeke/taggo/format/matroska"
+)
+
+
+
+// native backend for the Tags element of matroska and webm files
+type matroskaBackend struct{}
+
+type matroskaFile struct {
+  path string
+  file *matroska.File
+}
+
+type matroskaKey struct {
+  target int
+  name   string
+  // also read from the other target level and cleared there
+  fallback bool
+}
+
+// simple tags of the fields, the album title is the TITLE of the album level;
+// the album level counts the tracks and holds the disc number, the volume
+// level counts the discs; sort orders are nested SORT_WITH tags
+var matroskaKeys = map[string]matroskaKey{
+  "album":           {matroska.TargetAlbum, "TITLE", false},
+  "albumartist":     {matroska.TargetAlbum, "ARTIST", false},
+  "albumartistsort": {matroska.TargetAlbum, "ARTIST.SORT_WITH", false},
+  "albumgain":       {matroska.TargetAlbum, "REPLAYGAIN_GAIN", false},
+  "albumpeak":       {matroska.TargetAlbum, "REPLAYGAIN_PEAK", false},
+  "albumsort":       {matroska.TargetAlbum, "TITLE.SORT_WITH", false},
+  "artist":          {matroska.TargetTrack, "ARTIST", false},
+  "artistsort":      {matroska.TargetTrack, "ARTIST.SORT_WITH", false},
+  "bpm":             {matroska.TargetTrack, "BPM", false},
+  "catalog":         {matroska.TargetAlbum, "CATALOG_NUMBER", true},
+  "comment":         {matroska.TargetTrack, "COMMENT", true},
+  "composer":        {matroska.TargetTrack, "COMPOSER", true},
+  "composersort":    {matroska.TargetTrack, "COMPOSER.SORT_WITH", true},
+  "conductor":       {matroska.TargetTrack, "CONDUCTOR", true},
+  "copyright":       {matroska.TargetAlbum, "COPYRIGHT", true},
+  "date":            {matroska.TargetAlbum, "DATE_RELEASED", true},
+  "disc":            {matroska.TargetAlbum, "PART_NUMBER", false},
+  "disctotal":       {matroska.TargetVolume, "TOTAL_PARTS", false},
+  "encodedby":       {matroska.TargetTrack, "ENCODED_BY", true},
+  "genre":           {matroska.TargetTrack, "GENRE", true},
+  "isrc":            {matroska.TargetTrack, "ISRC", false},
+  "lyricist":        {matroska.TargetTrack, "LYRICIST", true},
+  "publisher":       {matroska.TargetAlbum, "LABEL", true},
+  "title":           {matroska.TargetTrack, "TITLE", false},
+  "titlesort":       {matroska.TargetTrack, "TITLE.SORT_WITH", false},
+  "track":           {matroska.TargetTrack, "PART_NUMBER", false},
+  "trackgain":       {matroska.TargetTrack, "REPLAYGAIN_GAIN", false},
+  "trackpeak":       {matroska.TargetTrack, "REPLAYGAIN_PEAK", false},
+  "tracktotal":      {matroska.TargetAlbum, "TOTAL_PARTS", false},
+  "year":            {matroska.TargetAlbum, "DATE_RELEASED", true},
+}
+
+func init() {
+  Register(matroskaBackend{}, 10)
+}
+
+func (matroskaBackend) Name() string {
+  return "matroska"
+}
+
+func (matroskaBackend) Capabilities() Capabilities {
+  return Capabilities{
+    Formats: []string{"matroska"},
+    // there are no official tags for compilations and groupings, the
+    // original date is a nested tag
+    Fields:  fieldsExcept("compilation", "grouping", "originaldate"),
+    Write:   true,
+  }
+}
+
+func (matroskaBackend) Open(path string, config Config) (File, error) {
+  file, err := matroska.ReadFile(path)
+  if err != nil {
+    return nil, err
+  }
+  return &matroskaFile{path: path, file: file}, nil
+}
+
+// other returns the target level a fallback is read from
+func (k matroskaKey) other() int {
+  if k.target == matroska.TargetAlbum {
+    return matroska.TargetTrack
+  }
+  return matroska.TargetAlbum
+}
+
+func (f *matroskaFile) Fields() map[string]string {
+  return joinValues(f.FieldValues())
+}
+
+// FieldValues returns the values of the fields,
+// each value is a simple tag of its own
+func (f *matroskaFile) FieldValues() map[string][]string {
+  values := make(map[string][]string)
+  for key, k := range matroskaKeys {
+    values[key] = f.file.Values(k.target, k.name)
+    if len(values[key]) == 0 && k.fallback {
+      values[key] = f.file.Values(k.other(), k.name)
+    }
+  }
+  values["date"] = valuesOf(FirstOf(values["date"]))
+  values["year"] = valuesOf(yearOf(FirstOf(values["year"])))
+  values["track"] = valuesOf(numberOf(FirstOf(values["track"])))
+  values["disc"] = valuesOf(numberOf(FirstOf(values["disc"])))
+  return values
+}
+
+func (f *matroskaFile) SetField(key string, value string) error {
+  return f.SetFieldValues(key, valuesOf(value))
+}
+
+func (f *matroskaFile) SetFieldValues(key string, values []string) error {
+  k, ok := matroskaKeys[key]
+  if !ok {
+    return errors.New(fmt.Sprintf("field '%s' is not supported by backend 'matroska'", key))
+  }
+  f.file.SetValues(k.target, k.name, values...)
+  if k.fallback {
+    f.file.SetValues(k.other(), k.name)
+  }
+  return nil
+}
+
+func (f *matroskaFile) Namespaces() []string {
+  return []string{"matroska"}
+}
+
+// RawFields lists the simple tags keyed by target level and name like 30:ARTIST,
+// parents of nested tags without a value of their own are left out
+func (f *matroskaFile) RawFields() []RawField {
+  var fields []RawField
+  for _, t := range f.file.SimpleTags() {
+    if t.Value == "" {
+      continue
+    }
+    fields = append(fields, RawField{"matroska", fmt.Sprintf("%d:%s", t.Target, t.Name), t.Value})
+  }
+  return fields
+}
+
+func (f *matroskaFile) RawValues(namespace string, key string) ([]string, error) {
+  target, name, err := splitMatroskaKey(key)
+  if err != nil {
+    return nil, err
+  }
+  return f.file.Values(target, name), nil
+}
+
+func (f *matroskaFile) SetRaw(namespace string, key string, values []string) error {
+  target, name, err := splitMatroskaKey(key)
+  if err != nil {
+    return err
+  }
+  f.file.SetValues(target, name, values...)
+  return nil
+}
+
+// splitMatroskaKey splits a key like 30:ARTIST into target level and
+// name, the album level is the default
+func splitMatroskaKey(key string) (int, string, error) {
+  target, name := matroska.TargetAlbum, key
+  if i := strings.Index(key, ":"); i >= 0 {
+    n, err := strconv.Atoi(key[:i])
+    if err != nil || n < 0 {
+      return 0, "", errors.New(fmt.Sprintf("invalid matroska target level '%s'", key[:i]))
+    }
+    target, name = n, key[i + 1:]
+  }
+  if name == "" || strings.Contains(name, "..") || strings.HasPrefix(name, ".") ||
+    strings.HasSuffix(name, ".") {
+    return 0, "", errors.New(fmt.Sprintf("invalid matroska tag name '%s'", name))
+  }
+  return target, name, nil
+}
+
+func (f *matroskaFile) Properties() Properties {
+  p := f.file.Properties()
+  props := Properties{
+    Length:     p.Length,
+    Samplerate: p.Samplerate,
+    Channels:   p.Channels,
+    Technical:  []Property{
+      {"Codec ID", p.CodecID},
+      {"Doc type", f.file.DocType},
+    },
+  }
+  if p.BitDepth > 0 {
+    props.Technical = append(props.Technical, Property{"Bits per sample", strconv.Itoa(p.BitDepth)})
+  }
+  if p.WritingApp != "" {
+    props.Technical = append(props.Technical, Property{"Writing app", p.WritingApp})
+  }
+  if p.Length > 0 {
+    props.Bitrate = int(float64(f.file.Size) * 8 / p.Length.Seconds() / 1000)
+  }
+  return props
+}
+
+var matroskaNames = map[uint32]string{
+  matroska.IDSeekHead: "SeekHead",
+  matroska.IDInfo:     "Info",
+  matroska.IDTracks:   "Tracks",
+  matroska.IDTags:     "Tags",
+  matroska.IDCluster:  "Cluster",
+  matroska.IDCues:     "Cues",
+  0xec:                "Void",
+  0x1043a770:          "Chapters",
+  0x1941a469:          "Attachments",
+}
+
+// Structure lists the top level elements of the segment, consecutive
+// clusters are summarised
+func (f *matroskaFile) Structure() []Element {
+  elements := []Element{{
+    Name:   "Segment",
+    Offset: f.file.Segment.Offset,
+    Size:   f.file.End() - f.file.Segment.Offset,
+    Info:   "doc type " + f.file.DocType,
+  }}
+  clusters := 0
+  for _, e := range f.file.Elements {
+    size := int64(e.Header) + e.Size
+    if e.ID == matroska.IDCluster {
+      if clusters == 0 {
+        elements = append(elements, Element{Name: "  Cluster", Offset: e.Offset})
+      }
+      clusters++
+      last := &elements[len(elements) - 1]
+      last.Size = e.Offset + size - last.Offset
+      last.Info = fmt.Sprintf("%d cluster(s)", clusters)
+      continue
+    }
+    clusters = 0
+
+    name, ok := matroskaNames[e.ID]
+    if !ok {
+      name = fmt.Sprintf("0x%x", e.ID)
+    }
+    el := Element{Name: "  " + name, Offset: e.Offset, Size: size}
+    if e.ID == matroska.IDTags {
+      for _, t := range f.file.SimpleTags() {
+        el.Children = append(el.Children, fmt.Sprintf("%d:%s=%s", t.Target, t.Name, t.Value))
+      }
+    }
+    elements = append(elements, el)
+  }
+  return elements
+}
+
+func (f *matroskaFile) Save() error {
+  return matroska.WriteFile(f.path, f.file)
+}
+
+func (f *matroskaFile) Close() error {
+  return nil
+}
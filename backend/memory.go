@@ -0,0 +1,97 @@
+package backend
+
+import (
+  "errors"
+  "fmt"
+)
+
+
+
+// Memory is a backend keeping files in memory, it is not registered
+// by default and meant to exercise the tag package without real files
+type Memory struct {
+  Files map[string]*MemoryFile
+}
+
+type MemoryFile struct {
+  Values map[string]string
+  Props  Properties
+  Saves  int
+  Closed bool
+  // values as of the last call to Save
+  saved map[string]string
+}
+
+func NewMemory() *Memory {
+  return &Memory{Files: make(map[string]*MemoryFile)}
+}
+
+// Add creates the in-memory file path with the given field values
+func (m *Memory) Add(path string, values map[string]string, props Properties) *MemoryFile {
+  f := &MemoryFile{Values: make(map[string]string), Props: props}
+  for k, v := range values {
+    f.Values[k] = v
+  }
+  f.saved = copyValues(f.Values)
+  m.Files[path] = f
+  return f
+}
+
+func (m *Memory) Name() string {
+  return "memory"
+}
+
+func (m *Memory) Capabilities() Capabilities {
+  return Capabilities{
+    Formats: []string{AnyFormat},
+    Fields:  AllFields,
+    Write:   true,
+  }
+}
+
+func (m *Memory) Open(path string, config Config) (File, error) {
+  f, ok := m.Files[path]
+  if !ok {
+    return nil, errors.New(fmt.Sprintf("no such file '%s'", path))
+  }
+  f.Values = copyValues(f.saved)
+  f.Closed = false
+  return f, nil
+}
+
+func (f *MemoryFile) Fields() map[string]string {
+  return copyValues(f.Values)
+}
+
+func (f *MemoryFile) SetField(key string, value string) error {
+  f.Values[key] = value
+  return nil
+}
+
+func (f *MemoryFile) Properties() Properties {
+  return f.Props
+}
+
+func (f *MemoryFile) Save() error {
+  f.saved = copyValues(f.Values)
+  f.Saves++
+  return nil
+}
+
+func (f *MemoryFile) Close() error {
+  f.Closed = true
+  return nil
+}
+
+// Saved returns the field values as of the last call to Save
+func (f *MemoryFile) Saved() map[string]string {
+  return copyValues(f.saved)
+}
+
+func copyValues(values map[string]string) map[string]string {
+  c := make(map[string]string)
+  for k, v := range values {
+    c[k] = v
+  }
+  return c
+}
@@ -0,0 +1,500 @@
+package backend
+
+import (
+  "errors"
+  "fmt"
+  "os"
+  "regexp"
+  "strconv"
+  "strings"
+)
+
+import (
+  chapters "github.com/elias-boemeke/taggo/chapters"
+  apev2 "github.com/elias-boemeke/taggo/format/apev2"
+  id3v1 "github.com/elias-boemeke/taggo/format/id3v1"
+  id3v2 "github.com/elias-boemeke/taggo/format/id3v2"
+  mpeg "github.com/elias-boemeke/taggo/format/mpeg"
+  parse "github.com/elias-boemeke/taggo/parse"
+)
+
+
+
+// native backend for MPEG audio files with id3v2, apev2 and id3v1 tags
+type mp3Backend struct{}
+
+type mp3File struct {
+  path   string
+  config Config
+  tag    *id3v2.Tag
+  hasTag bool
+  // nil if the file has no id3v1 tag
+  v1 *id3v1.Tag
+  // nil if the file has no apev2 tag, one is never created
+  ape   *apev2.Tag
+  props Properties
+}
+
+// which id3 versions are written, see Config.ID3Mode
+const (
+  ID3Auto   = ""
+  ID3V2     = "v2"
+  ID3V1     = "v1"
+  ID3Both   = "both"
+  ID3NoV1   = "no-v1"
+)
+
+// genre references of id3v2 like (17), 17 or (17)Rock
+var genreReference = regexp.MustCompile(`^\((\d+)\)(.*)$|^(\d+)$`)
+
+func init() {
+  Register(mp3Backend{}, 10)
+}
+
+func (mp3Backend) Name() string {
+  return "mp3"
+}
+
+func (mp3Backend) Capabilities() Capabilities {
+  return Capabilities{
+    Formats: []string{"mp3"},
+    Fields:  AllFields,
+    Write:   true,
+  }
+}
+
+func (mp3Backend) Open(path string, config Config) (File, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+  info, err := file.Stat()
+  if err != nil {
+    return nil, err
+  }
+
+  f := &mp3File{path: path, config: config}
+  f.tag, err = id3v2.Read(file)
+  if err == id3v2.ErrNoTag {
+    f.tag = id3v2.NewTag(4)
+  } else if err != nil {
+    return nil, err
+  }
+  f.hasTag = f.tag.Size > 0
+
+  start := int64(f.tag.Size)
+  end := info.Size()
+  if end - start >= id3v1.Size {
+    f.v1, err = id3v1.Read(file, end)
+    if err == nil {
+      end -= id3v1.Size
+    } else if err != id3v1.ErrNoTag {
+      return nil, err
+    }
+  }
+
+  f.ape, err = apev2.Read(file, end)
+  if err == nil {
+    end = f.ape.Offset
+  } else if err != apev2.ErrNoTag {
+    return nil, err
+  }
+
+  mp, err := mpeg.ReadProperties(file, start, end)
+  if err != nil {
+    return nil, errors.New(fmt.Sprintf("%s: %s", path, err))
+  }
+  f.props = mpegProperties(mp)
+  return f, nil
+}
+
+func mpegProperties(mp mpeg.Properties) Properties {
+  encoding := "CBR"
+  if mp.VBR {
+    encoding = "VBR"
+  }
+  return Properties{
+    Length:     mp.Length,
+    Bitrate:    mp.AverageBitrate,
+    Samplerate: mp.Samplerate,
+    Channels:   mp.Channels(),
+    Technical:  []Property{
+      {"Codec", mp.Header.String()},
+      {"Mode", mp.Mode.String()},
+      {"Encoding", encoding},
+    },
+  }
+}
+
+// Fields returns the values of the id3v2 tag,
+// completed by those of the apev2 and the id3v1 tag
+func (f *mp3File) Fields() map[string]string {
+  return joinValues(f.FieldValues())
+}
+
+func (f *mp3File) FieldValues() map[string][]string {
+  values := id3v2FieldValues(f.tag)
+  if f.ape != nil {
+    for k, v := range apeFieldValues(f.ape) {
+      if len(values[k]) == 0 {
+        values[k] = v
+      }
+    }
+  }
+  if f.v1 != nil {
+    for k, v := range v1Fields(f.v1) {
+      if len(values[k]) == 0 {
+        values[k] = valuesOf(v)
+      }
+    }
+  }
+  return values
+}
+
+func v1Fields(t *id3v1.Tag) map[string]string {
+  return map[string]string{
+    "album":   t.Album,
+    "artist":  t.Artist,
+    "comment": t.Comment,
+    "date":    t.Year,
+    "genre":   t.GenreName(),
+    "title":   t.Title,
+    "track":   t.TrackString(),
+    "year":    t.Year,
+  }
+}
+
+// Layers lists the id3v2, the apev2 and the id3v1 tag separately
+func (f *mp3File) Layers() []Layer {
+  var layers []Layer
+  if f.hasTag || len(f.tag.Frames) > 0 {
+    layers = append(layers, Layer{
+      Name:   fmt.Sprintf("ID3v2.%d", f.tag.Version),
+      Fields: id3v2Fields(f.tag),
+    })
+  }
+  if f.ape != nil {
+    layers = append(layers, Layer{Name: "APEv2", Fields: apeFields(f.ape)})
+  }
+  if f.v1 != nil {
+    layers = append(layers, Layer{
+      Name:   "ID3v" + f.v1.Version(),
+      Fields: v1Fields(f.v1),
+    })
+  }
+  return layers
+}
+
+func (f *mp3File) writesV2() bool {
+  return f.config.ID3Mode != ID3V1
+}
+
+func (f *mp3File) writesV1() bool {
+  switch f.config.ID3Mode {
+  case ID3V1, ID3Both:
+    return true
+  case ID3Auto:
+    return f.v1 != nil
+  default:
+    return false
+  }
+}
+
+func (f *mp3File) SetField(key string, value string) error {
+  return f.SetFieldValues(key, valuesOf(value))
+}
+
+// SetFieldValues writes all values to the id3v2 and the apev2 tag,
+// the id3v1 tag gets them joined
+func (f *mp3File) SetFieldValues(key string, values []string) error {
+  if !isField(key) {
+    return errors.New(fmt.Sprintf("field '%s' is not supported by backend 'mp3'", key))
+  }
+  if f.writesV2() {
+    setID3v2Values(f.tag, key, values)
+  }
+  if f.ape != nil {
+    setAPEValues(f.ape, key, values)
+  }
+  if f.writesV1() {
+    f.ensureV1()
+    f.setV1Field(key, strings.Join(values, ValueSeparator))
+  }
+  return nil
+}
+
+// Namespaces gives the id3v2 tag and an existing apev2 tag,
+// the id3v1 tag has fixed fields only
+func (f *mp3File) Namespaces() []string {
+  if f.ape != nil {
+    return []string{"id3v2", "ape"}
+  }
+  return []string{"id3v2"}
+}
+
+func (f *mp3File) RawFields() []RawField {
+  fields := id3v2RawFields(f.tag)
+  if f.ape != nil {
+    fields = append(fields, apeRawFields(f.ape)...)
+  }
+  return fields
+}
+
+func (f *mp3File) RawValues(namespace string, key string) ([]string, error) {
+  if namespace == "ape" {
+    return apeRawValues(f.ape, key), nil
+  }
+  return rawValues(id3v2RawFields(f.tag), key), nil
+}
+
+func (f *mp3File) SetRaw(namespace string, key string, values []string) error {
+  if namespace == "ape" {
+    return setAPERaw(f.ape, key, values)
+  }
+  return setID3v2Raw(f.tag, key, values)
+}
+
+// pictures are kept in the id3v2 tag only
+func (f *mp3File) Pictures() []Picture {
+  return id3v2Pictures(f.tag)
+}
+
+func (f *mp3File) SetPictures(pictures []Picture) error {
+  if !f.writesV2() {
+    return errors.New("pictures need an id3v2 tag, which is not written with --id3 v1")
+  }
+  setID3v2Pictures(f.tag, pictures)
+  return nil
+}
+
+// lyrics are kept in the id3v2 tag only
+func (f *mp3File) Lyrics() []Lyrics {
+  return id3v2Lyrics(f.tag)
+}
+
+func (f *mp3File) SetLyrics(lyrics []Lyrics) error {
+  if !f.writesV2() {
+    return errors.New("lyrics need an id3v2 tag, which is not written with --id3 v1")
+  }
+  setID3v2Lyrics(f.tag, lyrics)
+  return nil
+}
+
+func (f *mp3File) Chapters() ([]chapters.Chapter, error) {
+  return id3v2Chapters(f.tag), nil
+}
+
+func (f *mp3File) SetChapters(list []chapters.Chapter) error {
+  if !f.writesV2() {
+    return errors.New("chapters need an id3v2 tag, which is not written with --id3 v1")
+  }
+  return setID3v2Chapters(f.tag, list)
+}
+
+// ensureV1 creates a missing id3v1 tag from the values of the id3v2 tag
+func (f *mp3File) ensureV1() {
+  if f.v1 != nil {
+    return
+  }
+  f.v1 = id3v1.NewTag()
+  for k, v := range id3v2Fields(f.tag) {
+    f.setV1Field(k, v)
+  }
+}
+
+func (f *mp3File) setV1Field(key string, value string) {
+  switch key {
+  case "album":
+    f.v1.Album = value
+  case "artist":
+    f.v1.Artist = value
+  case "comment":
+    f.v1.Comment = value
+  case "title":
+    f.v1.Title = value
+  case "date", "year":
+    f.v1.Year = yearOf(value)
+  case "track":
+    f.v1.Track, _ = strconv.Atoi(value)
+  case "genre":
+    // id3v1 holds a single genre, the first one is taken
+    value = strings.SplitN(value, ValueSeparator, 2)[0]
+    id, ok := id3v1.GenreID(value)
+    if !ok && value != "" {
+      parse.LogWarning(fmt.Sprintf("%s: genre '%s' has no id3v1 number," +
+        " id3v1 genre is cleared", f.path, value))
+    }
+    f.v1.Genre = id
+  }
+}
+
+func (f *mp3File) Properties() Properties {
+  props := f.props
+  var tags []string
+  if f.hasTag {
+    tags = append(tags, fmt.Sprintf("2.%d.%d", f.tag.Version, f.tag.Revision))
+  }
+  if f.v1 != nil {
+    tags = append(tags, f.v1.Version())
+  }
+  if len(tags) > 0 {
+    props.Technical = append(props.Technical[:len(props.Technical):len(props.Technical)],
+      Property{"ID3", strings.Join(tags, ", ")})
+  }
+  if f.ape != nil {
+    props.Technical = append(props.Technical[:len(props.Technical):len(props.Technical)],
+      Property{"APE", apeVersion(f.ape)})
+  }
+  return props
+}
+
+func (f *mp3File) Save() error {
+  if f.writesV2() {
+    err := id3v2.WriteFile(f.path, f.tag, id3v2.WriteOptions{
+      Version: f.config.ID3v2Version,
+      Padding: f.config.Padding,
+      Compact: f.config.Compact,
+    })
+    if err != nil {
+      return err
+    }
+    f.hasTag = true
+  }
+
+  // the apev2 tag is written in front of the id3v1 tag
+  if f.ape != nil {
+    if err := apev2.WriteFile(f.path, f.ape); err != nil {
+      return err
+    }
+  }
+
+  if f.writesV1() {
+    f.ensureV1()
+    truncated, err := id3v1.WriteFile(f.path, f.v1)
+    if err != nil {
+      return err
+    }
+    if len(truncated) > 0 {
+      parse.LogWarning(fmt.Sprintf("%s: id3v1 field(s) %s truncated to fit",
+        f.path, strings.Join(truncated, ", ")))
+      // show what was actually written
+      if v1, err := id3v1.ReadFile(f.path); err == nil {
+        f.v1 = v1
+      }
+    }
+  } else if f.config.ID3Mode == ID3NoV1 && f.v1 != nil {
+    if err := id3v1.RemoveFile(f.path); err != nil {
+      return err
+    }
+    f.v1 = nil
+  }
+  return nil
+}
+
+func (f *mp3File) Close() error {
+  return nil
+}
+
+// resolveGenre replaces numeric id3v1 genre references by their names
+func resolveGenre(genre string) string {
+  m := genreReference.FindStringSubmatch(genre)
+  if m == nil {
+    return genre
+  }
+  if m[2] != "" {
+    // refinement given as text
+    return m[2]
+  }
+  n := m[1]
+  if n == "" {
+    n = m[3]
+  }
+  id, _ := strconv.Atoi(n)
+  if name := id3v1.GenreName(id); name != "" {
+    return name
+  }
+  return genre
+}
+
+func isField(key string) bool {
+  for _, f := range AllFields {
+    if f == key {
+      return true
+    }
+  }
+  return false
+}
+
+// fieldsExcept returns all fields but the given ones
+func fieldsExcept(excluded ...string) []string {
+  var fields []string
+  for _, f := range AllFields {
+    keep := true
+    for _, e := range excluded {
+      keep = keep && f != e
+    }
+    if keep {
+      fields = append(fields, f)
+    }
+  }
+  return fields
+}
+
+// yearOf returns the leading year of a date like 2021-03-05
+func yearOf(date string) string {
+  if len(date) >= 4 {
+    return date[0:4]
+  }
+  return date
+}
+
+// numberOf returns n of a value like n/total, a zero
+// in front of a total counts as no number
+func numberOf(value string) string {
+  if i := strings.Index(value, "/"); i >= 0 {
+    if n := strings.TrimSpace(value[:i]); n != "0" {
+      return n
+    }
+    return ""
+  }
+  return value
+}
+
+// totalOf returns the total of a value like n/total
+func totalOf(value string) string {
+  if i := strings.Index(value, "/"); i >= 0 {
+    return strings.TrimSpace(value[i + 1:])
+  }
+  return ""
+}
+
+// number and total field of the fields stored together as n/total
+var pairFields = map[string][2]string{
+  "track":      {"track", "tracktotal"},
+  "tracktotal": {"track", "tracktotal"},
+  "disc":       {"disc", "disctotal"},
+  "disctotal":  {"disc", "disctotal"},
+}
+
+// setPairPart returns the n/total value old with the number or the total,
+// whichever key is, replaced by value; a value holding a total
+// replaces the whole pair
+func setPairPart(old string, key string, value string) string {
+  n, total := numberOf(old), totalOf(old)
+  if key == pairFields[key][0] {
+    if strings.Contains(value, "/") {
+      return value
+    }
+    n = value
+  } else {
+    total = value
+  }
+  if total == "" {
+    return n
+  }
+  if n == "" {
+    n = "0"
+  }
+  return n + "/" + total
+}
@@ -0,0 +1,372 @@
+package backend
+
+import (
+  "errors"
+  "fmt"
+  "os"
+  "strconv"
+  "strings"
+  "unicode/utf8"
+)
+
+import (
+  chapters "github.com/elias-boemeke/taggo/chapters"
+  mp4 "github.com/elias-boemeke/taggo/format/mp4"
+)
+
+
+
+// native backend for the item list of mp4 files (m4a, m4b, mp4)
+type mp4Backend struct{}
+
+type mp4File struct {
+  path   string
+  config Config
+  file   *mp4.File
+}
+
+// text atoms of the fields, genre, numbers and pairs are handled separately
+var mp4Keys = map[string]string{
+  "album":           "©alb",
+  "albumartist":     "aART",
+  "albumartistsort": "soaa",
+  "albumgain":       mp4.Freeform + ":" + mp4.ITunes + ":replaygain_album_gain",
+  "albumpeak":       mp4.Freeform + ":" + mp4.ITunes + ":replaygain_album_peak",
+  "albumsort":       "soal",
+  "artist":          "©ART",
+  "artistsort":      "soar",
+  "catalog":         mp4.Freeform + ":" + mp4.ITunes + ":CATALOGNUMBER",
+  "comment":         "©cmt",
+  "composer":        "©wrt",
+  "composersort":    "soco",
+  "conductor":       mp4.Freeform + ":" + mp4.ITunes + ":CONDUCTOR",
+  "copyright":       "cprt",
+  "date":            "©day",
+  "encodedby":       "©too",
+  "grouping":        "©grp",
+  "isrc":            mp4.Freeform + ":" + mp4.ITunes + ":ISRC",
+  "lyricist":        mp4.Freeform + ":" + mp4.ITunes + ":LYRICIST",
+  "originaldate":    mp4.Freeform + ":" + mp4.ITunes + ":ORIGINALDATE",
+  "publisher":       mp4.Freeform + ":" + mp4.ITunes + ":LABEL",
+  "title":           "©nam",
+  "titlesort":       "sonm",
+  "trackgain":       mp4.Freeform + ":" + mp4.ITunes + ":replaygain_track_gain",
+  "trackpeak":       mp4.Freeform + ":" + mp4.ITunes + ":replaygain_track_peak",
+  "year":            "©day",
+}
+
+type mp4Integer struct {
+  atom string
+  size int
+}
+
+// integer atoms of the fields
+var mp4Integers = map[string]mp4Integer{
+  "bpm":         {"tmpo", 2},
+  "compilation": {"cpil", 1},
+}
+
+// atoms holding number and total of track and disc
+var mp4Pairs = map[string]string{
+  "track":      "trkn",
+  "tracktotal": "trkn",
+  "disc":       "disk",
+  "disctotal":  "disk",
+}
+
+func init() {
+  Register(mp4Backend{}, 10)
+}
+
+func (mp4Backend) Name() string {
+  return "mp4"
+}
+
+func (mp4Backend) Capabilities() Capabilities {
+  return Capabilities{
+    Formats: []string{"mp4"},
+    Fields:  AllFields,
+    Write:   true,
+  }
+}
+
+func (mp4Backend) Open(path string, config Config) (File, error) {
+  file, err := mp4.ReadFile(path)
+  if err != nil {
+    return nil, err
+  }
+  return &mp4File{path: path, config: config, file: file}, nil
+}
+
+func (f *mp4File) Fields() map[string]string {
+  return joinValues(f.FieldValues())
+}
+
+// FieldValues returns the values of the fields,
+// each value is a data atom of its own
+func (f *mp4File) FieldValues() map[string][]string {
+  values := make(map[string][]string)
+  for key, atom := range mp4Keys {
+    values[key] = f.file.Texts(atom)
+  }
+  for key, i := range mp4Integers {
+    values[key] = valuesOf(f.file.Text(i.atom))
+  }
+  for key, atom := range mp4Pairs {
+    if key == pairFields[key][0] {
+      values[key] = valuesOf(numberOf(f.file.PairString(atom)))
+    } else {
+      values[key] = valuesOf(totalOf(f.file.PairString(atom)))
+    }
+  }
+  values["genre"] = f.file.Genres()
+  values["date"] = valuesOf(f.file.Text(mp4Keys["date"]))
+  values["year"] = valuesOf(yearOf(f.file.Text(mp4Keys["year"])))
+  return values
+}
+
+func (f *mp4File) SetField(key string, value string) error {
+  return f.SetFieldValues(key, valuesOf(value))
+}
+
+func (f *mp4File) SetFieldValues(key string, values []string) error {
+  if key == "genre" {
+    f.file.SetGenre(values...)
+    return nil
+  }
+  value := strings.Join(values, ValueSeparator)
+  if atom, ok := mp4Pairs[key]; ok {
+    // a plain number keeps the total of the existing pair
+    return f.file.SetPairString(atom, setPairPart(f.file.PairString(atom), key, value))
+  }
+  if i, ok := mp4Integers[key]; ok {
+    if value == "" {
+      f.file.Remove(i.atom)
+      return nil
+    }
+    x, err := strconv.ParseInt(value, 10, 64)
+    if err != nil {
+      return errors.New(fmt.Sprintf("field '%s' needs a number, got '%s'", key, value))
+    }
+    f.file.SetInteger(i.atom, x, i.size)
+    return nil
+  }
+  atom, ok := mp4Keys[key]
+  if !ok {
+    return errors.New(fmt.Sprintf("field '%s' is not supported by backend 'mp4'", key))
+  }
+  f.file.SetText(atom, values...)
+  return nil
+}
+
+func (f *mp4File) Namespaces() []string {
+  return []string{"mp4"}
+}
+
+func (f *mp4File) RawFields() []RawField {
+  var fields []RawField
+  for _, item := range f.file.Items() {
+    fields = append(fields, RawField{"mp4", item.Key, item.String()})
+  }
+  return fields
+}
+
+func (f *mp4File) RawValues(namespace string, key string) ([]string, error) {
+  return f.file.Texts(key), nil
+}
+
+// SetRaw writes text items; pairs, genre and integer items are converted,
+// binary items can only be removed
+func (f *mp4File) SetRaw(namespace string, key string, values []string) error {
+  if !strings.HasPrefix(key, mp4.Freeform + ":") {
+    if utf8.RuneCountInString(key) != 4 || strings.IndexFunc(key,
+      func(r rune) bool { return r > 0xff }) >= 0 {
+      return errors.New(fmt.Sprintf("invalid mp4 atom '%s', expected 4 latin1" +
+        " characters or ----:mean:name", key))
+    }
+  } else if strings.Count(key, ":") < 2 {
+    return errors.New(fmt.Sprintf("invalid mp4 freeform key '%s', expected ----:mean:name", key))
+  }
+  if len(values) == 0 {
+    f.file.Remove(key)
+    return nil
+  }
+
+  existing := f.file.Get(key)
+  switch {
+  case key == "trkn" || key == "disk":
+    if len(values) > 1 {
+      return errSingleValue("mp4", key)
+    }
+    return f.file.SetPairString(key, values[0])
+  case key == "gnre" || key == "©gen":
+    f.file.SetGenre(values...)
+    return nil
+  case len(existing) > 0 && existing[0].Type == mp4.TypeInteger,
+    len(existing) == 0 && mp4IntegerSize(key) > 0:
+    size := mp4IntegerSize(key)
+    if len(existing) > 0 {
+      size = len(existing[0].Data)
+    }
+    var items []mp4.Item
+    for _, v := range values {
+      x, err := strconv.ParseInt(v, 10, 64)
+      if err != nil {
+        return errors.New(fmt.Sprintf("mp4 atom '%s' needs a number, got '%s'", key, v))
+      }
+      items = append(items, mp4.IntegerItem(x, size))
+    }
+    f.file.Set(key, items...)
+    return nil
+  case len(existing) > 0 && existing[0].Type != mp4.TypeUTF8 &&
+    existing[0].Type != mp4.TypeUTF16:
+    return errors.New(fmt.Sprintf("mp4 atom '%s' is binary and can only be removed", key))
+  }
+  f.file.SetText(key, values...)
+  return nil
+}
+
+// lyrics are kept as text in the ©lyr atom
+func (f *mp4File) Lyrics() []Lyrics {
+  return textLyrics(f.file.Texts("©lyr"))
+}
+
+func (f *mp4File) SetLyrics(lyrics []Lyrics) error {
+  f.file.SetText("©lyr", lyricsTexts(lyrics)...)
+  return nil
+}
+
+// mp4 data types of the pictures of the covr atom
+var mp4PictureTypes = map[uint32]string{
+  mp4.TypeGIF:  "image/gif",
+  mp4.TypeJPEG: "image/jpeg",
+  mp4.TypePNG:  "image/png",
+}
+
+// Pictures returns the images of the covr atom, which have
+// no type and description and are listed as front covers
+func (f *mp4File) Pictures() []Picture {
+  var pictures []Picture
+  for _, item := range f.file.Get("covr") {
+    mime, ok := mp4PictureTypes[item.Type]
+    if !ok {
+      mime = mimeOf(item.Data)
+    }
+    pictures = append(pictures, Picture{PictureFront, mime, "", item.Data})
+  }
+  return pictures
+}
+
+// SetPictures writes one data atom per picture to the covr atom,
+// type and description are lost
+func (f *mp4File) SetPictures(pictures []Picture) error {
+  var items []mp4.Item
+  for _, p := range pictures {
+    item := mp4.Item{Type: mp4.TypeBinary, Data: p.Data}
+    for t, mime := range mp4PictureTypes {
+      if strings.EqualFold(p.MIME, mime) {
+        item.Type = t
+      }
+    }
+    if item.Type == mp4.TypeBinary {
+      return errors.New(fmt.Sprintf("mp4 pictures must be jpeg, png or gif, not '%s'", p.MIME))
+    }
+    items = append(items, item)
+  }
+  f.file.Set("covr", items...)
+  return nil
+}
+
+// Chapters reads the chapter track, which is read by apple players,
+// else the nero chapters
+func (f *mp4File) Chapters() ([]chapters.Chapter, error) {
+  file, err := os.Open(f.path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+  list, err := f.file.TrackChapters(file)
+  if err != nil || len(list) > 0 {
+    return mp4Chapters(list), err
+  }
+  return mp4Chapters(f.file.NeroChapters()), nil
+}
+
+// SetChapters writes both a chapter track and nero chapters
+func (f *mp4File) SetChapters(list []chapters.Chapter) error {
+  if err := f.file.SetTrackChapters(mp4ChaptersOf(list)); err != nil {
+    return err
+  }
+  return f.file.SetNeroChapters(mp4ChaptersOf(list))
+}
+
+// mp4IntegerSize returns the size of the integer atoms of the fields, zero for others
+func mp4IntegerSize(atom string) int {
+  for _, i := range mp4Integers {
+    if i.atom == atom {
+      return i.size
+    }
+  }
+  return 0
+}
+
+func (f *mp4File) Properties() Properties {
+  p := f.file.Properties()
+  props := Properties{
+    Length:     p.Length,
+    Bitrate:    p.Bitrate,
+    Samplerate: p.Samplerate,
+    Channels:   p.Channels,
+  }
+  props.Technical = []Property{
+    {"Codec", p.Codec},
+    {"Brand", strings.TrimSpace(f.file.Brand)},
+  }
+  if p.BitsPerSample > 0 {
+    props.Technical = append(props.Technical,
+      Property{"Bits per sample", strconv.Itoa(p.BitsPerSample)})
+  }
+  return props
+}
+
+// Structure lists the top level boxes and the box tree of moov
+func (f *mp4File) Structure() []Element {
+  var elements []Element
+  for _, b := range f.file.Boxes {
+    if b == f.file.Moov {
+      elements = append(elements, f.boxElements(b, 0)...)
+      continue
+    }
+    e := Element{Name: b.Type, Offset: b.Offset, Size: b.Size}
+    if b.Type == "ftyp" {
+      e.Info = fmt.Sprintf("brand '%s'", f.file.Brand)
+    }
+    elements = append(elements, e)
+  }
+  return elements
+}
+
+// boxElements lists the box and its descendants indented by depth,
+// the items of ilst are shown as its children
+func (f *mp4File) boxElements(b *mp4.Box, depth int) []Element {
+  e := Element{Name: strings.Repeat("  ", depth) + b.Type, Offset: b.Offset, Size: b.Size}
+  if b.Type == "ilst" {
+    for _, item := range f.file.Items() {
+      e.Children = append(e.Children, item.Key + "=" + item.String())
+    }
+    return []Element{e}
+  }
+  elements := []Element{e}
+  for _, c := range b.Children {
+    elements = append(elements, f.boxElements(c, depth + 1)...)
+  }
+  return elements
+}
+
+func (f *mp4File) Save() error {
+  return mp4.WriteFile(f.path, f.file, f.config.Padding, f.config.Compact)
+}
+
+func (f *mp4File) Close() error {
+  return nil
+}
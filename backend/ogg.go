@@ -0,0 +1,202 @@
+package backend
+
+import (
+  "errors"
+  "fmt"
+  "strconv"
+  "time"
+)
+
+import (
+  chapters "github.com/elias-boemeke/taggo/chapters"
+  ogg "github.com/elias-boemeke/taggo/format/ogg"
+  vorbis "github.com/elias-boemeke/taggo/format/vorbis"
+)
+
+
+
+// native backend for the comment headers of Ogg Vorbis and Opus files
+type oggBackend struct{}
+
+type oggFile struct {
+  path    string
+  file    *ogg.File
+  comment *vorbis.Comment
+}
+
+func init() {
+  Register(oggBackend{}, 10)
+}
+
+func (oggBackend) Name() string {
+  return "ogg"
+}
+
+func (oggBackend) Capabilities() Capabilities {
+  return Capabilities{
+    Formats: []string{"ogg"},
+    Fields:  AllFields,
+    Write:   true,
+  }
+}
+
+func (oggBackend) Open(path string, config Config) (File, error) {
+  file, err := ogg.ReadFile(path)
+  if err != nil {
+    return nil, err
+  }
+  comment, err := file.Comment()
+  if err != nil {
+    return nil, err
+  }
+  return &oggFile{path: path, file: file, comment: comment}, nil
+}
+
+func (f *oggFile) Fields() map[string]string {
+  return vorbisFields(f.comment)
+}
+
+func (f *oggFile) FieldValues() map[string][]string {
+  return vorbisFieldValues(f.comment)
+}
+
+func (f *oggFile) SetField(key string, value string) error {
+  return f.SetFieldValues(key, valuesOf(value))
+}
+
+func (f *oggFile) SetFieldValues(key string, values []string) error {
+  if !setVorbisValues(f.comment, key, values, f.file.Codec == ogg.Opus) {
+    return errors.New(fmt.Sprintf("field '%s' is not supported by backend 'ogg'", key))
+  }
+  return nil
+}
+
+func (f *oggFile) Namespaces() []string {
+  return []string{"vorbis"}
+}
+
+func (f *oggFile) RawFields() []RawField {
+  return vorbisRawFields(f.comment)
+}
+
+func (f *oggFile) RawValues(namespace string, key string) ([]string, error) {
+  return f.comment.Get(key), nil
+}
+
+func (f *oggFile) SetRaw(namespace string, key string, values []string) error {
+  return setVorbisRaw(f.comment, key, values)
+}
+
+func (f *oggFile) Pictures() []Picture {
+  return vorbisPictures(f.comment)
+}
+
+func (f *oggFile) SetPictures(pictures []Picture) error {
+  setVorbisPictures(f.comment, pictures)
+  return nil
+}
+
+func (f *oggFile) Lyrics() []Lyrics {
+  return vorbisLyrics(f.comment)
+}
+
+func (f *oggFile) SetLyrics(lyrics []Lyrics) error {
+  setVorbisLyrics(f.comment, lyrics)
+  return nil
+}
+
+func (f *oggFile) Chapters() ([]chapters.Chapter, error) {
+  return vorbisChapters(f.comment), nil
+}
+
+func (f *oggFile) SetChapters(list []chapters.Chapter) error {
+  setVorbisChapters(f.comment, list)
+  return nil
+}
+
+func (f *oggFile) Properties() Properties {
+  var props Properties
+  samples := f.file.Samples()
+
+  if f.file.Codec == ogg.Vorbis {
+    info := f.file.Vorbis()
+    props.Samplerate = info.Samplerate
+    props.Channels = info.Channels
+    props.Technical = []Property{
+      {"Codec", "Vorbis"},
+      {"Nominal bitrate", strconv.Itoa(info.BitrateNominal / 1000)},
+    }
+  } else {
+    info := f.file.Opus()
+    props.Samplerate = ogg.OpusSamplerate
+    props.Channels = info.Channels
+    props.Technical = []Property{
+      {"Codec", "Opus"},
+      {"Input samplerate", strconv.Itoa(info.InputSamplerate)},
+      {"Pre-skip", strconv.Itoa(info.PreSkip)},
+      {"Output gain", fmt.Sprintf("%.2f dB", float64(info.OutputGain) / 256)},
+    }
+  }
+  props.Technical = append(props.Technical,
+    Property{"Samples", strconv.FormatInt(samples, 10)},
+    Property{"Vendor", f.comment.Vendor})
+
+  if props.Samplerate > 0 {
+    props.Length = time.Duration(float64(samples) / float64(props.Samplerate) *
+      float64(time.Second))
+  }
+  if props.Length > 0 {
+    audio := f.file.Size - f.file.HeaderEnd()
+    props.Bitrate = int(float64(audio) * 8 / props.Length.Seconds() / 1000)
+  }
+  return props
+}
+
+// Structure lists the header pages and a summary of the audio pages
+func (f *oggFile) Structure() []Element {
+  var elements []Element
+  audio := Element{Name: "audio pages"}
+  audioPages := 0
+  end := f.file.HeaderEnd()
+
+  for i, p := range f.file.Pages {
+    if p.Offset >= end {
+      if audioPages == 0 {
+        audio.Offset = p.Offset
+      }
+      audioPages++
+      audio.Size += int64(p.Size())
+      continue
+    }
+    e := Element{
+      Name:   fmt.Sprintf("page %d", p.Sequence),
+      Offset: p.Offset,
+      Size:   int64(p.Size()),
+      Info:   fmt.Sprintf("serial %08x, granule %d", p.Serial, p.Granule),
+    }
+    switch {
+    case i == 0:
+      e.Info += ", identification header"
+    case p.Serial == f.file.Serial && f.file.Codec == ogg.Vorbis:
+      e.Info += ", comment and setup headers"
+    case p.Serial == f.file.Serial:
+      e.Info += ", comment header"
+    }
+    elements = append(elements, e)
+  }
+
+  if len(elements) > 1 {
+    elements[1].Children = vorbisChildren(f.comment)
+  }
+  audio.Info = fmt.Sprintf("%d page(s)", audioPages)
+  return append(elements, audio)
+}
+
+func (f *oggFile) Save() error {
+  f.file.SetComment(f.comment)
+  return ogg.WriteFile(f.path, f.file)
+}
+
+func (f *oggFile) Close() error {
+  return nil
+}
@@ -0,0 +1,105 @@
+package backend
+
+import (
+  "bytes"
+  "image"
+  "image/color"
+  _ "image/gif"
+  _ "image/jpeg"
+  _ "image/png"
+  "strings"
+)
+
+import (
+  flac "github.com/elias-boemeke/taggo/format/flac"
+)
+
+
+
+// picture types shared by all formats, numbered like those of id3v2
+// APIC frames; the names are parse.PictureTypes
+const (
+  PictureOther = 0
+  PictureFront = 3
+)
+
+// mime type of data which is not a known image
+const UnknownMIME = "application/octet-stream"
+
+type Picture struct {
+  Type        int
+  MIME        string
+  Description string
+  Data        []byte
+}
+
+// ImageInfo returns the mime type and dimensions of a jpeg, png or gif image
+func ImageInfo(data []byte) (string, int, int, error) {
+  config, format, err := image.DecodeConfig(bytes.NewReader(data))
+  if err != nil {
+    return "", 0, 0, err
+  }
+  return "image/" + format, config.Width, config.Height, nil
+}
+
+// mimeOf returns the mime type of an image, UnknownMIME if it is none
+func mimeOf(data []byte) string {
+  if mime, _, _, err := ImageInfo(data); err == nil {
+    return mime
+  }
+  return UnknownMIME
+}
+
+// ExtensionOf returns the file extension of an image mime type with
+// the dot, .bin for data which is not a known image
+func ExtensionOf(mime string) string {
+  switch strings.ToLower(mime) {
+  case "image/jpeg", "image/jpg":
+    return ".jpg"
+  case "image/png":
+    return ".png"
+  case "image/gif":
+    return ".gif"
+  case "image/bmp":
+    return ".bmp"
+  }
+  return ".bin"
+}
+
+// flacPictures converts the PICTURE blocks of flac and vorbis comments
+func flacPictures(blocks []*flac.PictureBlock) []Picture {
+  var pictures []Picture
+  for _, b := range blocks {
+    pictures = append(pictures, Picture{int(b.Type), b.MIME, b.Description, b.Data})
+  }
+  return pictures
+}
+
+// flacBlock converts p to a PICTURE block, which also holds
+// the dimensions and colour depth of the image
+func flacBlock(p Picture) *flac.PictureBlock {
+  b := &flac.PictureBlock{Type: uint32(p.Type), MIME: p.MIME,
+    Description: p.Description, Data: p.Data}
+  config, _, err := image.DecodeConfig(bytes.NewReader(p.Data))
+  if err != nil {
+    return b
+  }
+  b.Width, b.Height = config.Width, config.Height
+  if palette, ok := config.ColorModel.(color.Palette); ok {
+    b.Depth, b.Colors = 8, len(palette)
+    return b
+  }
+  switch config.ColorModel {
+  case color.GrayModel:
+    b.Depth = 8
+  case color.Gray16Model:
+    b.Depth = 16
+  case color.RGBAModel, color.NRGBAModel, color.CMYKModel:
+    b.Depth = 32
+  case color.RGBA64Model, color.NRGBA64Model:
+    b.Depth = 64
+  default:
+    b.Depth = 24
+  }
+  return b
+}
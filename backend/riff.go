@@ -0,0 +1,475 @@
+package backend
+
+import (
+  "errors"
+  "fmt"
+  "strconv"
+  "strings"
+  "time"
+)
+
+import (
+  chapters "github.com/elias-boemeke/taggo/chapters"
+  id3v2 "github.com/elias-boemeke/taggo/format/id3v2"
+  riff "github.com/elias-boemeke/taggo/format/riff"
+)
+
+
+
+// native backend for wav and aiff files: INFO lists, aiff text chunks,
+// id3 chunks and the bext chunk of broadcast wave files
+type riffBackend struct{}
+
+type riffFile struct {
+  path   string
+  config Config
+  file   *riff.File
+  // nil if the file has none
+  tag  *id3v2.Tag
+  info *riff.Info
+  bext *riff.Bext
+}
+
+// INFO list ids of the fields, the others are kept in an id3 chunk
+var infoKeys = map[string]string{
+  "album":     riff.InfoAlbum,
+  "artist":    riff.InfoArtist,
+  "comment":   riff.InfoComment,
+  "copyright": riff.InfoCopyright,
+  "date":      riff.InfoDate,
+  "encodedby": riff.InfoTechnician,
+  "genre":     riff.InfoGenre,
+  "title":     riff.InfoTitle,
+  "track":     riff.InfoTrack,
+  "year":      riff.InfoDate,
+}
+
+// aiff text chunks of the fields
+var aiffKeys = map[string]string{
+  "artist":    riff.AIFFAuthor,
+  "comment":   riff.AIFFAnnotation,
+  "copyright": riff.AIFFCopyright,
+  "title":     riff.AIFFName,
+}
+
+func init() {
+  Register(riffBackend{}, 10)
+}
+
+func (riffBackend) Name() string {
+  return "riff"
+}
+
+func (riffBackend) Capabilities() Capabilities {
+  return Capabilities{
+    Formats: []string{"wav", "aiff"},
+    Fields:  AllFields,
+    Write:   true,
+  }
+}
+
+func (riffBackend) Open(path string, config Config) (File, error) {
+  file, err := riff.ReadFile(path)
+  if err != nil {
+    return nil, err
+  }
+  f := &riffFile{path: path, config: config, file: file}
+  if f.tag, err = file.ID3(); err != nil {
+    return nil, errors.New(fmt.Sprintf("id3 chunk: %s", err))
+  }
+  if f.info, err = file.Info(); err != nil {
+    return nil, err
+  }
+  if f.bext, err = file.Bext(); err != nil {
+    return nil, err
+  }
+  return f, nil
+}
+
+func (f *riffFile) isWave() bool {
+  return f.file.Form == "RIFF"
+}
+
+// Fields returns the values of the id3 chunk,
+// completed by those of the INFO list or the aiff text chunks
+func (f *riffFile) Fields() map[string]string {
+  return joinValues(f.FieldValues())
+}
+
+func (f *riffFile) FieldValues() map[string][]string {
+  values := make(map[string][]string)
+  if f.tag != nil {
+    values = id3v2FieldValues(f.tag)
+  }
+  for k, v := range f.nativeFields() {
+    if len(values[k]) == 0 {
+      values[k] = splitValues(v)
+    }
+  }
+  return values
+}
+
+func (f *riffFile) nativeFields() map[string]string {
+  values := make(map[string]string)
+  if f.isWave() {
+    if f.info != nil {
+      for key, id := range infoKeys {
+        values[key] = f.info.Get(id)
+      }
+      values["year"] = yearOf(values["year"])
+      values["track"] = numberOf(values["track"])
+    }
+  } else {
+    for key, id := range aiffKeys {
+      values[key] = f.file.Text(id)
+    }
+  }
+  return values
+}
+
+// wave files are written to the INFO list, aiff files to the id3 chunk
+// since its text chunks only hold title, artist, comment and copyright;
+// fields the INFO list has no id for go to an id3 chunk as well, which is
+// created from the INFO list if missing; an existing tag of the other kind
+// is updated too; the INFO list and the text chunks get several values joined
+func (f *riffFile) SetField(key string, value string) error {
+  return f.SetFieldValues(key, valuesOf(value))
+}
+
+func (f *riffFile) SetFieldValues(key string, values []string) error {
+  value := strings.Join(values, ValueSeparator)
+  if !isField(key) {
+    return errors.New(fmt.Sprintf("field '%s' is not supported by backend 'riff'", key))
+  }
+  _, inInfo := infoKeys[key]
+  if f.isWave() && inInfo {
+    if f.info == nil {
+      f.info = &riff.Info{}
+    }
+    f.info.Set(infoKeys[key], value)
+  } else if id, ok := aiffKeys[key]; ok && !f.isWave() && f.file.Chunk(id) != nil {
+    f.file.SetText(id, value)
+  }
+
+  if f.tag == nil && (!f.isWave() || !inInfo) {
+    f.tag = id3v2.NewTag(4)
+    if f.isWave() {
+      native := f.nativeFields()
+      for k, v := range native {
+        // both write TDRC, the year would cut the date short
+        if k == "year" && native["date"] != "" {
+          continue
+        }
+        setID3v2Field(f.tag, k, v)
+      }
+    }
+  }
+  if f.tag != nil {
+    setID3v2Values(f.tag, key, values)
+  }
+  return nil
+}
+
+// Namespaces gives the INFO list and the id3 chunk of wave files,
+// the id3 chunk and the text chunks of aiff files
+func (f *riffFile) Namespaces() []string {
+  if f.isWave() {
+    return []string{"info", "id3v2"}
+  }
+  return []string{"id3v2", "aiff"}
+}
+
+func (f *riffFile) RawFields() []RawField {
+  var fields []RawField
+  if f.isWave() && f.info != nil {
+    for _, field := range f.info.Fields {
+      fields = append(fields, RawField{"info", field.ID, field.Value})
+    }
+  }
+  if f.tag != nil {
+    fields = append(fields, id3v2RawFields(f.tag)...)
+  }
+  if !f.isWave() {
+    for _, c := range f.file.Chunks {
+      if isAIFFText(c.ID) {
+        fields = append(fields, RawField{"aiff", c.ID,
+          strings.TrimRight(string(c.Data), "\x00")})
+      }
+    }
+  }
+  return fields
+}
+
+func (f *riffFile) RawValues(namespace string, key string) ([]string, error) {
+  var values []string
+  for _, field := range f.RawFields() {
+    if field.Namespace == namespace && field.Key == key {
+      values = append(values, field.Value)
+    }
+  }
+  return values, nil
+}
+
+// SetRaw writes INFO fields, aiff text chunks and id3 frames,
+// a missing id3 chunk is created
+func (f *riffFile) SetRaw(namespace string, key string, values []string) error {
+  if namespace == "id3v2" {
+    if f.tag == nil {
+      f.tag = id3v2.NewTag(4)
+    }
+    return setID3v2Raw(f.tag, key, values)
+  }
+
+  if len(values) > 1 {
+    return errSingleValue(namespace, key)
+  }
+  value := ""
+  if len(values) > 0 {
+    value = values[0]
+  }
+  if namespace == "aiff" {
+    if !isAIFFText(key) {
+      return errors.New(fmt.Sprintf("'%s' is not an aiff text chunk, expected one of" +
+        " [%s, %s, %s, %s]", key, riff.AIFFName, riff.AIFFAuthor, riff.AIFFAnnotation,
+        riff.AIFFCopyright))
+    }
+    f.file.SetText(key, value)
+    return nil
+  }
+  if len(key) != 4 || strings.IndexFunc(key, func(r rune) bool { return r < 0x20 || r > 0x7e }) >= 0 {
+    return errors.New(fmt.Sprintf("invalid INFO id '%s', expected 4 ascii characters", key))
+  }
+  if f.info == nil {
+    f.info = &riff.Info{}
+  }
+  f.info.Set(key, value)
+  return nil
+}
+
+// pictures are kept in the id3 chunk, which is created if missing
+func (f *riffFile) Pictures() []Picture {
+  if f.tag == nil {
+    return nil
+  }
+  return id3v2Pictures(f.tag)
+}
+
+func (f *riffFile) SetPictures(pictures []Picture) error {
+  if f.tag == nil {
+    if len(pictures) == 0 {
+      return nil
+    }
+    f.tag = id3v2.NewTag(4)
+  }
+  setID3v2Pictures(f.tag, pictures)
+  return nil
+}
+
+// lyrics are kept in the id3 chunk like pictures
+func (f *riffFile) Lyrics() []Lyrics {
+  if f.tag == nil {
+    return nil
+  }
+  return id3v2Lyrics(f.tag)
+}
+
+func (f *riffFile) SetLyrics(lyrics []Lyrics) error {
+  if f.tag == nil {
+    if len(lyrics) == 0 {
+      return nil
+    }
+    f.tag = id3v2.NewTag(4)
+  }
+  setID3v2Lyrics(f.tag, lyrics)
+  return nil
+}
+
+func (f *riffFile) Chapters() ([]chapters.Chapter, error) {
+  if f.tag == nil {
+    return nil, nil
+  }
+  return id3v2Chapters(f.tag), nil
+}
+
+func (f *riffFile) SetChapters(list []chapters.Chapter) error {
+  if f.tag == nil {
+    if len(list) == 0 {
+      return nil
+    }
+    f.tag = id3v2.NewTag(4)
+  }
+  return setID3v2Chapters(f.tag, list)
+}
+
+func isAIFFText(id string) bool {
+  return id == riff.AIFFName || id == riff.AIFFAuthor || id == riff.AIFFAnnotation ||
+    id == riff.AIFFCopyright
+}
+
+func (f *riffFile) Properties() Properties {
+  var props Properties
+  if f.isWave() {
+    props = f.waveProperties()
+  } else {
+    props = f.aiffProperties()
+  }
+  if f.tag != nil {
+    props.Technical = append(props.Technical,
+      Property{"ID3", fmt.Sprintf("2.%d.%d", f.tag.Version, f.tag.Revision)})
+  }
+  return props
+}
+
+func (f *riffFile) waveProperties() Properties {
+  format, err := f.file.WaveFormat()
+  if err != nil {
+    return Properties{}
+  }
+  props := Properties{
+    Bitrate:    format.ByteRate * 8 / 1000,
+    Samplerate: format.Samplerate,
+    Channels:   format.Channels,
+    Technical:  []Property{
+      {"Format", format.Name()},
+      {"Bits per sample", strconv.Itoa(format.BitsPerSample)},
+      {"Block align", strconv.Itoa(format.BlockAlign)},
+    },
+  }
+  if audio := f.file.Audio(); audio != nil && format.ByteRate > 0 {
+    props.Length = time.Duration(float64(audio.Size) / float64(format.ByteRate) *
+      float64(time.Second))
+  }
+
+  if b := f.bext; b != nil {
+    props.Technical = append(props.Technical,
+      Property{"Originator", b.Originator},
+      Property{"Originator reference", b.OriginatorReference},
+      Property{"Origination", strings.TrimSpace(b.OriginationDate + " " + b.OriginationTime)},
+      Property{"Time reference", strconv.FormatUint(b.TimeReference, 10)})
+    if b.CodingHistory != "" {
+      history := strings.Split(strings.TrimSpace(b.CodingHistory), "\r\n")
+      props.Technical = append(props.Technical,
+        Property{"Coding history", strings.Join(history, "; ")})
+    }
+  }
+  return props
+}
+
+func (f *riffFile) aiffProperties() Properties {
+  common, err := f.file.Common()
+  if err != nil {
+    return Properties{}
+  }
+  props := Properties{
+    Samplerate: int(common.Samplerate),
+    Channels:   common.Channels,
+  }
+  if common.Samplerate > 0 {
+    props.Length = time.Duration(float64(common.Frames) / common.Samplerate *
+      float64(time.Second))
+  }
+  if props.Length > 0 && f.file.Audio() != nil {
+    props.Bitrate = int(float64(f.file.Audio().Size) * 8 / props.Length.Seconds() / 1000)
+  }
+  compression := common.Compression
+  if common.CompressionName != "" {
+    compression += " (" + common.CompressionName + ")"
+  }
+  props.Technical = []Property{
+    {"Format", compression},
+    {"Bits per sample", strconv.Itoa(common.BitsPerSample)},
+    {"Frames", strconv.FormatInt(common.Frames, 10)},
+  }
+  return props
+}
+
+// Layers lists the id3 chunk and the INFO list or aiff text chunks separately
+func (f *riffFile) Layers() []Layer {
+  var layers []Layer
+  if f.tag != nil {
+    layers = append(layers, Layer{
+      Name:   fmt.Sprintf("ID3v2.%d", f.tag.Version),
+      Fields: id3v2Fields(f.tag),
+    })
+  }
+  switch {
+  case f.isWave() && f.info != nil:
+    layers = append(layers, Layer{Name: "RIFF INFO", Fields: f.nativeFields()})
+  case !f.isWave():
+    for _, id := range aiffKeys {
+      if f.file.Chunk(id) != nil {
+        layers = append(layers, Layer{Name: "AIFF text", Fields: f.nativeFields()})
+        break
+      }
+    }
+  }
+  return layers
+}
+
+// Structure lists the chunks of the file
+func (f *riffFile) Structure() []Element {
+  elements := []Element{{
+    Name:   f.file.Form,
+    Offset: 0,
+    Size:   f.file.Size,
+    Info:   "form type " + f.file.Type,
+  }}
+  for _, c := range f.file.Chunks {
+    e := Element{Name: "  " + c.ID, Offset: c.Offset, Size: 8 + c.Size}
+    switch {
+    case c.ID == "fmt ":
+      if format, err := f.file.WaveFormat(); err == nil {
+        e.Info = fmt.Sprintf("%s, %d Hz, %d bit, %d channel(s), block align %d",
+          format.Name(), format.Samplerate, format.BitsPerSample, format.Channels,
+          format.BlockAlign)
+      }
+    case c.ID == "COMM":
+      if common, err := f.file.Common(); err == nil {
+        e.Info = fmt.Sprintf("%s, %g Hz, %d bit, %d channel(s), %d frames",
+          common.Compression, common.Samplerate, common.BitsPerSample,
+          common.Channels, common.Frames)
+      }
+    case c.ID == "LIST" && len(c.Data) >= 4:
+      e.Info = "list type " + string(c.Data[0:4])
+      if info, err := riff.DecodeInfo(c.Data); err == nil {
+        for _, field := range info.Fields {
+          e.Children = append(e.Children, field.ID + "=" + field.Value)
+        }
+      }
+    case c.ID == "id3 " || c.ID == "ID3 ":
+      if f.tag != nil {
+        e.Info = fmt.Sprintf("ID3v2.%d, %d frame(s)", f.tag.Version, len(f.tag.Frames))
+      }
+    case c.ID == "bext":
+      if f.bext != nil {
+        e.Info = fmt.Sprintf("version %d, originator '%s'", f.bext.Version,
+          f.bext.Originator)
+      }
+    case c.Data == nil:
+      e.Info = "audio"
+    case isAIFFText(c.ID):
+      e.Info = "'" + strings.TrimRight(string(c.Data), "\x00") + "'"
+    }
+    elements = append(elements, e)
+  }
+  return elements
+}
+
+func (f *riffFile) Save() error {
+  if f.tag != nil {
+    if f.config.ID3v2Version != 0 {
+      f.tag.ConvertVersion(f.config.ID3v2Version)
+    }
+    if err := f.file.SetID3(f.tag); err != nil {
+      return err
+    }
+  }
+  if f.info != nil {
+    f.file.SetInfo(f.info)
+  }
+  return riff.WriteFile(f.path, f.file)
+}
+
+func (f *riffFile) Close() error {
+  return nil
+}
@@ -0,0 +1,129 @@
+//go:build cgo
+// +build cgo
+
+package backend
+
+import (
+  "errors"
+  "fmt"
+  "strconv"
+)
+
+import (
+  taglib "github.com/wtolson/go-taglib"
+)
+
+
+
+// the generic backend provided by the taglib c library,
+// used for every format no native backend is registered for
+type taglibBackend struct{}
+
+type taglibFile struct {
+  file *taglib.File
+}
+
+func init() {
+  Register(taglibBackend{}, 100)
+}
+
+func (taglibBackend) Name() string {
+  return "taglib"
+}
+
+func (taglibBackend) Capabilities() Capabilities {
+  return Capabilities{
+    Formats: []string{AnyFormat},
+    Fields:  BasicFields,
+    Write:   true,
+  }
+}
+
+func (taglibBackend) Open(path string, config Config) (File, error) {
+  file, err := taglib.Read(path)
+  if err != nil {
+    return nil, err
+  }
+  if file == nil {
+    return nil, taglib.ErrInvalid
+  }
+  return &taglibFile{file}, nil
+}
+
+func (f *taglibFile) Fields() map[string]string {
+  strHideZero := func(n int) string {
+    if n == 0 {
+      return ""
+    }
+    return strconv.Itoa(n)
+  }
+  values := make(map[string]string)
+  values["album"]   = f.file.Album()
+  values["artist"]  = f.file.Artist()
+  values["comment"] = f.file.Comment()
+  values["genre"]   = f.file.Genre()
+  values["title"]   = f.file.Title()
+  values["track"]   = strHideZero(f.file.Track())
+  values["year"]    = strHideZero(f.file.Year())
+  return values
+}
+
+func (f *taglibFile) SetField(key string, value string) error {
+  // an empty value clears the number
+  number := func(s string) (int, error) {
+    if s == "" {
+      return 0, nil
+    }
+    n, err := strconv.Atoi(s)
+    if err != nil || n < 0 {
+      return 0, errors.New(fmt.Sprintf("field '%s' needs a number, got '%s'", key, value))
+    }
+    return n, nil
+  }
+
+  switch key {
+  case "album":
+    f.file.SetAlbum(value)
+  case "artist":
+    f.file.SetArtist(value)
+  case "comment":
+    f.file.SetComment(value)
+  case "genre":
+    f.file.SetGenre(value)
+  case "title":
+    f.file.SetTitle(value)
+  case "track":
+    n, err := number(numberOf(value))
+    if err != nil {
+      return err
+    }
+    f.file.SetTrack(n)
+  case "year":
+    n, err := number(yearOf(value))
+    if err != nil {
+      return err
+    }
+    f.file.SetYear(n)
+  default:
+    return errors.New(fmt.Sprintf("field '%s' is not supported by backend 'taglib'", key))
+  }
+  return nil
+}
+
+func (f *taglibFile) Properties() Properties {
+  return Properties{
+    Length:     f.file.Length(),
+    Bitrate:    f.file.Bitrate(),
+    Samplerate: f.file.Samplerate(),
+    Channels:   f.file.Channels(),
+  }
+}
+
+func (f *taglibFile) Save() error {
+  return f.file.Save()
+}
+
+func (f *taglibFile) Close() error {
+  f.file.Close()
+  return nil
+}
@@ -0,0 +1,225 @@
+package backend
+
+import (
+  "encoding/base64"
+  "errors"
+  "fmt"
+  "math"
+  "strconv"
+  "strings"
+)
+
+import (
+  flac "github.com/elias-boemeke/taggo/format/flac"
+  vorbis "github.com/elias-boemeke/taggo/format/vorbis"
+)
+
+
+
+// vorbis comment keys of the fields, shared by flac and ogg
+var vorbisKeys = map[string]string{
+  "album":           "ALBUM",
+  "albumartist":     "ALBUMARTIST",
+  "albumartistsort": "ALBUMARTISTSORT",
+  "albumgain":       "REPLAYGAIN_ALBUM_GAIN",
+  "albumpeak":       "REPLAYGAIN_ALBUM_PEAK",
+  "albumsort":       "ALBUMSORT",
+  "artist":          "ARTIST",
+  "artistsort":      "ARTISTSORT",
+  "bpm":             "BPM",
+  "catalog":         "CATALOGNUMBER",
+  "comment":         "COMMENT",
+  "compilation":     "COMPILATION",
+  "composer":        "COMPOSER",
+  "composersort":    "COMPOSERSORT",
+  "conductor":       "CONDUCTOR",
+  "copyright":       "COPYRIGHT",
+  "date":            "DATE",
+  "disc":            "DISCNUMBER",
+  "disctotal":       "DISCTOTAL",
+  "encodedby":       "ENCODEDBY",
+  "genre":           "GENRE",
+  "grouping":        "GROUPING",
+  "isrc":            "ISRC",
+  "lyricist":        "LYRICIST",
+  "originaldate":    "ORIGINALDATE",
+  "publisher":       "LABEL",
+  "title":           "TITLE",
+  "titlesort":       "TITLESORT",
+  "track":           "TRACKNUMBER",
+  "trackgain":       "REPLAYGAIN_TRACK_GAIN",
+  "trackpeak":       "REPLAYGAIN_TRACK_PEAK",
+  "tracktotal":      "TRACKTOTAL",
+  "year":            "DATE",
+}
+
+// keys read when the one of vorbisKeys is missing, they are removed
+// when the field is written
+var vorbisFallbacks = map[string][]string{
+  "comment":      {"DESCRIPTION"},
+  "disctotal":    {"TOTALDISCS"},
+  "originaldate": {"ORIGINALYEAR"},
+  "publisher":    {"PUBLISHER", "ORGANIZATION"},
+  "tracktotal":   {"TOTALTRACKS"},
+}
+
+// R128 gains of opus files, in steps of 1/256 dB relative to
+// -23 LUFS, written next to the REPLAYGAIN gains of -18 LUFS;
+// other files only get stale ones removed
+var vorbisR128 = map[string]string{
+  "albumgain": "R128_ALBUM_GAIN",
+  "trackgain": "R128_TRACK_GAIN",
+}
+
+// the difference between the reference loudness of ReplayGain and R128
+const r128Offset = 5.0
+
+func vorbisFields(c *vorbis.Comment) map[string]string {
+  return joinValues(vorbisFieldValues(c))
+}
+
+// vorbisFieldValues returns the values of the fields,
+// each value is a comment of its own
+func vorbisFieldValues(c *vorbis.Comment) map[string][]string {
+  values := make(map[string][]string)
+  for key, name := range vorbisKeys {
+    values[key] = c.Get(name)
+    for _, fallback := range vorbisFallbacks[key] {
+      if len(values[key]) == 0 {
+        values[key] = c.Get(fallback)
+      }
+    }
+  }
+  // totals given as n/total
+  for _, key := range []string{"track", "disc"} {
+    total := pairFields[key][1]
+    value := c.First(vorbisKeys[key])
+    if len(values[total]) == 0 {
+      values[total] = valuesOf(totalOf(value))
+    }
+    values[key] = valuesOf(numberOf(value))
+  }
+  values["date"] = valuesOf(c.First(vorbisKeys["date"]))
+  values["year"] = valuesOf(yearOf(c.First(vorbisKeys["year"])))
+  for key, name := range vorbisR128 {
+    if q, err := strconv.Atoi(c.First(name)); err == nil && len(values[key]) == 0 {
+      values[key] = valuesOf(formatGain(float64(q) / 256 + r128Offset))
+    }
+  }
+  return values
+}
+
+// formatGain formats a gain in dB the way ReplayGain tags hold it
+func formatGain(gain float64) string {
+  return fmt.Sprintf("%.2f dB", gain)
+}
+
+// parseGain reads a gain like -6.50 dB
+func parseGain(value string) (float64, bool) {
+  gain, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(
+    strings.TrimSpace(value), "dB")), 64)
+  return gain, err == nil
+}
+
+// setVorbisValues sets the comments of a field,
+// the R128 gains are written if r128 is set
+func setVorbisValues(c *vorbis.Comment, key string, values []string, r128 bool) bool {
+  name, ok := vorbisKeys[key]
+  if !ok {
+    return false
+  }
+  // number and total are kept apart, an n/total number is split
+  if pair, ok := pairFields[key]; ok {
+    fields := vorbisFields(c)
+    fields[key] = strings.Join(values, ValueSeparator)
+    n, total := fields[pair[0]], fields[pair[1]]
+    if strings.Contains(n, "/") {
+      n, total = numberOf(n), totalOf(n)
+    }
+    c.Set(vorbisKeys[pair[0]], n)
+    c.Set(vorbisKeys[pair[1]], total)
+    for _, fallback := range vorbisFallbacks[pair[1]] {
+      c.Remove(fallback)
+    }
+    return true
+  }
+  c.Set(name, values...)
+  for _, fallback := range vorbisFallbacks[key] {
+    c.Remove(fallback)
+  }
+  if gainName, ok := vorbisR128[key]; ok {
+    c.Remove(gainName)
+    if gain, ok := parseGain(strings.Join(values, ValueSeparator)); ok && r128 {
+      c.Set(gainName, strconv.Itoa(int(math.Round((gain - r128Offset) * 256))))
+    }
+  }
+  return true
+}
+
+// comments holding a base64 encoded flac PICTURE block
+const vorbisPictureKey = "METADATA_BLOCK_PICTURE"
+
+// vorbisPictures decodes the pictures of a comment, broken ones are skipped
+func vorbisPictures(c *vorbis.Comment) []Picture {
+  var blocks []*flac.PictureBlock
+  for _, v := range c.Get(vorbisPictureKey) {
+    b, err := base64.StdEncoding.DecodeString(v)
+    if err != nil {
+      continue
+    }
+    if p, err := flac.DecodePicture(b); err == nil {
+      blocks = append(blocks, p)
+    }
+  }
+  return flacPictures(blocks)
+}
+
+func setVorbisPictures(c *vorbis.Comment, pictures []Picture) {
+  var values []string
+  for _, p := range pictures {
+    values = append(values, base64.StdEncoding.EncodeToString(flacBlock(p).Encode()))
+  }
+  c.Set(vorbisPictureKey, values...)
+}
+
+// vorbisLyrics reads LYRICS and the UNSYNCEDLYRICS written by some taggers
+func vorbisLyrics(c *vorbis.Comment) []Lyrics {
+  return textLyrics(append(c.Get("LYRICS"), c.Get("UNSYNCEDLYRICS")...))
+}
+
+func setVorbisLyrics(c *vorbis.Comment, list []Lyrics) {
+  c.Set("LYRICS", lyricsTexts(list)...)
+  c.Remove("UNSYNCEDLYRICS")
+}
+
+// vorbisChildren lists the fields of a comment as children of an element
+func vorbisChildren(c *vorbis.Comment) []string {
+  var children []string
+  for _, f := range c.Fields {
+    children = append(children, f.Key + "=" + f.Value)
+  }
+  return children
+}
+
+func vorbisRawFields(c *vorbis.Comment) []RawField {
+  var fields []RawField
+  for _, f := range c.Fields {
+    fields = append(fields, RawField{"vorbis", f.Key, f.Value})
+  }
+  return fields
+}
+
+// setVorbisRaw replaces the values of key, which may be any
+// printable ascii but '='
+func setVorbisRaw(c *vorbis.Comment, key string, values []string) error {
+  if key == "" {
+    return errors.New("vorbis comment key is empty")
+  }
+  for _, r := range key {
+    if r < 0x20 || r > 0x7d || r == '=' {
+      return errors.New(fmt.Sprintf("invalid character '%c' in vorbis comment key '%s'", r, key))
+    }
+  }
+  c.Set(key, values...)
+  return nil
+}
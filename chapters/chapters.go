@@ -0,0 +1,218 @@
+package chapters
+
+import (
+  "errors"
+  "fmt"
+  "regexp"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+)
+
+
+
+type Chapter struct {
+  Start time.Duration
+  // zero where the format keeps no end
+  End   time.Duration
+  Title string
+}
+
+// Complete sorts the chapters by start and gives each one without end the
+// start of the next chapter as end, the last one ends at length
+func Complete(list []Chapter, length time.Duration) []Chapter {
+  list = append([]Chapter(nil), list...)
+  sort.SliceStable(list, func(i, j int) bool { return list[i].Start < list[j].Start })
+  for i := range list {
+    if list[i].End > list[i].Start {
+      continue
+    }
+    if i + 1 < len(list) {
+      list[i].End = list[i + 1].Start
+    } else if length > list[i].Start {
+      list[i].End = length
+    } else {
+      list[i].End = list[i].Start
+    }
+  }
+  return list
+}
+
+// times like 1:02:03.456, 02:03.4 or 3
+var timePattern = regexp.MustCompile(`^(?:(?:(\d+):)?(\d+):)?(\d+)(?:\.(\d{1,3}))?$`)
+
+// ParseTime reads a time of the form [[HH:]MM:]SS[.mmm]
+func ParseTime(s string) (time.Duration, error) {
+  m := timePattern.FindStringSubmatch(s)
+  if m == nil {
+    return 0, errors.New(fmt.Sprintf("invalid chapter time '%s'", s))
+  }
+  var t time.Duration
+  for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+    n, _ := strconv.Atoi(m[i + 1])
+    t += time.Duration(n) * unit
+  }
+  if m[4] != "" {
+    // fractions of one, two or three digits
+    frac, _ := strconv.Atoi(m[4])
+    for i := len(m[4]); i < 3; i++ {
+      frac *= 10
+    }
+    t += time.Duration(frac) * time.Millisecond
+  }
+  return t, nil
+}
+
+// FormatTime formats a time like 01:02:03.456
+func FormatTime(t time.Duration) string {
+  ms := int64(t / time.Millisecond)
+  return fmt.Sprintf("%02d:%02d:%02d.%03d", ms / 3600000, ms / 60000 % 60, ms / 1000 % 60,
+    ms % 1000)
+}
+
+// ParseText reads one chapter per line as start time and title separated
+// by white space; empty lines and lines starting with # are ignored
+func ParseText(text string) ([]Chapter, error) {
+  var list []Chapter
+  for n, line := range splitLines(text) {
+    line = strings.TrimSpace(line)
+    if line == "" || strings.HasPrefix(line, "#") {
+      continue
+    }
+    fields := []string{line}
+    if i := strings.IndexAny(line, " \t"); i >= 0 {
+      fields = []string{line[:i], strings.TrimSpace(line[i:])}
+    }
+    start, err := ParseTime(fields[0])
+    if err != nil {
+      return nil, errors.New(fmt.Sprintf("line %d: %s", n + 1, err))
+    }
+    c := Chapter{Start: start}
+    if len(fields) > 1 {
+      c.Title = fields[1]
+    }
+    list = append(list, c)
+  }
+  return list, nil
+}
+
+// Text formats the chapters for ParseText
+func Text(list []Chapter) string {
+  var b strings.Builder
+  for _, c := range list {
+    b.WriteString(FormatTime(c.Start) + " " + c.Title + "\n")
+  }
+  return b.String()
+}
+
+// first line of ffmpeg metadata files
+const FFMetadataHeader = ";FFMETADATA1"
+
+// IsFFMetadata reports whether text is an ffmpeg metadata file
+func IsFFMetadata(text string) bool {
+  return strings.HasPrefix(strings.TrimPrefix(text, "\uFEFF"), FFMetadataHeader)
+}
+
+// ParseFFMetadata reads the [CHAPTER] sections of an ffmpeg metadata file,
+// the global metadata and other sections are ignored
+func ParseFFMetadata(text string) ([]Chapter, error) {
+  var list []Chapter
+  var c *Chapter
+  var num, den int64 = 1, 1000000000
+  var start, end int64
+  finish := func() {
+    if c == nil {
+      return
+    }
+    c.Start, c.End = scale(start, num, den), scale(end, num, den)
+    list = append(list, *c)
+    c = nil
+  }
+
+  for n, line := range splitLines(text) {
+    line = strings.TrimSpace(line)
+    if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+      continue
+    }
+    if strings.HasPrefix(line, "[") {
+      finish()
+      if strings.EqualFold(line, "[CHAPTER]") {
+        c = &Chapter{}
+        // nanoseconds unless given otherwise
+        num, den, start, end = 1, 1000000000, 0, 0
+      }
+      continue
+    }
+    if c == nil {
+      continue
+    }
+    i := strings.Index(line, "=")
+    if i < 0 {
+      return nil, errors.New(fmt.Sprintf("line %d: expected KEY=VALUE", n + 1))
+    }
+    key, value := strings.ToUpper(line[:i]), unescapeFFMetadata(line[i + 1:])
+    var err error
+    switch key {
+    case "TIMEBASE":
+      _, err = fmt.Sscanf(value, "%d/%d", &num, &den)
+      if err == nil && (num <= 0 || den <= 0) {
+        err = errors.New("invalid")
+      }
+    case "START":
+      start, err = strconv.ParseInt(value, 10, 64)
+    case "END":
+      end, err = strconv.ParseInt(value, 10, 64)
+    case "TITLE":
+      c.Title = value
+    }
+    if err != nil {
+      return nil, errors.New(fmt.Sprintf("line %d: invalid %s '%s'", n + 1, key, value))
+    }
+  }
+  finish()
+  return list, nil
+}
+
+// scale converts a time in units of num/den seconds
+func scale(t int64, num int64, den int64) time.Duration {
+  t *= num
+  return time.Duration(t / den) * time.Second + time.Duration(t % den * int64(time.Second) / den)
+}
+
+// FFMetadata formats the chapters as ffmpeg metadata in milliseconds
+func FFMetadata(list []Chapter) string {
+  var b strings.Builder
+  b.WriteString(FFMetadataHeader + "\n")
+  for _, c := range list {
+    b.WriteString("\n[CHAPTER]\nTIMEBASE=1/1000\n")
+    b.WriteString(fmt.Sprintf("START=%d\nEND=%d\n", c.Start / time.Millisecond,
+      c.End / time.Millisecond))
+    b.WriteString("title=" + escapeFFMetadata(c.Title) + "\n")
+  }
+  return b.String()
+}
+
+// special characters of ffmpeg metadata are escaped by a backslash,
+// line breaks are not kept
+var ffmetadataSpecial = strings.NewReplacer("\\", "\\\\", "=", "\\=", ";", "\\;", "#", "\\#",
+  "\n", " ")
+
+func escapeFFMetadata(s string) string {
+  return ffmetadataSpecial.Replace(s)
+}
+
+func unescapeFFMetadata(s string) string {
+  var b strings.Builder
+  for i := 0; i < len(s); i++ {
+    if s[i] == '\\' && i + 1 < len(s) {
+      i++
+    }
+    b.WriteByte(s[i])
+  }
+  return b.String()
+}
+
+func splitLines(text string) []string {
+  return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
@@ -0,0 +1,97 @@
+package chapters
+
+import (
+  "reflect"
+  "testing"
+  "time"
+)
+
+
+
+func TestParseTime(t *testing.T) {
+  tests := []struct {
+    s    string
+    want time.Duration
+  }{
+    {"3", 3 * time.Second},
+    {"02:03.4", 2 * time.Minute + 3400 * time.Millisecond},
+    {"1:02:03.456", time.Hour + 2 * time.Minute + 3456 * time.Millisecond},
+  }
+  for _, tt := range tests {
+    got, err := ParseTime(tt.s)
+    if err != nil || got != tt.want {
+      t.Errorf("ParseTime('%s') = %s, %v", tt.s, got, err)
+    }
+    if back, err := ParseTime(FormatTime(got)); err != nil || back != got {
+      t.Errorf("'%s' formatted as '%s'", tt.s, FormatTime(got))
+    }
+  }
+  for _, s := range []string{"", "1:2:3:4", "1.2345", "a"} {
+    if _, err := ParseTime(s); err == nil {
+      t.Errorf("'%s' parsed", s)
+    }
+  }
+}
+
+func TestParseText(t *testing.T) {
+  list, err := ParseText("# chapters\r\n0 Intro\n\n01:30  Part one\n1:00:00\n")
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := []Chapter{
+    {Title: "Intro"},
+    {Start: 90 * time.Second, Title: "Part one"},
+    {Start: time.Hour},
+  }
+  if !reflect.DeepEqual(list, want) {
+    t.Errorf("chapters %+v", list)
+  }
+  if back, err := ParseText(Text(list)); err != nil || !reflect.DeepEqual(back, want) {
+    t.Errorf("read back %+v: %v", back, err)
+  }
+  if _, err := ParseText("0 Intro\nlater Outro"); err == nil || err.Error() !=
+      "line 2: invalid chapter time 'later'" {
+    t.Errorf("error %v", err)
+  }
+}
+
+func TestFFMetadata(t *testing.T) {
+  text := ";FFMETADATA1\ntitle=Book\n\n" +
+    "[CHAPTER]\nTIMEBASE=1/1000\nSTART=0\nEND=1500\ntitle=One\\; two\n" +
+    "[STREAM]\ntitle=ignored\n" +
+    "[CHAPTER]\nTIMEBASE=1/44100\nSTART=66150\nEND=88200\ntitle=Three\n"
+  if !IsFFMetadata("\uFEFF" + text) {
+    t.Error("not recognised")
+  }
+  list, err := ParseFFMetadata(text)
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := []Chapter{
+    {0, 1500 * time.Millisecond, "One; two"},
+    {1500 * time.Millisecond, 2 * time.Second, "Three"},
+  }
+  if !reflect.DeepEqual(list, want) {
+    t.Errorf("chapters %+v", list)
+  }
+  if back, err := ParseFFMetadata(FFMetadata(list)); err != nil || !reflect.DeepEqual(back, want) {
+    t.Errorf("read back %+v: %v", back, err)
+  }
+  if _, err := ParseFFMetadata("[CHAPTER]\nTIMEBASE=0/1"); err == nil {
+    t.Error("zero time base read")
+  }
+}
+
+func TestComplete(t *testing.T) {
+  list := Complete([]Chapter{
+    {Start: 60 * time.Second, Title: "Two"},
+    {Start: 0, End: 30 * time.Second, Title: "One"},
+  }, 90 * time.Second)
+  want := []Chapter{
+    {0, 30 * time.Second, "One"},
+    {60 * time.Second, 90 * time.Second, "Two"},
+  }
+  if !reflect.DeepEqual(list, want) {
+    t.Errorf("chapters %+v", list)
+  }
+}
@@ -0,0 +1,248 @@
+package checksum
+
+import (
+  "crypto/md5"
+  "crypto/sha256"
+  "encoding/binary"
+  "encoding/hex"
+  "errors"
+  "fmt"
+  "hash"
+  "io"
+  "os"
+)
+
+import (
+  detect "github.com/elias-boemeke/taggo/detect"
+  apev2 "github.com/elias-boemeke/taggo/format/apev2"
+  asf "github.com/elias-boemeke/taggo/format/asf"
+  ebml "github.com/elias-boemeke/taggo/format/ebml"
+  flac "github.com/elias-boemeke/taggo/format/flac"
+  id3v1 "github.com/elias-boemeke/taggo/format/id3v1"
+  id3v2 "github.com/elias-boemeke/taggo/format/id3v2"
+  matroska "github.com/elias-boemeke/taggo/format/matroska"
+  mp4 "github.com/elias-boemeke/taggo/format/mp4"
+  ogg "github.com/elias-boemeke/taggo/format/ogg"
+  riff "github.com/elias-boemeke/taggo/format/riff"
+)
+
+
+
+// Algorithms are the names of the supported hashes,
+// which are the extensions of their manifests as well
+var Algorithms = []string{"md5", "sha256"}
+
+func newHash(algorithm string) (hash.Hash, error) {
+  switch algorithm {
+  case "md5":
+    return md5.New(), nil
+  case "sha256":
+    return sha256.New(), nil
+  }
+  return nil, errors.New(fmt.Sprintf("unknown checksum algorithm '%s'", algorithm))
+}
+
+// Sum hashes the audio data of the file at path and returns the hash in
+// hex; the data is located by the container so that tags, pictures and
+// padding don't take part, editing them keeps the checksum
+func Sum(path string, algorithm string) (string, error) {
+  h, err := newHash(algorithm)
+  if err != nil {
+    return "", err
+  }
+  format, err := detect.Sniff(path)
+  if err != nil {
+    return "", err
+  }
+  file, err := os.Open(path)
+  if err != nil {
+    return "", err
+  }
+  defer file.Close()
+  info, err := file.Stat()
+  if err != nil {
+    return "", err
+  }
+
+  switch format {
+  case "mp3", "ape", "wavpack", "musepack":
+    err = streamAudio(file, info.Size(), h)
+  case "flac":
+    err = flacAudio(file, info.Size(), h)
+  case "ogg":
+    err = oggAudio(file, h)
+  case "mp4":
+    err = mp4Audio(file, info.Size(), h)
+  case "wav", "aiff":
+    err = riffAudio(file, info.Size(), h)
+  case "matroska":
+    err = matroskaAudio(file, info.Size(), h)
+  case "asf":
+    err = asfAudio(file, info.Size(), h)
+  case "":
+    err = errors.New("unknown file format")
+  default:
+    err = errors.New(fmt.Sprintf("audio data of format '%s' can't be located", format))
+  }
+  if err != nil {
+    return "", err
+  }
+  return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// copyRange writes the bytes between start and end of r to w
+func copyRange(w io.Writer, r io.ReaderAt, start int64, end int64) error {
+  if end < start {
+    return errors.New(fmt.Sprintf("audio data at offset %d has negative size", start))
+  }
+  n, err := io.Copy(w, io.NewSectionReader(r, start, end - start))
+  if err == nil && n != end - start {
+    err = errors.New(fmt.Sprintf("audio data at offset %d truncated", start))
+  }
+  return err
+}
+
+// trailingTags returns the end of the data in front of an apev2
+// and an id3v1 tag at the end of r, which ends at end
+func trailingTags(r io.ReaderAt, start int64, end int64) int64 {
+  if end - start >= id3v1.Size {
+    if _, err := id3v1.Read(r, end); err == nil {
+      end -= id3v1.Size
+    }
+  }
+  if t, err := apev2.Read(r, end); err == nil && t.Offset >= start {
+    end = t.Offset
+  }
+  return end
+}
+
+// streamAudio hashes the frames of formats without container,
+// which may be preceded by an id3v2 and followed by other tags
+func streamAudio(file *os.File, size int64, w io.Writer) error {
+  start := int64(0)
+  n, _, _, err := id3v2.ReadHeader(file)
+  if err == nil {
+    start = int64(n)
+  } else if err != id3v2.ErrNoTag {
+    return err
+  }
+  return copyRange(w, file, start, trailingTags(file, start, size))
+}
+
+// flacAudio hashes the frames behind the metadata blocks
+func flacAudio(file *os.File, size int64, w io.Writer) error {
+  m, err := flac.Read(file)
+  if err != nil {
+    return err
+  }
+  return copyRange(w, file, m.AudioStart, trailingTags(file, m.AudioStart, size))
+}
+
+// oggAudio hashes the header packets but the comment and the data of the
+// pages behind them; the pages themselves are renumbered when the
+// comment grows
+func oggAudio(file *os.File, w io.Writer) error {
+  f, err := ogg.ReadFile(file.Name())
+  if err != nil {
+    return err
+  }
+  for i, packet := range f.Headers {
+    if i != 1 {
+      w.Write(packet)
+    }
+  }
+  start := f.HeaderEnd()
+  for _, p := range f.Pages {
+    if p.Offset < start {
+      continue
+    }
+    data := p.Offset + int64(p.Size() - p.DataSize())
+    if err := copyRange(w, file, data, data + int64(p.DataSize())); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// mp4Audio hashes the mdat boxes but one of the chapter track
+func mp4Audio(file *os.File, size int64, w io.Writer) error {
+  f, err := mp4.Read(file, size)
+  if err != nil {
+    return err
+  }
+  boxes := f.MediaData()
+  if len(boxes) == 0 {
+    return errors.New("mp4 file has no mdat box")
+  }
+  for _, b := range boxes {
+    if err := copyRange(w, file, b.Offset, b.Offset + b.Size); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// riffAudio hashes the data chunk of wave and the SSND chunk of aiff files
+func riffAudio(file *os.File, size int64, w io.Writer) error {
+  f, err := riff.Read(file, size)
+  if err != nil {
+    return err
+  }
+  audio := f.Audio()
+  if audio == nil {
+    return errors.New("file has no audio chunk")
+  }
+  start := audio.Offset + 8
+  return copyRange(w, file, start, start + audio.Size)
+}
+
+// matroskaAudio hashes the clusters, one of unknown size
+// reaches to the end of the segment
+func matroskaAudio(file *os.File, size int64, w io.Writer) error {
+  f, err := matroska.Read(file, size)
+  if err != nil {
+    return err
+  }
+  clusters := 0
+  for _, e := range f.Elements {
+    if e.ID != matroska.IDCluster {
+      continue
+    }
+    clusters++
+    end := e.Offset + int64(e.Header) + e.Size
+    if e.Size == ebml.UnknownSize {
+      end = f.End()
+    }
+    if err := copyRange(w, file, e.Offset, end); err != nil {
+      return err
+    }
+  }
+  if clusters == 0 {
+    return errors.New("matroska file has no cluster")
+  }
+  return nil
+}
+
+// asfAudio hashes the data object behind the header object
+func asfAudio(file *os.File, size int64, w io.Writer) error {
+  f, err := asf.Read(file, size)
+  if err != nil {
+    return err
+  }
+  header := make([]byte, asf.ObjectHeaderSize)
+  if _, err := file.ReadAt(header, f.HeaderSize); err != nil {
+    return errors.New("asf data object missing")
+  }
+  var guid asf.GUID
+  copy(guid[:], header)
+  if guid != asf.DataGUID {
+    return errors.New("asf data object missing")
+  }
+  dataSize := int64(binary.LittleEndian.Uint64(header[16:]))
+  // streamed files may leave the size unset
+  end := f.HeaderSize + dataSize
+  if dataSize < asf.ObjectHeaderSize || end > size {
+    end = size
+  }
+  return copyRange(w, file, f.HeaderSize, end)
+}
@@ -0,0 +1,130 @@
+package checksum
+
+import (
+  "io/ioutil"
+  "path/filepath"
+  "testing"
+)
+
+import (
+  apev2 "github.com/elias-boemeke/taggo/format/apev2"
+  flac "github.com/elias-boemeke/taggo/format/flac"
+  id3v1 "github.com/elias-boemeke/taggo/format/id3v1"
+  id3v2 "github.com/elias-boemeke/taggo/format/id3v2"
+  riff "github.com/elias-boemeke/taggo/format/riff"
+)
+
+
+
+// flacFile returns a flac stream of STREAMINFO, 100 bytes of padding and frames
+func flacFile(frames string) []byte {
+  b := []byte("fLaC\x00\x00\x00\x22")
+  b = append(b, make([]byte, 34)...)
+  b = append(b, 0x81, 0, 0, 100)
+  b = append(b, make([]byte, 100)...)
+  return append(b, frames...)
+}
+
+// waveFile returns a wave file of a fmt and a data chunk
+func waveFile(data string) []byte {
+  b := []byte("RIFF\x00\x00\x00\x00WAVEfmt \x10\x00\x00\x00")
+  b = append(b, 1, 0, 1, 0, 0x44, 0xac, 0, 0, 0x88, 0x58, 0x01, 0, 2, 0, 16, 0)
+  b = append(b, "data"...)
+  b = append(b, byte(len(data)), 0, 0, 0)
+  b = append(b, data...)
+  b[4] = byte(len(b) - 8)
+  return b
+}
+
+func TestSum(t *testing.T) {
+  tests := []struct {
+    name    string
+    file    string
+    content []byte
+    other   []byte
+    // edits the metadata of the file at path
+    tag     func(path string) error
+  }{
+    {"mp3", "a.mp3", []byte("\xff\xfbframes"), []byte("\xff\xfbother"),
+      func(path string) error {
+        tg := id3v2.NewTag(4)
+        tg.SetText("TIT2", "Title")
+        if err := id3v2.WriteFile(path, tg, id3v2.WriteOptions{Padding: 100}); err != nil {
+          return err
+        }
+        ape := apev2.NewTag()
+        ape.SetText("Title", "Title")
+        if err := apev2.WriteFile(path, ape); err != nil {
+          return err
+        }
+        v1 := id3v1.NewTag()
+        v1.Title = "Title"
+        _, err := id3v1.WriteFile(path, v1)
+        return err
+      }},
+    {"flac", "a.flac", flacFile("\xff\xf8frames"), flacFile("\xff\xf8other"),
+      func(path string) error {
+        m, err := flac.ReadFile(path)
+        if err != nil {
+          return err
+        }
+        c, err := m.Comment()
+        if err != nil {
+          return err
+        }
+        c.Set("TITLE", string(make([]byte, 500)))
+        m.SetComment(c)
+        return flac.WriteFile(path, m, -1, false)
+      }},
+    {"wave", "a.wav", waveFile("samples"), waveFile("another"),
+      func(path string) error {
+        f, err := riff.ReadFile(path)
+        if err != nil {
+          return err
+        }
+        f.SetChunk("LIST", []byte("INFOINAM\x06\x00\x00\x00Title\x00"))
+        return riff.WriteFile(path, f)
+      }},
+  }
+  for _, tt := range tests {
+    dir := t.TempDir()
+    path, other := filepath.Join(dir, tt.file), filepath.Join(dir, "other" + tt.file)
+    if err := ioutil.WriteFile(path, tt.content, 0644); err != nil {
+      t.Fatal(err)
+    }
+    if err := ioutil.WriteFile(other, tt.other, 0644); err != nil {
+      t.Fatal(err)
+    }
+    for _, algorithm := range Algorithms {
+      before, err := Sum(path, algorithm)
+      if err != nil {
+        t.Fatalf("%s: %s", tt.name, err)
+      }
+      if sum, err := Sum(other, algorithm); err != nil || sum == before {
+        t.Errorf("%s: other audio has %s checksum %s: %v", tt.name, algorithm, sum, err)
+      }
+      if err := tt.tag(path); err != nil {
+        t.Fatalf("%s: %s", tt.name, err)
+      }
+      if after, err := Sum(path, algorithm); err != nil || after != before {
+        t.Errorf("%s: %s checksum %s became %s: %v", tt.name, algorithm, before, after, err)
+      }
+    }
+  }
+}
+
+func TestSumErrors(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "a.mp3")
+  if err := ioutil.WriteFile(path, []byte("\xff\xfbframes"), 0644); err != nil {
+    t.Fatal(err)
+  }
+  if _, err := Sum(path, "crc32"); err == nil {
+    t.Error("unknown algorithm used")
+  }
+  if err := ioutil.WriteFile(path, []byte("text"), 0644); err != nil {
+    t.Fatal(err)
+  }
+  if _, err := Sum(path, "md5"); err == nil {
+    t.Error("unknown format hashed")
+  }
+}
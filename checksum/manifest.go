@@ -0,0 +1,122 @@
+package checksum
+
+import (
+  "bufio"
+  "errors"
+  "fmt"
+  "os"
+  "path/filepath"
+  "regexp"
+  "strings"
+)
+
+
+
+// lines of md5sum and sha256sum, a star marks binary mode
+var manifestLine = regexp.MustCompile(`^([0-9a-fA-F]+) [ *](.+)$`)
+
+// Manifest holds the checksums of a sidecar file as written by --checksum,
+// md5sum or sha256sum; its extension names the algorithm
+type Manifest struct {
+  Algorithm string
+  // files in the order of the manifest, relative paths are taken from
+  // the working directory like by md5sum -c and as --checksum prints them
+  Files []string
+  sums  map[string]string
+}
+
+// Line returns the manifest line of the checksum of the file at path,
+// the format of md5sum and sha256sum
+func Line(sum string, path string) string {
+  return sum + "  " + path
+}
+
+// ReadManifest reads the manifest at path
+func ReadManifest(path string) (*Manifest, error) {
+  m := &Manifest{
+    Algorithm: strings.ToLower(strings.TrimPrefix(filepath.Ext(path), ".")),
+    sums:      make(map[string]string),
+  }
+  h, err := newHash(m.Algorithm)
+  if err != nil {
+    return nil, errors.New(fmt.Sprintf("manifest '%s' needs one of the extensions .%s",
+      path, strings.Join(Algorithms, ", .")))
+  }
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+
+  scanner := bufio.NewScanner(file)
+  for n := 1; scanner.Scan(); n++ {
+    line := strings.TrimRight(scanner.Text(), "\r")
+    if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+      continue
+    }
+    match := manifestLine.FindStringSubmatch(line)
+    if match == nil || len(match[1]) != 2 * h.Size() {
+      return nil, errors.New(fmt.Sprintf("manifest '%s' line %d is no %s checksum line",
+        path, n, m.Algorithm))
+    }
+    name := match[2]
+    key, err := filepath.Abs(name)
+    if err != nil {
+      return nil, err
+    }
+    if _, ok := m.sums[key]; !ok {
+      m.Files = append(m.Files, name)
+    }
+    m.sums[key] = strings.ToLower(match[1])
+  }
+  if err := scanner.Err(); err != nil {
+    return nil, err
+  }
+  return m, nil
+}
+
+// Lookup returns the checksum of the file at path, false if it isn't listed
+func (m *Manifest) Lookup(path string) (string, bool) {
+  key, err := filepath.Abs(path)
+  if err != nil {
+    return "", false
+  }
+  sum, ok := m.sums[key]
+  return sum, ok
+}
+
+// Verify compares the checksum of the file at path with the manifest
+func (m *Manifest) Verify(path string) error {
+  expected, ok := m.Lookup(path)
+  if !ok {
+    return errors.New("not listed in the manifest")
+  }
+  sum, err := Sum(path, m.Algorithm)
+  if err != nil {
+    return err
+  }
+  if sum != expected {
+    return errors.New(fmt.Sprintf("audio differs from the manifest, %s checksum is %s", m.Algorithm, sum))
+  }
+  return nil
+}
+
+// Missing returns the files of the manifest which are not among files
+// and don't exist either
+func (m *Manifest) Missing(files []string) []string {
+  given := make(map[string]bool)
+  for _, f := range files {
+    if key, err := filepath.Abs(f); err == nil {
+      given[key] = true
+    }
+  }
+  var missing []string
+  for _, f := range m.Files {
+    if key, err := filepath.Abs(f); err == nil && !given[key] {
+      if _, err := os.Stat(f); err != nil {
+        missing = append(missing, f)
+      }
+    }
+  }
+  return missing
+}
@@ -0,0 +1,70 @@
+package checksum
+
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+
+
+// the manifest printed by --checksum into the album directory, as in
+// taggo -R album --checksum md5 > album/album.md5
+func TestManifestRoundTrip(t *testing.T) {
+  wd, err := os.Getwd()
+  if err != nil {
+    t.Fatal(err)
+  }
+  if err := os.Chdir(t.TempDir()); err != nil {
+    t.Fatal(err)
+  }
+  defer os.Chdir(wd)
+
+  if err := os.Mkdir("album", 0755); err != nil {
+    t.Fatal(err)
+  }
+  files := []string{filepath.Join("album", "a.wav"), filepath.Join("album", "b.wav")}
+  var lines []string
+  for i, f := range files {
+    if err := ioutil.WriteFile(f, waveFile(strings.Repeat("s", i + 1)), 0644); err != nil {
+      t.Fatal(err)
+    }
+    sum, err := Sum(f, "md5")
+    if err != nil {
+      t.Fatal(err)
+    }
+    lines = append(lines, Line(sum, f))
+  }
+  path := filepath.Join("album", "album.md5")
+  if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n") + "\n"), 0644); err != nil {
+    t.Fatal(err)
+  }
+
+  m, err := ReadManifest(path)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if missing := m.Missing(nil); len(missing) != 0 {
+    t.Errorf("files %q missing", missing)
+  }
+  for _, f := range m.Files {
+    if err := m.Verify(f); err != nil {
+      t.Errorf("%s: %s", f, err)
+    }
+  }
+
+  if err := ioutil.WriteFile(files[1], waveFile("other"), 0644); err != nil {
+    t.Fatal(err)
+  }
+  if err := m.Verify(files[1]); err == nil {
+    t.Error("changed audio verified")
+  }
+  if err := os.Remove(files[0]); err != nil {
+    t.Fatal(err)
+  }
+  if missing := m.Missing(nil); len(missing) != 1 || missing[0] != files[0] {
+    t.Errorf("files %q missing, want %q", missing, files[:1])
+  }
+}
@@ -0,0 +1,119 @@
+package cover
+
+import (
+  "bytes"
+  "image"
+  "image/draw"
+  _ "image/gif"
+  "image/jpeg"
+  "image/png"
+)
+
+
+
+// a jpeg which is not resized is only recompressed if this saves at
+// least a tenth, recompressing again and again would only lose quality
+const minSaving = 10
+
+// Normalise shrinks an image so that none of its sides exceeds maxSize
+// and encodes it as jpeg of the given quality if that is smaller, images
+// with transparency stay png; the new data and its mime type are returned,
+// ok is false if data is no jpeg, png or gif image or is kept as it is
+func Normalise(data []byte, maxSize int, quality int) ([]byte, string, bool) {
+  img, format, err := image.Decode(bytes.NewReader(data))
+  if err != nil {
+    return nil, "", false
+  }
+  resized := false
+  if b := img.Bounds(); b.Dx() > maxSize || b.Dy() > maxSize {
+    img = Resize(img, maxSize)
+    resized = true
+  }
+
+  // without resizing the original competes as well
+  var best []byte
+  mime := ""
+  if !resized {
+    best = data
+  }
+  if format == "jpeg" || opaque(img) {
+    var buf bytes.Buffer
+    if jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}) == nil &&
+      smaller(buf.Len(), best, format == "jpeg" && !resized) {
+      best, mime = buf.Bytes(), "image/jpeg"
+    }
+  }
+  if resized && format != "jpeg" {
+    var buf bytes.Buffer
+    if png.Encode(&buf, img) == nil && (best == nil || buf.Len() < len(best)) {
+      best, mime = buf.Bytes(), "image/png"
+    }
+  }
+  if mime == "" {
+    return nil, "", false
+  }
+  return best, mime, true
+}
+
+// smaller reports whether n bytes beat best, by minSaving percent if demanded
+func smaller(n int, best []byte, demand bool) bool {
+  switch {
+  case best == nil:
+    return true
+  case demand:
+    return n * 100 <= len(best) * (100 - minSaving)
+  }
+  return n < len(best)
+}
+
+func opaque(img image.Image) bool {
+  if o, ok := img.(interface{ Opaque() bool }); ok {
+    return o.Opaque()
+  }
+  return false
+}
+
+// Resize scales img down to fit into a square of size pixels keeping its
+// aspect ratio, each pixel is the average of the pixels it covers
+func Resize(img image.Image, size int) *image.RGBA {
+  b := img.Bounds()
+  w, h := size, size
+  if b.Dx() > b.Dy() {
+    h = max(1, b.Dy() * size / b.Dx())
+  } else {
+    w = max(1, b.Dx() * size / b.Dy())
+  }
+
+  src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+  draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+  dst := image.NewRGBA(image.Rect(0, 0, w, h))
+  for y := 0; y < h; y++ {
+    y0, y1 := y * b.Dy() / h, max((y + 1) * b.Dy() / h, y * b.Dy() / h + 1)
+    for x := 0; x < w; x++ {
+      x0, x1 := x * b.Dx() / w, max((x + 1) * b.Dx() / w, x * b.Dx() / w + 1)
+      var sum [4]int
+      for sy := y0; sy < y1; sy++ {
+        row := src.Pix[sy * src.Stride + x0 * 4:sy * src.Stride + x1 * 4]
+        for i := 0; i < len(row); i += 4 {
+          sum[0] += int(row[i])
+          sum[1] += int(row[i + 1])
+          sum[2] += int(row[i + 2])
+          sum[3] += int(row[i + 3])
+        }
+      }
+      n := (y1 - y0) * (x1 - x0)
+      p := dst.Pix[y * dst.Stride + x * 4:]
+      for i := range sum {
+        p[i] = uint8(sum[i] / n)
+      }
+    }
+  }
+  return dst
+}
+
+func max(a int, b int) int {
+  if a > b {
+    return a
+  }
+  return b
+}
@@ -0,0 +1,94 @@
+package cover
+
+import (
+  "bytes"
+  "image"
+  "image/color"
+  "image/jpeg"
+  "image/png"
+  "testing"
+)
+
+
+
+// picture returns an image of the given size whose left half is red and
+// right half is blue, with the given alpha
+func picture(w int, h int, alpha uint8) *image.NRGBA {
+  img := image.NewNRGBA(image.Rect(0, 0, w, h))
+  seed := uint32(1)
+  for y := 0; y < h; y++ {
+    for x := 0; x < w; x++ {
+      c := color.NRGBA{R: 255, A: alpha}
+      if x >= w / 2 {
+        c = color.NRGBA{B: 255, A: alpha}
+      }
+      // noise keeps png from compressing well, like in photos
+      seed = seed * 1103515245 + 12345
+      c.G = uint8(seed >> 16)
+      img.SetNRGBA(x, y, c)
+    }
+  }
+  return img
+}
+
+func encode(t *testing.T, img image.Image, format string) []byte {
+  var buf bytes.Buffer
+  var err error
+  if format == "png" {
+    err = png.Encode(&buf, img)
+  } else {
+    err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+  }
+  if err != nil {
+    t.Fatal(err)
+  }
+  return buf.Bytes()
+}
+
+func TestResize(t *testing.T) {
+  resized := Resize(picture(400, 200, 255), 100)
+  if b := resized.Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+    t.Fatalf("resized to %v", b)
+  }
+  left, right := resized.RGBAAt(10, 10), resized.RGBAAt(90, 10)
+  if left.R != 255 || left.B != 0 || right.R != 0 || right.B != 255 {
+    t.Errorf("colors %v and %v", left, right)
+  }
+  if b := Resize(picture(10, 400, 255), 100).Bounds(); b.Dx() != 2 || b.Dy() != 100 {
+    t.Errorf("portrait resized to %v", b)
+  }
+}
+
+func TestNormalise(t *testing.T) {
+  tests := []struct {
+    name string
+    data []byte
+    // zero if the image is kept
+    size int
+    mime string
+  }{
+    {"opaque png", encode(t, picture(600, 400, 255), "png"), 300, "image/jpeg"},
+    {"transparent png", encode(t, picture(600, 400, 128), "png"), 300, "image/png"},
+    {"large jpeg", encode(t, picture(400, 600, 255), "jpeg"), 300, "image/jpeg"},
+    {"small jpeg", encode(t, picture(200, 100, 255), "jpeg"), 0, ""},
+    {"no image", []byte("text"), 0, ""},
+  }
+  for _, tt := range tests {
+    data, mime, ok := Normalise(tt.data, 300, 90)
+    if ok != (tt.size > 0) || mime != tt.mime {
+      t.Errorf("%s: normalised %v to '%s'", tt.name, ok, mime)
+      continue
+    }
+    if !ok {
+      continue
+    }
+    img, _, err := image.Decode(bytes.NewReader(data))
+    if err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+    if b := img.Bounds(); b.Dx() != tt.size && b.Dy() != tt.size || b.Dx() > tt.size ||
+        b.Dy() > tt.size {
+      t.Errorf("%s: normalised to %v", tt.name, b)
+    }
+  }
+}
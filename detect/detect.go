@@ -0,0 +1,168 @@
+package detect
+
+import (
+  "bytes"
+  "io"
+  "os"
+  "path/filepath"
+  "strings"
+)
+
+
+
+type format struct {
+  Name       string
+  Extensions []string
+  match      func(head []byte) bool
+}
+
+// number of bytes read from the beginning of a file for sniffing
+const headSize = 64
+
+var asfGUID = []byte{
+  0x30, 0x26, 0xb2, 0x75, 0x8e, 0x66, 0xcf, 0x11,
+  0xa6, 0xd9, 0x00, 0xaa, 0x00, 0x62, 0xce, 0x6c,
+}
+
+var formats = [...]format {
+  {"mp3",      []string{".mp3", ".mp2"},                 matchMPEG},
+  {"flac",     []string{".flac"},                        matchPrefix("fLaC")},
+  {"ogg",      []string{".ogg", ".oga", ".opus", ".spx"}, matchPrefix("OggS")},
+  {"mp4",      []string{".m4a", ".m4b", ".m4p", ".mp4"}, matchMP4},
+  {"wav",      []string{".wav"},                         matchRIFF("WAVE")},
+  {"aiff",     []string{".aif", ".aiff", ".aifc"},       matchAIFF},
+  {"ape",      []string{".ape"},                         matchPrefix("MAC ")},
+  {"wavpack",  []string{".wv"},                          matchPrefix("wvpk")},
+  {"musepack", []string{".mpc"},                         matchMusepack},
+  {"matroska", []string{".mka", ".mkv", ".webm"},        matchPrefix("\x1a\x45\xdf\xa3")},
+  {"asf",      []string{".wma", ".asf"},                 matchBytes(asfGUID)},
+}
+
+// ByExtension returns the name of the format belonging to the extension
+// of path or an empty string if the extension is unknown
+func ByExtension(path string) string {
+  ext := strings.ToLower(filepath.Ext(path))
+  for _, f := range formats {
+    for _, e := range f.Extensions {
+      if e == ext {
+        return f.Name
+      }
+    }
+  }
+  return ""
+}
+
+// SniffBytes returns the name of the format recognised by the magic bytes
+// at the beginning of head or an empty string if nothing matched
+func SniffBytes(head []byte) string {
+  for _, f := range formats {
+    if f.match(head) {
+      return f.Name
+    }
+  }
+  return ""
+}
+
+// Sniff reads the beginning of the file at path and passes it to SniffBytes;
+// an ID3v2 tag in front of the content is skipped, so FLAC or other formats
+// behind a tag are recognised as well
+func Sniff(path string) (string, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return "", err
+  }
+  defer file.Close()
+
+  head, _, err := readHead(file, headSize)
+  if err != nil {
+    return "", err
+  }
+  return SniffBytes(head), nil
+}
+
+// readHead reads n bytes of content, skipping an ID3v2 tag at the beginning;
+// the offset of the content is returned as well
+func readHead(file *os.File, n int) ([]byte, int64, error) {
+  head := make([]byte, n)
+  read, err := file.ReadAt(head, 0)
+  if err != nil && err != io.EOF {
+    return nil, 0, err
+  }
+  head = head[:read]
+  size := id3v2Size(head)
+  if size == 0 {
+    return head, 0, nil
+  }
+
+  behind := make([]byte, n)
+  read, err = file.ReadAt(behind, size)
+  if err != nil && err != io.EOF {
+    return nil, 0, err
+  }
+  // content behind the tag that is not recognised, e.g. MPEG frames
+  // after junk, keeps the file an mp3
+  if SniffBytes(behind[:read]) == "" {
+    return head, 0, nil
+  }
+  return behind[:read], size, nil
+}
+
+// id3v2Size returns the size of the ID3v2 tag at the beginning of head
+// including header and footer, zero if there is none
+func id3v2Size(head []byte) int64 {
+  if len(head) < 10 || string(head[0:3]) != "ID3" {
+    return 0
+  }
+  var size int64
+  for _, c := range head[6:10] {
+    if c & 0x80 != 0 {
+      return 0
+    }
+    size = size << 7 | int64(c)
+  }
+  size += 10
+  if head[5] & 0x10 != 0 {
+    size += 10
+  }
+  return size
+}
+
+func matchPrefix(magic string) func([]byte) bool {
+  return matchBytes([]byte(magic))
+}
+
+func matchBytes(magic []byte) func([]byte) bool {
+  return func(head []byte) bool {
+    return bytes.HasPrefix(head, magic)
+  }
+}
+
+func matchRIFF(form string) func([]byte) bool {
+  return func(head []byte) bool {
+    return len(head) >= 12 && string(head[0:4]) == "RIFF" &&
+      string(head[8:12]) == form
+  }
+}
+
+func matchAIFF(head []byte) bool {
+  return len(head) >= 12 && string(head[0:4]) == "FORM" &&
+    (string(head[8:12]) == "AIFF" || string(head[8:12]) == "AIFC")
+}
+
+func matchMP4(head []byte) bool {
+  return len(head) >= 8 && string(head[4:8]) == "ftyp"
+}
+
+func matchMusepack(head []byte) bool {
+  return bytes.HasPrefix(head, []byte("MPCK")) || bytes.HasPrefix(head, []byte("MP+"))
+}
+
+// an ID3v2 header or an MPEG audio frame sync (layer bits set,
+// which excludes AAC ADTS streams)
+func matchMPEG(head []byte) bool {
+  if bytes.HasPrefix(head, []byte("ID3")) {
+    return true
+  }
+  return len(head) >= 2 && head[0] == 0xff && head[1] & 0xe0 == 0xe0 &&
+    head[1] & 0x06 != 0
+}
@@ -0,0 +1,53 @@
+package detect
+
+import (
+  "testing"
+)
+
+
+
+func TestByExtension(t *testing.T) {
+  tests := []struct {
+    path string
+    want string
+  }{
+    {"a.mp3", "mp3"},
+    {"dir.flac/a.OGG", "ogg"},
+    {"a.m4b", "mp4"},
+    {"a.webm", "matroska"},
+    {"a.txt", ""},
+    {"flac", ""},
+  }
+  for _, tt := range tests {
+    if got := ByExtension(tt.path); got != tt.want {
+      t.Errorf("ByExtension('%s') = '%s', want '%s'", tt.path, got, tt.want)
+    }
+  }
+}
+
+func TestSniffBytes(t *testing.T) {
+  tests := []struct {
+    name string
+    head string
+    want string
+  }{
+    {"mpeg frame", "\xff\xfb\x90\x00", "mp3"},
+    {"adts", "\xff\xf1\x50\x80", ""},
+    {"flac", "fLaC\x00\x00\x00\x22", "flac"},
+    {"ogg", "OggS\x00\x02", "ogg"},
+    {"mp4", "\x00\x00\x00\x20ftypM4A ", "mp4"},
+    {"wave", "RIFF\x24\x00\x00\x00WAVEfmt ", "wav"},
+    {"riff of another form", "RIFF\x24\x00\x00\x00AVI LIST", ""},
+    {"aifc", "FORM\x00\x00\x00\x20AIFCFVER", "aiff"},
+    {"musepack sv7", "MP+\x17", "musepack"},
+    {"matroska", "\x1a\x45\xdf\xa3\x9f", "matroska"},
+    {"asf", string(asfGUID), "asf"},
+    {"too short", "RIFF", ""},
+    {"empty", "", ""},
+  }
+  for _, tt := range tests {
+    if got := SniffBytes([]byte(tt.head)); got != tt.want {
+      t.Errorf("%s: sniffed '%s', want '%s'", tt.name, got, tt.want)
+    }
+  }
+}
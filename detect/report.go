@@ -0,0 +1,154 @@
+package detect
+
+import (
+  "bytes"
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "os"
+  "path/filepath"
+  "strings"
+)
+
+
+
+// result of inspecting the content of a file
+type Report struct {
+  // name of the format, empty if it is not recognised
+  Format string
+  // container and codec as far as the beginning of the file tells
+  Description string
+  // the content is preceded by an ID3v2 tag
+  ID3v2 bool
+  // extension the file should have
+  Extension string
+  // the extension of the file belongs to the format
+  Matches bool
+}
+
+// number of bytes inspected for a report
+const reportSize = 4096
+
+// Inspect recognises the format of the file at path by its content
+func Inspect(path string) (Report, error) {
+  var r Report
+  file, err := os.Open(path)
+  if err != nil {
+    return r, err
+  }
+  defer file.Close()
+
+  head, offset, err := readHead(file, reportSize)
+  if err != nil {
+    return r, err
+  }
+  r.ID3v2 = offset > 0 || id3v2Size(head) > 0
+  r.Format = SniffBytes(head)
+  if r.Format == "" {
+    return r, nil
+  }
+  r.Description, r.Extension = describe(r.Format, head)
+  r.Matches = ByExtension(path) == r.Format
+  return r, nil
+}
+
+// describe names container and codec and picks the extension of the format
+func describe(format string, head []byte) (string, string) {
+  switch format {
+  case "mp3":
+    if size := id3v2Size(head); size > 0 && int(size) < len(head) {
+      head = head[size:]
+    }
+    return mpegDescription(head), ".mp3"
+  case "flac":
+    return "FLAC", ".flac"
+  case "ogg":
+    codecs := []struct{ magic, name, extension string }{
+      {"\x01vorbis", "Vorbis", ".ogg"},
+      {"OpusHead", "Opus", ".opus"},
+      {"\x7fFLAC", "FLAC", ".oga"},
+      {"Speex   ", "Speex", ".spx"},
+    }
+    for _, c := range codecs {
+      if len(head) > 28 && bytes.HasPrefix(head[28:], []byte(c.magic)) {
+        return "Ogg " + c.name, c.extension
+      }
+    }
+    return "Ogg", ".ogg"
+  case "mp4":
+    brand := strings.TrimSpace(string(head[8:12]))
+    extension := ".m4a"
+    switch brand {
+    case "M4B":
+      extension = ".m4b"
+    case "M4P":
+      extension = ".m4p"
+    case "M4A":
+    default:
+      extension = ".mp4"
+    }
+    return fmt.Sprintf("MP4 (brand %s)", brand), extension
+  case "wav":
+    if len(head) >= 22 && string(head[12:16]) == "fmt " {
+      return fmt.Sprintf("RIFF WAVE (format tag 0x%04x)", binary.LittleEndian.Uint16(head[20:])), ".wav"
+    }
+    return "RIFF WAVE", ".wav"
+  case "aiff":
+    if string(head[8:12]) == "AIFC" {
+      return "AIFF-C", ".aifc"
+    }
+    return "AIFF", ".aiff"
+  case "ape":
+    return "Monkey's Audio", ".ape"
+  case "wavpack":
+    return "WavPack", ".wv"
+  case "musepack":
+    if bytes.HasPrefix(head, []byte("MPCK")) {
+      return "Musepack SV8", ".mpc"
+    }
+    return "Musepack SV7", ".mpc"
+  case "matroska":
+    // the DocType element of the EBML header
+    if i := bytes.Index(head, []byte{0x42, 0x82}); i >= 0 && i + 3 < len(head) {
+      size := int(head[i + 2] & 0x7f)
+      if i + 3 + size <= len(head) && string(head[i + 3:i + 3 + size]) == "webm" {
+        return "WebM", ".webm"
+      }
+    }
+    return "Matroska", ".mka"
+  case "asf":
+    return "ASF", ".wma"
+  }
+  return format, ""
+}
+
+var mpegVersions = map[byte]string{0: "MPEG-2.5", 2: "MPEG-2", 3: "MPEG-1"}
+
+// mpegDescription names version and layer of the frame at the beginning of head
+func mpegDescription(head []byte) string {
+  if len(head) < 4 || head[0] != 0xff || head[1] & 0xe0 != 0xe0 {
+    return "MPEG audio"
+  }
+  version, ok := mpegVersions[head[1] >> 3 & 3]
+  layer := 4 - int(head[1] >> 1 & 3)
+  if !ok || layer == 4 {
+    return "MPEG audio"
+  }
+  return fmt.Sprintf("%s Layer %d", version, layer)
+}
+
+// FixExtension renames the file at path to the extension of the report if
+// its extension does not belong to the format; the new path is returned
+func FixExtension(path string, r Report) (string, error) {
+  if r.Format == "" || r.Matches || r.Extension == "" {
+    return path, nil
+  }
+  fixed := strings.TrimSuffix(path, filepath.Ext(path)) + r.Extension
+  if _, err := os.Lstat(fixed); err == nil {
+    return path, errors.New(fmt.Sprintf("unable to rename '%s', '%s' already exists", path, fixed))
+  }
+  if err := os.Rename(path, fixed); err != nil {
+    return path, err
+  }
+  return fixed, nil
+}
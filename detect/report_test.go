@@ -0,0 +1,88 @@
+package detect
+
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "testing"
+)
+
+
+
+// id3v2 returns an ID3v2.4 tag of size bytes behind the header
+func id3v2(size int) []byte {
+  b := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, byte(size >> 7), byte(size & 0x7f)}
+  return append(b, make([]byte, size)...)
+}
+
+// oggPage returns the start of an ogg page whose packet begins with magic
+func oggPage(magic string) []byte {
+  b := append([]byte("OggS"), make([]byte, 24)...)
+  return append(b, magic...)
+}
+
+func TestInspect(t *testing.T) {
+  tests := []struct {
+    name    string
+    file    string
+    content []byte
+    want    Report
+  }{
+    {"mp3", "a.mp3", []byte("\xff\xfb\x90\x00"),
+      Report{Format: "mp3", Description: "MPEG-1 Layer 3", Extension: ".mp3", Matches: true}},
+    {"mp3 behind id3v2", "a.mp3", append(id3v2(100), "\xff\xf3\x90\x00"...),
+      Report{Format: "mp3", Description: "MPEG-2 Layer 3", ID3v2: true, Extension: ".mp3",
+        Matches: true}},
+    {"flac behind id3v2", "a.mp3", append(id3v2(100), "fLaC\x00\x00\x00\x22"...),
+      Report{Format: "flac", Description: "FLAC", ID3v2: true, Extension: ".flac"}},
+    {"vorbis", "a.ogg", oggPage("\x01vorbis"),
+      Report{Format: "ogg", Description: "Ogg Vorbis", Extension: ".ogg", Matches: true}},
+    {"opus", "a.ogg", oggPage("OpusHead"),
+      Report{Format: "ogg", Description: "Ogg Opus", Extension: ".opus", Matches: true}},
+    {"flac in ogg", "a.ogg", oggPage("\x7fFLAC"),
+      Report{Format: "ogg", Description: "Ogg FLAC", Extension: ".oga", Matches: true}},
+    {"m4b", "a.mp4", []byte("\x00\x00\x00\x20ftypM4B "),
+      Report{Format: "mp4", Description: "MP4 (brand M4B)", Extension: ".m4b", Matches: true}},
+    {"unknown", "a.mp3", []byte("text"), Report{}},
+  }
+  for _, tt := range tests {
+    path := filepath.Join(t.TempDir(), tt.file)
+    if err := ioutil.WriteFile(path, tt.content, 0644); err != nil {
+      t.Fatal(err)
+    }
+    r, err := Inspect(path)
+    if err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+    if r != tt.want {
+      t.Errorf("%s: report %+v, want %+v", tt.name, r, tt.want)
+    }
+  }
+}
+
+func TestFixExtension(t *testing.T) {
+  dir := t.TempDir()
+  path := filepath.Join(dir, "a.mp3")
+  if err := ioutil.WriteFile(path, []byte("fLaC\x00\x00\x00\x22"), 0644); err != nil {
+    t.Fatal(err)
+  }
+  r, err := Inspect(path)
+  if err != nil {
+    t.Fatal(err)
+  }
+  fixed, err := FixExtension(path, r)
+  if err != nil || fixed != filepath.Join(dir, "a.flac") {
+    t.Fatalf("renamed to '%s': %v", fixed, err)
+  }
+  if _, err := os.Stat(fixed); err != nil {
+    t.Error(err)
+  }
+
+  // an existing file is not replaced
+  if err := ioutil.WriteFile(path, []byte("fLaC\x00\x00\x00\x22"), 0644); err != nil {
+    t.Fatal(err)
+  }
+  if renamed, err := FixExtension(path, r); err == nil || renamed != path {
+    t.Errorf("renamed onto an existing file: '%s'", renamed)
+  }
+}
@@ -0,0 +1,291 @@
+package apev2
+
+import (
+  "bytes"
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "strings"
+)
+
+import (
+  id3v1 "github.com/elias-boemeke/taggo/format/id3v1"
+  rewrite "github.com/elias-boemeke/taggo/format/rewrite"
+)
+
+
+
+var ErrNoTag = errors.New("no apev2 tag")
+
+// size of header and footer
+const FooterSize = 32
+
+const preamble = "APETAGEX"
+
+// flags of header, footer and items
+const (
+  FlagReadOnly    = 1
+  FlagHasHeader   = 1 << 31
+  FlagHasNoFooter = 1 << 30
+  FlagIsHeader    = 1 << 29
+)
+
+// item types, bits 1 and 2 of the item flags
+const (
+  TypeText    = 0
+  TypeBinary  = 1
+  TypeLocator = 2
+)
+
+type Item struct {
+  Key   string
+  Flags uint32
+  Value []byte
+}
+
+type Tag struct {
+  // 2000 for APEv2, 1000 for APEv1
+  Version uint32
+  Items   []*Item
+  // position and size including the header when the tag was read
+  Offset int64
+  Size   int64
+}
+
+func NewTag() *Tag {
+  return &Tag{Version: 2000}
+}
+
+// Type returns the type of the item value
+func (item *Item) Type() int {
+  return int(item.Flags >> 1 & 3)
+}
+
+// Values splits a text item into its values, which are separated by zero bytes
+func (item *Item) Values() []string {
+  return strings.Split(string(item.Value), "\x00")
+}
+
+// String formats the value of an item
+func (item *Item) String() string {
+  switch item.Type() {
+  case TypeText, TypeLocator:
+    return strings.Join(item.Values(), "; ")
+  }
+  // binary items, cover art starts with a zero terminated file name
+  if i := bytes.IndexByte(item.Value, 0); i >= 0 && i < 256 &&
+    strings.HasPrefix(strings.ToLower(item.Key), "cover art") {
+    return fmt.Sprintf("<binary '%s', %d bytes>", item.Value[:i], len(item.Value) - i - 1)
+  }
+  return fmt.Sprintf("<binary, %d bytes>", len(item.Value))
+}
+
+// Get returns the item with the given key, keys are case insensitive
+func (t *Tag) Get(key string) *Item {
+  for _, item := range t.Items {
+    if strings.EqualFold(item.Key, key) {
+      return item
+    }
+  }
+  return nil
+}
+
+// Text returns the values of a text item joined by "; "
+func (t *Tag) Text(key string) string {
+  if item := t.Get(key); item != nil && item.Type() == TypeText {
+    return item.String()
+  }
+  return ""
+}
+
+// SetText sets a text item, without values it is removed
+func (t *Tag) SetText(key string, values ...string) {
+  var nonEmpty []string
+  for _, v := range values {
+    if v != "" {
+      nonEmpty = append(nonEmpty, v)
+    }
+  }
+  if len(nonEmpty) == 0 {
+    t.Remove(key)
+    return
+  }
+  t.Set(&Item{Key: key, Flags: TypeText << 1, Value: []byte(strings.Join(nonEmpty, "\x00"))})
+}
+
+// SetBinary sets a binary item, a nil value removes it
+func (t *Tag) SetBinary(key string, value []byte) {
+  if value == nil {
+    t.Remove(key)
+    return
+  }
+  t.Set(&Item{Key: key, Flags: TypeBinary << 1, Value: value})
+}
+
+// Set replaces the item with the same key or appends it
+func (t *Tag) Set(item *Item) {
+  for i, old := range t.Items {
+    if strings.EqualFold(old.Key, item.Key) {
+      // keep the spelling of the key found in the file
+      item.Key = old.Key
+      t.Items[i] = item
+      return
+    }
+  }
+  t.Items = append(t.Items, item)
+}
+
+func (t *Tag) Remove(key string) {
+  kept := t.Items[:0]
+  for _, item := range t.Items {
+    if !strings.EqualFold(item.Key, key) {
+      kept = append(kept, item)
+    }
+  }
+  t.Items = kept
+}
+
+// Read reads the tag whose footer ends at end
+func Read(r io.ReaderAt, end int64) (*Tag, error) {
+  if end < FooterSize {
+    return nil, ErrNoTag
+  }
+  footer := make([]byte, FooterSize)
+  if _, err := r.ReadAt(footer, end - FooterSize); err != nil {
+    return nil, err
+  }
+  if string(footer[0:8]) != preamble {
+    return nil, ErrNoTag
+  }
+  version := binary.LittleEndian.Uint32(footer[8:])
+  size := int64(binary.LittleEndian.Uint32(footer[12:]))
+  count := int(binary.LittleEndian.Uint32(footer[16:]))
+  flags := binary.LittleEndian.Uint32(footer[20:])
+  if size < FooterSize || size > end {
+    return nil, errors.New(fmt.Sprintf("apev2 tag has invalid size %d", size))
+  }
+
+  t := &Tag{Version: version, Offset: end - size, Size: size}
+  if flags & FlagHasHeader != 0 && t.Offset >= FooterSize {
+    t.Offset -= FooterSize
+    t.Size += FooterSize
+  }
+  body := make([]byte, size - FooterSize)
+  if _, err := r.ReadAt(body, end - size); err != nil {
+    return nil, err
+  }
+
+  for i, pos := 0, 0; i < count; i++ {
+    if pos + 9 > len(body) {
+      return nil, errors.New("apev2 tag truncated")
+    }
+    valueSize := int(binary.LittleEndian.Uint32(body[pos:]))
+    itemFlags := binary.LittleEndian.Uint32(body[pos + 4:])
+    pos += 8
+    keyEnd := bytes.IndexByte(body[pos:], 0)
+    if keyEnd < 0 || pos + keyEnd + 1 + valueSize > len(body) {
+      return nil, errors.New("apev2 tag truncated")
+    }
+    item := &Item{
+      Key:   string(body[pos:pos + keyEnd]),
+      Flags: itemFlags,
+    }
+    pos += keyEnd + 1
+    item.Value = append([]byte(nil), body[pos:pos + valueSize]...)
+    pos += valueSize
+    t.Items = append(t.Items, item)
+  }
+  return t, nil
+}
+
+// Encode returns the tag with header and footer
+func (t *Tag) Encode() []byte {
+  var body []byte
+  for _, item := range t.Items {
+    b := make([]byte, 8)
+    binary.LittleEndian.PutUint32(b, uint32(len(item.Value)))
+    binary.LittleEndian.PutUint32(b[4:], item.Flags)
+    b = append(append(b, item.Key...), 0)
+    body = append(body, append(b, item.Value...)...)
+  }
+
+  frame := func(flags uint32) []byte {
+    b := make([]byte, FooterSize)
+    copy(b, preamble)
+    binary.LittleEndian.PutUint32(b[8:], 2000)
+    binary.LittleEndian.PutUint32(b[12:], uint32(len(body) + FooterSize))
+    binary.LittleEndian.PutUint32(b[16:], uint32(len(t.Items)))
+    binary.LittleEndian.PutUint32(b[20:], flags)
+    return b
+  }
+  b := frame(FlagHasHeader | FlagIsHeader)
+  b = append(b, body...)
+  return append(b, frame(FlagHasHeader)...)
+}
+
+// locate returns the tag at the end of the file, which may be followed by an
+// id3v1 tag, and the position where the trailing data starts
+func locate(file *os.File) (*Tag, int64, error) {
+  info, err := file.Stat()
+  if err != nil {
+    return nil, 0, err
+  }
+  end := info.Size()
+  if _, err := id3v1.Read(file, end); err == nil {
+    end -= id3v1.Size
+  }
+  t, err := Read(file, end)
+  return t, end, err
+}
+
+func ReadFile(path string) (*Tag, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+  t, _, err := locate(file)
+  return t, err
+}
+
+// WriteFile writes the tag to the end of the file at path, in front of an
+// id3v1 tag; an existing apev2 tag is replaced
+func WriteFile(path string, t *Tag) error {
+  return replace(path, t.Encode())
+}
+
+// RemoveFile removes the apev2 tag of the file at path if there is one
+func RemoveFile(path string) error {
+  return replace(path, nil)
+}
+
+func replace(path string, data []byte) error {
+  file, err := os.Open(path)
+  if err != nil {
+    return err
+  }
+  old, end, err := locate(file)
+  if err != nil && err != ErrNoTag {
+    file.Close()
+    return err
+  }
+  start := end
+  if old != nil {
+    start = old.Offset
+  }
+  info, err := file.Stat()
+  if err != nil {
+    file.Close()
+    return err
+  }
+  // the trailing id3v1 tag is written again so the file is changed in place
+  trailer := make([]byte, info.Size() - end)
+  _, err = file.ReadAt(trailer, end)
+  file.Close()
+  if err != nil {
+    return err
+  }
+  return rewrite.Replace(path, start, info.Size(), append(data, trailer...))
+}
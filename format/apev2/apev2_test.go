@@ -0,0 +1,101 @@
+package apev2
+
+import (
+  "bytes"
+  "io/ioutil"
+  "path/filepath"
+  "reflect"
+  "testing"
+)
+
+
+
+var audio = []byte("MAC audio frames")
+
+// v1Tag returns an id3v1 tag with a title
+func v1Tag(title string) []byte {
+  b := make([]byte, 128)
+  copy(b, "TAG")
+  copy(b[3:], title)
+  b[127] = 255
+  return b
+}
+
+func TestRoundTrip(t *testing.T) {
+  tag := NewTag()
+  tag.SetText("Title", "Title")
+  tag.SetText("Artist", "One", "", "Two")
+  tag.SetBinary("Cover Art (Front)", []byte("front.jpg\x00jpeg"))
+
+  b := tag.Encode()
+  read, err := Read(bytes.NewReader(append(append([]byte(nil), audio...), b...)),
+    int64(len(audio) + len(b)))
+  if err != nil {
+    t.Fatal(err)
+  }
+  if read.Offset != int64(len(audio)) || read.Size != int64(len(b)) || read.Version != 2000 {
+    t.Errorf("read at %d with %d bytes, version %d", read.Offset, read.Size, read.Version)
+  }
+  if got := read.Get("ARTIST").Values(); !reflect.DeepEqual(got, []string{"One", "Two"}) {
+    t.Errorf("artist %q", got)
+  }
+  if read.Text("title") != "Title" {
+    t.Errorf("title '%s'", read.Text("title"))
+  }
+  cover := read.Get("cover art (front)")
+  if cover == nil || cover.Type() != TypeBinary || cover.String() != "<binary 'front.jpg', 4 bytes>" {
+    t.Errorf("cover %v", cover)
+  }
+}
+
+func TestWriteFile(t *testing.T) {
+  old := NewTag()
+  old.SetText("Title", "Old title that is longer than the new one")
+  tests := []struct {
+    name     string
+    trailer  []byte
+    // existing tag in front of the trailer
+    existing []byte
+  }{
+    {"new tag", nil, nil},
+    {"replaced", nil, old.Encode()},
+    {"in front of id3v1", v1Tag("v1"), nil},
+    {"replaced in front of id3v1", v1Tag("v1"), old.Encode()},
+  }
+  for _, tt := range tests {
+    path := filepath.Join(t.TempDir(), "a.ape")
+    b := bytes.Join([][]byte{audio, tt.existing, tt.trailer}, nil)
+    if err := ioutil.WriteFile(path, b, 0644); err != nil {
+      t.Fatal(err)
+    }
+
+    tag := NewTag()
+    tag.SetText("Title", "New")
+    if err := WriteFile(path, tag); err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+    b, err := ioutil.ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    want := bytes.Join([][]byte{audio, tag.Encode(), tt.trailer}, nil)
+    if !bytes.Equal(b, want) {
+      t.Errorf("%s: file of %d bytes, want %d", tt.name, len(b), len(want))
+    }
+    read, err := ReadFile(path)
+    if err != nil || read.Text("Title") != "New" {
+      t.Errorf("%s: tag not read back: %v", tt.name, err)
+    }
+
+    if err := RemoveFile(path); err != nil {
+      t.Fatal(err)
+    }
+    b, err = ioutil.ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if !bytes.Equal(b, append(append([]byte(nil), audio...), tt.trailer...)) {
+      t.Errorf("%s: removing the tag left %d bytes", tt.name, len(b))
+    }
+  }
+}
@@ -0,0 +1,207 @@
+package asf
+
+import (
+  "bytes"
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "time"
+)
+
+
+
+type GUID [16]byte
+
+// object guids in their byte order on disk
+var (
+  HeaderGUID = GUID{0x30, 0x26, 0xb2, 0x75, 0x8e, 0x66, 0xcf, 0x11,
+    0xa6, 0xd9, 0x00, 0xaa, 0x00, 0x62, 0xce, 0x6c}
+  FilePropertiesGUID = GUID{0xa1, 0xdc, 0xab, 0x8c, 0x47, 0xa9, 0xcf, 0x11,
+    0x8e, 0xe4, 0x00, 0xc0, 0x0c, 0x20, 0x53, 0x65}
+  StreamPropertiesGUID = GUID{0x91, 0x07, 0xdc, 0xb7, 0xb7, 0xa9, 0xcf, 0x11,
+    0x8e, 0xe6, 0x00, 0xc0, 0x0c, 0x20, 0x53, 0x65}
+  ContentDescriptionGUID = GUID{0x33, 0x26, 0xb2, 0x75, 0x8e, 0x66, 0xcf, 0x11,
+    0xa6, 0xd9, 0x00, 0xaa, 0x00, 0x62, 0xce, 0x6c}
+  ExtendedContentDescriptionGUID = GUID{0x40, 0xa4, 0xd0, 0xd2, 0x07, 0xe3, 0xd2, 0x11,
+    0x97, 0xf0, 0x00, 0xa0, 0xc9, 0x5e, 0xa8, 0x50}
+  HeaderExtensionGUID = GUID{0xb5, 0x03, 0xbf, 0x5f, 0x2e, 0xa9, 0xcf, 0x11,
+    0x8e, 0xe3, 0x00, 0xc0, 0x0c, 0x20, 0x53, 0x65}
+  PaddingGUID = GUID{0x74, 0xd4, 0x06, 0x18, 0xdf, 0xca, 0x09, 0x45,
+    0xa4, 0xba, 0x9a, 0xab, 0xcb, 0x96, 0xaa, 0xe8}
+  DataGUID = GUID{0x36, 0x26, 0xb2, 0x75, 0x8e, 0x66, 0xcf, 0x11,
+    0xa6, 0xd9, 0x00, 0xaa, 0x00, 0x62, 0xce, 0x6c}
+  AudioMediaGUID = GUID{0x40, 0x9e, 0x69, 0xf8, 0x4d, 0x5b, 0xcf, 0x11,
+    0xa8, 0xfd, 0x00, 0x80, 0x5f, 0x5c, 0x44, 0x2b}
+)
+
+var objectNames = map[GUID]string{
+  HeaderGUID:                     "Header",
+  FilePropertiesGUID:             "File Properties",
+  StreamPropertiesGUID:           "Stream Properties",
+  ContentDescriptionGUID:         "Content Description",
+  ExtendedContentDescriptionGUID: "Extended Content Description",
+  HeaderExtensionGUID:            "Header Extension",
+  PaddingGUID:                    "Padding",
+  DataGUID:                       "Data",
+}
+
+// Name returns the name of a known object or the guid in its usual notation
+func (g GUID) Name() string {
+  if name, ok := objectNames[g]; ok {
+    return name
+  }
+  return fmt.Sprintf("%08X-%04X-%04X-%X-%X", binary.LittleEndian.Uint32(g[0:]),
+    binary.LittleEndian.Uint16(g[4:]), binary.LittleEndian.Uint16(g[6:]), g[8:10], g[10:])
+}
+
+// size of guid and size of an object header
+const ObjectHeaderSize = 24
+
+// the header object has a count and two reserved bytes in front of its children
+const headerPrefix = 6
+
+var ErrNotASF = errors.New("not an asf file")
+
+type Object struct {
+  GUID   GUID
+  Offset int64
+  Data   []byte
+}
+
+func (o *Object) Size() int64 {
+  return ObjectHeaderSize + int64(len(o.Data))
+}
+
+func (o *Object) Encode() []byte {
+  b := make([]byte, ObjectHeaderSize, o.Size())
+  copy(b, o.GUID[:])
+  binary.LittleEndian.PutUint64(b[16:], uint64(o.Size()))
+  return append(b, o.Data...)
+}
+
+type File struct {
+  // children of the header object
+  Objects []*Object
+  // the two reserved bytes of the header object
+  Reserved   [2]byte
+  HeaderSize int64
+  Size       int64
+}
+
+func ReadFile(path string) (*File, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+  info, err := file.Stat()
+  if err != nil {
+    return nil, err
+  }
+  return Read(file, info.Size())
+}
+
+func Read(r io.ReaderAt, size int64) (*File, error) {
+  b := make([]byte, ObjectHeaderSize + headerPrefix)
+  if _, err := r.ReadAt(b, 0); err != nil || !bytes.Equal(b[0:16], HeaderGUID[:]) {
+    return nil, ErrNotASF
+  }
+  f := &File{HeaderSize: int64(binary.LittleEndian.Uint64(b[16:])), Size: size}
+  count := int(binary.LittleEndian.Uint32(b[24:]))
+  copy(f.Reserved[:], b[28:30])
+  if f.HeaderSize < int64(len(b)) || f.HeaderSize > size || f.HeaderSize > 64 << 20 {
+    return nil, errors.New(fmt.Sprintf("asf header object has invalid size %d", f.HeaderSize))
+  }
+
+  header := make([]byte, f.HeaderSize)
+  if _, err := r.ReadAt(header, 0); err != nil {
+    return nil, err
+  }
+  pos := int64(len(b))
+  for i := 0; i < count; i++ {
+    if pos + ObjectHeaderSize > f.HeaderSize {
+      return nil, errors.New("asf header object truncated")
+    }
+    objectSize := int64(binary.LittleEndian.Uint64(header[pos + 16:]))
+    if objectSize < ObjectHeaderSize || pos + objectSize > f.HeaderSize {
+      return nil, errors.New(fmt.Sprintf("asf object at offset %d has invalid size %d",
+        pos, objectSize))
+    }
+    o := &Object{Offset: pos, Data: header[pos + ObjectHeaderSize:pos + objectSize]}
+    copy(o.GUID[:], header[pos:])
+    f.Objects = append(f.Objects, o)
+    pos += objectSize
+  }
+  return f, nil
+}
+
+// Object returns the first header object with the given guid or nil
+func (f *File) Object(guid GUID) *Object {
+  for _, o := range f.Objects {
+    if o.GUID == guid {
+      return o
+    }
+  }
+  return nil
+}
+
+type Properties struct {
+  Length time.Duration
+  // in kbit/s
+  Bitrate       int
+  Channels      int
+  Samplerate    int
+  BitsPerSample int
+  FormatTag     uint16
+}
+
+var codecNames = map[uint16]string{
+  0x0160: "WMA 1",
+  0x0161: "WMA 2",
+  0x0162: "WMA Pro",
+  0x0163: "WMA Lossless",
+  0x000a: "WMA Voice",
+}
+
+// Codec names the format tag of the audio stream
+func (p Properties) Codec() string {
+  if name, ok := codecNames[p.FormatTag]; ok {
+    return name
+  }
+  return fmt.Sprintf("0x%04x", p.FormatTag)
+}
+
+func (f *File) Properties() Properties {
+  var p Properties
+  if o := f.Object(FilePropertiesGUID); o != nil && len(o.Data) >= 80 {
+    duration := binary.LittleEndian.Uint64(o.Data[40:])
+    preroll := binary.LittleEndian.Uint64(o.Data[56:])
+    // play duration in 100 ns units includes the preroll in ms
+    length := time.Duration(duration) * 100 - time.Duration(preroll) * time.Millisecond
+    if length > 0 {
+      p.Length = length
+    }
+    p.Bitrate = int(binary.LittleEndian.Uint32(o.Data[76:]) / 1000)
+  }
+  for _, o := range f.Objects {
+    if o.GUID != StreamPropertiesGUID || len(o.Data) < 54 + 16 {
+      continue
+    }
+    if !bytes.Equal(o.Data[0:16], AudioMediaGUID[:]) {
+      continue
+    }
+    // the type specific data is a WAVEFORMATEX structure
+    w := o.Data[54:]
+    p.FormatTag = binary.LittleEndian.Uint16(w[0:])
+    p.Channels = int(binary.LittleEndian.Uint16(w[2:]))
+    p.Samplerate = int(binary.LittleEndian.Uint32(w[4:]))
+    if bitrate := int(binary.LittleEndian.Uint32(w[8:])) * 8 / 1000; bitrate > 0 {
+      p.Bitrate = bitrate
+    }
+    p.BitsPerSample = int(binary.LittleEndian.Uint16(w[14:]))
+    break
+  }
+  return p
+}
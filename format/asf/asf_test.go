@@ -0,0 +1,115 @@
+package asf
+
+import (
+  "bytes"
+  "encoding/binary"
+  "io/ioutil"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+
+
+var data = (&Object{GUID: DataGUID, Data: []byte("data packets")}).Encode()
+
+// file returns a header object with file properties and a padding object
+// of padding bytes unless negative, followed by the data object
+func file(padding int) []byte {
+  objects := []*Object{{GUID: FilePropertiesGUID, Data: make([]byte, 80)}}
+  if padding >= 0 {
+    objects = append(objects, &Object{GUID: PaddingGUID, Data: make([]byte, padding)})
+  }
+  header := make([]byte, ObjectHeaderSize + headerPrefix)
+  copy(header, HeaderGUID[:])
+  binary.LittleEndian.PutUint32(header[24:], uint32(len(objects)))
+  header[28], header[29] = 1, 2
+  for _, o := range objects {
+    header = append(header, o.Encode()...)
+  }
+  binary.LittleEndian.PutUint64(header[16:], uint64(len(header)))
+  return append(header, data...)
+}
+
+func TestWriteFile(t *testing.T) {
+  tests := []struct {
+    name    string
+    padding int
+    title   int
+    // padding requested from WriteFile
+    write   int
+    compact bool
+    // the data object keeps its offset
+    inPlace bool
+  }{
+    {"padding used", 1000, 100, 64, false, true},
+    {"padding exceeded", 16, 1000, 64, false, false},
+    {"no padding", -1, 10, 64, false, false},
+    {"compacted", 5000, 10, 64, true, false},
+  }
+  for _, tt := range tests {
+    path := filepath.Join(t.TempDir(), "a.wma")
+    if err := ioutil.WriteFile(path, file(tt.padding), 0644); err != nil {
+      t.Fatal(err)
+    }
+    f, err := ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    offset := f.HeaderSize
+
+    c, err := f.Content()
+    if err != nil {
+      t.Fatal(err)
+    }
+    title := strings.Repeat("t", tt.title)
+    c.Fields[Title] = title
+    c.SetTexts("WM/Genre", "Rock", "", "Pop")
+    c.SetText("WM/AlbumTitle", "Album")
+    if err := f.SetContent(c); err != nil {
+      t.Fatal(err)
+    }
+    if err := WriteFile(path, f, tt.write, tt.compact); err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+
+    b, err := ioutil.ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    read, err := ReadFile(path)
+    if err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+    if (read.HeaderSize == offset) != tt.inPlace {
+      t.Errorf("%s: data object moved from %d to %d", tt.name, offset, read.HeaderSize)
+    }
+    if !bytes.Equal(b[read.HeaderSize:], data) {
+      t.Errorf("%s: data object changed", tt.name)
+    }
+    if read.Reserved != [2]byte{1, 2} {
+      t.Errorf("%s: reserved bytes %v", tt.name, read.Reserved)
+    }
+    if size := binary.LittleEndian.Uint64(read.Object(FilePropertiesGUID).Data[16:]);
+        size != uint64(len(b)) {
+      t.Errorf("%s: file properties hold size %d of %d bytes", tt.name, size, len(b))
+    }
+    if p := read.Object(PaddingGUID); tt.compact && (p == nil || len(p.Data) != tt.write) {
+      t.Errorf("%s: padding not compacted to %d bytes", tt.name, tt.write)
+    }
+
+    c, err = read.Content()
+    if err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+    if c.Fields[Title] != title || c.Fields[Author] != "" {
+      t.Errorf("%s: fields %q", tt.name, c.Fields)
+    }
+    if got := c.Texts("wm/genre"); strings.Join(got, ";") != "Rock;Pop" {
+      t.Errorf("%s: genres %q", tt.name, got)
+    }
+    if got := c.Text("WM/AlbumTitle"); got != "Album" {
+      t.Errorf("%s: album '%s'", tt.name, got)
+    }
+  }
+}
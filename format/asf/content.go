@@ -0,0 +1,365 @@
+package asf
+
+import (
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "strconv"
+  "strings"
+  "unicode/utf16"
+)
+
+
+
+// fields of the content description object
+const (
+  Title = iota
+  Author
+  Copyright
+  Description
+  Rating
+)
+
+// value types of extended content descriptors
+const (
+  TypeUnicode = 0
+  TypeBytes   = 1
+  TypeBool    = 2
+  TypeDWord   = 3
+  TypeQWord   = 4
+  TypeWord    = 5
+)
+
+type Descriptor struct {
+  Name  string
+  Type  uint16
+  Value []byte
+}
+
+// the content description and the extended content description object
+type Content struct {
+  Fields      [5]string
+  Descriptors []*Descriptor
+}
+
+func decodeUTF16(b []byte) string {
+  units := make([]uint16, len(b) / 2)
+  for i := range units {
+    units[i] = binary.LittleEndian.Uint16(b[2 * i:])
+  }
+  // strings are zero terminated
+  for len(units) > 0 && units[len(units) - 1] == 0 {
+    units = units[:len(units) - 1]
+  }
+  return string(utf16.Decode(units))
+}
+
+// encodeUTF16 encodes s as zero terminated UTF-16LE
+func encodeUTF16(s string) []byte {
+  units := append(utf16.Encode([]rune(s)), 0)
+  b := make([]byte, 2 * len(units))
+  for i, u := range units {
+    binary.LittleEndian.PutUint16(b[2 * i:], u)
+  }
+  return b
+}
+
+// Content decodes the content description objects of the header,
+// objects that are missing leave their fields empty
+func (f *File) Content() (*Content, error) {
+  c := &Content{}
+  if o := f.Object(ContentDescriptionGUID); o != nil {
+    b := o.Data
+    if len(b) < 10 {
+      return nil, errors.New("asf content description truncated")
+    }
+    pos := 10
+    for i := range c.Fields {
+      n := int(binary.LittleEndian.Uint16(b[2 * i:]))
+      if pos + n > len(b) {
+        return nil, errors.New("asf content description truncated")
+      }
+      c.Fields[i] = decodeUTF16(b[pos:pos + n])
+      pos += n
+    }
+  }
+
+  if o := f.Object(ExtendedContentDescriptionGUID); o != nil {
+    b := o.Data
+    errTruncated := errors.New("asf extended content description truncated")
+    if len(b) < 2 {
+      return nil, errTruncated
+    }
+    count := int(binary.LittleEndian.Uint16(b))
+    pos := 2
+    for i := 0; i < count; i++ {
+      if pos + 2 > len(b) {
+        return nil, errTruncated
+      }
+      n := int(binary.LittleEndian.Uint16(b[pos:]))
+      if pos + 2 + n + 4 > len(b) {
+        return nil, errTruncated
+      }
+      d := &Descriptor{Name: decodeUTF16(b[pos + 2:pos + 2 + n])}
+      pos += 2 + n
+      d.Type = binary.LittleEndian.Uint16(b[pos:])
+      n = int(binary.LittleEndian.Uint16(b[pos + 2:]))
+      pos += 4
+      if pos + n > len(b) {
+        return nil, errTruncated
+      }
+      d.Value = append([]byte(nil), b[pos:pos + n]...)
+      pos += n
+      c.Descriptors = append(c.Descriptors, d)
+    }
+  }
+  return c, nil
+}
+
+// SetContent replaces the content description objects, they are created
+// in front of the padding or at the end of the header when missing
+func (f *File) SetContent(c *Content) error {
+  var description []byte
+  lengths := make([]byte, 10)
+  for i, field := range c.Fields {
+    var value []byte
+    if field != "" {
+      value = encodeUTF16(field)
+    }
+    if len(value) > 0xffff {
+      return errors.New("asf content description field exceeds 64 kB")
+    }
+    binary.LittleEndian.PutUint16(lengths[2 * i:], uint16(len(value)))
+    description = append(description, value...)
+  }
+  f.setObject(ContentDescriptionGUID, append(lengths, description...))
+
+  extended := make([]byte, 2)
+  binary.LittleEndian.PutUint16(extended, uint16(len(c.Descriptors)))
+  for _, d := range c.Descriptors {
+    name := encodeUTF16(d.Name)
+    if len(d.Value) > 0xffff {
+      return errors.New(fmt.Sprintf("asf attribute '%s' exceeds 64 kB", d.Name))
+    }
+    b := make([]byte, 2)
+    binary.LittleEndian.PutUint16(b, uint16(len(name)))
+    b = append(b, name...)
+    typeAndLength := make([]byte, 4)
+    binary.LittleEndian.PutUint16(typeAndLength, d.Type)
+    binary.LittleEndian.PutUint16(typeAndLength[2:], uint16(len(d.Value)))
+    extended = append(extended, append(append(b, typeAndLength...), d.Value...)...)
+  }
+  f.setObject(ExtendedContentDescriptionGUID, extended)
+  return nil
+}
+
+func (f *File) setObject(guid GUID, data []byte) {
+  if o := f.Object(guid); o != nil {
+    o.Data = data
+    return
+  }
+  o := &Object{GUID: guid, Offset: -1, Data: data}
+  for i, other := range f.Objects {
+    if other.GUID == PaddingGUID {
+      f.Objects = append(f.Objects[:i], append([]*Object{o}, f.Objects[i:]...)...)
+      return
+    }
+  }
+  f.Objects = append(f.Objects, o)
+}
+
+// Get returns the descriptor with the given name, names are case insensitive
+func (c *Content) Get(name string) *Descriptor {
+  for _, d := range c.Descriptors {
+    if strings.EqualFold(d.Name, name) {
+      return d
+    }
+  }
+  return nil
+}
+
+// Text returns the value of the descriptor as string
+func (c *Content) Text(name string) string {
+  if d := c.Get(name); d != nil {
+    return d.String()
+  }
+  return ""
+}
+
+// Texts returns the values of all descriptors with the given name
+func (c *Content) Texts(name string) []string {
+  var values []string
+  for _, d := range c.Descriptors {
+    if strings.EqualFold(d.Name, name) {
+      values = append(values, d.String())
+    }
+  }
+  return values
+}
+
+// Set replaces the descriptor with the same name or appends it
+func (c *Content) Set(d *Descriptor) {
+  for i, old := range c.Descriptors {
+    if strings.EqualFold(old.Name, d.Name) {
+      c.Descriptors[i] = d
+      return
+    }
+  }
+  c.Descriptors = append(c.Descriptors, d)
+}
+
+// SetText sets a unicode descriptor, an empty value removes it
+func (c *Content) SetText(name string, value string) {
+  if value == "" {
+    c.Remove(name)
+    return
+  }
+  c.Set(&Descriptor{Name: name, Type: TypeUnicode, Value: encodeUTF16(value)})
+}
+
+// SetTexts replaces the descriptors with the given name by one unicode
+// descriptor per value, which take the place of the first old one
+func (c *Content) SetTexts(name string, values ...string) {
+  var added []*Descriptor
+  for _, v := range values {
+    if v != "" {
+      added = append(added, &Descriptor{Name: name, Type: TypeUnicode, Value: encodeUTF16(v)})
+    }
+  }
+  var kept []*Descriptor
+  for _, d := range c.Descriptors {
+    if !strings.EqualFold(d.Name, name) {
+      kept = append(kept, d)
+    } else if added != nil {
+      kept = append(kept, added...)
+      added = nil
+    }
+  }
+  c.Descriptors = append(kept, added...)
+}
+
+// SetDWord sets a 32 bit number descriptor
+func (c *Content) SetDWord(name string, value uint32) {
+  b := make([]byte, 4)
+  binary.LittleEndian.PutUint32(b, value)
+  c.Set(&Descriptor{Name: name, Type: TypeDWord, Value: b})
+}
+
+// SetBool sets a boolean descriptor
+func (c *Content) SetBool(name string, value bool) {
+  b := make([]byte, 4)
+  if value {
+    b[0] = 1
+  }
+  c.Set(&Descriptor{Name: name, Type: TypeBool, Value: b})
+}
+
+func (c *Content) Remove(name string) {
+  var kept []*Descriptor
+  for _, d := range c.Descriptors {
+    if !strings.EqualFold(d.Name, name) {
+      kept = append(kept, d)
+    }
+  }
+  c.Descriptors = kept
+}
+
+// String formats the value of the descriptor
+func (d *Descriptor) String() string {
+  b := d.Value
+  switch {
+  case d.Type == TypeUnicode:
+    return decodeUTF16(b)
+  case d.Type == TypeBool && len(b) >= 2:
+    return strconv.FormatBool(b[0] != 0)
+  case d.Type == TypeDWord && len(b) >= 4:
+    return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(b)), 10)
+  case d.Type == TypeQWord && len(b) >= 8:
+    return strconv.FormatUint(binary.LittleEndian.Uint64(b), 10)
+  case d.Type == TypeWord && len(b) >= 2:
+    return strconv.FormatUint(uint64(binary.LittleEndian.Uint16(b)), 10)
+  case strings.EqualFold(d.Name, "WM/Picture"):
+    if p, err := DecodePicture(b); err == nil {
+      return fmt.Sprintf("<%s, type %d, %d bytes>", p.MIME, p.Type, len(p.Data))
+    }
+  }
+  return fmt.Sprintf("<binary, %d bytes>", len(b))
+}
+
+// the value of a WM/Picture descriptor
+type Picture struct {
+  Type        byte
+  MIME        string
+  Description string
+  Data        []byte
+}
+
+func DecodePicture(b []byte) (*Picture, error) {
+  errTruncated := errors.New("asf picture truncated")
+  if len(b) < 5 {
+    return nil, errTruncated
+  }
+  p := &Picture{Type: b[0]}
+  size := int(binary.LittleEndian.Uint32(b[1:]))
+  b = b[5:]
+  var strs [2]string
+  for i := range strs {
+    // zero terminated UTF-16LE
+    end := 0
+    for end + 1 < len(b) && (b[end] != 0 || b[end + 1] != 0) {
+      end += 2
+    }
+    if end + 2 > len(b) {
+      return nil, errTruncated
+    }
+    strs[i] = decodeUTF16(b[:end])
+    b = b[end + 2:]
+  }
+  if size > len(b) {
+    return nil, errTruncated
+  }
+  p.MIME, p.Description, p.Data = strs[0], strs[1], b[:size]
+  return p, nil
+}
+
+func (p *Picture) Encode() []byte {
+  b := make([]byte, 5)
+  b[0] = p.Type
+  binary.LittleEndian.PutUint32(b[1:], uint32(len(p.Data)))
+  b = append(b, encodeUTF16(p.MIME)...)
+  b = append(b, encodeUTF16(p.Description)...)
+  return append(b, p.Data...)
+}
+
+// Pictures decodes all WM/Picture descriptors, broken ones are skipped
+func (c *Content) Pictures() []*Picture {
+  var pictures []*Picture
+  for _, d := range c.Descriptors {
+    if !strings.EqualFold(d.Name, "WM/Picture") || d.Type != TypeBytes {
+      continue
+    }
+    if p, err := DecodePicture(d.Value); err == nil {
+      pictures = append(pictures, p)
+    }
+  }
+  return pictures
+}
+
+// SetPictures replaces the WM/Picture descriptors, which take the place
+// of the first old one
+func (c *Content) SetPictures(pictures []*Picture) {
+  var added []*Descriptor
+  for _, p := range pictures {
+    added = append(added, &Descriptor{Name: "WM/Picture", Type: TypeBytes, Value: p.Encode()})
+  }
+  var kept []*Descriptor
+  for _, d := range c.Descriptors {
+    if !strings.EqualFold(d.Name, "WM/Picture") {
+      kept = append(kept, d)
+    } else if added != nil {
+      kept = append(kept, added...)
+      added = nil
+    }
+  }
+  c.Descriptors = append(kept, added...)
+}
@@ -0,0 +1,71 @@
+package asf
+
+import (
+  "encoding/binary"
+)
+
+import (
+  rewrite "github.com/elias-boemeke/taggo/format/rewrite"
+)
+
+
+
+// size of the padding object written when the header has to grow
+const DefaultPadding = 1024
+
+// WriteFile writes the header object of f to the file at path; a padding
+// object absorbs changes in size so the data object mostly stays in place;
+// if compact is set free space beyond padding is given back
+func WriteFile(path string, f *File, padding int, compact bool) error {
+  var objects []*Object
+  for _, o := range f.Objects {
+    if o.GUID != PaddingGUID {
+      objects = append(objects, o)
+    }
+  }
+  size := int64(ObjectHeaderSize + headerPrefix)
+  for _, o := range objects {
+    size += o.Size()
+  }
+
+  if padding < 0 {
+    padding = DefaultPadding
+  }
+  // a padding object needs at least its header
+  switch free := f.HeaderSize - size; {
+  case free == 0:
+  case free >= ObjectHeaderSize && (!compact || free - ObjectHeaderSize <= int64(padding)):
+    objects = append(objects, &Object{GUID: PaddingGUID, Data: make([]byte, free - ObjectHeaderSize)})
+  default:
+    objects = append(objects, &Object{GUID: PaddingGUID, Data: make([]byte, padding)})
+  }
+  size = int64(ObjectHeaderSize + headerPrefix)
+  for _, o := range objects {
+    size += o.Size()
+  }
+
+  // the file properties carry the size of the whole file
+  if o := f.Object(FilePropertiesGUID); o != nil && len(o.Data) >= 24 {
+    binary.LittleEndian.PutUint64(o.Data[16:], uint64(f.Size - f.HeaderSize + size))
+  }
+
+  header := make([]byte, ObjectHeaderSize + headerPrefix, size)
+  copy(header, HeaderGUID[:])
+  binary.LittleEndian.PutUint64(header[16:], uint64(size))
+  binary.LittleEndian.PutUint32(header[24:], uint32(len(objects)))
+  copy(header[28:], f.Reserved[:])
+  for _, o := range objects {
+    header = append(header, o.Encode()...)
+  }
+
+  if err := rewrite.Replace(path, 0, f.HeaderSize, header); err != nil {
+    return err
+  }
+  // read back to pick up the new offsets
+  n, err := ReadFile(path)
+  if err != nil {
+    return err
+  }
+  *f = *n
+  return nil
+}
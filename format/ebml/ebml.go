@@ -0,0 +1,230 @@
+package ebml
+
+import (
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "io"
+  "math"
+)
+
+
+
+// marks an element of unknown size
+const UnknownSize = -1
+
+// the id of a Void element, used to fill up free space
+const Void = 0xec
+
+var errInvalid = errors.New("invalid ebml variable size integer")
+
+type Element struct {
+  // the id including its length marker, e.g. 0x1a45dfa3
+  ID uint32
+  // position of the element header in the file when it was read
+  Offset int64
+  Header int
+  // size of the data or UnknownSize
+  Size int64
+  // data of a leaf element
+  Data     []byte
+  Children []*Element
+  Master   bool
+}
+
+// readVint reads a variable size integer, with keepMarker the length marker
+// is part of the value as for element ids
+func readVint(b []byte, keepMarker bool) (uint64, int, error) {
+  if len(b) == 0 || b[0] == 0 {
+    return 0, 0, errInvalid
+  }
+  length := 1
+  for mask := byte(0x80); b[0] & mask == 0; mask >>= 1 {
+    length++
+  }
+  if length > 8 || length > len(b) {
+    return 0, 0, errInvalid
+  }
+  x := uint64(b[0])
+  if !keepMarker {
+    x &= uint64(0xff >> length)
+  }
+  for _, c := range b[1:length] {
+    x = x << 8 | uint64(c)
+  }
+  return x, length, nil
+}
+
+// ReadHeader reads id and size of the element at offset
+func ReadHeader(r io.ReaderAt, offset int64) (*Element, error) {
+  b := make([]byte, 12)
+  n, _ := r.ReadAt(b, offset)
+  b = b[:n]
+  id, idLength, err := readVint(b, true)
+  if err != nil || idLength > 4 {
+    return nil, errors.New(fmt.Sprintf("invalid ebml element id at offset %d", offset))
+  }
+  size, sizeLength, err := readVint(b[idLength:], false)
+  if err != nil {
+    return nil, errors.New(fmt.Sprintf("invalid ebml element size at offset %d", offset))
+  }
+  e := &Element{ID: uint32(id), Offset: offset, Header: idLength + sizeLength, Size: int64(size)}
+  // all bits set marks an unknown size
+  if size == 1 << (7 * uint(sizeLength)) - 1 {
+    e.Size = UnknownSize
+  }
+  return e, nil
+}
+
+// Parse parses the elements of b, those in masters are parsed recursively
+func Parse(b []byte, offset int64, masters map[uint32]bool) ([]*Element, error) {
+  var elements []*Element
+  for pos := 0; pos < len(b); {
+    id, idLength, err := readVint(b[pos:], true)
+    if err != nil || idLength > 4 {
+      return nil, errors.New(fmt.Sprintf("invalid ebml element id at offset %d", offset + int64(pos)))
+    }
+    size, sizeLength, err := readVint(b[pos + idLength:], false)
+    if err != nil {
+      return nil, errors.New(fmt.Sprintf("invalid ebml element size at offset %d", offset + int64(pos)))
+    }
+    header := idLength + sizeLength
+    end := pos + header + int(size)
+    if size == 1 << (7 * uint(sizeLength)) - 1 || size > uint64(len(b)) || end > len(b) {
+      // unknown sizes within a parsed element extend to its end
+      end = len(b)
+    }
+    e := &Element{
+      ID:     uint32(id),
+      Offset: offset + int64(pos),
+      Header: header,
+      Size:   int64(end - pos - header),
+    }
+    data := b[pos + header:end]
+    if masters[e.ID] {
+      e.Master = true
+      if e.Children, err = Parse(data, e.Offset + int64(header), masters); err != nil {
+        return nil, err
+      }
+    } else {
+      e.Data = append([]byte(nil), data...)
+    }
+    elements = append(elements, e)
+    pos = end
+  }
+  return elements, nil
+}
+
+// EncodeID encodes an element id, the length is given by its marker
+func EncodeID(id uint32) []byte {
+  b := make([]byte, 4)
+  binary.BigEndian.PutUint32(b, id)
+  for len(b) > 1 && b[0] == 0 {
+    b = b[1:]
+  }
+  return b
+}
+
+// EncodeSize encodes size with at least length bytes
+func EncodeSize(size int64, length int) []byte {
+  for length < 8 && uint64(size) >= 1 << (7 * uint(length)) - 1 {
+    length++
+  }
+  b := make([]byte, length)
+  x := uint64(size)
+  for i := length - 1; i >= 0; i-- {
+    b[i] = byte(x)
+    x >>= 8
+  }
+  b[0] |= 0x80 >> uint(length - 1)
+  return b
+}
+
+// Encode encodes the element with its children
+func (e *Element) Encode() []byte {
+  data := e.Data
+  if e.Master {
+    data = nil
+    for _, c := range e.Children {
+      data = append(data, c.Encode()...)
+    }
+  }
+  b := append(EncodeID(e.ID), EncodeSize(int64(len(data)), 1)...)
+  return append(b, data...)
+}
+
+// EncodeVoid returns a Void element of exactly size bytes, size being at least 2
+func EncodeVoid(size int) []byte {
+  // the size field grows with the amount of data
+  for length := 1; length <= 8; length++ {
+    data := size - 1 - length
+    if data >= 0 && uint64(data) < 1 << (7 * uint(length)) - 1 {
+      b := append([]byte{Void}, EncodeSize(int64(data), length)...)
+      return append(b, make([]byte, data)...)
+    }
+  }
+  return nil
+}
+
+// Child returns the first child with the given id or nil
+func (e *Element) Child(id uint32) *Element {
+  for _, c := range e.Children {
+    if c.ID == id {
+      return c
+    }
+  }
+  return nil
+}
+
+// ChildrenOf returns all children with the given id
+func (e *Element) ChildrenOf(id uint32) []*Element {
+  var children []*Element
+  for _, c := range e.Children {
+    if c.ID == id {
+      children = append(children, c)
+    }
+  }
+  return children
+}
+
+func (e *Element) Uint() uint64 {
+  var x uint64
+  for _, c := range e.Data {
+    x = x << 8 | uint64(c)
+  }
+  return x
+}
+
+func (e *Element) Float() float64 {
+  switch len(e.Data) {
+  case 4:
+    return float64(math.Float32frombits(binary.BigEndian.Uint32(e.Data)))
+  case 8:
+    return math.Float64frombits(binary.BigEndian.Uint64(e.Data))
+  }
+  return 0
+}
+
+func (e *Element) String() string {
+  // strings may be padded with zero bytes
+  end := len(e.Data)
+  for end > 0 && e.Data[end - 1] == 0 {
+    end--
+  }
+  return string(e.Data[:end])
+}
+
+// NewString returns a leaf element holding s
+func NewString(id uint32, s string) *Element {
+  return &Element{ID: id, Data: []byte(s)}
+}
+
+// NewUint returns a leaf element holding x with as few bytes as possible
+func NewUint(id uint32, x uint64) *Element {
+  b := make([]byte, 8)
+  binary.BigEndian.PutUint64(b, x)
+  for len(b) > 1 && b[0] == 0 {
+    b = b[1:]
+  }
+  return &Element{ID: id, Data: b}
+}
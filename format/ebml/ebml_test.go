@@ -0,0 +1,76 @@
+package ebml
+
+import (
+  "bytes"
+  "testing"
+)
+
+
+
+func TestEncodeVoid(t *testing.T) {
+  // sizes at the borders of the lengths of the size field
+  for _, size := range []int{2, 3, 127, 128, 129, 130, 16384, 16385, 16386, 1 << 21 + 3} {
+    b := EncodeVoid(size)
+    if len(b) != size {
+      t.Errorf("void of %d bytes encoded to %d bytes", size, len(b))
+      continue
+    }
+    elements, err := Parse(b, 0, nil)
+    if err != nil || len(elements) != 1 {
+      t.Errorf("void of %d bytes unreadable: %v", size, err)
+      continue
+    }
+    e := elements[0]
+    if e.ID != Void || int64(e.Header) + e.Size != int64(size) {
+      t.Errorf("void of %d bytes read as id %x of %d + %d bytes", size, e.ID, e.Header, e.Size)
+    }
+  }
+}
+
+func TestEncodeSize(t *testing.T) {
+  tests := []struct {
+    size   int64
+    length int
+    want   []byte
+  }{
+    {0, 1, []byte{0x80}},
+    {126, 1, []byte{0xfe}},
+    // all bits set would mark an unknown size
+    {127, 1, []byte{0x40, 0x7f}},
+    {5, 4, []byte{0x10, 0x00, 0x00, 0x05}},
+  }
+  for _, tt := range tests {
+    if got := EncodeSize(tt.size, tt.length); !bytes.Equal(got, tt.want) {
+      t.Errorf("EncodeSize(%d, %d) = %x, want %x", tt.size, tt.length, got, tt.want)
+    }
+  }
+}
+
+func TestRoundTrip(t *testing.T) {
+  const master, leaf = 0x1254c367, 0x4487
+  e := &Element{ID: master, Master: true, Children: []*Element{
+    NewString(leaf, "value"),
+    NewUint(0x68ca, 50),
+    {ID: master, Master: true, Children: []*Element{NewString(leaf, "nested")}},
+  }}
+  b := e.Encode()
+
+  elements, err := Parse(b, 100, map[uint32]bool{master: true})
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(elements) != 1 || !bytes.Equal(elements[0].Encode(), b) {
+    t.Fatalf("elements %v encode differently", elements)
+  }
+  read := elements[0]
+  if read.Offset != 100 || int64(read.Header) + read.Size != int64(len(b)) {
+    t.Errorf("read at %d with %d + %d bytes", read.Offset, read.Header, read.Size)
+  }
+  if read.Child(leaf).String() != "value" || read.Child(0x68ca).Uint() != 50 ||
+      read.Child(master).Child(leaf).String() != "nested" {
+    t.Errorf("children %v", read.Children)
+  }
+  if nested := read.Child(master).Child(leaf); nested.Offset != 100 + int64(len(b) - 9) {
+    t.Errorf("nested element at %d", nested.Offset)
+  }
+}
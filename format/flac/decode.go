@@ -0,0 +1,455 @@
+package flac
+
+import (
+  "bufio"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+)
+
+
+
+// channel assignments of frames beyond independent channels
+const (
+  leftSide  = 8
+  sideRight = 9
+  midSide   = 10
+)
+
+var errFrameSync = errors.New("flac frame sync code not found")
+
+// DecodeFile decodes the audio frames of the flac file at path and calls
+// block with the samples of each frame by channel, given as integers of
+// Info.BitsPerSample bits; decoding stops after Info.TotalSamples samples
+// if known, else at the end of the frames
+func DecodeFile(path string, block func(samples [][]int32) error) (Info, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return Info{}, err
+  }
+  defer file.Close()
+  m, err := Read(file)
+  if err != nil {
+    return Info{}, err
+  }
+  if _, err := file.Seek(m.AudioStart, io.SeekStart); err != nil {
+    return m.Info, err
+  }
+
+  br := &bitReader{r: bufio.NewReaderSize(file, 1 << 16)}
+  decoded := int64(0)
+  for m.Info.TotalSamples == 0 || decoded < m.Info.TotalSamples {
+    samples, err := br.frame(m.Info)
+    if (err == io.EOF || err == errFrameSync) && m.Info.TotalSamples == 0 {
+      break
+    } else if err == io.EOF || err == io.ErrUnexpectedEOF {
+      return m.Info, errors.New(fmt.Sprintf("flac frame truncated after %d samples", decoded))
+    } else if err != nil {
+      return m.Info, errors.New(fmt.Sprintf("flac frame after %d samples: %s", decoded, err))
+    }
+    if rest := m.Info.TotalSamples - decoded; m.Info.TotalSamples > 0 && int64(len(samples[0])) > rest {
+      for i := range samples {
+        samples[i] = samples[i][:rest]
+      }
+    }
+    decoded += int64(len(samples[0]))
+    if err := block(samples); err != nil {
+      return m.Info, err
+    }
+  }
+  return m.Info, nil
+}
+
+// bitReader reads big endian bit fields
+type bitReader struct {
+  r     *bufio.Reader
+  cache uint64
+  bits  uint
+}
+
+// read returns the next n bits, n <= 56
+func (b *bitReader) read(n uint) (uint64, error) {
+  for b.bits < n {
+    c, err := b.r.ReadByte()
+    if err != nil {
+      return 0, err
+    }
+    b.cache = b.cache << 8 | uint64(c)
+    b.bits += 8
+  }
+  b.bits -= n
+  v := b.cache >> b.bits & (1 << n - 1)
+  b.cache &= 1 << b.bits - 1
+  return v, nil
+}
+
+// signed reads n bits in two's complement
+func (b *bitReader) signed(n uint) (int64, error) {
+  v, err := b.read(n)
+  if err != nil || n == 0 {
+    return 0, err
+  }
+  if v & (1 << (n - 1)) != 0 {
+    return int64(v) - 1 << n, nil
+  }
+  return int64(v), nil
+}
+
+// unary counts the zero bits before the next one bit
+func (b *bitReader) unary() (uint64, error) {
+  n := uint64(0)
+  for {
+    if b.bits == 0 {
+      c, err := b.r.ReadByte()
+      if err != nil {
+        return 0, err
+      }
+      // a whole byte of zeros
+      if c == 0 {
+        n += 8
+        continue
+      }
+      b.cache, b.bits = uint64(c), 8
+    }
+    b.bits--
+    if b.cache >> b.bits & 1 != 0 {
+      b.cache &= 1 << b.bits - 1
+      return n, nil
+    }
+    n++
+  }
+}
+
+// align skips the bits up to the next byte
+func (b *bitReader) align() {
+  b.bits -= b.bits % 8
+  b.cache &= 1 << b.bits - 1
+}
+
+// frame decodes the next frame, io.EOF marks the end of the stream
+func (b *bitReader) frame(info Info) ([][]int32, error) {
+  sync, err := b.read(15)
+  if err != nil {
+    if err == io.ErrUnexpectedEOF {
+      return nil, io.EOF
+    }
+    return nil, err
+  }
+  if sync != 0x7ffc {
+    return nil, errFrameSync
+  }
+  if _, err := b.read(1); err != nil {
+    return nil, unexpected(err)
+  }
+  h, err := b.read(16)
+  if err != nil {
+    return nil, unexpected(err)
+  }
+  sizeCode, rateCode := h >> 12, h >> 8 & 0x0f
+  assignment, depthCode := int(h >> 4 & 0x0f), h >> 1 & 0x07
+
+  // frame or sample number, coded like utf-8
+  first, err := b.read(8)
+  if err != nil {
+    return nil, unexpected(err)
+  }
+  for mask := uint64(0x80); first & mask != 0 && mask > 1; mask >>= 1 {
+    if mask != 0x80 {
+      if _, err := b.read(8); err != nil {
+        return nil, unexpected(err)
+      }
+    }
+  }
+
+  size := 0
+  switch {
+  case sizeCode == 1:
+    size = 192
+  case sizeCode >= 2 && sizeCode <= 5:
+    size = 576 << (sizeCode - 2)
+  case sizeCode == 6:
+    v, err := b.read(8)
+    if err != nil {
+      return nil, unexpected(err)
+    }
+    size = int(v) + 1
+  case sizeCode == 7:
+    v, err := b.read(16)
+    if err != nil {
+      return nil, unexpected(err)
+    }
+    size = int(v) + 1
+  case sizeCode >= 8:
+    size = 256 << (sizeCode - 8)
+  default:
+    return nil, errors.New("reserved block size")
+  }
+  switch rateCode {
+  case 12:
+    _, err = b.read(8)
+  case 13, 14:
+    _, err = b.read(16)
+  }
+  if err != nil {
+    return nil, unexpected(err)
+  }
+
+  depth := info.BitsPerSample
+  if depthCode != 0 {
+    depth = []int{0, 8, 12, 0, 16, 20, 24, 32}[depthCode]
+    if depth == 0 {
+      return nil, errors.New("reserved sample size")
+    }
+  }
+  channels := assignment + 1
+  if assignment >= leftSide {
+    if assignment > midSide {
+      return nil, errors.New("reserved channel assignment")
+    }
+    channels = 2
+  }
+  // crc-8 of the header
+  if _, err := b.read(8); err != nil {
+    return nil, unexpected(err)
+  }
+
+  samples := make([][]int32, channels)
+  for c := range samples {
+    bits := depth
+    // the side channel needs a bit more
+    if (assignment == leftSide && c == 1) || (assignment == sideRight && c == 0) ||
+        (assignment == midSide && c == 1) {
+      bits++
+    }
+    samples[c], err = b.subframe(size, uint(bits))
+    if err != nil {
+      return nil, unexpected(err)
+    }
+  }
+  b.align()
+  // crc-16 of the frame
+  if _, err := b.read(16); err != nil {
+    return nil, unexpected(err)
+  }
+
+  switch assignment {
+  case leftSide:
+    for i, side := range samples[1] {
+      samples[1][i] = samples[0][i] - side
+    }
+  case sideRight:
+    for i, side := range samples[0] {
+      samples[0][i] = samples[1][i] + side
+    }
+  case midSide:
+    for i := range samples[0] {
+      mid, side := int64(samples[0][i]) << 1 | int64(samples[1][i]) & 1, int64(samples[1][i])
+      samples[0][i] = int32((mid + side) >> 1)
+      samples[1][i] = int32((mid - side) >> 1)
+    }
+  }
+  return samples, nil
+}
+
+// unexpected turns the end of the stream within a frame into an error
+func unexpected(err error) error {
+  if err == io.EOF {
+    return io.ErrUnexpectedEOF
+  }
+  return err
+}
+
+// subframe decodes size samples of bits bits
+func (b *bitReader) subframe(size int, bits uint) ([]int32, error) {
+  h, err := b.read(8)
+  if err != nil {
+    return nil, err
+  }
+  if h & 0x80 != 0 {
+    return nil, errors.New("invalid subframe header")
+  }
+  kind := h >> 1 & 0x3f
+  wasted := uint(0)
+  if h & 1 != 0 {
+    k, err := b.unary()
+    if err != nil {
+      return nil, err
+    }
+    wasted = uint(k) + 1
+    if wasted >= bits {
+      return nil, errors.New("invalid wasted bits")
+    }
+    bits -= wasted
+  }
+
+  samples := make([]int32, size)
+  switch {
+  case kind == 0:
+    v, err := b.signed(bits)
+    if err != nil {
+      return nil, err
+    }
+    for i := range samples {
+      samples[i] = int32(v)
+    }
+
+  case kind == 1:
+    for i := range samples {
+      v, err := b.signed(bits)
+      if err != nil {
+        return nil, err
+      }
+      samples[i] = int32(v)
+    }
+
+  case kind >= 8 && kind <= 12:
+    order := int(kind - 8)
+    if err := b.warmup(samples, order, bits); err != nil {
+      return nil, err
+    }
+    if err := b.residual(samples, order); err != nil {
+      return nil, err
+    }
+    fixedPrediction(samples, order)
+
+  case kind >= 32:
+    order := int(kind - 31)
+    if err := b.warmup(samples, order, bits); err != nil {
+      return nil, err
+    }
+    p, err := b.read(4)
+    if err != nil {
+      return nil, err
+    }
+    if p == 15 {
+      return nil, errors.New("invalid lpc precision")
+    }
+    shift, err := b.signed(5)
+    if err != nil {
+      return nil, err
+    }
+    if shift < 0 {
+      return nil, errors.New("negative lpc shift")
+    }
+    coefficients := make([]int64, order)
+    for i := range coefficients {
+      if coefficients[i], err = b.signed(uint(p) + 1); err != nil {
+        return nil, err
+      }
+    }
+    if err := b.residual(samples, order); err != nil {
+      return nil, err
+    }
+    for i := order; i < size; i++ {
+      sum := int64(0)
+      for j, c := range coefficients {
+        sum += c * int64(samples[i - j - 1])
+      }
+      samples[i] += int32(sum >> uint(shift))
+    }
+
+  default:
+    return nil, errors.New(fmt.Sprintf("reserved subframe type %d", kind))
+  }
+
+  if wasted > 0 {
+    for i := range samples {
+      samples[i] <<= wasted
+    }
+  }
+  return samples, nil
+}
+
+// warmup reads the unpredicted samples in front of the residual
+func (b *bitReader) warmup(samples []int32, order int, bits uint) error {
+  if order > len(samples) {
+    return errors.New("predictor order exceeds the block size")
+  }
+  for i := 0; i < order; i++ {
+    v, err := b.signed(bits)
+    if err != nil {
+      return err
+    }
+    samples[i] = int32(v)
+  }
+  return nil
+}
+
+// residual reads the rice coded residual of the samples behind
+// the warm up samples
+func (b *bitReader) residual(samples []int32, order int) error {
+  method, err := b.read(2)
+  if err != nil {
+    return err
+  }
+  if method > 1 {
+    return errors.New("reserved residual coding method")
+  }
+  paramBits, escape := uint(4), uint64(15)
+  if method == 1 {
+    paramBits, escape = 5, 31
+  }
+  partitionOrder, err := b.read(4)
+  if err != nil {
+    return err
+  }
+  partitions := 1 << partitionOrder
+  if len(samples) % partitions != 0 || len(samples) / partitions < order {
+    return errors.New("invalid residual partition order")
+  }
+
+  i := order
+  for p := 0; p < partitions; p++ {
+    end := (p + 1) * len(samples) / partitions
+    param, err := b.read(paramBits)
+    if err != nil {
+      return err
+    }
+    if param == escape {
+      n, err := b.read(5)
+      if err != nil {
+        return err
+      }
+      for ; i < end; i++ {
+        v, err := b.signed(uint(n))
+        if err != nil {
+          return err
+        }
+        samples[i] = int32(v)
+      }
+      continue
+    }
+    for ; i < end; i++ {
+      q, err := b.unary()
+      if err != nil {
+        return err
+      }
+      low, err := b.read(uint(param))
+      if err != nil {
+        return err
+      }
+      v := q << param | low
+      // zigzag coding of signed values
+      samples[i] = int32(v >> 1) ^ -int32(v & 1)
+    }
+  }
+  return nil
+}
+
+// fixedPrediction adds the prediction of the fixed polynomial
+// predictor of order to the residual behind the warm up samples
+func fixedPrediction(s []int32, order int) {
+  for i := order; i < len(s); i++ {
+    switch order {
+    case 1:
+      s[i] += s[i-1]
+    case 2:
+      s[i] += 2 * s[i-1] - s[i-2]
+    case 3:
+      s[i] += 3 * s[i-1] - 3 * s[i-2] + s[i-3]
+    case 4:
+      s[i] += 4 * s[i-1] - 6 * s[i-2] + 4 * s[i-3] - s[i-4]
+    }
+  }
+}
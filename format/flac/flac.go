@@ -0,0 +1,283 @@
+package flac
+
+import (
+  "encoding/binary"
+  "encoding/hex"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+)
+
+import (
+  id3v2 "github.com/elias-boemeke/taggo/format/id3v2"
+  rewrite "github.com/elias-boemeke/taggo/format/rewrite"
+  vorbis "github.com/elias-boemeke/taggo/format/vorbis"
+)
+
+
+
+type BlockType byte
+const (
+  StreamInfo    BlockType = 0
+  Padding       BlockType = 1
+  Application   BlockType = 2
+  SeekTable     BlockType = 3
+  VorbisComment BlockType = 4
+  CueSheet      BlockType = 5
+  Picture       BlockType = 6
+)
+
+const DefaultPadding = 4096
+
+// block lengths are stored in 24 bits
+const maxBlockLength = 1 << 24 - 1
+
+var ErrNotFLAC = errors.New("not a flac file")
+
+type Block struct {
+  Type BlockType
+  Data []byte
+}
+
+type Metadata struct {
+  // offset of the fLaC marker, non-zero if an id3v2 tag precedes it
+  Offset int64
+  Blocks []*Block
+  // offset of the first audio frame
+  AudioStart int64
+  // size of the whole file
+  FileSize int64
+  Info Info
+}
+
+type Info struct {
+  MinBlockSize  int
+  MaxBlockSize  int
+  MinFrameSize  int
+  MaxFrameSize  int
+  Samplerate    int
+  Channels      int
+  BitsPerSample int
+  // zero if unknown
+  TotalSamples int64
+  MD5          [16]byte
+}
+
+var blockNames = map[BlockType]string{
+  StreamInfo:    "STREAMINFO",
+  Padding:       "PADDING",
+  Application:   "APPLICATION",
+  SeekTable:     "SEEKTABLE",
+  VorbisComment: "VORBIS_COMMENT",
+  CueSheet:      "CUESHEET",
+  Picture:       "PICTURE",
+}
+
+func (t BlockType) String() string {
+  if name, ok := blockNames[t]; ok {
+    return name
+  }
+  return fmt.Sprintf("UNKNOWN(%d)", byte(t))
+}
+
+// MD5String returns the md5 signature of the unencoded audio in hex
+func (i Info) MD5String() string {
+  return hex.EncodeToString(i.MD5[:])
+}
+
+// ReadFile reads the metadata blocks of the flac file at path
+func ReadFile(path string) (*Metadata, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+  info, err := file.Stat()
+  if err != nil {
+    return nil, err
+  }
+  m, err := Read(file)
+  if err != nil {
+    return nil, err
+  }
+  m.FileSize = info.Size()
+  return m, nil
+}
+
+// Read reads the metadata blocks from the beginning of r,
+// skipping an id3v2 tag in front of the flac stream
+func Read(r io.ReadSeeker) (*Metadata, error) {
+  m := &Metadata{}
+  size, _, _, err := id3v2.ReadHeader(r)
+  if err == nil {
+    m.Offset = int64(size)
+  } else if err != id3v2.ErrNoTag {
+    return nil, err
+  }
+  if _, err := r.Seek(m.Offset, io.SeekStart); err != nil {
+    return nil, err
+  }
+
+  marker := make([]byte, 4)
+  if _, err := io.ReadFull(r, marker); err != nil || string(marker) != "fLaC" {
+    return nil, ErrNotFLAC
+  }
+  pos := m.Offset + 4
+
+  header := make([]byte, 4)
+  for last := false; !last; {
+    if _, err := io.ReadFull(r, header); err != nil {
+      return nil, errors.New(fmt.Sprintf("flac metadata truncated: %s", err))
+    }
+    last = header[0] & 0x80 != 0
+    length := int(header[1]) << 16 | int(header[2]) << 8 | int(header[3])
+    b := &Block{Type: BlockType(header[0] & 0x7f), Data: make([]byte, length)}
+    if _, err := io.ReadFull(r, b.Data); err != nil {
+      return nil, errors.New(fmt.Sprintf("flac %s block truncated: %s", b.Type, err))
+    }
+    m.Blocks = append(m.Blocks, b)
+    pos += 4 + int64(length)
+  }
+  m.AudioStart = pos
+
+  if len(m.Blocks) == 0 || m.Blocks[0].Type != StreamInfo {
+    return nil, errors.New("flac stream does not start with STREAMINFO")
+  }
+  m.Info, err = decodeInfo(m.Blocks[0].Data)
+  if err != nil {
+    return nil, err
+  }
+  return m, nil
+}
+
+func decodeInfo(b []byte) (Info, error) {
+  var i Info
+  if len(b) < 34 {
+    return i, errors.New("flac STREAMINFO truncated")
+  }
+  i.MinBlockSize = int(binary.BigEndian.Uint16(b[0:]))
+  i.MaxBlockSize = int(binary.BigEndian.Uint16(b[2:]))
+  i.MinFrameSize = int(b[4]) << 16 | int(b[5]) << 8 | int(b[6])
+  i.MaxFrameSize = int(b[7]) << 16 | int(b[8]) << 8 | int(b[9])
+  // 20 bits sample rate, 3 bits channels, 5 bits bits per sample, 36 bits samples
+  v := binary.BigEndian.Uint64(b[10:])
+  i.Samplerate    = int(v >> 44)
+  i.Channels      = int(v >> 41 & 0x07) + 1
+  i.BitsPerSample = int(v >> 36 & 0x1f) + 1
+  i.TotalSamples  = int64(v & 0xfffffffff)
+  copy(i.MD5[:], b[18:34])
+  return i, nil
+}
+
+// BlocksOf returns all blocks of type t
+func (m *Metadata) BlocksOf(t BlockType) []*Block {
+  var blocks []*Block
+  for _, b := range m.Blocks {
+    if b.Type == t {
+      blocks = append(blocks, b)
+    }
+  }
+  return blocks
+}
+
+// Comment decodes the VORBIS_COMMENT block, a new comment
+// is returned if there is none
+func (m *Metadata) Comment() (*vorbis.Comment, error) {
+  blocks := m.BlocksOf(VorbisComment)
+  if len(blocks) == 0 {
+    return vorbis.NewComment("taggo"), nil
+  }
+  c, _, err := vorbis.Decode(blocks[0].Data)
+  return c, err
+}
+
+// SetComment replaces the VORBIS_COMMENT block, it is inserted
+// after STREAMINFO if there was none
+func (m *Metadata) SetComment(c *vorbis.Comment) {
+  data := c.Encode()
+  for _, b := range m.Blocks {
+    if b.Type == VorbisComment {
+      b.Data = data
+      return
+    }
+  }
+  block := &Block{Type: VorbisComment, Data: data}
+  m.Blocks = append(m.Blocks[:1], append([]*Block{block}, m.Blocks[1:]...)...)
+}
+
+// SeekPoints returns the number of points in the SEEKTABLE blocks
+func (m *Metadata) SeekPoints() int {
+  n := 0
+  for _, b := range m.BlocksOf(SeekTable) {
+    n += len(b.Data) / 18
+  }
+  return n
+}
+
+// Encode encodes the fLaC marker and all blocks followed by a single
+// PADDING block of padding bytes (none if padding is negative)
+func (m *Metadata) Encode(padding int) ([]byte, error) {
+  var blocks []*Block
+  for _, b := range m.Blocks {
+    if b.Type != Padding {
+      blocks = append(blocks, b)
+    }
+  }
+  if padding >= 0 {
+    blocks = append(blocks, &Block{Type: Padding, Data: make([]byte, padding)})
+  }
+
+  out := []byte("fLaC")
+  for i, b := range blocks {
+    if len(b.Data) > maxBlockLength {
+      return nil, errors.New(fmt.Sprintf("flac %s block exceeds 16 MB", b.Type))
+    }
+    header := []byte{byte(b.Type), byte(len(b.Data) >> 16),
+      byte(len(b.Data) >> 8), byte(len(b.Data))}
+    if i == len(blocks) - 1 {
+      header[0] |= 0x80
+    }
+    out = append(out, header...)
+    out = append(out, b.Data...)
+  }
+  return out, nil
+}
+
+// WriteFile writes the metadata blocks to the flac file at path; existing
+// padding is used up if possible so that the audio does not move, else
+// padding bytes (DefaultPadding if negative) are reserved; if compact is
+// set free space beyond that is given back
+func WriteFile(path string, m *Metadata, padding int, compact bool) error {
+  oldSize := int(m.AudioStart - m.Offset)
+
+  data, err := m.Encode(-1)
+  if err != nil {
+    return err
+  }
+  if padding < 0 {
+    padding = DefaultPadding
+  }
+  // a padding block needs at least its header of 4 bytes
+  if free := oldSize - len(data); free == 0 {
+    padding = -1
+  } else if free >= 4 && free - 4 <= maxBlockLength && (!compact || free - 4 <= padding) {
+    padding = free - 4
+  }
+  data, err = m.Encode(padding)
+  if err != nil {
+    return err
+  }
+
+  err = rewrite.Replace(path, m.Offset, m.AudioStart, data)
+  if err != nil {
+    return err
+  }
+  // read back to pick up the written padding and offsets
+  n, err := ReadFile(path)
+  if err != nil {
+    return err
+  }
+  *m = *n
+  return nil
+}
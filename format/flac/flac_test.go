@@ -0,0 +1,141 @@
+package flac
+
+import (
+  "bytes"
+  "io/ioutil"
+  "path/filepath"
+  "reflect"
+  "strings"
+  "testing"
+)
+
+
+
+var frames = []byte("\xff\xf8audio frames")
+
+// file returns a flac stream with STREAMINFO and a PADDING block of
+// padding bytes in front of frames, behind prefix
+func file(prefix []byte, padding int) []byte {
+  info := make([]byte, 34)
+  // 44100 Hz, 2 channels, 16 bits, 1000 samples
+  copy(info[10:], []byte{0x0a, 0xc4, 0x42, 0xf0, 0x00, 0x00, 0x03, 0xe8})
+  b := append(append([]byte(nil), prefix...), "fLaC"...)
+  b = append(b, byte(StreamInfo), 0, 0, 34)
+  b = append(b, info...)
+  b = append(b, 0x80 | byte(Padding), byte(padding >> 16), byte(padding >> 8), byte(padding))
+  b = append(b, make([]byte, padding)...)
+  return append(b, frames...)
+}
+
+func TestRead(t *testing.T) {
+  m, err := Read(bytes.NewReader(file(nil, 100)))
+  if err != nil {
+    t.Fatal(err)
+  }
+  if m.Info.Samplerate != 44100 || m.Info.Channels != 2 || m.Info.BitsPerSample != 16 ||
+      m.Info.TotalSamples != 1000 {
+    t.Errorf("info %+v", m.Info)
+  }
+  if len(m.Blocks) != 2 || m.AudioStart != 4 + 38 + 104 {
+    t.Errorf("%d blocks, audio at %d", len(m.Blocks), m.AudioStart)
+  }
+}
+
+func TestWriteFilePadding(t *testing.T) {
+  id3 := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 4, 0, 0, 0, 0}
+  tests := []struct {
+    name    string
+    prefix  []byte
+    padding int
+    comment int
+    // writing options
+    reserve int
+    compact bool
+    // the audio keeps its offset
+    inPlace bool
+  }{
+    {"padding reused", nil, 1000, 100, -1, false, true},
+    // a block of 4 + 27 + 77 bytes takes the place of the 104 bytes of padding
+    {"padding used up", nil, 100, 77, -1, false, true},
+    {"padding exceeded", nil, 100, 200, 16, false, false},
+    {"behind id3v2", id3, 1000, 100, -1, false, true},
+    {"compact", nil, 1000, 10, 16, true, false},
+  }
+  for _, tt := range tests {
+    path := filepath.Join(t.TempDir(), "a.flac")
+    if err := ioutil.WriteFile(path, file(tt.prefix, tt.padding), 0644); err != nil {
+      t.Fatal(err)
+    }
+    m, err := ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    audioStart := m.AudioStart
+
+    c, err := m.Comment()
+    if err != nil {
+      t.Fatal(err)
+    }
+    c.Set("TITLE", strings.Repeat("x", tt.comment))
+    m.SetComment(c)
+    if err := WriteFile(path, m, tt.reserve, tt.compact); err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+
+    b, err := ioutil.ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if !bytes.HasPrefix(b, tt.prefix) || !bytes.HasSuffix(b, frames) {
+      t.Errorf("%s: prefix or audio data changed", tt.name)
+    }
+    read, err := ReadFile(path)
+    if err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+    if (read.AudioStart == audioStart) != tt.inPlace {
+      t.Errorf("%s: audio moved from %d to %d", tt.name, audioStart, read.AudioStart)
+    }
+    if read.AudioStart + int64(len(frames)) != int64(len(b)) {
+      t.Errorf("%s: audio at %d in %d bytes", tt.name, read.AudioStart, len(b))
+    }
+    if c, err := read.Comment(); err != nil || c.First("TITLE") != strings.Repeat("x", tt.comment) {
+      t.Errorf("%s: comment not read back: %v", tt.name, err)
+    }
+  }
+}
+
+func TestPictures(t *testing.T) {
+  m, err := Read(bytes.NewReader(file(nil, 100)))
+  if err != nil {
+    t.Fatal(err)
+  }
+  front := &PictureBlock{Type: 3, MIME: "image/png", Description: "front", Width: 2,
+    Height: 3, Depth: 24, Data: []byte("png")}
+  back := &PictureBlock{Type: 4, MIME: "image/jpeg", Data: []byte("jpeg")}
+  m.SetPictures([]*PictureBlock{front, back})
+
+  read, err := DecodePicture(m.BlocksOf(Picture)[0].Data)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if !reflect.DeepEqual(read, front) {
+    t.Errorf("picture %+v, want %+v", read, front)
+  }
+  if _, err := DecodePicture(front.Encode()[:20]); err == nil {
+    t.Error("truncated picture decoded")
+  }
+
+  // new pictures take the place of the old ones
+  m.SetPictures([]*PictureBlock{back})
+  var types []BlockType
+  for _, b := range m.Blocks {
+    types = append(types, b.Type)
+  }
+  if !reflect.DeepEqual(types, []BlockType{StreamInfo, Padding, Picture}) {
+    t.Errorf("blocks %v", types)
+  }
+  if pictures := m.Pictures(); len(pictures) != 1 || string(pictures[0].Data) != "jpeg" {
+    t.Errorf("pictures %+v", pictures)
+  }
+}
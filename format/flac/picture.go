@@ -0,0 +1,129 @@
+package flac
+
+import (
+  "encoding/binary"
+  "errors"
+)
+
+
+
+// PictureBlock is the content of a PICTURE block, the same
+// structure is used by METADATA_BLOCK_PICTURE vorbis comments
+type PictureBlock struct {
+  Type        uint32
+  MIME        string
+  Description string
+  Width       int
+  Height      int
+  Depth       int
+  Colors      int
+  Data        []byte
+}
+
+var errPictureTruncated = errors.New("flac PICTURE block truncated")
+
+func DecodePicture(b []byte) (*PictureBlock, error) {
+  pos := 0
+  u32 := func() (uint32, error) {
+    if len(b) - pos < 4 {
+      return 0, errPictureTruncated
+    }
+    v := binary.BigEndian.Uint32(b[pos:])
+    pos += 4
+    return v, nil
+  }
+  bytes := func() ([]byte, error) {
+    n, err := u32()
+    if err != nil {
+      return nil, err
+    }
+    if uint32(len(b) - pos) < n {
+      return nil, errPictureTruncated
+    }
+    v := b[pos:pos + int(n)]
+    pos += int(n)
+    return v, nil
+  }
+
+  p := &PictureBlock{}
+  var err error
+  var v []byte
+  var n uint32
+  if p.Type, err = u32(); err != nil {
+    return nil, err
+  }
+  if v, err = bytes(); err != nil {
+    return nil, err
+  }
+  p.MIME = string(v)
+  if v, err = bytes(); err != nil {
+    return nil, err
+  }
+  p.Description = string(v)
+  for _, field := range []*int{&p.Width, &p.Height, &p.Depth, &p.Colors} {
+    if n, err = u32(); err != nil {
+      return nil, err
+    }
+    *field = int(n)
+  }
+  if v, err = bytes(); err != nil {
+    return nil, err
+  }
+  p.Data = append([]byte(nil), v...)
+  return p, nil
+}
+
+func (p *PictureBlock) Encode() []byte {
+  b := make([]byte, 0, 32 + len(p.MIME) + len(p.Description) + len(p.Data))
+  u32 := func(v uint32) {
+    n := make([]byte, 4)
+    binary.BigEndian.PutUint32(n, v)
+    b = append(b, n...)
+  }
+  u32(p.Type)
+  u32(uint32(len(p.MIME)))
+  b = append(b, p.MIME...)
+  u32(uint32(len(p.Description)))
+  b = append(b, p.Description...)
+  u32(uint32(p.Width))
+  u32(uint32(p.Height))
+  u32(uint32(p.Depth))
+  u32(uint32(p.Colors))
+  u32(uint32(len(p.Data)))
+  return append(b, p.Data...)
+}
+
+// Pictures decodes all PICTURE blocks, broken ones are skipped
+func (m *Metadata) Pictures() []*PictureBlock {
+  var pictures []*PictureBlock
+  for _, b := range m.BlocksOf(Picture) {
+    if p, err := DecodePicture(b.Data); err == nil {
+      pictures = append(pictures, p)
+    }
+  }
+  return pictures
+}
+
+// SetPictures replaces the PICTURE blocks, they are placed
+// where the first one was or at the end
+func (m *Metadata) SetPictures(pictures []*PictureBlock) {
+  var blocks []*Block
+  inserted := false
+  insert := func() {
+    for _, p := range pictures {
+      blocks = append(blocks, &Block{Type: Picture, Data: p.Encode()})
+    }
+    inserted = true
+  }
+  for _, b := range m.Blocks {
+    if b.Type != Picture {
+      blocks = append(blocks, b)
+    } else if !inserted {
+      insert()
+    }
+  }
+  if !inserted {
+    insert()
+  }
+  m.Blocks = blocks
+}
@@ -0,0 +1,200 @@
+package id3v1
+
+import (
+  "bytes"
+  "errors"
+  "io"
+  "os"
+  "strconv"
+  "strings"
+)
+
+import (
+  rewrite "github.com/elias-boemeke/taggo/format/rewrite"
+)
+
+
+
+// size of the tag at the end of a file
+const Size = 128
+
+var ErrNoTag = errors.New("no id3v1 tag")
+
+type Tag struct {
+  Title   string
+  Artist  string
+  Album   string
+  Year    string
+  Comment string
+  // track number of version 1.1, zero for version 1.0
+  Track int
+  Genre byte
+}
+
+func NewTag() *Tag {
+  return &Tag{Genre: NoGenre}
+}
+
+// Version returns "1.1" if the tag carries a track number, else "1.0"
+func (t *Tag) Version() string {
+  if t.Track > 0 {
+    return "1.1"
+  }
+  return "1.0"
+}
+
+// Decode decodes the 128 bytes of a tag
+func Decode(b []byte) (*Tag, error) {
+  if len(b) != Size || string(b[0:3]) != "TAG" {
+    return nil, ErrNoTag
+  }
+  t := &Tag{
+    Title:  decodeString(b[3:33]),
+    Artist: decodeString(b[33:63]),
+    Album:  decodeString(b[63:93]),
+    Year:   decodeString(b[93:97]),
+    Genre:  b[127],
+  }
+  // version 1.1 stores the track in the last byte of the comment
+  if b[125] == 0 && b[126] != 0 {
+    t.Comment = decodeString(b[97:125])
+    t.Track = int(b[126])
+  } else {
+    t.Comment = decodeString(b[97:127])
+  }
+  return t, nil
+}
+
+// Encode encodes the tag to 128 bytes, the names of
+// fields that had to be truncated are returned as well
+func (t *Tag) Encode() ([]byte, []string) {
+  var truncated []string
+  b := make([]byte, Size)
+  copy(b, "TAG")
+  put := func(name string, field []byte, value string) {
+    if !encodeString(field, value) {
+      truncated = append(truncated, name)
+    }
+  }
+  put("title", b[3:33], t.Title)
+  put("artist", b[33:63], t.Artist)
+  put("album", b[63:93], t.Album)
+  put("year", b[93:97], t.Year)
+  if t.Track > 0 && t.Track <= 255 {
+    put("comment", b[97:125], t.Comment)
+    b[126] = byte(t.Track)
+  } else {
+    if t.Track > 255 {
+      truncated = append(truncated, "track")
+    }
+    put("comment", b[97:127], t.Comment)
+  }
+  b[127] = t.Genre
+  return b, truncated
+}
+
+// GenreName returns the name of the genre of the tag
+func (t *Tag) GenreName() string {
+  return GenreName(int(t.Genre))
+}
+
+// TrackString returns the track number or an empty string if there is none
+func (t *Tag) TrackString() string {
+  if t.Track == 0 {
+    return ""
+  }
+  return strconv.Itoa(t.Track)
+}
+
+// decodeString decodes an ISO-8859-1 field padded with zeros or spaces
+func decodeString(b []byte) string {
+  if i := bytes.IndexByte(b, 0); i >= 0 {
+    b = b[:i]
+  }
+  runes := make([]rune, len(b))
+  for i, c := range b {
+    runes[i] = rune(c)
+  }
+  return strings.TrimRight(string(runes), " ")
+}
+
+// encodeString fills field with value in ISO-8859-1, characters not
+// representable are replaced; false is returned if value was truncated
+func encodeString(field []byte, value string) bool {
+  i := 0
+  for _, r := range value {
+    if i == len(field) {
+      return false
+    }
+    if r > 0xff {
+      r = '?'
+    }
+    field[i] = byte(r)
+    i++
+  }
+  return true
+}
+
+// Read reads the tag at the end of r, size being the size of r
+func Read(r io.ReaderAt, size int64) (*Tag, error) {
+  if size < Size {
+    return nil, ErrNoTag
+  }
+  b := make([]byte, Size)
+  if _, err := r.ReadAt(b, size - Size); err != nil {
+    return nil, err
+  }
+  return Decode(b)
+}
+
+// ReadFile reads the tag at the end of the file at path
+func ReadFile(path string) (*Tag, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+  info, err := file.Stat()
+  if err != nil {
+    return nil, err
+  }
+  return Read(file, info.Size())
+}
+
+// WriteFile replaces the tag at the end of the file at path or appends
+// it if there is none; the names of truncated fields are returned
+func WriteFile(path string, t *Tag) ([]string, error) {
+  start, end, err := location(path)
+  if err != nil {
+    return nil, err
+  }
+  b, truncated := t.Encode()
+  return truncated, rewrite.Replace(path, start, end, b)
+}
+
+// RemoveFile removes the tag at the end of the file at path
+func RemoveFile(path string) error {
+  start, end, err := location(path)
+  if err != nil || start == end {
+    return err
+  }
+  return rewrite.Replace(path, start, end, nil)
+}
+
+// location returns the region of an existing tag, which is empty
+// and positioned at the end of the file if there is none
+func location(path string) (int64, int64, error) {
+  info, err := os.Stat(path)
+  if err != nil {
+    return 0, 0, err
+  }
+  size := info.Size()
+  _, err = ReadFile(path)
+  if err == ErrNoTag {
+    return size, size, nil
+  }
+  if err != nil {
+    return 0, 0, err
+  }
+  return size - Size, size, nil
+}
@@ -0,0 +1,102 @@
+package id3v1
+
+import (
+  "bytes"
+  "io/ioutil"
+  "path/filepath"
+  "reflect"
+  "strings"
+  "testing"
+)
+
+
+
+func TestRoundTrip(t *testing.T) {
+  tests := []struct {
+    name      string
+    tag       Tag
+    version   string
+    truncated []string
+  }{
+    {"version 1.0", Tag{Title: "Title", Artist: "Artist", Year: "1999", Comment: "Comment",
+      Genre: 17}, "1.0", nil},
+    {"version 1.1", Tag{Title: "Title", Comment: "Comment", Track: 12, Genre: NoGenre},
+      "1.1", nil},
+    {"latin1", Tag{Title: "Ärger", Album: "Café", Genre: NoGenre}, "1.0", nil},
+    {"truncated", Tag{Title: strings.Repeat("t", 31), Comment: strings.Repeat("c", 29),
+      Track: 3, Genre: NoGenre}, "1.1", []string{"title", "comment"}},
+  }
+  for _, tt := range tests {
+    b, truncated := tt.tag.Encode()
+    if len(b) != Size || !reflect.DeepEqual(truncated, tt.truncated) {
+      t.Errorf("%s: encoded to %d bytes, truncated %q", tt.name, len(b), truncated)
+    }
+    read, err := Decode(b)
+    if err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+    want := tt.tag
+    if len(tt.truncated) > 0 {
+      want.Title, want.Comment = want.Title[:30], want.Comment[:28]
+    }
+    if *read != want || read.Version() != tt.version {
+      t.Errorf("%s: read %+v of version %s", tt.name, *read, read.Version())
+    }
+  }
+}
+
+func TestGenreName(t *testing.T) {
+  tests := []struct {
+    genre byte
+    want  string
+  }{
+    {0, "Blues"},
+    {17, "Rock"},
+    {NoGenre, ""},
+  }
+  for _, tt := range tests {
+    tag := Tag{Genre: tt.genre}
+    if got := tag.GenreName(); got != tt.want {
+      t.Errorf("genre %d named '%s', want '%s'", tt.genre, got, tt.want)
+    }
+  }
+}
+
+func TestWriteFile(t *testing.T) {
+  audio := []byte("\xff\xfbaudio")
+  path := filepath.Join(t.TempDir(), "a.mp3")
+  if err := ioutil.WriteFile(path, audio, 0644); err != nil {
+    t.Fatal(err)
+  }
+  for _, title := range []string{"appended", "replaced"} {
+    tag := NewTag()
+    tag.Title = title
+    if _, err := WriteFile(path, tag); err != nil {
+      t.Fatal(err)
+    }
+    b, err := ioutil.ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if len(b) != len(audio) + Size || !bytes.HasPrefix(b, audio) {
+      t.Errorf("%s: file of %d bytes", title, len(b))
+    }
+    if read, err := ReadFile(path); err != nil || read.Title != title {
+      t.Errorf("%s: tag not read back: %v", title, err)
+    }
+  }
+
+  if err := RemoveFile(path); err != nil {
+    t.Fatal(err)
+  }
+  b, err := ioutil.ReadFile(path)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if !bytes.Equal(b, audio) {
+    t.Errorf("removing the tag left %d bytes", len(b))
+  }
+  if _, err := ReadFile(path); err != ErrNoTag {
+    t.Errorf("read without tag: %v", err)
+  }
+}
@@ -0,0 +1,186 @@
+package id3v2
+
+import (
+  "bytes"
+  "encoding/binary"
+  "errors"
+  "fmt"
+)
+
+
+
+// byte offsets of CHAP frames holding this value are not used
+const NoOffset = 0xffffffff
+
+// flags of CTOC frames
+const (
+  TOCTopLevel = 0x02
+  TOCOrdered  = 0x01
+)
+
+// the content of a CHAP frame
+type Chapter struct {
+  ID string
+  // times in milliseconds
+  Start uint32
+  End   uint32
+  StartOffset uint32
+  EndOffset   uint32
+  // embedded frames, TIT2 holding the title
+  Frames []*Frame
+}
+
+// the content of a CTOC frame
+type TableOfContents struct {
+  ID       string
+  Flags    byte
+  Children []string
+  Frames   []*Frame
+}
+
+// Title returns the value of the embedded TIT2 frame
+func (c *Chapter) Title() string {
+  sub := &Tag{Frames: c.Frames}
+  return sub.Text("TIT2")
+}
+
+// SetTitle replaces the embedded TIT2 frame, using encodings of version
+func (c *Chapter) SetTitle(version byte, title string) {
+  sub := &Tag{Version: version, Frames: c.Frames}
+  sub.SetText("TIT2", title)
+  c.Frames = sub.Frames
+}
+
+// Chapters returns the content of all CHAP frames in tag order
+func (t *Tag) Chapters() []Chapter {
+  var chapters []Chapter
+  for _, f := range t.FramesByID("CHAP") {
+    if c, ok := t.decodeChapter(f); ok {
+      chapters = append(chapters, c)
+    }
+  }
+  return chapters
+}
+
+// SetChapters replaces all CHAP frames
+func (t *Tag) SetChapters(chapters []Chapter) error {
+  var frames [][]byte
+  for _, c := range chapters {
+    data := append([]byte(c.ID), 0)
+    times := make([]byte, 16)
+    binary.BigEndian.PutUint32(times, c.Start)
+    binary.BigEndian.PutUint32(times[4:], c.End)
+    binary.BigEndian.PutUint32(times[8:], c.StartOffset)
+    binary.BigEndian.PutUint32(times[12:], c.EndOffset)
+    data = append(data, times...)
+    sub, err := t.encodeFrames(c.Frames)
+    if err != nil {
+      return err
+    }
+    frames = append(frames, append(data, sub...))
+  }
+  t.RemoveID("CHAP")
+  for _, data := range frames {
+    t.AddFrame("CHAP", data)
+  }
+  return nil
+}
+
+// TablesOfContents returns the content of all CTOC frames in tag order
+func (t *Tag) TablesOfContents() []TableOfContents {
+  var tocs []TableOfContents
+  for _, f := range t.FramesByID("CTOC") {
+    if toc, ok := t.decodeTableOfContents(f); ok {
+      tocs = append(tocs, toc)
+    }
+  }
+  return tocs
+}
+
+// SetTablesOfContents replaces all CTOC frames
+func (t *Tag) SetTablesOfContents(tocs []TableOfContents) error {
+  var frames [][]byte
+  for _, toc := range tocs {
+    if len(toc.Children) > 0xff {
+      return errors.New(fmt.Sprintf("id3v2 table of contents '%s' exceeds 255 entries", toc.ID))
+    }
+    data := append([]byte(toc.ID), 0, toc.Flags, byte(len(toc.Children)))
+    for _, child := range toc.Children {
+      data = append(append(data, []byte(child)...), 0)
+    }
+    sub, err := t.encodeFrames(toc.Frames)
+    if err != nil {
+      return err
+    }
+    frames = append(frames, append(data, sub...))
+  }
+  t.RemoveID("CTOC")
+  for _, data := range frames {
+    t.AddFrame("CTOC", data)
+  }
+  return nil
+}
+
+func (t *Tag) decodeChapter(f *Frame) (Chapter, bool) {
+  end := bytes.IndexByte(f.Data, 0)
+  if f.Encrypted || end < 0 || len(f.Data) < end + 17 {
+    return Chapter{}, false
+  }
+  b := f.Data[end + 1:]
+  c := Chapter{
+    ID:          string(f.Data[:end]),
+    Start:       binary.BigEndian.Uint32(b),
+    End:         binary.BigEndian.Uint32(b[4:]),
+    StartOffset: binary.BigEndian.Uint32(b[8:]),
+    EndOffset:   binary.BigEndian.Uint32(b[12:]),
+  }
+  c.Frames = t.decodeFrames(b[16:])
+  return c, true
+}
+
+func (t *Tag) decodeTableOfContents(f *Frame) (TableOfContents, bool) {
+  end := bytes.IndexByte(f.Data, 0)
+  if f.Encrypted || end < 0 || len(f.Data) < end + 3 {
+    return TableOfContents{}, false
+  }
+  toc := TableOfContents{ID: string(f.Data[:end]), Flags: f.Data[end + 1]}
+  count := int(f.Data[end + 2])
+  b := f.Data[end + 3:]
+  for i := 0; i < count; i++ {
+    n := bytes.IndexByte(b, 0)
+    if n < 0 {
+      return TableOfContents{}, false
+    }
+    toc.Children = append(toc.Children, string(b[:n]))
+    b = b[n + 1:]
+  }
+  toc.Frames = t.decodeFrames(b)
+  return toc, true
+}
+
+// decodeFrames reads embedded frames, which use the headers of the
+// tag version; broken ones end the list
+func (t *Tag) decodeFrames(b []byte) []*Frame {
+  var frames []*Frame
+  for len(b) >= HeaderSize && validFrameID(b[0:4]) {
+    f, n, err := readFrame(b, t.Version, false)
+    if err != nil {
+      break
+    }
+    frames = append(frames, f)
+    b = b[n:]
+  }
+  return frames
+}
+
+func (t *Tag) encodeFrames(frames []*Frame) ([]byte, error) {
+  var data []byte
+  for _, f := range frames {
+    b, err := f.encode(t.Version)
+    if err != nil {
+      return nil, err
+    }
+    data = append(data, b...)
+  }
+  return data, nil
+}
@@ -0,0 +1,97 @@
+package id3v2
+
+import (
+  "strings"
+)
+
+
+
+// frames only defined in version 4 without a version 3 counterpart
+var onlyVersion4 = map[string]bool{
+  "ASPI": true, "EQU2": true, "RVA2": true, "SEEK": true, "SIGN": true,
+  "TDEN": true, "TDRL": true, "TDTG": true, "TMCL": true, "TMOO": true,
+  "TPRO": true, "TSST": true,
+}
+
+// frames only defined in version 3 without a version 4 counterpart
+var onlyVersion3 = map[string]bool{
+  "EQUA": true, "RVAD": true, "TRDA": true, "TSIZ": true,
+}
+
+// ConvertVersion converts the frames of the tag to the given major
+// version, dates are moved between TYER/TDAT/TIME and TDRC
+func (t *Tag) ConvertVersion(version byte) {
+  if t.Version == version {
+    return
+  }
+  // embedded frames of chapters use the headers of the version
+  if chapters, tocs := t.Chapters(), t.TablesOfContents(); len(chapters) + len(tocs) > 0 {
+    defer t.convertChapters(chapters, tocs)
+  }
+
+  date, original := t.Date(), t.OriginalDate()
+  t.RemoveFrames(func(f *Frame) bool {
+    return f.ID == "TYER" || f.ID == "TDAT" || f.ID == "TIME" || f.ID == "TORY" ||
+      f.ID == "TDRC" || f.ID == "TDOR"
+  })
+  if version >= 4 {
+    t.RemoveFrames(func(f *Frame) bool { return onlyVersion3[f.ID] })
+  } else {
+    t.RemoveFrames(func(f *Frame) bool { return onlyVersion4[f.ID] })
+  }
+  t.Version = version
+  t.convertEncodings()
+  t.SetDate(date)
+  t.SetOriginalDate(original)
+  if version >= 4 {
+    renameFrames(t, "IPLS", "TIPL")
+  } else {
+    renameFrames(t, "TIPL", "IPLS")
+  }
+}
+
+// convertEncodings re-encodes text frames using encodings unknown to the
+// current version and joins multiple values for version 3
+func (t *Tag) convertEncodings() {
+  for _, f := range t.Frames {
+    if f.Encrypted || len(f.Data) == 0 || !isTextFrame(f.ID) {
+      continue
+    }
+    enc := f.Data[0]
+    values := DecodeStrings(enc, f.Data[1:])
+    var prefix []string
+    if f.ID == "TXXX" && len(values) > 0 {
+      prefix, values = values[:1], values[1:]
+    }
+    if t.Version < 4 && (enc > EncodingUTF16 || len(values) > 1) {
+      f.Data = t.encodeText(prefix, values)
+    }
+  }
+}
+
+// convertChapters writes chapters and tables of contents read
+// before the conversion with the current version
+func (t *Tag) convertChapters(chapters []Chapter, tocs []TableOfContents) {
+  for i := range chapters {
+    sub := &Tag{Version: t.Version, Frames: chapters[i].Frames}
+    sub.convertEncodings()
+  }
+  for i := range tocs {
+    sub := &Tag{Version: t.Version, Frames: tocs[i].Frames}
+    sub.convertEncodings()
+  }
+  t.SetChapters(chapters)
+  t.SetTablesOfContents(tocs)
+}
+
+func isTextFrame(id string) bool {
+  return strings.HasPrefix(id, "T")
+}
+
+func renameFrames(t *Tag, from string, to string) {
+  for _, f := range t.Frames {
+    if f.ID == from {
+      f.ID = to
+    }
+  }
+}
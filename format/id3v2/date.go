@@ -0,0 +1,68 @@
+package id3v2
+
+import (
+  "regexp"
+)
+
+
+
+// timestamps of version 4 like 2021-03-05T10:20:30, shorter ones are allowed
+var timestamp = regexp.MustCompile(`^(\d{4})(-(\d{2})(-(\d{2})(T(\d{2}):(\d{2})(:\d{2})?)?)?)?$`)
+
+// Date returns the recording date as timestamp; version 3 assembles
+// it from TYER, TDAT (DDMM) and TIME (HHMM)
+func (t *Tag) Date() string {
+  if t.Version >= 4 {
+    return t.Text("TDRC")
+  }
+  date := t.Text("TYER")
+  if dm := t.Text("TDAT"); len(date) == 4 && len(dm) == 4 {
+    date += "-" + dm[2:4] + "-" + dm[0:2]
+    if hm := t.Text("TIME"); len(hm) == 4 {
+      date += "T" + hm[0:2] + ":" + hm[2:4]
+    }
+  }
+  return date
+}
+
+// SetDate sets the recording date, version 3 keeps the parts of the
+// timestamp it has frames for, a month without day is dropped
+func (t *Tag) SetDate(date string) {
+  if t.Version >= 4 {
+    t.SetText("TDRC", date)
+    return
+  }
+  year, dm, hm := date, "", ""
+  if m := timestamp.FindStringSubmatch(date); m != nil {
+    year = m[1]
+    if m[5] != "" {
+      dm = m[5] + m[3]
+    }
+    if m[7] != "" {
+      hm = m[7] + m[8]
+    }
+  }
+  t.SetText("TYER", year)
+  t.SetText("TDAT", dm)
+  t.SetText("TIME", hm)
+}
+
+// OriginalDate returns the original release date,
+// version 3 has the year only (TORY)
+func (t *Tag) OriginalDate() string {
+  if t.Version >= 4 {
+    return t.Text("TDOR")
+  }
+  return t.Text("TORY")
+}
+
+func (t *Tag) SetOriginalDate(date string) {
+  if t.Version >= 4 {
+    t.SetText("TDOR", date)
+    return
+  }
+  if m := timestamp.FindStringSubmatch(date); m != nil {
+    date = m[1]
+  }
+  t.SetText("TORY", date)
+}
@@ -0,0 +1,245 @@
+package id3v2
+
+import (
+  "bytes"
+  "strings"
+)
+
+
+
+type Comment struct {
+  Language    string
+  Description string
+  Text        string
+}
+
+// FramesByID returns all frames with the given id in tag order
+func (t *Tag) FramesByID(id string) []*Frame {
+  var frames []*Frame
+  for _, f := range t.Frames {
+    if f.ID == id {
+      frames = append(frames, f)
+    }
+  }
+  return frames
+}
+
+// RemoveFrames removes all frames for which remove returns true
+func (t *Tag) RemoveFrames(remove func(*Frame) bool) {
+  frames := t.Frames[:0]
+  for _, f := range t.Frames {
+    if !remove(f) {
+      frames = append(frames, f)
+    }
+  }
+  t.Frames = frames
+}
+
+// RemoveID removes all frames with the given id
+func (t *Tag) RemoveID(id string) {
+  t.RemoveFrames(func(f *Frame) bool { return f.ID == id })
+}
+
+// AddFrame appends a frame with the given id and content
+func (t *Tag) AddFrame(id string, data []byte) *Frame {
+  f := &Frame{ID: id, Data: data}
+  t.Frames = append(t.Frames, f)
+  return f
+}
+
+// TextValues returns the values of the first text frame with the given id
+func (t *Tag) TextValues(id string) []string {
+  for _, f := range t.Frames {
+    if f.ID == id && !f.Encrypted && len(f.Data) > 0 {
+      return t.DecodeValues(id, f.Data[0], f.Data[1:])
+    }
+  }
+  return nil
+}
+
+// version 3 text frames holding lists, for which a slash is the
+// conventional separator; titles and user text often contain slashes
+var listFrames = map[string]bool{
+  "TPE1": true, "TPE2": true, "TPE3": true, "TPE4": true, "TCOM": true,
+  "TEXT": true, "TCON": true, "TOLY": true, "TOPE": true,
+}
+
+// DecodeValues decodes the values of a text frame with the given id,
+// version 3 list frames join them by a slash since it has no separator
+func (t *Tag) DecodeValues(id string, enc byte, data []byte) []string {
+  values := DecodeStrings(enc, data)
+  if t.Version >= 4 || !listFrames[id] {
+    return values
+  }
+  var split []string
+  for _, v := range values {
+    split = append(split, strings.Split(v, "/")...)
+  }
+  return split
+}
+
+// Text returns the value of the first text frame with the given id,
+// multiple values are joined by a slash
+func (t *Tag) Text(id string) string {
+  return strings.Join(t.TextValues(id), "/")
+}
+
+// SetText replaces the text frames with the given id, the frame is
+// removed when no non-empty value is given
+func (t *Tag) SetText(id string, values ...string) {
+  values = nonEmpty(values)
+  // the first frame is reused to keep its position and flags
+  var f *Frame
+  if frames := t.FramesByID(id); len(frames) > 0 {
+    f = frames[0]
+  }
+  t.RemoveFrames(func(o *Frame) bool { return o.ID == id && o != f })
+  if len(values) == 0 {
+    t.RemoveID(id)
+    return
+  }
+  if f == nil {
+    f = t.AddFrame(id, nil)
+  }
+  f.Data = t.encodeText(nil, values)
+}
+
+// UserText returns the values of the TXXX frame with the given description
+func (t *Tag) UserText(description string) []string {
+  for _, f := range t.FramesByID("TXXX") {
+    if f.Encrypted || len(f.Data) == 0 {
+      continue
+    }
+    desc, rest := SplitString(f.Data[0], f.Data[1:])
+    if strings.EqualFold(DecodeString(f.Data[0], desc), description) {
+      return DecodeStrings(f.Data[0], rest)
+    }
+  }
+  return nil
+}
+
+// SetUserText replaces the TXXX frame with the given description
+func (t *Tag) SetUserText(description string, values ...string) {
+  t.RemoveFrames(func(f *Frame) bool {
+    if f.ID != "TXXX" || f.Encrypted || len(f.Data) == 0 {
+      return false
+    }
+    desc, _ := SplitString(f.Data[0], f.Data[1:])
+    return strings.EqualFold(DecodeString(f.Data[0], desc), description)
+  })
+  values = nonEmpty(values)
+  if len(values) == 0 {
+    return
+  }
+  t.AddFrame("TXXX", t.encodeText([]string{description}, values))
+}
+
+// encodeText encodes the strings of prefix, each terminated, followed by
+// values, for version 3 values are joined by a slash into one string
+func (t *Tag) encodeText(prefix []string, values []string) []byte {
+  if t.Version < 4 {
+    values = []string{strings.Join(values, "/")}
+  }
+  enc := BestEncoding(t.Version, append(append([]string{}, prefix...), values...)...)
+  data := []byte{enc}
+  for _, p := range prefix {
+    data = append(data, EncodeString(enc, p)...)
+    data = append(data, Terminator(enc)...)
+  }
+  for i, v := range values {
+    if i > 0 {
+      data = append(data, Terminator(enc)...)
+    }
+    data = append(data, EncodeString(enc, v)...)
+  }
+  return data
+}
+
+// Comments returns the content of all COMM frames
+func (t *Tag) Comments() []Comment {
+  var comments []Comment
+  for _, f := range t.FramesByID("COMM") {
+    if c, ok := decodeComment(f); ok {
+      comments = append(comments, c)
+    }
+  }
+  return comments
+}
+
+// Comment returns the text of the first comment with the given
+// description in any language
+func (t *Tag) Comment(description string) string {
+  for _, c := range t.Comments() {
+    if c.Description == description {
+      return c.Text
+    }
+  }
+  return ""
+}
+
+// SetComment replaces the comments with the given description,
+// an empty text removes them; the language of a replaced comment
+// is kept, new comments use language lang
+func (t *Tag) SetComment(lang string, description string, text string) {
+  for _, c := range t.Comments() {
+    if c.Description == description {
+      lang = c.Language
+      break
+    }
+  }
+  t.RemoveFrames(func(f *Frame) bool {
+    c, ok := decodeComment(f)
+    return ok && f.ID == "COMM" && c.Description == description
+  })
+  if text == "" {
+    return
+  }
+  t.AddFrame("COMM", t.EncodeLanguageText(lang, description, text))
+}
+
+// EncodeLanguageText encodes the content shared by COMM and USLT frames
+func (t *Tag) EncodeLanguageText(lang string, description string, text string) []byte {
+  enc := BestEncoding(t.Version, description, text)
+  data := []byte{enc}
+  data = append(data, []byte(normLanguage(lang))...)
+  data = append(data, EncodeString(enc, description)...)
+  data = append(data, Terminator(enc)...)
+  return append(data, EncodeString(enc, text)...)
+}
+
+// DecodeLanguageText decodes the content shared by COMM and USLT frames
+func DecodeLanguageText(data []byte) (string, string, string, bool) {
+  if len(data) < 4 {
+    return "", "", "", false
+  }
+  enc := data[0]
+  lang := string(bytes.TrimRight(data[1:4], "\x00"))
+  desc, text := SplitString(enc, data[4:])
+  return lang, DecodeString(enc, desc), DecodeString(enc, text), true
+}
+
+func decodeComment(f *Frame) (Comment, bool) {
+  if f.Encrypted {
+    return Comment{}, false
+  }
+  lang, desc, text, ok := DecodeLanguageText(f.Data)
+  return Comment{lang, desc, text}, ok
+}
+
+func normLanguage(lang string) string {
+  lang = strings.ToLower(lang)
+  if len(lang) != 3 {
+    return "XXX"
+  }
+  return lang
+}
+
+func nonEmpty(values []string) []string {
+  var v []string
+  for _, s := range values {
+    if s != "" {
+      v = append(v, s)
+    }
+  }
+  return v
+}
@@ -0,0 +1,296 @@
+package id3v2
+
+import (
+  "bytes"
+  "compress/zlib"
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "io"
+  "io/ioutil"
+)
+
+
+
+// tag header flags
+const (
+  flagUnsynchronisation = 0x80
+  flagExtendedHeader    = 0x40
+  flagExperimental      = 0x20
+  flagFooter            = 0x10
+)
+
+const HeaderSize = 10
+
+var ErrNoTag = errors.New("no id3v2 tag")
+
+type Tag struct {
+  // major version, 3 or 4; version 2 tags are read as version 3
+  Version  byte
+  Revision byte
+  Frames   []*Frame
+  // padding found after the frames when the tag was read
+  Padding int
+  // size of the tag including header and footer when it was read
+  Size int
+}
+
+type Frame struct {
+  ID string
+  // frame content without compression and unsynchronisation,
+  // for encrypted frames the content as stored in the file
+  Data []byte
+
+  DiscardOnTagAlter  bool
+  DiscardOnFileAlter bool
+  ReadOnly           bool
+
+  Grouped bool
+  GroupID byte
+
+  Encrypted  bool
+  Encryption byte
+  // size of the decrypted content of compressed encrypted frames
+  compressed bool
+  dataLength uint32
+}
+
+func NewTag(version byte) *Tag {
+  return &Tag{Version: version}
+}
+
+func syncsafe(b []byte) int {
+  return int(b[0] & 0x7f) << 21 | int(b[1] & 0x7f) << 14 |
+    int(b[2] & 0x7f) << 7 | int(b[3] & 0x7f)
+}
+
+func putSyncsafe(b []byte, n int) {
+  b[0] = byte(n >> 21) & 0x7f
+  b[1] = byte(n >> 14) & 0x7f
+  b[2] = byte(n >> 7) & 0x7f
+  b[3] = byte(n) & 0x7f
+}
+
+// removeUnsynchronisation reverts the unsynchronisation scheme,
+// every 0xff 0x00 sequence becomes 0xff
+func removeUnsynchronisation(b []byte) []byte {
+  out := make([]byte, 0, len(b))
+  for i := 0; i < len(b); i++ {
+    out = append(out, b[i])
+    if b[i] == 0xff && i + 1 < len(b) && b[i + 1] == 0x00 {
+      i++
+    }
+  }
+  return out
+}
+
+// ReadHeader reads the tag header and returns the tag size including
+// header and footer, the tag version and the header flags
+func ReadHeader(r io.Reader) (int, byte, byte, error) {
+  header := make([]byte, HeaderSize)
+  if _, err := io.ReadFull(r, header); err != nil {
+    if err == io.EOF || err == io.ErrUnexpectedEOF {
+      return 0, 0, 0, ErrNoTag
+    }
+    return 0, 0, 0, err
+  }
+  if string(header[0:3]) != "ID3" || header[3] == 0xff || header[4] == 0xff {
+    return 0, 0, 0, ErrNoTag
+  }
+  size := HeaderSize + syncsafe(header[6:10])
+  flags := header[5]
+  if header[3] >= 4 && flags & flagFooter != 0 {
+    size += HeaderSize
+  }
+  return size, header[3], flags, nil
+}
+
+// Read reads a tag from the beginning of r
+func Read(r io.Reader) (*Tag, error) {
+  size, version, flags, err := ReadHeader(r)
+  if err != nil {
+    return nil, err
+  }
+  if version < 2 || version > 4 {
+    return nil, errors.New(fmt.Sprintf("unsupported id3v2 version 2.%d", version))
+  }
+
+  bodySize := size - HeaderSize
+  if version >= 4 && flags & flagFooter != 0 {
+    bodySize -= HeaderSize
+  }
+  body := make([]byte, bodySize)
+  if _, err := io.ReadFull(r, body); err != nil {
+    return nil, errors.New(fmt.Sprintf("id3v2 tag truncated: %s", err))
+  }
+
+  if version <= 3 && flags & flagUnsynchronisation != 0 {
+    body = removeUnsynchronisation(body)
+  }
+
+  if version == 2 {
+    // the flag of the extended header means compression in version 2
+    if flags & flagExtendedHeader != 0 {
+      return nil, errors.New("compressed id3v2.2 tags are not supported")
+    }
+    frames, padding, err := readFrames22(body)
+    if err != nil {
+      return nil, err
+    }
+    return &Tag{Version: 3, Frames: frames, Padding: padding, Size: size}, nil
+  }
+
+  tag := &Tag{Version: version, Size: size}
+
+  if flags & flagExtendedHeader != 0 {
+    if len(body) < 4 {
+      return nil, errors.New("id3v2 extended header truncated")
+    }
+    var extSize int
+    if version == 3 {
+      // size excludes the size field itself
+      extSize = int(binary.BigEndian.Uint32(body)) + 4
+    } else {
+      extSize = syncsafe(body)
+    }
+    if extSize > len(body) {
+      return nil, errors.New("id3v2 extended header exceeds tag")
+    }
+    body = body[extSize:]
+  }
+
+  for len(body) >= HeaderSize {
+    if body[0] == 0 || !validFrameID(body[0:4]) {
+      break
+    }
+    frame, n, err := readFrame(body, version, flags & flagUnsynchronisation != 0)
+    if err != nil {
+      return nil, err
+    }
+    tag.Frames = append(tag.Frames, frame)
+    body = body[n:]
+  }
+  tag.Padding = len(body)
+
+  return tag, nil
+}
+
+func validFrameID(id []byte) bool {
+  for _, c := range id {
+    if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+      return false
+    }
+  }
+  return true
+}
+
+// readFrame reads the frame at the beginning of b and
+// returns it with the number of bytes consumed
+func readFrame(b []byte, version byte, tagUnsync bool) (*Frame, int, error) {
+  id := string(b[0:4])
+  var size int
+  if version >= 4 {
+    size = syncsafe(b[4:8])
+  } else {
+    size = int(binary.BigEndian.Uint32(b[4:8]))
+  }
+  if size > len(b) - HeaderSize {
+    return nil, 0, errors.New(fmt.Sprintf("id3v2 frame '%s' exceeds tag", id))
+  }
+  status, format := b[8], b[9]
+  data := b[HeaderSize:HeaderSize + size]
+  f := &Frame{ID: id}
+
+  var unsync, hasLength bool
+  if version >= 4 {
+    f.DiscardOnTagAlter  = status & 0x40 != 0
+    f.DiscardOnFileAlter = status & 0x20 != 0
+    f.ReadOnly           = status & 0x10 != 0
+    f.Grouped            = format & 0x40 != 0
+    f.compressed         = format & 0x08 != 0
+    f.Encrypted          = format & 0x04 != 0
+    unsync               = format & 0x02 != 0 || tagUnsync
+    hasLength            = format & 0x01 != 0
+  } else {
+    f.DiscardOnTagAlter  = status & 0x80 != 0
+    f.DiscardOnFileAlter = status & 0x40 != 0
+    f.ReadOnly           = status & 0x20 != 0
+    f.compressed         = format & 0x80 != 0
+    f.Encrypted          = format & 0x40 != 0
+    f.Grouped            = format & 0x20 != 0
+    hasLength            = f.compressed
+  }
+
+  // additional header bytes, in the order of their flags
+  take := func(n int) ([]byte, error) {
+    if len(data) < n {
+      return nil, errors.New(fmt.Sprintf("id3v2 frame '%s' truncated", id))
+    }
+    v := data[:n]
+    data = data[n:]
+    return v, nil
+  }
+  if version >= 4 {
+    if f.Grouped {
+      v, err := take(1)
+      if err != nil {
+        return nil, 0, err
+      }
+      f.GroupID = v[0]
+    }
+    if f.Encrypted {
+      v, err := take(1)
+      if err != nil {
+        return nil, 0, err
+      }
+      f.Encryption = v[0]
+    }
+    if hasLength {
+      v, err := take(4)
+      if err != nil {
+        return nil, 0, err
+      }
+      f.dataLength = uint32(syncsafe(v))
+    }
+  } else {
+    if hasLength {
+      v, err := take(4)
+      if err != nil {
+        return nil, 0, err
+      }
+      f.dataLength = binary.BigEndian.Uint32(v)
+    }
+    if f.Encrypted {
+      v, err := take(1)
+      if err != nil {
+        return nil, 0, err
+      }
+      f.Encryption = v[0]
+    }
+    if f.Grouped {
+      v, err := take(1)
+      if err != nil {
+        return nil, 0, err
+      }
+      f.GroupID = v[0]
+    }
+  }
+
+  if unsync {
+    data = removeUnsynchronisation(data)
+  }
+  if f.compressed && !f.Encrypted {
+    zr, err := zlib.NewReader(bytes.NewReader(data))
+    if err != nil {
+      return nil, 0, errors.New(fmt.Sprintf("id3v2 frame '%s': %s", id, err))
+    }
+    data, err = ioutil.ReadAll(zr)
+    if err != nil {
+      return nil, 0, errors.New(fmt.Sprintf("id3v2 frame '%s': %s", id, err))
+    }
+    f.compressed = false
+  }
+  f.Data = append([]byte(nil), data...)
+
+  return f, HeaderSize + size, nil
+}
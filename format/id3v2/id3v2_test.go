@@ -0,0 +1,245 @@
+package id3v2
+
+import (
+  "bytes"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "reflect"
+  "strings"
+  "testing"
+)
+
+
+
+// tag returns a tag of the given header flags around body
+func tag(version byte, flags byte, body []byte) []byte {
+  header := []byte{'I', 'D', '3', version, 0, flags, 0, 0, 0, 0}
+  putSyncsafe(header[6:10], len(body))
+  return append(header, body...)
+}
+
+// frame returns a frame of version 3 or 4 with the given format flags
+func frame(version byte, id string, format byte, data []byte) []byte {
+  b := append([]byte(id), 0, 0, 0, 0, 0, format)
+  if version >= 4 {
+    putSyncsafe(b[4:8], len(data))
+  } else {
+    b[4], b[5], b[6], b[7] = byte(len(data) >> 24), byte(len(data) >> 16),
+      byte(len(data) >> 8), byte(len(data))
+  }
+  return append(b, data...)
+}
+
+func TestRoundTrip(t *testing.T) {
+  for _, version := range []byte{3, 4} {
+    tg := NewTag(version)
+    tg.SetText("TIT2", "Title")
+    tg.SetText("TPE1", "One", "Two")
+    tg.SetUserText("SOURCE", "web")
+    tg.SetComment("eng", "", "Comment")
+
+    b, err := tg.Encode(32)
+    if err != nil {
+      t.Fatal(err)
+    }
+    read, err := Read(bytes.NewReader(b))
+    if err != nil {
+      t.Fatal(err)
+    }
+    if read.Version != version || read.Padding != 32 || read.Size != len(b) {
+      t.Errorf("v2.%d: read version %d, padding %d, size %d", version,
+        read.Version, read.Padding, read.Size)
+    }
+    if got := read.TextValues("TPE1"); !reflect.DeepEqual(got, []string{"One", "Two"}) {
+      t.Errorf("v2.%d: TPE1 %q", version, got)
+    }
+    if got := read.Text("TIT2"); got != "Title" {
+      t.Errorf("v2.%d: TIT2 '%s'", version, got)
+    }
+    if got := read.UserText("SOURCE"); !reflect.DeepEqual(got, []string{"web"}) {
+      t.Errorf("v2.%d: TXXX:SOURCE %q", version, got)
+    }
+    if got := read.Comment(""); got != "Comment" {
+      t.Errorf("v2.%d: COMM '%s'", version, got)
+    }
+  }
+}
+
+func TestUnsynchronisation(t *testing.T) {
+  text := []byte{EncodingISO88591, 'a', 0xff, 0xe0, 'b'}
+  synced := []byte{EncodingISO88591, 'a', 0xff, 0x00, 0xe0, 'b'}
+  tests := []struct {
+    name string
+    b    []byte
+  }{
+    // the whole tag is unsynchronised, frame sizes are those of the content
+    {"v2.3 tag", tag(3, flagUnsynchronisation,
+      append(frame(3, "TIT2", 0, text)[:10], synced...))},
+    // frame sizes include the inserted bytes
+    {"v2.4 frame", tag(4, 0, frame(4, "TIT2", 0x02, synced))},
+    {"v2.4 tag", tag(4, flagUnsynchronisation, frame(4, "TIT2", 0, synced))},
+  }
+  for _, tt := range tests {
+    read, err := Read(bytes.NewReader(tt.b))
+    if err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+    if len(read.Frames) != 1 || !bytes.Equal(read.Frames[0].Data, text) {
+      t.Errorf("%s: frames %v", tt.name, read.Frames)
+    }
+  }
+}
+
+func TestReadVersion2(t *testing.T) {
+  frame22 := func(id string, data []byte) []byte {
+    n := len(data)
+    return append([]byte{id[0], id[1], id[2], byte(n >> 16), byte(n >> 8), byte(n)}, data...)
+  }
+  body := frame22("TT2", []byte("\x00Title"))
+  body = append(body, frame22("TP1", []byte("\x00Artist"))...)
+  body = append(body, frame22("PIC", []byte("\x00PNG\x03\x00png"))...)
+  body = append(body, frame22("XXX", []byte("skipped"))...)
+  body = append(body, make([]byte, 8)...)
+
+  read, err := Read(bytes.NewReader(tag(2, 0, body)))
+  if err != nil {
+    t.Fatal(err)
+  }
+  if read.Version != 3 || read.Padding != 8 || len(read.Frames) != 3 {
+    t.Fatalf("read version %d, padding %d, %d frames", read.Version, read.Padding,
+      len(read.Frames))
+  }
+  if read.Text("TIT2") != "Title" || read.Text("TPE1") != "Artist" {
+    t.Errorf("read TIT2 '%s', TPE1 '%s'", read.Text("TIT2"), read.Text("TPE1"))
+  }
+  pictures := read.Pictures()
+  if len(pictures) != 1 || pictures[0].MIME != "image/png" || string(pictures[0].Data) != "png" {
+    t.Errorf("pictures %+v", pictures)
+  }
+  if _, err := read.Encode(0); err != nil {
+    t.Error(err)
+  }
+
+  if _, err := Read(bytes.NewReader(tag(2, flagExtendedHeader, body))); err == nil {
+    t.Error("compressed tag read")
+  }
+}
+
+func TestSplitVersion3(t *testing.T) {
+  tg := NewTag(3)
+  tg.SetText("TPE1", "One", "Two")
+  tg.SetText("TIT2", "AC/DC")
+  tg.SetText("TRCK", "3/12")
+  tg.SetUserText("URL", "http://x/y")
+  tests := []struct {
+    id   string
+    want []string
+  }{
+    {"TPE1", []string{"One", "Two"}},
+    {"TIT2", []string{"AC/DC"}},
+    {"TRCK", []string{"3/12"}},
+  }
+  for _, tt := range tests {
+    if got := tg.TextValues(tt.id); !reflect.DeepEqual(got, tt.want) {
+      t.Errorf("%s %q, want %q", tt.id, got, tt.want)
+    }
+  }
+  if got := tg.UserText("URL"); !reflect.DeepEqual(got, []string{"http://x/y"}) {
+    t.Errorf("TXXX:URL %q", got)
+  }
+
+  // converting keeps the values as they are stored
+  tg.ConvertVersion(4)
+  for _, id := range []string{"TPE1", "TIT2"} {
+    data := tg.FramesByID(id)[0].Data
+    if got := DecodeStrings(data[0], data[1:]); len(got) != 1 {
+      t.Errorf("%s as version 4 %q", id, got)
+    }
+  }
+  if got := tg.UserText("URL"); !reflect.DeepEqual(got, []string{"http://x/y"}) {
+    t.Errorf("TXXX:URL as version 4 %q", got)
+  }
+}
+
+func TestPictures(t *testing.T) {
+  pictures := []Picture{
+    {Type: 3, MIME: "image/jpeg", Description: "Front", Data: []byte("\xff\xd8jpeg")},
+    {Type: 4, MIME: "image/png", Description: "Rückseite", Data: []byte("png")},
+  }
+  for _, version := range []byte{3, 4} {
+    tg := NewTag(version)
+    tg.SetPictures(pictures)
+    b, err := tg.Encode(0)
+    if err != nil {
+      t.Fatal(err)
+    }
+    read, err := Read(bytes.NewReader(b))
+    if err != nil {
+      t.Fatal(err)
+    }
+    if got := read.Pictures(); !reflect.DeepEqual(got, pictures) {
+      t.Errorf("v2.%d: pictures %+v", version, got)
+    }
+  }
+}
+
+func TestWriteFilePadding(t *testing.T) {
+  audio := []byte("\xff\xfbaudio")
+  path := filepath.Join(t.TempDir(), "a.mp3")
+  if err := ioutil.WriteFile(path, audio, 0644); err != nil {
+    t.Fatal(err)
+  }
+  size := func() int64 {
+    info, err := os.Stat(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    return info.Size()
+  }
+  write := func(op WriteOptions, values ...string) {
+    tg, err := ReadFile(path)
+    if err == ErrNoTag {
+      tg, err = NewTag(4), nil
+    }
+    if err != nil {
+      t.Fatal(err)
+    }
+    tg.SetText("TIT2", values...)
+    if err := WriteFile(path, tg, op); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  tests := []struct {
+    name    string
+    op      WriteOptions
+    values  []string
+    grows   bool
+    shrinks bool
+  }{
+    {"new tag", WriteOptions{Padding: 64}, []string{"Title"}, true, false},
+    {"padding reused", WriteOptions{Padding: 64}, []string{"A longer title"}, false, false},
+    {"shrinking keeps space", WriteOptions{Padding: 64}, []string{"T"}, false, false},
+    {"beyond padding", WriteOptions{Padding: 8}, []string{strings.Repeat("x", 200)}, true, false},
+    {"compact", WriteOptions{Padding: 8, Compact: true}, []string{"T"}, false, true},
+  }
+  for _, tt := range tests {
+    before := size()
+    write(tt.op, tt.values...)
+    after := size()
+    if tt.grows != (after > before) || tt.shrinks != (after < before) {
+      t.Errorf("%s: size %d became %d", tt.name, before, after)
+    }
+    b, err := ioutil.ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if !bytes.HasSuffix(b, audio) {
+      t.Errorf("%s: audio data changed", tt.name)
+    }
+    if tg, err := Read(bytes.NewReader(b)); err != nil || int64(tg.Size + len(audio)) != after {
+      t.Errorf("%s: tag unreadable or of wrong size: %v", tt.name, err)
+    }
+  }
+}
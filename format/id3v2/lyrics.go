@@ -0,0 +1,112 @@
+package id3v2
+
+import (
+  "bytes"
+  "encoding/binary"
+)
+
+
+
+// timestamp formats of SYLT frames
+const (
+  TimeMPEGFrames   = 1
+  TimeMilliseconds = 2
+)
+
+// content type of SYLT frames holding lyrics
+const ContentLyrics = 1
+
+// the content of a SYLT frame
+type SyncedText struct {
+  Language    string
+  Description string
+  TimeFormat  byte
+  ContentType byte
+  Events      []SyncEvent
+}
+
+type SyncEvent struct {
+  Time uint32
+  Text string
+}
+
+// UnsyncedLyrics returns the content of all USLT frames
+func (t *Tag) UnsyncedLyrics() []Comment {
+  var lyrics []Comment
+  for _, f := range t.FramesByID("USLT") {
+    if c, ok := decodeComment(f); ok {
+      lyrics = append(lyrics, c)
+    }
+  }
+  return lyrics
+}
+
+// SetUnsyncedLyrics replaces all USLT frames, empty texts are left out
+func (t *Tag) SetUnsyncedLyrics(lyrics []Comment) {
+  t.RemoveID("USLT")
+  for _, l := range lyrics {
+    if l.Text != "" {
+      t.AddFrame("USLT", t.EncodeLanguageText(l.Language, l.Description, l.Text))
+    }
+  }
+}
+
+// SyncedTexts returns the content of all SYLT frames
+func (t *Tag) SyncedTexts() []SyncedText {
+  var texts []SyncedText
+  for _, f := range t.FramesByID("SYLT") {
+    if s, ok := decodeSyncedText(f); ok {
+      texts = append(texts, s)
+    }
+  }
+  return texts
+}
+
+// SetSyncedTexts replaces all SYLT frames
+func (t *Tag) SetSyncedTexts(texts []SyncedText) {
+  t.RemoveID("SYLT")
+  for _, s := range texts {
+    values := []string{s.Description}
+    for _, e := range s.Events {
+      values = append(values, e.Text)
+    }
+    enc := BestEncoding(t.Version, values...)
+    data := []byte{enc}
+    data = append(data, []byte(normLanguage(s.Language))...)
+    data = append(data, s.TimeFormat, s.ContentType)
+    data = append(data, EncodeString(enc, s.Description)...)
+    data = append(data, Terminator(enc)...)
+    for _, e := range s.Events {
+      data = append(data, EncodeString(enc, e.Text)...)
+      data = append(data, Terminator(enc)...)
+      time := make([]byte, 4)
+      binary.BigEndian.PutUint32(time, e.Time)
+      data = append(data, time...)
+    }
+    t.AddFrame("SYLT", data)
+  }
+}
+
+func decodeSyncedText(f *Frame) (SyncedText, bool) {
+  if f.Encrypted || len(f.Data) < 6 {
+    return SyncedText{}, false
+  }
+  enc := f.Data[0]
+  s := SyncedText{
+    Language:    string(bytes.TrimRight(f.Data[1:4], "\x00")),
+    TimeFormat:  f.Data[4],
+    ContentType: f.Data[5],
+  }
+  desc, b := SplitString(enc, f.Data[6:])
+  s.Description = DecodeString(enc, desc)
+  for len(b) > 0 {
+    var text []byte
+    text, b = SplitString(enc, b)
+    if len(b) < 4 {
+      break
+    }
+    s.Events = append(s.Events, SyncEvent{binary.BigEndian.Uint32(b), DecodeString(enc, text)})
+    b = b[4:]
+  }
+  return s, true
+}
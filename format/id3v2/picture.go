@@ -0,0 +1,56 @@
+package id3v2
+
+import (
+  "bytes"
+)
+
+
+
+// the content of an APIC frame
+type Picture struct {
+  Type        byte
+  MIME        string
+  Description string
+  Data        []byte
+}
+
+// Pictures returns the content of all APIC frames in tag order
+func (t *Tag) Pictures() []Picture {
+  var pictures []Picture
+  for _, f := range t.FramesByID("APIC") {
+    if p, ok := decodePicture(f); ok {
+      pictures = append(pictures, p)
+    }
+  }
+  return pictures
+}
+
+// SetPictures replaces all APIC frames by pictures
+func (t *Tag) SetPictures(pictures []Picture) {
+  t.RemoveID("APIC")
+  for _, p := range pictures {
+    enc := BestEncoding(t.Version, p.Description)
+    data := []byte{enc}
+    data = append(data, EncodeString(EncodingISO88591, p.MIME)...)
+    data = append(data, 0, p.Type)
+    data = append(data, EncodeString(enc, p.Description)...)
+    data = append(data, Terminator(enc)...)
+    t.AddFrame("APIC", append(data, p.Data...))
+  }
+}
+
+func decodePicture(f *Frame) (Picture, bool) {
+  if f.Encrypted || len(f.Data) < 2 {
+    return Picture{}, false
+  }
+  enc := f.Data[0]
+  end := bytes.IndexByte(f.Data[1:], 0)
+  if end < 0 || 1 + end + 2 > len(f.Data) {
+    return Picture{}, false
+  }
+  p := Picture{MIME: string(f.Data[1:1 + end]), Type: f.Data[1 + end + 1]}
+  desc, data := SplitString(enc, f.Data[1 + end + 2:])
+  p.Description = DecodeString(enc, desc)
+  p.Data = data
+  return p, true
+}
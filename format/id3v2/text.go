@@ -0,0 +1,137 @@
+package id3v2
+
+import (
+  "bytes"
+  "encoding/binary"
+  "unicode/utf16"
+)
+
+
+
+// text encodings as defined by the id3v2 standard
+const (
+  EncodingISO88591 byte = 0
+  EncodingUTF16    byte = 1
+  EncodingUTF16BE  byte = 2
+  EncodingUTF8     byte = 3
+)
+
+// DecodeString decodes b given in encoding enc, a trailing
+// terminator is removed
+func DecodeString(enc byte, b []byte) string {
+  switch enc {
+  case EncodingISO88591:
+    b = bytes.TrimRight(b, "\x00")
+    runes := make([]rune, len(b))
+    for i, c := range b {
+      runes[i] = rune(c)
+    }
+    return string(runes)
+
+  case EncodingUTF16, EncodingUTF16BE:
+    var order binary.ByteOrder = binary.BigEndian
+    if len(b) >= 2 && b[0] == 0xff && b[1] == 0xfe {
+      order = binary.LittleEndian
+      b = b[2:]
+    } else if len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff {
+      b = b[2:]
+    }
+    units := make([]uint16, 0, len(b) / 2)
+    for i := 0; i + 1 < len(b); i += 2 {
+      units = append(units, order.Uint16(b[i:]))
+    }
+    for len(units) > 0 && units[len(units) - 1] == 0 {
+      units = units[:len(units) - 1]
+    }
+    return string(utf16.Decode(units))
+
+  default:
+    return string(bytes.TrimRight(b, "\x00"))
+  }
+}
+
+// EncodeString encodes s in encoding enc without terminator
+func EncodeString(enc byte, s string) []byte {
+  switch enc {
+  case EncodingISO88591:
+    b := make([]byte, 0, len(s))
+    for _, r := range s {
+      if r > 0xff {
+        r = '?'
+      }
+      b = append(b, byte(r))
+    }
+    return b
+
+  case EncodingUTF16:
+    b := []byte{0xff, 0xfe}
+    for _, u := range utf16.Encode([]rune(s)) {
+      b = append(b, byte(u), byte(u >> 8))
+    }
+    return b
+
+  case EncodingUTF16BE:
+    var b []byte
+    for _, u := range utf16.Encode([]rune(s)) {
+      b = append(b, byte(u >> 8), byte(u))
+    }
+    return b
+
+  default:
+    return []byte(s)
+  }
+}
+
+// Terminator returns the string terminator of encoding enc
+func Terminator(enc byte) []byte {
+  if enc == EncodingUTF16 || enc == EncodingUTF16BE {
+    return []byte{0, 0}
+  }
+  return []byte{0}
+}
+
+// SplitString splits b at the first terminator of encoding enc,
+// returning the string before and the bytes after the terminator
+func SplitString(enc byte, b []byte) ([]byte, []byte) {
+  if enc == EncodingUTF16 || enc == EncodingUTF16BE {
+    for i := 0; i + 1 < len(b); i += 2 {
+      if b[i] == 0 && b[i + 1] == 0 {
+        return b[:i], b[i + 2:]
+      }
+    }
+    return b, nil
+  }
+  i := bytes.IndexByte(b, 0)
+  if i < 0 {
+    return b, nil
+  }
+  return b[:i], b[i + 1:]
+}
+
+// DecodeStrings decodes all terminator separated strings of b
+func DecodeStrings(enc byte, b []byte) []string {
+  var values []string
+  for len(b) > 0 {
+    var s []byte
+    s, b = SplitString(enc, b)
+    values = append(values, DecodeString(enc, s))
+  }
+  // a single trailing terminator does not start another value
+  return values
+}
+
+// BestEncoding returns the most compact encoding able to
+// represent all of values in the given tag version
+func BestEncoding(version byte, values ...string) byte {
+  for _, s := range values {
+    for _, r := range s {
+      if r > 0xff {
+        if version >= 4 {
+          return EncodingUTF8
+        }
+        return EncodingUTF16
+      }
+    }
+  }
+  return EncodingISO88591
+}
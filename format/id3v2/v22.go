@@ -0,0 +1,83 @@
+package id3v2
+
+import (
+  "errors"
+  "fmt"
+  "strings"
+)
+
+
+
+// size of a version 2 frame header, 3 bytes id and 3 bytes size
+const headerSize22 = 6
+
+// version 3 counterparts of the version 2 frames,
+// frames without one are dropped when reading
+var frames22 = map[string]string{
+  "BUF": "RBUF", "CNT": "PCNT", "COM": "COMM", "CRA": "AENC", "ETC": "ETCO",
+  "EQU": "EQUA", "GEO": "GEOB", "IPL": "IPLS", "LNK": "LINK", "MCI": "MCDI",
+  "MLL": "MLLT", "PIC": "APIC", "POP": "POPM", "REV": "RVRB", "RVA": "RVAD",
+  "SLT": "SYLT", "STC": "SYTC", "TAL": "TALB", "TBP": "TBPM", "TCM": "TCOM",
+  "TCO": "TCON", "TCP": "TCMP", "TCR": "TCOP", "TDA": "TDAT", "TDY": "TDLY",
+  "TEN": "TENC", "TFT": "TFLT", "TIM": "TIME", "TKE": "TKEY", "TLA": "TLAN",
+  "TLE": "TLEN", "TMT": "TMED", "TOA": "TOPE", "TOF": "TOFN", "TOL": "TOLY",
+  "TOR": "TORY", "TOT": "TOAL", "TP1": "TPE1", "TP2": "TPE2", "TP3": "TPE3",
+  "TP4": "TPE4", "TPA": "TPOS", "TPB": "TPUB", "TRC": "TSRC", "TRD": "TRDA",
+  "TRK": "TRCK", "TS2": "TSO2", "TSA": "TSOA", "TSC": "TSOC", "TSI": "TSIZ",
+  "TSP": "TSOP", "TSS": "TSSE", "TST": "TSOT", "TT1": "TIT1", "TT2": "TIT2",
+  "TT3": "TIT3", "TXT": "TEXT", "TXX": "TXXX", "TYE": "TYER", "UFI": "UFID",
+  "ULT": "USLT", "WAF": "WOAF", "WAR": "WOAR", "WAS": "WOAS", "WCM": "WCOM",
+  "WCP": "WCOP", "WPB": "WPUB", "WXX": "WXXX",
+}
+
+// readFrames22 reads the frames of a version 2 tag body as version 3
+// frames and returns them with the size of the padding behind them
+func readFrames22(body []byte) ([]*Frame, int, error) {
+  var frames []*Frame
+  for len(body) >= headerSize22 {
+    if body[0] == 0 || !validFrameID(body[0:3]) {
+      break
+    }
+    id := string(body[0:3])
+    size := int(body[3]) << 16 | int(body[4]) << 8 | int(body[5])
+    if size > len(body) - headerSize22 {
+      return nil, 0, errors.New(fmt.Sprintf("id3v2 frame '%s' exceeds tag", id))
+    }
+    data := body[headerSize22:headerSize22 + size]
+    body = body[headerSize22 + size:]
+
+    newID, ok := frames22[id]
+    if !ok {
+      continue
+    }
+    if id == "PIC" {
+      var err error
+      if data, err = convertPicture22(data); err != nil {
+        return nil, 0, err
+      }
+    }
+    frames = append(frames, &Frame{ID: newID, Data: append([]byte(nil), data...)})
+  }
+  return frames, len(body), nil
+}
+
+// convertPicture22 replaces the 3 character image format of a PIC frame
+// by the mime type of an APIC frame
+func convertPicture22(data []byte) ([]byte, error) {
+  if len(data) < 4 {
+    return nil, errors.New("id3v2 frame 'PIC' truncated")
+  }
+  var mime string
+  switch format := strings.ToUpper(string(data[1:4])); format {
+  case "JPG":
+    mime = "image/jpeg"
+  case "-->":
+    mime = "-->"
+  default:
+    mime = "image/" + strings.ToLower(strings.TrimRight(format, "\x00 "))
+  }
+  out := []byte{data[0]}
+  out = append(out, mime...)
+  out = append(out, 0)
+  return append(out, data[4:]...), nil
+}
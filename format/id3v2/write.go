@@ -0,0 +1,194 @@
+package id3v2
+
+import (
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+)
+
+import (
+  rewrite "github.com/elias-boemeke/taggo/format/rewrite"
+)
+
+
+
+type WriteOptions struct {
+  // major version written, 3 or 4; zero keeps the version of the tag
+  Version byte
+  // padding added when the tag has to grow, negative values
+  // select DefaultPadding
+  Padding int
+  // give back the space of a shrinking tag beyond the padding
+  // instead of keeping it
+  Compact bool
+}
+
+const DefaultPadding = 1024
+
+// Encode returns the tag with a header and padding bytes of padding,
+// frames are written uncompressed and without unsynchronisation
+func (t *Tag) Encode(padding int) ([]byte, error) {
+  if t.Version != 3 && t.Version != 4 {
+    return nil, errors.New(fmt.Sprintf("unsupported id3v2 version 2.%d", t.Version))
+  }
+
+  body := make([]byte, 0)
+  for _, f := range t.Frames {
+    b, err := f.encode(t.Version)
+    if err != nil {
+      return nil, err
+    }
+    body = append(body, b...)
+  }
+  body = append(body, make([]byte, padding)...)
+
+  if len(body) > 0x0fffffff {
+    return nil, errors.New("id3v2 tag exceeds 256 MB")
+  }
+
+  header := []byte{'I', 'D', '3', t.Version, 0, 0, 0, 0, 0, 0}
+  putSyncsafe(header[6:10], len(body))
+  return append(header, body...), nil
+}
+
+func (f *Frame) encode(version byte) ([]byte, error) {
+  if len(f.ID) != 4 {
+    return nil, errors.New(fmt.Sprintf("invalid id3v2 frame id '%s'", f.ID))
+  }
+
+  var status, format byte
+  var extra []byte
+  if version >= 4 {
+    if f.DiscardOnTagAlter {
+      status |= 0x40
+    }
+    if f.DiscardOnFileAlter {
+      status |= 0x20
+    }
+    if f.ReadOnly {
+      status |= 0x10
+    }
+    if f.Grouped {
+      format |= 0x40
+      extra = append(extra, f.GroupID)
+    }
+    if f.Encrypted {
+      format |= 0x04
+      extra = append(extra, f.Encryption)
+      if f.compressed {
+        format |= 0x08 | 0x01
+        length := make([]byte, 4)
+        putSyncsafe(length, int(f.dataLength))
+        extra = append(extra, length...)
+      }
+    }
+  } else {
+    if f.DiscardOnTagAlter {
+      status |= 0x80
+    }
+    if f.DiscardOnFileAlter {
+      status |= 0x40
+    }
+    if f.ReadOnly {
+      status |= 0x20
+    }
+    if f.Encrypted && f.compressed {
+      format |= 0x80
+      length := make([]byte, 4)
+      binary.BigEndian.PutUint32(length, f.dataLength)
+      extra = append(extra, length...)
+    }
+    if f.Encrypted {
+      format |= 0x40
+      extra = append(extra, f.Encryption)
+    }
+    if f.Grouped {
+      format |= 0x20
+      extra = append(extra, f.GroupID)
+    }
+  }
+
+  size := len(extra) + len(f.Data)
+  header := make([]byte, HeaderSize)
+  copy(header, f.ID)
+  if version >= 4 {
+    putSyncsafe(header[4:8], size)
+  } else {
+    binary.BigEndian.PutUint32(header[4:8], uint32(size))
+  }
+  header[8] = status
+  header[9] = format
+
+  b := append(header, extra...)
+  return append(b, f.Data...), nil
+}
+
+// ReadFile reads the tag at the beginning of the file at path
+func ReadFile(path string) (*Tag, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+  return Read(file)
+}
+
+// WriteFile writes the tag to the beginning of the file at path,
+// replacing an existing tag; if the frames fit into the space of
+// the existing tag it is overwritten in place
+func WriteFile(path string, t *Tag, op WriteOptions) error {
+  if op.Version != 0 {
+    t.ConvertVersion(op.Version)
+  }
+  padding := op.Padding
+  if padding < 0 {
+    padding = DefaultPadding
+  }
+
+  oldSize, err := existingSize(path)
+  if err != nil {
+    return err
+  }
+
+  data, err := t.Encode(0)
+  if err != nil {
+    return err
+  }
+  if free := oldSize - len(data); free >= 0 && free <= 0x0fffffff &&
+    (!op.Compact || free <= padding) {
+    padding = free
+  }
+  data, err = t.Encode(padding)
+  if err != nil {
+    return err
+  }
+  return rewrite.Replace(path, 0, int64(oldSize), data)
+}
+
+// RemoveFile removes the tag at the beginning of the file at path
+func RemoveFile(path string) error {
+  oldSize, err := existingSize(path)
+  if err != nil || oldSize == 0 {
+    return err
+  }
+  return rewrite.Replace(path, 0, int64(oldSize), nil)
+}
+
+func existingSize(path string) (int, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return 0, err
+  }
+  defer file.Close()
+
+  size, _, _, err := ReadHeader(file)
+  if err == ErrNoTag {
+    return 0, nil
+  }
+  if err != nil && err != io.EOF {
+    return 0, err
+  }
+  return size, nil
+}
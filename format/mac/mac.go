@@ -0,0 +1,97 @@
+package mac
+
+import (
+  "encoding/binary"
+  "errors"
+  "io"
+  "time"
+)
+
+
+
+// stream properties of a Monkey's Audio file
+type Properties struct {
+  // e.g. 3990 for version 3.99
+  Version       int
+  Compression   int
+  Channels      int
+  Samplerate    int
+  BitsPerSample int
+  Samples       int64
+  Length        time.Duration
+}
+
+var ErrNotMAC = errors.New("not a monkey's audio file")
+
+var compressionNames = map[int]string{
+  1000: "fast",
+  2000: "normal",
+  3000: "high",
+  4000: "extra high",
+  5000: "insane",
+}
+
+// CompressionName names the compression level
+func (p Properties) CompressionName() string {
+  if name, ok := compressionNames[p.Compression]; ok {
+    return name
+  }
+  return "unknown"
+}
+
+func ReadProperties(r io.ReaderAt) (Properties, error) {
+  var p Properties
+  b := make([]byte, 76)
+  if n, _ := r.ReadAt(b, 0); n < 32 || string(b[0:4]) != "MAC " {
+    return p, ErrNotMAC
+  }
+  p.Version = int(binary.LittleEndian.Uint16(b[4:]))
+
+  var blocksPerFrame, finalFrameBlocks, totalFrames uint32
+  if p.Version >= 3980 {
+    // the header follows the descriptor
+    descriptor := int64(binary.LittleEndian.Uint32(b[8:]))
+    h := make([]byte, 24)
+    if _, err := r.ReadAt(h, descriptor); err != nil {
+      return p, errors.New("monkey's audio header truncated")
+    }
+    p.Compression    = int(binary.LittleEndian.Uint16(h[0:]))
+    blocksPerFrame   = binary.LittleEndian.Uint32(h[4:])
+    finalFrameBlocks = binary.LittleEndian.Uint32(h[8:])
+    totalFrames      = binary.LittleEndian.Uint32(h[12:])
+    p.BitsPerSample  = int(binary.LittleEndian.Uint16(h[16:]))
+    p.Channels       = int(binary.LittleEndian.Uint16(h[18:]))
+    p.Samplerate     = int(binary.LittleEndian.Uint32(h[20:]))
+  } else {
+    flags := binary.LittleEndian.Uint16(b[8:])
+    p.Compression    = int(binary.LittleEndian.Uint16(b[6:]))
+    p.Channels       = int(binary.LittleEndian.Uint16(b[10:]))
+    p.Samplerate     = int(binary.LittleEndian.Uint32(b[12:]))
+    totalFrames      = binary.LittleEndian.Uint32(b[24:])
+    finalFrameBlocks = binary.LittleEndian.Uint32(b[28:])
+    switch {
+    case flags & 1 != 0:
+      p.BitsPerSample = 8
+    case flags & 8 != 0:
+      p.BitsPerSample = 24
+    default:
+      p.BitsPerSample = 16
+    }
+    switch {
+    case p.Version >= 3950:
+      blocksPerFrame = 73728 * 4
+    case p.Version >= 3900 || (p.Version >= 3800 && p.Compression == 4000):
+      blocksPerFrame = 73728
+    default:
+      blocksPerFrame = 9216
+    }
+  }
+
+  if totalFrames > 0 {
+    p.Samples = int64(totalFrames - 1) * int64(blocksPerFrame) + int64(finalFrameBlocks)
+  }
+  if p.Samplerate > 0 {
+    p.Length = time.Duration(float64(p.Samples) / float64(p.Samplerate) * float64(time.Second))
+  }
+  return p, nil
+}
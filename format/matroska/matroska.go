@@ -0,0 +1,278 @@
+package matroska
+
+import (
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "time"
+)
+
+import (
+  ebml "github.com/elias-boemeke/taggo/format/ebml"
+)
+
+
+
+// element ids
+const (
+  IDEBML     = 0x1a45dfa3
+  IDDocType  = 0x4282
+  IDSegment  = 0x18538067
+  IDSeekHead = 0x114d9b74
+  IDSeek     = 0x4dbb
+  IDSeekID   = 0x53ab
+  IDSeekPos  = 0x53ac
+  IDInfo     = 0x1549a966
+  IDTracks   = 0x1654ae6b
+  IDTags     = 0x1254c367
+  IDCluster  = 0x1f43b675
+  IDCues     = 0x1c53bb6b
+
+  IDTimecodeScale = 0x2ad7b1
+  IDDuration      = 0x4489
+  IDMuxingApp     = 0x4d80
+  IDWritingApp    = 0x5741
+
+  IDTrackEntry = 0xae
+  IDTrackType  = 0x83
+  IDCodecID    = 0x86
+  IDAudio      = 0xe1
+  IDSampling   = 0xb5
+  IDChannels   = 0x9f
+  IDBitDepth   = 0x6264
+
+  IDTag             = 0x7373
+  IDTargets         = 0x63c0
+  IDTargetTypeValue = 0x68ca
+  IDTargetType      = 0x63ca
+  IDSimpleTag       = 0x67c8
+  IDTagName         = 0x45a3
+  IDTagLanguage     = 0x447a
+  IDTagString       = 0x4487
+  IDTagBinary       = 0x4485
+)
+
+// master elements below Segment that are parsed
+var masters = map[uint32]bool{
+  IDSeekHead: true, IDSeek: true, IDInfo: true, IDTracks: true, IDTrackEntry: true,
+  IDAudio: true, IDTags: true, IDTag: true, IDTargets: true, IDSimpleTag: true,
+}
+
+// top level elements that are loaded completely
+var loaded = map[uint32]bool{
+  IDSeekHead: true, IDInfo: true, IDTracks: true, IDTags: true,
+}
+
+// the track type of audio tracks
+const trackAudio = 2
+
+var ErrNotMatroska = errors.New("not a matroska file")
+
+type File struct {
+  DocType string
+  Segment *ebml.Element
+  // children of the segment, only those in loaded have their data read
+  Elements []*ebml.Element
+  Size     int64
+}
+
+// Start returns the position of the segment data, seek positions are relative to it
+func (f *File) Start() int64 {
+  return f.Segment.Offset + int64(f.Segment.Header)
+}
+
+// End returns the end of the segment
+func (f *File) End() int64 {
+  if f.Segment.Size == ebml.UnknownSize {
+    return f.Size
+  }
+  return f.Start() + f.Segment.Size
+}
+
+func ReadFile(path string) (*File, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+  info, err := file.Stat()
+  if err != nil {
+    return nil, err
+  }
+  return Read(file, info.Size())
+}
+
+func Read(r io.ReaderAt, size int64) (*File, error) {
+  header, err := ebml.ReadHeader(r, 0)
+  if err != nil || header.ID != IDEBML || header.Size == ebml.UnknownSize {
+    return nil, ErrNotMatroska
+  }
+  f := &File{Size: size}
+  if elements, err := readElements(r, header.Offset + int64(header.Header), header.Size, nil); err == nil {
+    for _, e := range elements {
+      if e.ID == IDDocType {
+        f.DocType = e.String()
+      }
+    }
+  }
+
+  offset := int64(header.Header) + header.Size
+  for offset < size {
+    e, err := ebml.ReadHeader(r, offset)
+    if err != nil {
+      return nil, err
+    }
+    if e.ID == IDSegment {
+      f.Segment = e
+      break
+    }
+    if e.Size == ebml.UnknownSize {
+      break
+    }
+    offset += int64(e.Header) + e.Size
+  }
+  if f.Segment == nil {
+    return nil, errors.New("matroska file has no segment")
+  }
+
+  // the top level elements are visited by their headers, clusters are
+  // skipped; the seek head is consulted for elements that were not reached
+  end := f.End()
+  seen := make(map[int64]bool)
+  for offset := f.Start(); offset < end; {
+    e, err := ebml.ReadHeader(r, offset)
+    if err != nil {
+      break
+    }
+    if err := f.add(r, e); err != nil {
+      return nil, err
+    }
+    seen[offset] = true
+    if e.Size == ebml.UnknownSize {
+      break
+    }
+    offset += int64(e.Header) + e.Size
+  }
+  for _, pos := range f.seekPositions() {
+    offset := f.Start() + int64(pos)
+    if seen[offset] || offset >= end {
+      continue
+    }
+    seen[offset] = true
+    if e, err := ebml.ReadHeader(r, offset); err == nil && loaded[e.ID] {
+      if err := f.add(r, e); err != nil {
+        return nil, err
+      }
+    }
+  }
+  return f, nil
+}
+
+// add appends a top level element, reading the data of those in loaded
+func (f *File) add(r io.ReaderAt, e *ebml.Element) error {
+  if loaded[e.ID] && e.Size != ebml.UnknownSize {
+    children, err := readElements(r, e.Offset + int64(e.Header), e.Size, masters)
+    if err != nil {
+      return err
+    }
+    e.Master = true
+    e.Children = children
+  }
+  f.Elements = append(f.Elements, e)
+  return nil
+}
+
+func readElements(r io.ReaderAt, offset int64, size int64, masters map[uint32]bool) ([]*ebml.Element, error) {
+  if size > 64 << 20 {
+    return nil, errors.New(fmt.Sprintf("matroska element at offset %d too large", offset))
+  }
+  b := make([]byte, size)
+  if _, err := r.ReadAt(b, offset); err != nil {
+    return nil, errors.New(fmt.Sprintf("matroska element at offset %d truncated", offset))
+  }
+  return ebml.Parse(b, offset, masters)
+}
+
+// seekPositions returns the positions of the seek head entries
+func (f *File) seekPositions() []uint64 {
+  var positions []uint64
+  for _, e := range f.Elements {
+    if e.ID != IDSeekHead {
+      continue
+    }
+    for _, seek := range e.ChildrenOf(IDSeek) {
+      if pos := seek.Child(IDSeekPos); pos != nil {
+        positions = append(positions, pos.Uint())
+      }
+    }
+  }
+  return positions
+}
+
+// Element returns the first top level element with the given id
+func (f *File) Element(id uint32) *ebml.Element {
+  for _, e := range f.Elements {
+    if e.ID == id {
+      return e
+    }
+  }
+  return nil
+}
+
+type Properties struct {
+  Length     time.Duration
+  CodecID    string
+  Samplerate int
+  Channels   int
+  BitDepth   int
+  MuxingApp  string
+  WritingApp string
+}
+
+func (f *File) Properties() Properties {
+  var p Properties
+  if info := f.Element(IDInfo); info != nil {
+    scale := uint64(1000000)
+    if e := info.Child(IDTimecodeScale); e != nil {
+      scale = e.Uint()
+    }
+    if e := info.Child(IDDuration); e != nil {
+      p.Length = time.Duration(e.Float() * float64(scale))
+    }
+    if e := info.Child(IDMuxingApp); e != nil {
+      p.MuxingApp = e.String()
+    }
+    if e := info.Child(IDWritingApp); e != nil {
+      p.WritingApp = e.String()
+    }
+  }
+
+  tracks := f.Element(IDTracks)
+  if tracks == nil {
+    return p
+  }
+  for _, track := range tracks.ChildrenOf(IDTrackEntry) {
+    if t := track.Child(IDTrackType); t == nil || t.Uint() != trackAudio {
+      continue
+    }
+    if e := track.Child(IDCodecID); e != nil {
+      p.CodecID = e.String()
+    }
+    // defaults of the specification
+    p.Samplerate, p.Channels = 8000, 1
+    if audio := track.Child(IDAudio); audio != nil {
+      if e := audio.Child(IDSampling); e != nil {
+        p.Samplerate = int(e.Float())
+      }
+      if e := audio.Child(IDChannels); e != nil {
+        p.Channels = int(e.Uint())
+      }
+      if e := audio.Child(IDBitDepth); e != nil {
+        p.BitDepth = int(e.Uint())
+      }
+    }
+    break
+  }
+  return p
+}
@@ -0,0 +1,122 @@
+package matroska
+
+import (
+  "bytes"
+  "io/ioutil"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+import (
+  ebml "github.com/elias-boemeke/taggo/format/ebml"
+)
+
+
+
+var cluster = (&ebml.Element{ID: IDCluster, Data: []byte("cluster data")}).Encode()
+
+func master(id uint32, children ...*ebml.Element) *ebml.Element {
+  return &ebml.Element{ID: id, Master: true, Children: children}
+}
+
+// file returns a matroska file whose segment holds a seek head pointing to
+// Tags, Info, Tags with a title, a Void of void bytes unless zero and a
+// cluster; Tags is put last if last is set
+func file(title string, void int, last bool) []byte {
+  tags := master(IDTags, master(IDTag, master(IDTargets, ebml.NewUint(IDTargetTypeValue, 50)),
+    master(IDSimpleTag, ebml.NewString(IDTagName, "TITLE"), ebml.NewString(IDTagString, title)),
+  )).Encode()
+  info := master(IDInfo, ebml.NewUint(IDTimecodeScale, 1000000)).Encode()
+  var free []byte
+  if void > 0 {
+    free = ebml.EncodeVoid(void)
+  }
+  seekHead := func(position int) []byte {
+    // a position field of 4 bytes leaves room for the file to grow
+    pos := &ebml.Element{ID: IDSeekPos, Data: []byte{0, 0, byte(position >> 8), byte(position)}}
+    return master(IDSeekHead, master(IDSeek,
+      &ebml.Element{ID: IDSeekID, Data: ebml.EncodeID(IDTags)}, pos)).Encode()
+  }
+
+  head := len(seekHead(0))
+  var data []byte
+  if last {
+    data = bytes.Join([][]byte{seekHead(head + len(info) + len(cluster)), info, cluster, tags},
+      nil)
+  } else {
+    data = bytes.Join([][]byte{seekHead(head + len(info)), info, tags, free, cluster}, nil)
+  }
+  header := master(IDEBML, ebml.NewString(IDDocType, "matroska")).Encode()
+  header = append(header, ebml.EncodeID(IDSegment)...)
+  header = append(header, ebml.EncodeSize(int64(len(data)), 8)...)
+  return append(header, data...)
+}
+
+func TestWriteFile(t *testing.T) {
+  tests := []struct {
+    name    string
+    void    int
+    last    bool
+    title   int
+    // Tags keeps its offset
+    inPlace bool
+  }{
+    {"void reused", 200, false, 100, true},
+    {"shrinking leaves a void", 0, false, 2, true},
+    {"void exceeded", 20, false, 1000, false},
+    {"last element grows", 0, true, 1000, true},
+  }
+  for _, tt := range tests {
+    path := filepath.Join(t.TempDir(), "a.mka")
+    if err := ioutil.WriteFile(path, file(strings.Repeat("t", 10), tt.void, tt.last), 0644); err != nil {
+      t.Fatal(err)
+    }
+    f, err := ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    offset := f.Element(IDTags).Offset
+
+    title := strings.Repeat("x", tt.title)
+    f.Set(50, "TITLE", title)
+    if err := WriteFile(path, f); err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+
+    b, err := ioutil.ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if !bytes.Contains(b, cluster) {
+      t.Errorf("%s: cluster changed", tt.name)
+    }
+    read, err := ReadFile(path)
+    if err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+    if read.End() != int64(len(b)) {
+      t.Errorf("%s: segment ends at %d in %d bytes", tt.name, read.End(), len(b))
+    }
+    tags := read.Element(IDTags)
+    if (tags.Offset == offset) != tt.inPlace {
+      t.Errorf("%s: Tags moved from %d to %d", tt.name, offset, tags.Offset)
+    }
+    if positions := read.seekPositions(); len(positions) != 1 ||
+        read.Start() + int64(positions[0]) != tags.Offset {
+      t.Errorf("%s: seek head points to %v, Tags at %d", tt.name, positions,
+        tags.Offset - read.Start())
+    }
+    if got := read.Get(50, "TITLE"); got != title {
+      t.Errorf("%s: title of %d bytes read back", tt.name, len(got))
+    }
+    // every byte of the segment belongs to an element
+    var size int64
+    for _, e := range read.Elements {
+      size += int64(e.Header) + e.Size
+    }
+    if size != read.Segment.Size {
+      t.Errorf("%s: elements of %d bytes in a segment of %d", tt.name, size, read.Segment.Size)
+    }
+  }
+}
@@ -0,0 +1,241 @@
+package matroska
+
+import (
+  "fmt"
+  "strings"
+)
+
+import (
+  ebml "github.com/elias-boemeke/taggo/format/ebml"
+)
+
+
+
+// target type values of the Targets element
+const (
+  TargetVolume = 60
+  TargetAlbum  = 50
+  TargetTrack  = 30
+)
+
+var targetTypes = map[int]string{
+  TargetVolume: "VOLUME",
+  TargetAlbum:  "ALBUM",
+  TargetTrack:  "TRACK",
+}
+
+type SimpleTag struct {
+  Target int
+  // names of nested tags are joined with their parents by a dot
+  Name  string
+  Value string
+}
+
+// TargetTypeValue returns the target level of a Tag element, 50 by default
+func TargetTypeValue(tag *ebml.Element) int {
+  if targets := tag.Child(IDTargets); targets != nil {
+    if e := targets.Child(IDTargetTypeValue); e != nil {
+      return int(e.Uint())
+    }
+  }
+  return TargetAlbum
+}
+
+// findTag returns the first Tag element of the target level
+func (f *File) findTag(target int) *ebml.Element {
+  tags := f.Element(IDTags)
+  if tags == nil {
+    return nil
+  }
+  for _, tag := range tags.ChildrenOf(IDTag) {
+    if TargetTypeValue(tag) == target {
+      return tag
+    }
+  }
+  return nil
+}
+
+func tagName(simple *ebml.Element) string {
+  if e := simple.Child(IDTagName); e != nil {
+    return e.String()
+  }
+  return ""
+}
+
+// Get returns the value of the simple tag called name on the target level,
+// names of nested tags are joined with their parents by a dot
+func (f *File) Get(target int, name string) string {
+  if values := f.Values(target, name); len(values) > 0 {
+    return values[0]
+  }
+  return ""
+}
+
+// Values returns the values of all simple tags called name on the target level
+func (f *File) Values(target int, name string) []string {
+  tag := f.findTag(target)
+  if tag == nil {
+    return nil
+  }
+  return getSimple(tag.Children, strings.Split(name, "."))
+}
+
+func getSimple(children []*ebml.Element, path []string) []string {
+  var values []string
+  for _, c := range children {
+    if c.ID != IDSimpleTag || !strings.EqualFold(tagName(c), path[0]) {
+      continue
+    }
+    if len(path) > 1 {
+      // nested tags are read below the first parent only
+      return getSimple(c.Children, path[1:])
+    }
+    if e := c.Child(IDTagString); e != nil {
+      values = append(values, e.String())
+    }
+  }
+  return values
+}
+
+// Set sets the simple tag called name on the target level, Tags and Tag
+// elements are created as needed; an empty value removes the simple tag
+func (f *File) Set(target int, name string, value string) {
+  if value == "" {
+    f.SetValues(target, name)
+  } else {
+    f.SetValues(target, name, value)
+  }
+}
+
+// SetValues sets one simple tag called name per value on the target level,
+// no values remove the simple tags
+func (f *File) SetValues(target int, name string, values ...string) {
+  tag := f.findTag(target)
+  if tag == nil {
+    if len(values) == 0 {
+      return
+    }
+    tag = f.newTag(target)
+  }
+  tag.Children = setSimple(tag.Children, strings.Split(name, "."), values)
+}
+
+// setSimple sets the simple tags at path below children, nested tags are
+// set below the first parent, which is created without a value if missing
+func setSimple(children []*ebml.Element, path []string, values []string) []*ebml.Element {
+  var kept []*ebml.Element
+  found := false
+  for _, c := range children {
+    if c.ID != IDSimpleTag || !strings.EqualFold(tagName(c), path[0]) {
+      kept = append(kept, c)
+      continue
+    }
+    if len(path) > 1 {
+      if !found {
+        c.Children = setSimple(c.Children, path[1:], values)
+      }
+      found = true
+      kept = append(kept, c)
+      continue
+    }
+    if len(values) > 0 && !found {
+      // nested tags and the language of the first one are kept
+      setChild(c, ebml.NewString(IDTagString, values[0]))
+      c.Children = removeChild(c.Children, IDTagBinary)
+      kept = append(kept, c)
+      for _, v := range values[1:] {
+        kept = append(kept, newSimple(path[0], v))
+      }
+    }
+    found = true
+  }
+  if !found && len(values) > 0 {
+    if len(path) > 1 {
+      simple := &ebml.Element{ID: IDSimpleTag, Master: true, Children: []*ebml.Element{
+        ebml.NewString(IDTagName, path[0]),
+      }}
+      simple.Children = setSimple(simple.Children, path[1:], values)
+      kept = append(kept, simple)
+    } else {
+      for _, v := range values {
+        kept = append(kept, newSimple(path[0], v))
+      }
+    }
+  }
+  return kept
+}
+
+func newSimple(name string, value string) *ebml.Element {
+  return &ebml.Element{ID: IDSimpleTag, Master: true, Children: []*ebml.Element{
+    ebml.NewString(IDTagName, name),
+    ebml.NewString(IDTagString, value),
+  }}
+}
+
+func (f *File) newTag(target int) *ebml.Element {
+  tags := f.Element(IDTags)
+  if tags == nil {
+    tags = &ebml.Element{ID: IDTags, Offset: -1, Master: true}
+    f.Elements = append(f.Elements, tags)
+  }
+  targets := &ebml.Element{ID: IDTargets, Master: true, Children: []*ebml.Element{
+    ebml.NewUint(IDTargetTypeValue, uint64(target)),
+  }}
+  if name, ok := targetTypes[target]; ok {
+    targets.Children = append(targets.Children, ebml.NewString(IDTargetType, name))
+  }
+  tag := &ebml.Element{ID: IDTag, Master: true, Children: []*ebml.Element{targets}}
+  tags.Children = append(tags.Children, tag)
+  return tag
+}
+
+// setChild replaces the first child with the id of e or appends e
+func setChild(parent *ebml.Element, e *ebml.Element) {
+  for i, c := range parent.Children {
+    if c.ID == e.ID {
+      parent.Children[i] = e
+      return
+    }
+  }
+  parent.Children = append(parent.Children, e)
+}
+
+func removeChild(children []*ebml.Element, id uint32) []*ebml.Element {
+  var kept []*ebml.Element
+  for _, c := range children {
+    if c.ID != id {
+      kept = append(kept, c)
+    }
+  }
+  return kept
+}
+
+// SimpleTags lists all simple tags of all Tag elements
+func (f *File) SimpleTags() []SimpleTag {
+  tags := f.Element(IDTags)
+  if tags == nil {
+    return nil
+  }
+  var list []SimpleTag
+  var walk func(target int, prefix string, simple *ebml.Element)
+  walk = func(target int, prefix string, simple *ebml.Element) {
+    name := prefix + tagName(simple)
+    value := ""
+    if e := simple.Child(IDTagString); e != nil {
+      value = e.String()
+    } else if e := simple.Child(IDTagBinary); e != nil {
+      value = fmt.Sprintf("<binary, %d bytes>", len(e.Data))
+    }
+    list = append(list, SimpleTag{target, name, value})
+    for _, c := range simple.ChildrenOf(IDSimpleTag) {
+      walk(target, name + ".", c)
+    }
+  }
+  for _, tag := range tags.ChildrenOf(IDTag) {
+    target := TargetTypeValue(tag)
+    for _, simple := range tag.ChildrenOf(IDSimpleTag) {
+      walk(target, "", simple)
+    }
+  }
+  return list
+}
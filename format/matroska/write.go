@@ -0,0 +1,125 @@
+package matroska
+
+import (
+  "bytes"
+  "errors"
+)
+
+import (
+  ebml "github.com/elias-boemeke/taggo/format/ebml"
+  rewrite "github.com/elias-boemeke/taggo/format/rewrite"
+)
+
+
+
+// WriteFile writes the Tags element of f to the file at path. It is rewritten
+// in place if it fits its old space and a Void element directly behind it,
+// the rest being filled with a Void element; otherwise the old element is
+// voided and the new one appended to the segment
+func WriteFile(path string, f *File) error {
+  tags := f.Element(IDTags)
+  if tags == nil {
+    return nil
+  }
+  data := tags.Encode()
+
+  if tags.Offset >= 0 {
+    start := tags.Offset
+    end := start + int64(tags.Header) + tags.Size
+    for _, e := range f.Elements {
+      if e.ID == ebml.Void && e.Offset == end && e.Size != ebml.UnknownSize {
+        end += int64(e.Header) + e.Size
+      }
+    }
+
+    free := end - start - int64(len(data))
+    switch {
+    case free == 0 || free >= 2:
+      if free > 0 {
+        data = append(data, ebml.EncodeVoid(int(free))...)
+      }
+      return finish(path, f, rewrite.Replace(path, start, end, data))
+    case end == f.End():
+      // the last element of the segment simply grows
+      if err := f.resizeSegment(path, int64(len(data)) - (end - start)); err != nil {
+        return err
+      }
+      return finish(path, f, rewrite.Replace(path, start, end, data))
+    }
+    if err := rewrite.Replace(path, start, end, ebml.EncodeVoid(int(end - start))); err != nil {
+      return err
+    }
+  }
+
+  // appended at the end of the segment
+  position := f.End()
+  if err := f.updateSeek(path, IDTags, position - f.Start()); err != nil {
+    return err
+  }
+  if err := f.resizeSegment(path, int64(len(data))); err != nil {
+    return err
+  }
+  return finish(path, f, rewrite.Replace(path, position, position, data))
+}
+
+// finish reads the file back after writing to pick up the new offsets
+func finish(path string, f *File, err error) error {
+  if err != nil {
+    return err
+  }
+  n, err := ReadFile(path)
+  if err != nil {
+    return err
+  }
+  *f = *n
+  return nil
+}
+
+// resizeSegment changes the size of the segment by delta,
+// keeping the length of its size field
+func (f *File) resizeSegment(path string, delta int64) error {
+  if f.Segment.Size == ebml.UnknownSize || delta == 0 {
+    return nil
+  }
+  idLength := len(ebml.EncodeID(IDSegment))
+  length := f.Segment.Header - idLength
+  size := ebml.EncodeSize(f.Segment.Size + delta, length)
+  if len(size) != length {
+    return errors.New("matroska segment size does not fit its size field")
+  }
+  offset := f.Segment.Offset + int64(idLength)
+  return rewrite.Replace(path, offset, offset + int64(length), size)
+}
+
+// updateSeek points the seek head entries of id to position; an entry whose
+// position field is too short for the new value is voided instead
+func (f *File) updateSeek(path string, id uint32, position int64) error {
+  target := ebml.EncodeID(id)
+  for _, head := range f.Elements {
+    if head.ID != IDSeekHead {
+      continue
+    }
+    for _, seek := range head.ChildrenOf(IDSeek) {
+      seekID := seek.Child(IDSeekID)
+      pos := seek.Child(IDSeekPos)
+      if seekID == nil || pos == nil || !bytes.Equal(seekID.Data, target) {
+        continue
+      }
+      var err error
+      value := ebml.NewUint(IDSeekPos, uint64(position)).Data
+      if len(value) <= len(pos.Data) {
+        padded := make([]byte, len(pos.Data))
+        copy(padded[len(padded) - len(value):], value)
+        offset := pos.Offset + int64(pos.Header)
+        err = rewrite.Replace(path, offset, offset + int64(len(padded)), padded)
+      } else {
+        size := int64(seek.Header) + seek.Size
+        err = rewrite.Replace(path, seek.Offset, seek.Offset + size, ebml.EncodeVoid(int(size)))
+      }
+      if err != nil {
+        return err
+      }
+    }
+  }
+  return nil
+}
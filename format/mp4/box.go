@@ -0,0 +1,193 @@
+package mp4
+
+import (
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "io"
+)
+
+
+
+type Box struct {
+  // four character code, bytes are taken as ISO-8859-1 (©nam)
+  Type string
+  // position of the box in the file when it was read
+  Offset int64
+  // size including the header when it was read
+  Size int64
+  // bytes in front of the children of a container (version and flags)
+  Prefix []byte
+  // payload of a leaf box
+  Data     []byte
+  Children []*Box
+  Container bool
+}
+
+// boxes whose payload consists of child boxes
+var containers = map[string]bool{
+  "moov": true, "trak": true, "mdia": true, "minf": true, "stbl": true,
+  "udta": true, "edts": true, "dinf": true, "ilst": true, "mvex": true,
+  "meta": true,
+}
+
+var errTruncated = errors.New("mp4 box truncated")
+
+// atomName decodes a four character code, bytes are ISO-8859-1
+func atomName(b []byte) string {
+  runes := make([]rune, len(b))
+  for i, c := range b {
+    runes[i] = rune(c)
+  }
+  return string(runes)
+}
+
+// atomBytes encodes a four character code to ISO-8859-1
+func atomBytes(s string) []byte {
+  b := make([]byte, 0, 4)
+  for _, r := range s {
+    b = append(b, byte(r))
+  }
+  return b
+}
+
+// readHeader reads the header of the box at offset, returning its type,
+// its total size and the size of the header
+func readHeader(r io.ReaderAt, offset int64, limit int64) (string, int64, int, error) {
+  b := make([]byte, 16)
+  if _, err := r.ReadAt(b[:8], offset); err != nil {
+    return "", 0, 0, errTruncated
+  }
+  typ := atomName(b[4:8])
+  size := int64(binary.BigEndian.Uint32(b))
+  header := 8
+  switch size {
+  case 0:
+    // box extends to the end of the file
+    size = limit - offset
+  case 1:
+    if _, err := r.ReadAt(b[8:16], offset + 8); err != nil {
+      return "", 0, 0, errTruncated
+    }
+    size = int64(binary.BigEndian.Uint64(b[8:]))
+    header = 16
+  }
+  if size < int64(header) || offset + size > limit {
+    return "", 0, 0, errors.New(fmt.Sprintf("mp4 box '%s' at offset %d has invalid size %d",
+      typ, offset, size))
+  }
+  return typ, size, header, nil
+}
+
+// parseBoxes parses the boxes of b, offset being the position of b in the file
+func parseBoxes(b []byte, offset int64, parent string) ([]*Box, error) {
+  var boxes []*Box
+  for pos := 0; pos + 8 <= len(b); {
+    size := int64(binary.BigEndian.Uint32(b[pos:]))
+    typ := atomName(b[pos + 4:pos + 8])
+    header := 8
+    if size == 1 {
+      if pos + 16 > len(b) {
+        return nil, errTruncated
+      }
+      size = int64(binary.BigEndian.Uint64(b[pos + 8:]))
+      header = 16
+    } else if size == 0 {
+      size = int64(len(b) - pos)
+    }
+    if size < int64(header) || int64(pos) + size > int64(len(b)) {
+      return nil, errors.New(fmt.Sprintf("mp4 box '%s' at offset %d has invalid size %d",
+        typ, offset + int64(pos), size))
+    }
+    payload := b[pos + header:pos + int(size)]
+    box := &Box{Type: typ, Offset: offset + int64(pos), Size: size}
+    if err := box.parsePayload(payload, offset + int64(pos + header), parent); err != nil {
+      return nil, err
+    }
+    boxes = append(boxes, box)
+    pos += int(size)
+  }
+  return boxes, nil
+}
+
+func (box *Box) parsePayload(payload []byte, offset int64, parent string) error {
+  prefix := 0
+  switch {
+  case box.Type == "meta":
+    // iso meta boxes carry version and flags, quicktime ones do not
+    if len(payload) >= 8 && string(payload[4:8]) != "hdlr" {
+      prefix = 4
+    }
+  case parent == "ilst":
+    // items of the item list contain data, mean and name boxes
+  case !containers[box.Type]:
+    box.Data = append([]byte(nil), payload...)
+    return nil
+  }
+  if len(payload) < prefix {
+    return errTruncated
+  }
+  box.Container = true
+  box.Prefix = append([]byte(nil), payload[:prefix]...)
+  children, err := parseBoxes(payload[prefix:], offset + int64(prefix), box.Type)
+  if err != nil {
+    return err
+  }
+  box.Children = children
+  return nil
+}
+
+// Encode encodes the box with its children
+func (box *Box) Encode() []byte {
+  var payload []byte
+  if box.Container {
+    payload = append(payload, box.Prefix...)
+    for _, c := range box.Children {
+      payload = append(payload, c.Encode()...)
+    }
+  } else {
+    payload = box.Data
+  }
+
+  size := 8 + len(payload)
+  var header []byte
+  if size > 0xffffffff {
+    header = make([]byte, 16)
+    binary.BigEndian.PutUint32(header, 1)
+    binary.BigEndian.PutUint64(header[8:], uint64(size + 8))
+  } else {
+    header = make([]byte, 8)
+    binary.BigEndian.PutUint32(header, uint32(size))
+  }
+  copy(header[4:8], atomBytes(box.Type))
+  return append(header, payload...)
+}
+
+// Child returns the first child of the given type
+func (box *Box) Child(typ string) *Box {
+  for _, c := range box.Children {
+    if c.Type == typ {
+      return c
+    }
+  }
+  return nil
+}
+
+// Find follows the path of box types, returning nil if it does not exist
+func (box *Box) Find(path ...string) *Box {
+  b := box
+  for _, typ := range path {
+    if b = b.Child(typ); b == nil {
+      return nil
+    }
+  }
+  return b
+}
+
+// Walk calls fn for the box and all of its descendants, depth first
+func (box *Box) Walk(fn func(b *Box, depth int), depth int) {
+  fn(box, depth)
+  for _, c := range box.Children {
+    c.Walk(fn, depth + 1)
+  }
+}
@@ -0,0 +1,539 @@
+package mp4
+
+import (
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "io"
+  "time"
+  "unicode/utf16"
+)
+
+
+
+type Chapter struct {
+  Start time.Duration
+  // zero for nero chapters, which keep no end
+  End   time.Duration
+  Title string
+}
+
+// nero chapters count in units of 100 nanoseconds
+const neroUnit = 100 * time.Nanosecond
+
+// NeroChapters returns the chapters of the chpl box in moov/udta
+func (f *File) NeroChapters() []Chapter {
+  chpl := f.Moov.Find("udta", "chpl")
+  if chpl == nil || len(chpl.Data) < 5 {
+    return nil
+  }
+  b := chpl.Data[4:]
+  // version 1 has four more reserved bytes
+  if chpl.Data[0] == 1 {
+    if len(b) < 5 {
+      return nil
+    }
+    b = b[4:]
+  }
+  count := int(b[0])
+  b = b[1:]
+  var chapters []Chapter
+  for i := 0; i < count && len(b) >= 9; i++ {
+    start := binary.BigEndian.Uint64(b)
+    n := int(b[8])
+    if 9 + n > len(b) {
+      break
+    }
+    chapters = append(chapters, Chapter{
+      Start: time.Duration(start) * neroUnit,
+      Title: string(b[9:9 + n]),
+    })
+    b = b[9 + n:]
+  }
+  return chapters
+}
+
+// SetNeroChapters replaces the chpl box, no chapters remove it;
+// titles are cut to 255 bytes
+func (f *File) SetNeroChapters(chapters []Chapter) error {
+  if len(chapters) > 0xff {
+    return errors.New("nero chapters are limited to 255")
+  }
+  udta := f.Moov.Child("udta")
+  if udta != nil {
+    udta.removeChildren("chpl")
+  }
+  if len(chapters) == 0 {
+    return nil
+  }
+  data := []byte{1, 0, 0, 0, 0, 0, 0, 0, byte(len(chapters))}
+  for _, c := range chapters {
+    start := make([]byte, 8)
+    binary.BigEndian.PutUint64(start, uint64(c.Start / neroUnit))
+    title := []byte(c.Title)
+    if len(title) > 0xff {
+      title = title[:0xff]
+    }
+    data = append(append(data, start...), byte(len(title)))
+    data = append(data, title...)
+  }
+  if udta == nil {
+    udta = &Box{Type: "udta", Container: true}
+    f.Moov.Children = append(f.Moov.Children, udta)
+  }
+  udta.Children = append(udta.Children, &Box{Type: "chpl", Data: data})
+  return nil
+}
+
+// ChapterTrack returns the trak referenced as chapters by the audio track
+func (f *File) ChapterTrack() *Box {
+  audio := f.audioTrack()
+  if audio == nil {
+    return nil
+  }
+  ids := chapterReferences(audio)
+  for _, trak := range f.Moov.Children {
+    if trak.Type != "trak" {
+      continue
+    }
+    for _, id := range ids {
+      if trackID(trak) == id {
+        return trak
+      }
+    }
+  }
+  return nil
+}
+
+// TrackChapters reads the text samples of the chapter track from r,
+// each sample ending where the next one starts
+func (f *File) TrackChapters(r io.ReaderAt) ([]Chapter, error) {
+  trak := f.ChapterTrack()
+  if trak == nil {
+    return nil, nil
+  }
+  scale, _ := timing(trak.Find("mdia", "mdhd"))
+  samples, err := trackSamples(trak)
+  if err != nil {
+    return nil, err
+  }
+  if scale == 0 {
+    return nil, errors.New("mp4 chapter track has no timescale")
+  }
+
+  var chapters []Chapter
+  for _, s := range samples {
+    b := make([]byte, s.size)
+    if _, err := r.ReadAt(b, s.offset); err != nil {
+      return nil, errors.New(fmt.Sprintf("mp4 chapter sample at offset %d truncated", s.offset))
+    }
+    chapters = append(chapters, Chapter{
+      Start: time.Duration(s.time) * time.Second / time.Duration(scale),
+      End:   time.Duration(s.time + s.duration) * time.Second / time.Duration(scale),
+      Title: decodeTextSample(b),
+    })
+  }
+  return chapters, nil
+}
+
+// SetTrackChapters replaces the chapter track, no chapters remove it; the
+// samples are written to an mdat box behind moov by WriteFile, which takes
+// the place of the one of an earlier chapter track written that way
+func (f *File) SetTrackChapters(chapters []Chapter) error {
+  audio := f.audioTrack()
+  if audio == nil {
+    return errors.New("mp4 file has no audio track to refer to chapters")
+  }
+  if old := f.ChapterTrack(); old != nil {
+    f.chapterMdat = f.ownedMdat(old)
+    f.Moov.removeChildren("trak", old)
+  }
+  for _, trak := range f.Moov.Children {
+    if trak.Type == "trak" {
+      setChapterReference(trak, 0)
+    }
+  }
+  f.chapterData, f.chapterOffsets = nil, nil
+  if len(chapters) == 0 {
+    return nil
+  }
+
+  id := f.nextTrackID()
+  movieScale, _ := timing(f.Moov.Child("mvhd"))
+  trak, samples := chapterTrack(id, movieScale, chapters)
+  f.Moov.Children = append(f.Moov.Children, trak)
+  setChapterReference(audio, id)
+  f.chapterData = samples
+  f.chapterOffsets = trak.Find("mdia", "minf", "stbl", "stco")
+  return nil
+}
+
+type sample struct {
+  offset   int64
+  size     int
+  time     uint64
+  duration uint64
+}
+
+// trackSamples resolves position and timing of the samples of a track
+func trackSamples(trak *Box) ([]sample, error) {
+  stbl := trak.Find("mdia", "minf", "stbl")
+  if stbl == nil {
+    return nil, errors.New("mp4 track has no sample table")
+  }
+  errTable := func(typ string) error {
+    return errors.New(fmt.Sprintf("mp4 box '%s' truncated", typ))
+  }
+
+  // sizes
+  stsz := stbl.Child("stsz")
+  if stsz == nil || len(stsz.Data) < 12 {
+    return nil, errTable("stsz")
+  }
+  fixed := int(binary.BigEndian.Uint32(stsz.Data[4:]))
+  count := int(binary.BigEndian.Uint32(stsz.Data[8:]))
+  if fixed == 0 && 12 + count * 4 > len(stsz.Data) {
+    return nil, errTable("stsz")
+  }
+  samples := make([]sample, count)
+  for i := range samples {
+    samples[i].size = fixed
+    if fixed == 0 {
+      samples[i].size = int(binary.BigEndian.Uint32(stsz.Data[12 + i * 4:]))
+    }
+  }
+
+  // times
+  stts := stbl.Child("stts")
+  if stts == nil || len(stts.Data) < 8 {
+    return nil, errTable("stts")
+  }
+  entries := int(binary.BigEndian.Uint32(stts.Data[4:]))
+  if 8 + entries * 8 > len(stts.Data) {
+    return nil, errTable("stts")
+  }
+  var t uint64
+  for i, e := 0, 0; e < entries; e++ {
+    n := int(binary.BigEndian.Uint32(stts.Data[8 + e * 8:]))
+    delta := uint64(binary.BigEndian.Uint32(stts.Data[12 + e * 8:]))
+    for ; n > 0 && i < count; n, i = n - 1, i + 1 {
+      samples[i].time, samples[i].duration = t, delta
+      t += delta
+    }
+  }
+
+  // chunks
+  var chunks []int64
+  if stco := stbl.Child("stco"); stco != nil && len(stco.Data) >= 8 {
+    n := int(binary.BigEndian.Uint32(stco.Data[4:]))
+    if 8 + n * 4 > len(stco.Data) {
+      return nil, errTable("stco")
+    }
+    for i := 0; i < n; i++ {
+      chunks = append(chunks, int64(binary.BigEndian.Uint32(stco.Data[8 + i * 4:])))
+    }
+  } else if co64 := stbl.Child("co64"); co64 != nil && len(co64.Data) >= 8 {
+    n := int(binary.BigEndian.Uint32(co64.Data[4:]))
+    if 8 + n * 8 > len(co64.Data) {
+      return nil, errTable("co64")
+    }
+    for i := 0; i < n; i++ {
+      chunks = append(chunks, int64(binary.BigEndian.Uint64(co64.Data[8 + i * 8:])))
+    }
+  }
+  stsc := stbl.Child("stsc")
+  if stsc == nil || len(stsc.Data) < 8 {
+    return nil, errTable("stsc")
+  }
+  entries = int(binary.BigEndian.Uint32(stsc.Data[4:]))
+  if 8 + entries * 12 > len(stsc.Data) {
+    return nil, errTable("stsc")
+  }
+  i := 0
+  for e := 0; e < entries; e++ {
+    first := int(binary.BigEndian.Uint32(stsc.Data[8 + e * 12:])) - 1
+    perChunk := int(binary.BigEndian.Uint32(stsc.Data[12 + e * 12:]))
+    last := len(chunks)
+    if e + 1 < entries {
+      last = int(binary.BigEndian.Uint32(stsc.Data[20 + e * 12:])) - 1
+    }
+    for c := first; c >= 0 && c < last && c < len(chunks); c++ {
+      offset := chunks[c]
+      for n := 0; n < perChunk && i < count; n, i = n + 1, i + 1 {
+        samples[i].offset = offset
+        offset += int64(samples[i].size)
+      }
+    }
+  }
+  if i < count {
+    return nil, errors.New("mp4 sample table refers to missing chunks")
+  }
+  return samples, nil
+}
+
+// decodeTextSample reads the length prefixed text of a text sample,
+// utf-16 if it starts with a byte order mark
+func decodeTextSample(b []byte) string {
+  if len(b) < 2 {
+    return ""
+  }
+  n := int(binary.BigEndian.Uint16(b))
+  if 2 + n > len(b) {
+    n = len(b) - 2
+  }
+  text := b[2:2 + n]
+  if len(text) >= 2 && text[0] == 0xfe && text[1] == 0xff {
+    units := make([]uint16, (len(text) - 2) / 2)
+    for i := range units {
+      units[i] = binary.BigEndian.Uint16(text[2 + i * 2:])
+    }
+    return string(utf16.Decode(units))
+  }
+  return string(text)
+}
+
+// encd box marking text samples as utf-8
+var textEncoding = []byte{0, 0, 0, 12, 'e', 'n', 'c', 'd', 0, 0, 1, 0}
+
+// chapterTrack builds a disabled text track with one sample per chapter,
+// all samples forming a single chunk whose offset is set when written
+func chapterTrack(id uint32, movieScale uint32, chapters []Chapter) (*Box, []byte) {
+  var data []byte
+  var sizes, times []byte
+  var total uint64
+  for _, c := range chapters {
+    title := []byte(c.Title)
+    if len(title) > 0xffff {
+      title = title[:0xffff]
+    }
+    s := make([]byte, 2)
+    binary.BigEndian.PutUint16(s, uint16(len(title)))
+    s = append(append(s, title...), textEncoding...)
+    data = append(data, s...)
+    sizes = appendUint32(sizes, uint32(len(s)))
+    ms := uint64(0)
+    if c.End > c.Start {
+      ms = uint64((c.End - c.Start) / time.Millisecond)
+    }
+    times = appendUint32(appendUint32(times, 1), uint32(ms))
+    total += ms
+  }
+  count := uint32(len(chapters))
+  movieDuration := total
+  if movieScale > 0 {
+    movieDuration = total * uint64(movieScale) / 1000
+  }
+
+  tkhd := make([]byte, 84)
+  binary.BigEndian.PutUint32(tkhd[12:], id)
+  binary.BigEndian.PutUint32(tkhd[20:], uint32(movieDuration))
+  copy(tkhd[40:], identityMatrix)
+  mdhd := make([]byte, 24)
+  binary.BigEndian.PutUint32(mdhd[12:], 1000)
+  binary.BigEndian.PutUint32(mdhd[16:], uint32(total))
+  // language und
+  binary.BigEndian.PutUint16(mdhd[20:], 0x55c4)
+  hdlr := make([]byte, 24, 33)
+  copy(hdlr[8:], "text")
+  hdlr = append(hdlr, "Chapters\x00"...)
+  gmin := []byte{0, 0, 0, 0, 0, 0x40, 0x80, 0, 0x80, 0, 0x80, 0, 0, 0, 0, 0}
+  dref := []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 12, 'u', 'r', 'l', ' ', 0, 0, 0, 1}
+  // quicktime text sample description with reference index 1
+  entry := make([]byte, 60)
+  binary.BigEndian.PutUint32(entry, 60)
+  copy(entry[4:], "text")
+  binary.BigEndian.PutUint16(entry[14:], 1)
+  stsd := append([]byte{0, 0, 0, 0, 0, 0, 0, 1}, entry...)
+  stsc := []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 1}
+  stsc = appendUint32(appendUint32(stsc, count), 1)
+
+  leaf := func(typ string, data []byte) *Box {
+    return &Box{Type: typ, Data: data}
+  }
+  container := func(typ string, children ...*Box) *Box {
+    return &Box{Type: typ, Container: true, Children: children}
+  }
+  trak := container("trak",
+    leaf("tkhd", tkhd),
+    container("mdia",
+      leaf("mdhd", mdhd),
+      leaf("hdlr", hdlr),
+      container("minf",
+        container("gmhd", leaf("gmin", gmin), leaf("text", identityMatrix)),
+        container("dinf", leaf("dref", dref)),
+        container("stbl",
+          leaf("stsd", stsd),
+          leaf("stts", append(appendUint32([]byte{0, 0, 0, 0}, count), times...)),
+          leaf("stsc", stsc),
+          leaf("stsz", append(appendUint32(make([]byte, 8), count), sizes...)),
+          leaf("stco", []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0})))))
+  return trak, data
+}
+
+var identityMatrix = []byte{
+  0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+  0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0,
+  0, 0, 0, 0, 0, 0, 0, 0, 0x40, 0, 0, 0,
+}
+
+func appendUint32(b []byte, x uint32) []byte {
+  v := make([]byte, 4)
+  binary.BigEndian.PutUint32(v, x)
+  return append(b, v...)
+}
+
+// trackID returns the id of the tkhd box of a trak
+func trackID(trak *Box) uint32 {
+  tkhd := trak.Child("tkhd")
+  if tkhd == nil || len(tkhd.Data) < 24 {
+    return 0
+  }
+  if tkhd.Data[0] == 1 {
+    if len(tkhd.Data) < 32 {
+      return 0
+    }
+    return binary.BigEndian.Uint32(tkhd.Data[20:])
+  }
+  return binary.BigEndian.Uint32(tkhd.Data[12:])
+}
+
+// nextTrackID takes the next track id of mvhd and advances it
+func (f *File) nextTrackID() uint32 {
+  var id uint32 = 1
+  for _, trak := range f.Moov.Children {
+    if trak.Type == "trak" && trackID(trak) >= id {
+      id = trackID(trak) + 1
+    }
+  }
+  mvhd := f.Moov.Child("mvhd")
+  if mvhd == nil {
+    return id
+  }
+  pos := 96
+  if len(mvhd.Data) > 0 && mvhd.Data[0] == 1 {
+    pos = 108
+  }
+  if pos + 4 > len(mvhd.Data) {
+    return id
+  }
+  if next := binary.BigEndian.Uint32(mvhd.Data[pos:]); next > id {
+    id = next
+  }
+  binary.BigEndian.PutUint32(mvhd.Data[pos:], id + 1)
+  return id
+}
+
+// chapterReferences returns the track ids of the chap reference in tref
+func chapterReferences(trak *Box) []uint32 {
+  tref := trak.Child("tref")
+  if tref == nil {
+    return nil
+  }
+  refs, _ := parseBoxes(tref.Data, 0, "tref")
+  var ids []uint32
+  for _, r := range refs {
+    if r.Type != "chap" {
+      continue
+    }
+    for i := 0; i + 4 <= len(r.Data); i += 4 {
+      ids = append(ids, binary.BigEndian.Uint32(r.Data[i:]))
+    }
+  }
+  return ids
+}
+
+// setChapterReference replaces the chap reference of a trak,
+// zero removes it and an empty tref
+func setChapterReference(trak *Box, id uint32) {
+  tref := trak.Child("tref")
+  var refs []*Box
+  if tref != nil {
+    refs, _ = parseBoxes(tref.Data, 0, "tref")
+  }
+  var data []byte
+  for _, r := range refs {
+    if r.Type != "chap" {
+      data = append(data, r.Encode()...)
+    }
+  }
+  if id != 0 {
+    data = append(data, (&Box{Type: "chap", Data: appendUint32(nil, id)}).Encode()...)
+  }
+  switch {
+  case tref != nil && len(data) == 0:
+    trak.removeChildren("tref")
+  case tref != nil:
+    tref.Data = data
+  case len(data) > 0:
+    // tref follows tkhd
+    at := 0
+    for i, c := range trak.Children {
+      if c.Type == "tkhd" {
+        at = i + 1
+      }
+    }
+    tref = &Box{Type: "tref", Data: data}
+    trak.Children = append(trak.Children[:at], append([]*Box{tref}, trak.Children[at:]...)...)
+  }
+}
+
+// ownedMdat returns the mdat box directly behind moov if it holds
+// nothing but the samples of trak
+func (f *File) ownedMdat(trak *Box) *Box {
+  var mdat *Box
+  for i, b := range f.Boxes {
+    if b == f.Moov && i + 1 < len(f.Boxes) && f.Boxes[i + 1].Type == "mdat" {
+      mdat = f.Boxes[i + 1]
+    }
+  }
+  samples, err := trackSamples(trak)
+  if mdat == nil || err != nil || len(samples) == 0 {
+    return nil
+  }
+  offset := mdat.Offset + 8
+  for _, s := range samples {
+    if s.offset != offset {
+      return nil
+    }
+    offset += int64(s.size)
+  }
+  if offset != mdat.Offset + mdat.Size {
+    return nil
+  }
+  return mdat
+}
+
+// removeChildren removes the children of the given type,
+// only those in only if given
+func (box *Box) removeChildren(typ string, only ...*Box) {
+  children := box.Children[:0]
+  for _, c := range box.Children {
+    remove := c.Type == typ
+    if remove && len(only) > 0 {
+      remove = false
+      for _, o := range only {
+        remove = remove || c == o
+      }
+    }
+    if !remove {
+      children = append(children, c)
+    }
+  }
+  box.Children = children
+}
+
+// MediaData returns the mdat boxes, an mdat holding nothing but
+// the samples of the chapter track is left out
+func (f *File) MediaData() []*Box {
+  var chapters *Box
+  if trak := f.ChapterTrack(); trak != nil {
+    chapters = f.ownedMdat(trak)
+  }
+  var boxes []*Box
+  for _, b := range f.Boxes {
+    if b.Type == "mdat" && b != chapters {
+      boxes = append(boxes, b)
+    }
+  }
+  return boxes
+}
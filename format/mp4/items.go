@@ -0,0 +1,368 @@
+package mp4
+
+import (
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "strconv"
+  "strings"
+  "unicode/utf16"
+)
+
+import (
+  id3v1 "github.com/elias-boemeke/taggo/format/id3v1"
+)
+
+
+
+// well known types of data atoms
+const (
+  TypeBinary  = 0
+  TypeUTF8    = 1
+  TypeUTF16   = 2
+  TypeGIF     = 12
+  TypeJPEG    = 13
+  TypePNG     = 14
+  TypeInteger = 21
+)
+
+// prefix of the keys of freeform items
+const Freeform = "----"
+
+// mean of the freeform items written by itunes
+const ITunes = "com.apple.iTunes"
+
+type Item struct {
+  // atom name or ----:mean:name for freeform items
+  Key  string
+  Type uint32
+  Data []byte
+}
+
+// ItemList returns the ilst box or nil if the file has none
+func (f *File) ItemList() *Box {
+  return f.Moov.Find("udta", "meta", "ilst")
+}
+
+// itemList returns the ilst box, creating udta, meta and ilst as needed
+func (f *File) itemList() *Box {
+  udta := f.Moov.Child("udta")
+  if udta == nil {
+    udta = &Box{Type: "udta", Container: true}
+    f.Moov.Children = append(f.Moov.Children, udta)
+  }
+  meta := udta.Child("meta")
+  if meta == nil {
+    hdlr := make([]byte, 25)
+    copy(hdlr[8:], "mdirappl")
+    meta = &Box{Type: "meta", Container: true, Prefix: make([]byte, 4), Children: []*Box{
+      {Type: "hdlr", Data: hdlr},
+    }}
+    udta.Children = append(udta.Children, meta)
+  }
+  ilst := meta.Child("ilst")
+  if ilst == nil {
+    ilst = &Box{Type: "ilst", Container: true}
+    meta.Children = append(meta.Children, ilst)
+  }
+  return ilst
+}
+
+// itemKey returns the key of an item box of the ilst
+func itemKey(item *Box) string {
+  if item.Type != Freeform {
+    return item.Type
+  }
+  var mean, name string
+  if b := item.Child("mean"); b != nil && len(b.Data) >= 4 {
+    mean = string(b.Data[4:])
+  }
+  if b := item.Child("name"); b != nil && len(b.Data) >= 4 {
+    name = string(b.Data[4:])
+  }
+  return Freeform + ":" + mean + ":" + name
+}
+
+// Items lists the data atoms of all items in file order
+func (f *File) Items() []Item {
+  ilst := f.ItemList()
+  if ilst == nil {
+    return nil
+  }
+  var items []Item
+  for _, item := range ilst.Children {
+    key := itemKey(item)
+    for _, d := range item.Children {
+      if d.Type != "data" || len(d.Data) < 8 {
+        continue
+      }
+      items = append(items, Item{
+        Key:  key,
+        Type: binary.BigEndian.Uint32(d.Data) & 0xffffff,
+        Data: d.Data[8:],
+      })
+    }
+  }
+  return items
+}
+
+// Get returns the data atoms of the item with the given key
+func (f *File) Get(key string) []Item {
+  var items []Item
+  for _, item := range f.Items() {
+    if item.Key == key {
+      items = append(items, item)
+    }
+  }
+  return items
+}
+
+// Set replaces the item with the given key by one data atom per value,
+// the item is removed if no values are given
+func (f *File) Set(key string, values ...Item) {
+  if len(values) == 0 {
+    f.Remove(key)
+    return
+  }
+  item := &Box{Type: key, Container: true}
+  if strings.HasPrefix(key, Freeform + ":") {
+    parts := strings.SplitN(key, ":", 3)
+    if len(parts) < 3 {
+      parts = append(parts, "")
+    }
+    item.Type = Freeform
+    item.Children = []*Box{
+      {Type: "mean", Data: append(make([]byte, 4), parts[1]...)},
+      {Type: "name", Data: append(make([]byte, 4), parts[2]...)},
+    }
+  }
+  for _, v := range values {
+    data := make([]byte, 8, 8 + len(v.Data))
+    binary.BigEndian.PutUint32(data, v.Type & 0xffffff)
+    item.Children = append(item.Children, &Box{Type: "data", Data: append(data, v.Data...)})
+  }
+
+  // the item takes the position of the first one it replaces
+  ilst := f.itemList()
+  var children []*Box
+  for _, c := range ilst.Children {
+    if itemKey(c) != key {
+      children = append(children, c)
+    } else if item != nil {
+      children = append(children, item)
+      item = nil
+    }
+  }
+  if item != nil {
+    children = append(children, item)
+  }
+  ilst.Children = children
+}
+
+// Remove removes the item with the given key
+func (f *File) Remove(key string) {
+  ilst := f.ItemList()
+  if ilst == nil {
+    return
+  }
+  kept := ilst.Children[:0]
+  for _, item := range ilst.Children {
+    if itemKey(item) != key {
+      kept = append(kept, item)
+    }
+  }
+  ilst.Children = kept
+}
+
+// String formats the value of the data atom
+func (item Item) String() string {
+  switch {
+  case item.Key == "trkn" || item.Key == "disk":
+    n, total := decodePair(item.Data)
+    return formatPair(n, total)
+  case item.Key == "gnre" && len(item.Data) == 2:
+    return genreOf(item.Data)
+  }
+  switch item.Type {
+  case TypeUTF8:
+    return string(item.Data)
+  case TypeUTF16:
+    return decodeUTF16(item.Data)
+  case TypeInteger:
+    return strconv.FormatInt(decodeInteger(item.Data), 10)
+  case TypeGIF:
+    return fmt.Sprintf("<image/gif, %d bytes>", len(item.Data))
+  case TypeJPEG:
+    return fmt.Sprintf("<image/jpeg, %d bytes>", len(item.Data))
+  case TypePNG:
+    return fmt.Sprintf("<image/png, %d bytes>", len(item.Data))
+  }
+  return fmt.Sprintf("<binary, %d bytes>", len(item.Data))
+}
+
+// Texts returns the values of the item with the given key as strings
+func (f *File) Texts(key string) []string {
+  var values []string
+  for _, item := range f.Get(key) {
+    values = append(values, item.String())
+  }
+  return values
+}
+
+// Text returns the values of the item joined by "; "
+func (f *File) Text(key string) string {
+  return strings.Join(f.Texts(key), "; ")
+}
+
+// SetText sets the item to UTF-8 text values
+func (f *File) SetText(key string, values ...string) {
+  var items []Item
+  for _, v := range values {
+    items = append(items, Item{Type: TypeUTF8, Data: []byte(v)})
+  }
+  f.Set(key, items...)
+}
+
+// SetInteger sets an integer item of size bytes, e.g. 2 for tmpo and 1 for cpil
+func (f *File) SetInteger(key string, x int64, size int) {
+  f.Set(key, IntegerItem(x, size))
+}
+
+// IntegerItem returns a big endian integer data atom of size bytes
+func IntegerItem(x int64, size int) Item {
+  data := make([]byte, size)
+  for i := size - 1; i >= 0; i-- {
+    data[i] = byte(x)
+    x >>= 8
+  }
+  return Item{Type: TypeInteger, Data: data}
+}
+
+// Pair returns number and total of a trkn or disk item
+func (f *File) Pair(key string) (int, int) {
+  items := f.Get(key)
+  if len(items) == 0 {
+    return 0, 0
+  }
+  return decodePair(items[0].Data)
+}
+
+// SetPair sets a trkn or disk item, it is removed if both are zero
+func (f *File) SetPair(key string, n int, total int) {
+  if n == 0 && total == 0 {
+    f.Remove(key)
+    return
+  }
+  // disk omits the trailing two bytes of trkn
+  data := make([]byte, 8)
+  if key == "disk" {
+    data = data[:6]
+  }
+  binary.BigEndian.PutUint16(data[2:], uint16(n))
+  binary.BigEndian.PutUint16(data[4:], uint16(total))
+  f.Set(key, Item{Type: TypeBinary, Data: data})
+}
+
+// PairString returns a trkn or disk item as n/total
+func (f *File) PairString(key string) string {
+  return formatPair(f.Pair(key))
+}
+
+// SetPairString sets a trkn or disk item from a value like n/total or n
+func (f *File) SetPairString(key string, value string) error {
+  if value == "" {
+    f.Remove(key)
+    return nil
+  }
+  n, total, err := ParsePair(value)
+  if err != nil {
+    return err
+  }
+  f.SetPair(key, n, total)
+  return nil
+}
+
+// ParsePair parses a value like n/total, total being optional
+func ParsePair(value string) (int, int, error) {
+  parts := strings.SplitN(value, "/", 2)
+  numbers := []int{0, 0}
+  for i, p := range parts {
+    if p == "" && i == 1 {
+      continue
+    }
+    x, err := strconv.Atoi(strings.TrimSpace(p))
+    if err != nil || x < 0 || x > 0xffff {
+      return 0, 0, errors.New(fmt.Sprintf("invalid number pair '%s'", value))
+    }
+    numbers[i] = x
+  }
+  return numbers[0], numbers[1], nil
+}
+
+// Genre returns the ©gen text or the resolved gnre id3v1 genre
+func (f *File) Genre() string {
+  return strings.Join(f.Genres(), "; ")
+}
+
+// Genres returns the values of ©gen or the resolved gnre id3v1 genre
+func (f *File) Genres() []string {
+  if texts := f.Texts("©gen"); len(texts) > 0 {
+    return texts
+  }
+  return f.Texts("gnre")
+}
+
+// SetGenre writes the genre as ©gen text, replacing a gnre item
+func (f *File) SetGenre(values ...string) {
+  f.Remove("gnre")
+  f.SetText("©gen", values...)
+}
+
+func decodePair(b []byte) (int, int) {
+  var n, total int
+  if len(b) >= 4 {
+    n = int(binary.BigEndian.Uint16(b[2:]))
+  }
+  if len(b) >= 6 {
+    total = int(binary.BigEndian.Uint16(b[4:]))
+  }
+  return n, total
+}
+
+func formatPair(n int, total int) string {
+  switch {
+  case total > 0:
+    return fmt.Sprintf("%d/%d", n, total)
+  case n > 0:
+    return strconv.Itoa(n)
+  }
+  return ""
+}
+
+// genreOf resolves a gnre item holding the id3v1 genre id plus one
+func genreOf(b []byte) string {
+  id := int(binary.BigEndian.Uint16(b))
+  if name := id3v1.GenreName(id - 1); name != "" {
+    return name
+  }
+  return strconv.Itoa(id)
+}
+
+func decodeInteger(b []byte) int64 {
+  var x int64
+  if len(b) > 0 && b[0] & 0x80 != 0 {
+    x = -1
+  }
+  for _, c := range b {
+    x = x << 8 | int64(c)
+  }
+  return x
+}
+
+func decodeUTF16(b []byte) string {
+  units := make([]uint16, len(b) / 2)
+  for i := range units {
+    units[i] = binary.BigEndian.Uint16(b[2 * i:])
+  }
+  return string(utf16.Decode(units))
+}
@@ -0,0 +1,247 @@
+package mp4
+
+import (
+  "encoding/binary"
+  "errors"
+  "io"
+  "os"
+  "time"
+)
+
+
+
+var ErrNotMP4 = errors.New("not an mp4 file")
+
+type File struct {
+  // top level boxes, only moov is read completely
+  Boxes []*Box
+  Moov  *Box
+  Size  int64
+  Brand string
+
+  // samples of a chapter track set by SetTrackChapters, the offset
+  // of their chunk in chapterOffsets is set by WriteFile
+  chapterData    []byte
+  chapterOffsets *Box
+  // mdat of the replaced chapter track, given up by WriteFile
+  chapterMdat *Box
+}
+
+type Properties struct {
+  Length     time.Duration
+  // codec of the first audio track (mp4a, alac, ...)
+  Format     string
+  Codec      string
+  Channels   int
+  Samplerate int
+  BitsPerSample int
+  // in kbit/s, zero if unknown
+  Bitrate int
+}
+
+// ReadFile reads the top level boxes and the moov box of the file at path
+func ReadFile(path string) (*File, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+  info, err := file.Stat()
+  if err != nil {
+    return nil, err
+  }
+  return Read(file, info.Size())
+}
+
+func Read(r io.ReaderAt, size int64) (*File, error) {
+  f := &File{Size: size}
+  for offset := int64(0); offset < size; {
+    typ, boxSize, header, err := readHeader(r, offset, size)
+    if err != nil {
+      if offset == 0 {
+        return nil, ErrNotMP4
+      }
+      return nil, err
+    }
+    if offset == 0 && typ != "ftyp" {
+      return nil, ErrNotMP4
+    }
+    box := &Box{Type: typ, Offset: offset, Size: boxSize}
+
+    if typ == "moov" || typ == "ftyp" {
+      payload := make([]byte, boxSize - int64(header))
+      if _, err := r.ReadAt(payload, offset + int64(header)); err != nil {
+        return nil, errTruncated
+      }
+      if err := box.parsePayload(payload, offset + int64(header), ""); err != nil {
+        return nil, err
+      }
+      if typ == "moov" && f.Moov == nil {
+        f.Moov = box
+      }
+      if typ == "ftyp" && len(payload) >= 4 {
+        f.Brand = string(payload[0:4])
+      }
+    }
+    f.Boxes = append(f.Boxes, box)
+    offset += boxSize
+  }
+  if f.Moov == nil {
+    return nil, errors.New("mp4 file has no moov box")
+  }
+  return f, nil
+}
+
+// audioTrack returns the first trak whose handler is sound
+func (f *File) audioTrack() *Box {
+  for _, trak := range f.Moov.Children {
+    if trak.Type != "trak" {
+      continue
+    }
+    hdlr := trak.Find("mdia", "hdlr")
+    if hdlr != nil && len(hdlr.Data) >= 12 && string(hdlr.Data[8:12]) == "soun" {
+      return trak
+    }
+  }
+  return nil
+}
+
+// timing returns timescale and duration of a mvhd or mdhd box
+func timing(b *Box) (uint32, uint64) {
+  if b == nil || len(b.Data) < 4 {
+    return 0, 0
+  }
+  d := b.Data
+  if d[0] == 1 && len(d) >= 32 {
+    return binary.BigEndian.Uint32(d[20:]), binary.BigEndian.Uint64(d[24:])
+  }
+  if len(d) >= 20 {
+    return binary.BigEndian.Uint32(d[12:]), uint64(binary.BigEndian.Uint32(d[16:]))
+  }
+  return 0, 0
+}
+
+func (f *File) Properties() Properties {
+  var p Properties
+  scale, duration := timing(f.Moov.Child("mvhd"))
+  trak := f.audioTrack()
+  if trak != nil {
+    if s, d := timing(trak.Find("mdia", "mdhd")); s > 0 {
+      scale, duration = s, d
+    }
+  }
+  if scale > 0 {
+    p.Length = time.Duration(float64(duration) / float64(scale) * float64(time.Second))
+  }
+  if trak == nil {
+    return p
+  }
+
+  stsd := trak.Find("mdia", "minf", "stbl", "stsd")
+  if stsd == nil || len(stsd.Data) < 8 + 36 {
+    return p
+  }
+  // first sample entry behind version, flags and entry count
+  entry := stsd.Data[8:]
+  entrySize := int(binary.BigEndian.Uint32(entry))
+  if entrySize > len(entry) || entrySize < 36 {
+    return p
+  }
+  entry = entry[:entrySize]
+  p.Format        = string(entry[4:8])
+  p.Channels      = int(binary.BigEndian.Uint16(entry[24:]))
+  p.BitsPerSample = int(binary.BigEndian.Uint16(entry[26:]))
+  p.Samplerate    = int(binary.BigEndian.Uint32(entry[32:]) >> 16)
+
+  children, _ := parseBoxes(entry[36:], 0, "")
+  for _, c := range children {
+    switch c.Type {
+    case "esds":
+      p.Codec = "AAC"
+      if object, bitrate := parseESDS(c.Data); bitrate > 0 {
+        p.Bitrate = bitrate / 1000
+        if object == 0x6b || object == 0x69 {
+          p.Codec = "MP3"
+        }
+      }
+    case "alac":
+      p.Codec = "ALAC"
+      if len(c.Data) >= 28 {
+        p.BitsPerSample = int(c.Data[9])
+        p.Channels      = int(c.Data[13])
+        p.Bitrate       = int(binary.BigEndian.Uint32(c.Data[20:])) / 1000
+        p.Samplerate    = int(binary.BigEndian.Uint32(c.Data[24:]))
+      }
+    }
+  }
+  if p.Codec == "" {
+    p.Codec = p.Format
+  }
+
+  if p.Bitrate == 0 && p.Length > 0 {
+    var media int64
+    for _, b := range f.Boxes {
+      if b.Type == "mdat" {
+        media += b.Size
+      }
+    }
+    p.Bitrate = int(float64(media) * 8 / p.Length.Seconds() / 1000)
+  }
+  return p
+}
+
+// parseESDS returns object type and average bitrate of the
+// decoder config descriptor in an esds box
+func parseESDS(b []byte) (byte, int) {
+  if len(b) < 4 {
+    return 0, 0
+  }
+  b = b[4:]
+  // descriptor lengths use up to four bytes of seven bits
+  descriptor := func() (byte, []byte) {
+    if len(b) < 2 {
+      return 0, nil
+    }
+    tag := b[0]
+    n, i := 0, 1
+    for ; i < len(b) && i <= 4; i++ {
+      n = n << 7 | int(b[i] & 0x7f)
+      if b[i] & 0x80 == 0 {
+        i++
+        break
+      }
+    }
+    if i + n > len(b) {
+      return 0, nil
+    }
+    content := b[i:i + n]
+    return tag, content
+  }
+
+  tag, es := descriptor()
+  if tag != 0x03 || len(es) < 3 {
+    return 0, 0
+  }
+  // es id and flags with optional fields
+  flags := es[2]
+  es = es[3:]
+  if flags & 0x80 != 0 && len(es) >= 2 {
+    es = es[2:]
+  }
+  if flags & 0x40 != 0 && len(es) >= 1 {
+    // the url length is read from the file
+    if 1 + int(es[0]) > len(es) {
+      return 0, 0
+    }
+    es = es[1 + int(es[0]):]
+  }
+  if flags & 0x20 != 0 && len(es) >= 2 {
+    es = es[2:]
+  }
+  b = es
+  tag, config := descriptor()
+  if tag != 0x04 || len(config) < 13 {
+    return 0, 0
+  }
+  return config[0], int(binary.BigEndian.Uint32(config[9:]))
+}
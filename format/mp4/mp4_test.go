@@ -0,0 +1,159 @@
+package mp4
+
+import (
+  "bytes"
+  "encoding/binary"
+  "io/ioutil"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+
+
+var samples = []byte("audio samples")
+
+func box(typ string, payload ...[]byte) []byte {
+  b := make([]byte, 8)
+  copy(b[4:], typ)
+  for _, p := range payload {
+    b = append(b, p...)
+  }
+  binary.BigEndian.PutUint32(b, uint32(len(b)))
+  return b
+}
+
+// chunkOffsets returns the payload of stco or co64 with a single entry
+func chunkOffsets(width int, offset int64) []byte {
+  b := make([]byte, 8 + width)
+  b[7] = 1
+  if width == 8 {
+    binary.BigEndian.PutUint64(b[8:], uint64(offset))
+  } else {
+    binary.BigEndian.PutUint32(b[8:], uint32(offset))
+  }
+  return b
+}
+
+// file returns ftyp, moov with two tracks whose stco and co64 point to
+// the samples and an mdat holding them; a free box of free bytes follows
+// moov unless free is negative, the mdat is put in front of moov if first
+func file(free int, first bool) []byte {
+  ftyp := box("ftyp", []byte("M4A \x00\x00\x00\x00"))
+  mdat := box("mdat", samples)
+  moov := func(offset int64) []byte {
+    stbl := func(typ string, width int) []byte {
+      return box("trak", box("mdia", box("minf", box("stbl",
+        box(typ, chunkOffsets(width, offset))))))
+    }
+    return box("moov", stbl("stco", 4), stbl("co64", 8))
+  }
+  var padding []byte
+  if free >= 0 {
+    padding = box("free", make([]byte, free))
+  }
+
+  if first {
+    offset := int64(len(ftyp) + 8)
+    return bytes.Join([][]byte{ftyp, mdat, moov(offset), padding}, nil)
+  }
+  offset := int64(len(ftyp) + len(moov(0)) + len(padding) + 8)
+  return bytes.Join([][]byte{ftyp, moov(offset), padding, mdat}, nil)
+}
+
+// offsets returns the chunk offsets of stco and co64
+func offsets(f *File) []int64 {
+  var o []int64
+  f.Moov.Walk(func(b *Box, depth int) {
+    switch b.Type {
+    case "stco":
+      o = append(o, int64(binary.BigEndian.Uint32(b.Data[8:])))
+    case "co64":
+      o = append(o, int64(binary.BigEndian.Uint64(b.Data[8:])))
+    }
+  }, 0)
+  return o
+}
+
+func TestWriteFileShiftsChunks(t *testing.T) {
+  tests := []struct {
+    name    string
+    free    int
+    first   bool
+    title   int
+    // the samples keep their offset
+    inPlace bool
+  }{
+    {"free box used", 1000, false, 100, true},
+    {"free box exceeded", 16, false, 1000, false},
+    {"no free box", -1, false, 100, false},
+    {"moov behind mdat", -1, true, 1000, true},
+  }
+  for _, tt := range tests {
+    path := filepath.Join(t.TempDir(), "a.m4a")
+    if err := ioutil.WriteFile(path, file(tt.free, tt.first), 0644); err != nil {
+      t.Fatal(err)
+    }
+    f, err := ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    before := offsets(f)
+
+    f.SetText("©nam", strings.Repeat("x", tt.title))
+    if err := WriteFile(path, f, 64, false); err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+
+    b, err := ioutil.ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    read, err := ReadFile(path)
+    if err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+    after := offsets(read)
+    if len(after) != 2 {
+      t.Fatalf("%s: %d chunk offset tables", tt.name, len(after))
+    }
+    for i, o := range after {
+      if o + int64(len(samples)) > int64(len(b)) ||
+          !bytes.Equal(b[o:o + int64(len(samples))], samples) {
+        t.Errorf("%s: chunk offset %d points to no samples", tt.name, o)
+      }
+      if (o == before[i]) != tt.inPlace {
+        t.Errorf("%s: chunk offset %d became %d", tt.name, before[i], o)
+      }
+    }
+    if got := read.Text("©nam"); got != strings.Repeat("x", tt.title) {
+      t.Errorf("%s: title of %d bytes read back", tt.name, len(got))
+    }
+  }
+}
+
+func TestParseESDS(t *testing.T) {
+  // version and flags, es descriptor with an url flag, decoder config
+  esds := func(url ...byte) []byte {
+    config := []byte{0x04, 13, 0x40, 0x15, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, 0xf4, 0}
+    es := append([]byte{0, 1, 0x40}, url...)
+    es = append(es, config...)
+    return append([]byte{0, 0, 0, 0, 0x03, byte(len(es))}, es...)
+  }
+  tests := []struct {
+    name    string
+    b       []byte
+    object  byte
+    bitrate int
+  }{
+    {"url", esds(3, 'a', 'b', 'c'), 0x40, 128000},
+    {"url beyond descriptor", esds(200, 'a'), 0, 0},
+    {"truncated", []byte{0, 0, 0}, 0, 0},
+  }
+  for _, tt := range tests {
+    object, bitrate := parseESDS(tt.b)
+    if object != tt.object || bitrate != tt.bitrate {
+      t.Errorf("%s: object %#x, bitrate %d", tt.name, object, bitrate)
+    }
+  }
+}
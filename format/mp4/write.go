@@ -0,0 +1,141 @@
+package mp4
+
+import (
+  "encoding/binary"
+  "errors"
+  "fmt"
+)
+
+import (
+  rewrite "github.com/elias-boemeke/taggo/format/rewrite"
+)
+
+
+
+// size of the free box written behind moov when it has to grow
+const DefaultPadding = 1024
+
+// WriteFile writes the moov box of f to the file at path; a free box directly
+// behind moov is used up first, if moov still does not fit the following boxes
+// are moved and the chunk offsets of stco and co64 are adjusted; if compact
+// is set free space beyond padding is given back; the samples of a new
+// chapter track follow moov in an mdat box of their own
+func WriteFile(path string, f *File, padding int, compact bool) error {
+  index := -1
+  for i, b := range f.Boxes {
+    if b == f.Moov {
+      index = i
+    }
+  }
+  if index < 0 {
+    return errors.New("mp4 file has no moov box")
+  }
+  start := f.Moov.Offset
+  end := start + f.Moov.Size
+  if index + 1 < len(f.Boxes) && f.Boxes[index + 1] == f.chapterMdat {
+    index++
+    end += f.chapterMdat.Size
+  }
+  if index + 1 < len(f.Boxes) && isFree(f.Boxes[index + 1].Type) {
+    end += f.Boxes[index + 1].Size
+  }
+
+  data := f.Moov.Encode()
+  moovSize := len(data)
+  if f.chapterData != nil {
+    data = append(data, (&Box{Type: "mdat", Data: f.chapterData}).Encode()...)
+  }
+  limit := padding
+  if limit < 0 {
+    limit = DefaultPadding
+  }
+  // a free box needs at least its header of 8 bytes
+  switch free := end - start - int64(len(data)); {
+  case free == 0:
+  case end == f.Size:
+    // nothing follows moov, the file is simply cut or extended
+    if padding > 0 {
+      data = append(data, freeBox(padding)...)
+    }
+  case free >= 8 && (!compact || free - 8 <= int64(limit)):
+    data = append(data, freeBox(int(free) - 8)...)
+  default:
+    data = append(data, freeBox(limit)...)
+  }
+
+  if delta := start + int64(len(data)) - end; delta != 0 {
+    if err := shiftChunks(f.Moov, end, delta); err != nil {
+      return err
+    }
+  }
+  if f.chapterData != nil {
+    // behind the header of the mdat following moov
+    offset := start + int64(moovSize) + 8
+    if offset > 0xffffffff {
+      return errors.New("mp4 chapter samples exceed 32 bit offsets")
+    }
+    binary.BigEndian.PutUint32(f.chapterOffsets.Data[8:], uint32(offset))
+  }
+  copy(data, f.Moov.Encode())
+
+  err := rewrite.Replace(path, start, end, data)
+  if err != nil {
+    return err
+  }
+  // read back to pick up the new offsets
+  n, err := ReadFile(path)
+  if err != nil {
+    return err
+  }
+  *f = *n
+  return nil
+}
+
+func isFree(typ string) bool {
+  return typ == "free" || typ == "skip"
+}
+
+func freeBox(size int) []byte {
+  b := make([]byte, 8 + size)
+  binary.BigEndian.PutUint32(b, uint32(8 + size))
+  copy(b[4:], "free")
+  return b
+}
+
+// shiftChunks moves all chunk offsets at or behind from by delta
+func shiftChunks(moov *Box, from int64, delta int64) error {
+  var err error
+  moov.Walk(func(b *Box, depth int) {
+    if err != nil || (b.Type != "stco" && b.Type != "co64") || len(b.Data) < 8 {
+      return
+    }
+    count := int(binary.BigEndian.Uint32(b.Data[4:]))
+    width := 4
+    if b.Type == "co64" {
+      width = 8
+    }
+    if 8 + count * width > len(b.Data) {
+      err = errors.New(fmt.Sprintf("mp4 box '%s' truncated", b.Type))
+      return
+    }
+    for i := 0; i < count; i++ {
+      entry := b.Data[8 + i * width:]
+      if width == 8 {
+        if offset := int64(binary.BigEndian.Uint64(entry)); offset >= from {
+          binary.BigEndian.PutUint64(entry, uint64(offset + delta))
+        }
+        continue
+      }
+      offset := int64(binary.BigEndian.Uint32(entry))
+      if offset < from {
+        continue
+      }
+      if offset + delta > 0xffffffff {
+        err = errors.New("mp4 chunk offsets exceed 32 bits, stco would need to become co64")
+        return
+      }
+      binary.BigEndian.PutUint32(entry, uint32(offset + delta))
+    }
+  }, 0)
+  return err
+}
@@ -0,0 +1,251 @@
+package mpeg
+
+import (
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "io"
+  "time"
+)
+
+
+
+type Header struct {
+  // 1, 2 or 25 for MPEG-2.5
+  Version    int
+  Layer      int
+  Bitrate    int
+  Samplerate int
+  Padding    bool
+  Mode       ChannelMode
+}
+
+type ChannelMode int
+const (
+  Stereo      ChannelMode = iota
+  JointStereo
+  DualChannel
+  Mono
+)
+
+type Properties struct {
+  Header
+  Length time.Duration
+  // average bitrate in kbit/s
+  AverageBitrate int
+  Frames         int
+  VBR            bool
+  // offset of the first frame relative to the start of the audio
+  Offset int64
+}
+
+// how far into the audio data the first frame is searched for
+const searchLimit = 64 * 1024
+
+var bitrates = [2][3][16]int{
+  // MPEG-1, layer 1 to 3
+  {
+    {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, -1},
+    {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, -1},
+    {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, -1},
+  },
+  // MPEG-2 and MPEG-2.5, layer 1 to 3
+  {
+    {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, -1},
+    {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, -1},
+    {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, -1},
+  },
+}
+
+var samplerates = map[int][3]int{
+  1:  {44100, 48000, 32000},
+  2:  {22050, 24000, 16000},
+  25: {11025, 12000, 8000},
+}
+
+func (m ChannelMode) String() string {
+  switch m {
+  case Stereo:
+    return "stereo"
+  case JointStereo:
+    return "joint stereo"
+  case DualChannel:
+    return "dual channel"
+  default:
+    return "mono"
+  }
+}
+
+func (h Header) Channels() int {
+  if h.Mode == Mono {
+    return 1
+  }
+  return 2
+}
+
+func (h Header) SamplesPerFrame() int {
+  switch {
+  case h.Layer == 1:
+    return 384
+  case h.Layer == 3 && h.Version != 1:
+    return 576
+  default:
+    return 1152
+  }
+}
+
+// FrameSize returns the size of the frame in bytes including the header
+func (h Header) FrameSize() int {
+  pad := 0
+  if h.Padding {
+    pad = 1
+  }
+  if h.Layer == 1 {
+    return (12 * h.Bitrate * 1000 / h.Samplerate + pad) * 4
+  }
+  return h.SamplesPerFrame() / 8 * h.Bitrate * 1000 / h.Samplerate + pad
+}
+
+func (h Header) String() string {
+  version := fmt.Sprintf("MPEG-%d", h.Version)
+  if h.Version == 25 {
+    version = "MPEG-2.5"
+  }
+  return fmt.Sprintf("%s Layer %d", version, h.Layer)
+}
+
+// ParseHeader parses the four byte frame header b
+func ParseHeader(b []byte) (Header, bool) {
+  var h Header
+  if len(b) < 4 || b[0] != 0xff || b[1] & 0xe0 != 0xe0 {
+    return h, false
+  }
+  switch (b[1] >> 3) & 0x03 {
+  case 0:
+    h.Version = 25
+  case 2:
+    h.Version = 2
+  case 3:
+    h.Version = 1
+  default:
+    return h, false
+  }
+  layer := (b[1] >> 1) & 0x03
+  if layer == 0 {
+    return h, false
+  }
+  h.Layer = 4 - int(layer)
+
+  table := 0
+  if h.Version != 1 {
+    table = 1
+  }
+  h.Bitrate = bitrates[table][h.Layer - 1][b[2] >> 4]
+  // free format (0) is not supported
+  if h.Bitrate <= 0 {
+    return h, false
+  }
+  rate := (b[2] >> 2) & 0x03
+  if rate == 3 {
+    return h, false
+  }
+  h.Samplerate = samplerates[h.Version][rate]
+  h.Padding = b[2] & 0x02 != 0
+  h.Mode = ChannelMode(b[3] >> 6)
+  return h, true
+}
+
+// ReadProperties reads the audio properties of the MPEG stream found in
+// r between start and end; the first frame is validated by the header of
+// the following one and searched for within the first 64 KiB
+func ReadProperties(r io.ReaderAt, start int64, end int64) (Properties, error) {
+  var p Properties
+  size := end - start
+  n := int64(searchLimit + 4)
+  if n > size {
+    n = size
+  }
+  buf := make([]byte, n)
+  if _, err := r.ReadAt(buf, start); err != nil && err != io.EOF {
+    return p, err
+  }
+
+  for i := 0; i + 4 <= len(buf); i++ {
+    h, ok := ParseHeader(buf[i:])
+    if !ok {
+      continue
+    }
+    next := make([]byte, 4)
+    pos := start + int64(i) + int64(h.FrameSize())
+    if pos + 4 <= end {
+      if _, err := r.ReadAt(next, pos); err != nil {
+        continue
+      }
+      nh, ok := ParseHeader(next)
+      if !ok || nh.Version != h.Version || nh.Layer != h.Layer ||
+          nh.Samplerate != h.Samplerate {
+        continue
+      }
+    }
+    p.Header = h
+    p.Offset = int64(i)
+    return p, p.complete(r, start + int64(i), end)
+  }
+  return p, errors.New("no MPEG audio frame found")
+}
+
+// complete fills in length and average bitrate from a Xing/Info
+// or VBRI header in the first frame, or assumes a constant bitrate
+func (p *Properties) complete(r io.ReaderAt, frameStart int64, end int64) error {
+  frame := make([]byte, p.FrameSize())
+  n, err := r.ReadAt(frame, frameStart)
+  if err != nil && err != io.EOF {
+    return err
+  }
+  frame = frame[:n]
+
+  // offset of the Xing header behind the side information
+  xing := 4
+  switch {
+  case p.Version == 1 && p.Mode != Mono:
+    xing += 32
+  case p.Version == 1 || p.Mode != Mono:
+    xing += 17
+  default:
+    xing += 9
+  }
+
+  audio := end - frameStart
+  if len(frame) >= xing + 12 && (string(frame[xing:xing + 4]) == "Xing" ||
+      string(frame[xing:xing + 4]) == "Info") {
+    flags := binary.BigEndian.Uint32(frame[xing + 4:])
+    if flags & 0x01 != 0 {
+      p.Frames = int(binary.BigEndian.Uint32(frame[xing + 8:]))
+    }
+    if flags & 0x02 != 0 && len(frame) >= xing + 16 {
+      bytes := int64(binary.BigEndian.Uint32(frame[xing + 12:]))
+      if bytes > 0 && bytes <= audio {
+        audio = bytes
+      }
+    }
+    p.VBR = string(frame[xing:xing + 4]) == "Xing"
+  } else if len(frame) >= 36 + 18 && string(frame[36:40]) == "VBRI" {
+    bytes := int64(binary.BigEndian.Uint32(frame[36 + 10:]))
+    if bytes > 0 && bytes <= audio {
+      audio = bytes
+    }
+    p.Frames = int(binary.BigEndian.Uint32(frame[36 + 14:]))
+    p.VBR = true
+  }
+
+  if p.Frames > 0 {
+    samples := int64(p.Frames) * int64(p.SamplesPerFrame())
+    p.Length = time.Duration(float64(samples) / float64(p.Samplerate) * float64(time.Second))
+  } else {
+    p.Length = time.Duration(float64(audio) * 8 / float64(p.Bitrate * 1000) * float64(time.Second))
+  }
+  if p.Length > 0 {
+    p.AverageBitrate = int(float64(audio) * 8 / p.Length.Seconds() / 1000)
+  }
+  return nil
+}
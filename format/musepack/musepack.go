@@ -0,0 +1,106 @@
+package musepack
+
+import (
+  "encoding/binary"
+  "errors"
+  "io"
+  "time"
+)
+
+
+
+// stream properties of a Musepack file, stream version 7 or 8
+type Properties struct {
+  StreamVersion int
+  Channels      int
+  Samplerate    int
+  Samples       int64
+  Length        time.Duration
+}
+
+var ErrNotMusepack = errors.New("not a musepack file")
+
+var samplerates = []int{44100, 48000, 37800, 32000}
+
+// samples per frame of stream version 7
+const frameSamples = 1152
+
+func ReadProperties(r io.ReaderAt) (Properties, error) {
+  var p Properties
+  b := make([]byte, 64)
+  n, _ := r.ReadAt(b, 0)
+  b = b[:n]
+  var err error
+  switch {
+  case len(b) >= 12 && string(b[0:3]) == "MP+":
+    p.StreamVersion = int(b[3] & 0xf)
+    frames := binary.LittleEndian.Uint32(b[4:])
+    flags := binary.LittleEndian.Uint32(b[8:])
+    p.Channels = 2
+    p.Samplerate = samplerates[flags >> 16 & 3]
+    p.Samples = int64(frames) * frameSamples
+  case len(b) >= 4 && string(b[0:4]) == "MPCK":
+    err = readSV8(r, &p)
+  default:
+    return p, ErrNotMusepack
+  }
+  if err != nil {
+    return p, err
+  }
+  if p.Samplerate > 0 {
+    p.Length = time.Duration(float64(p.Samples) / float64(p.Samplerate) * float64(time.Second))
+  }
+  return p, nil
+}
+
+// readSV8 reads the stream header packet, the first packet with key SH
+func readSV8(r io.ReaderAt, p *Properties) error {
+  offset := int64(4)
+  for i := 0; i < 16; i++ {
+    b := make([]byte, 64)
+    n, _ := r.ReadAt(b, offset)
+    b = b[:n]
+    if len(b) < 3 {
+      break
+    }
+    key := string(b[0:2])
+    size, length := varint(b[2:])
+    if length == 0 || size < int64(2 + length) {
+      break
+    }
+    if key == "SH" {
+      // crc, version, sample count, beginning silence, rate and channels
+      pos := 2 + length + 4
+      if pos >= len(b) {
+        break
+      }
+      p.StreamVersion = int(b[pos])
+      samples, l := varint(b[pos + 1:])
+      silence, m := varint(b[pos + 1 + l:])
+      pos += 1 + l + m
+      if l == 0 || m == 0 || pos + 2 > len(b) {
+        break
+      }
+      p.Samples = samples - silence
+      if index := int(b[pos] >> 5); index < len(samplerates) {
+        p.Samplerate = samplerates[index]
+      }
+      p.Channels = int(b[pos + 1] >> 4) + 1
+      return nil
+    }
+    offset += size
+  }
+  return errors.New("musepack stream header not found")
+}
+
+// varint decodes a number of seven bit groups, returning it and its length
+func varint(b []byte) (int64, int) {
+  var x int64
+  for i := 0; i < len(b) && i < 9; i++ {
+    x = x << 7 | int64(b[i] & 0x7f)
+    if b[i] & 0x80 == 0 {
+      return x, i + 1
+    }
+  }
+  return 0, 0
+}
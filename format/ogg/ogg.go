@@ -0,0 +1,248 @@
+package ogg
+
+import (
+  "bytes"
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+)
+
+import (
+  vorbis "github.com/elias-boemeke/taggo/format/vorbis"
+)
+
+
+
+const (
+  Vorbis = "vorbis"
+  Opus   = "opus"
+)
+
+type File struct {
+  Serial uint32
+  Codec  string
+  // header packets: identification, comment and for vorbis setup
+  Headers [][]byte
+  // all pages of the file without data
+  Pages []*Page
+  // pages of the stream after the first one holding the header packets
+  headerPages []*Page
+  Size        int64
+}
+
+type VorbisInfo struct {
+  Channels   int
+  Samplerate int
+  // in bit/s, zero if unset
+  BitrateMaximum int
+  BitrateNominal int
+  BitrateMinimum int
+}
+
+type OpusInfo struct {
+  Version         int
+  Channels        int
+  PreSkip         int
+  InputSamplerate int
+  // Q7.8 fixed point dB
+  OutputGain    int
+  MappingFamily int
+}
+
+// samplerate of the opus granule position
+const OpusSamplerate = 48000
+
+// ReadFile reads the pages and the header packets of the first
+// logical stream of the ogg file at path
+func ReadFile(path string) (*File, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+  info, err := file.Stat()
+  if err != nil {
+    return nil, err
+  }
+
+  f := &File{Size: info.Size()}
+  for offset := int64(0); offset < f.Size; {
+    p, err := ReadPage(file, offset, false)
+    if err != nil {
+      if offset == 0 {
+        return nil, ErrNotOgg
+      }
+      return nil, errors.New(fmt.Sprintf("ogg page at offset %d: %s", offset, err))
+    }
+    f.Pages = append(f.Pages, p)
+    offset += int64(p.Size())
+  }
+  if f.Pages[0].Flags & FlagFirst == 0 {
+    return nil, errors.New("ogg stream does not start with a first page")
+  }
+  f.Serial = f.Pages[0].Serial
+
+  if err := f.readHeaders(file); err != nil {
+    return nil, err
+  }
+  return f, nil
+}
+
+// readHeaders assembles the header packets of the stream, the packets
+// after the identification have to fill their pages completely
+func (f *File) readHeaders(r io.ReaderAt) error {
+  count := 0
+  var packet []byte
+  for i, p := range f.Pages {
+    if p.Serial != f.Serial {
+      if count > 0 {
+        return errors.New("ogg header pages are interleaved with other streams")
+      }
+      continue
+    }
+    page, err := ReadPage(r, p.Offset, true)
+    if err != nil {
+      return err
+    }
+    packets, complete := page.packets()
+    for j, data := range packets {
+      packet = append(packet, data...)
+      if j == len(packets) - 1 && !complete {
+        break
+      }
+      f.Headers = append(f.Headers, packet)
+      packet = nil
+      count++
+      if count == 1 {
+        if err := f.detectCodec(); err != nil {
+          return err
+        }
+      }
+      if count == f.headerCount() {
+        if j != len(packets) - 1 {
+          return errors.New("ogg header packets share a page with audio packets")
+        }
+        f.headerPages = f.Pages[1:i + 1]
+        return nil
+      }
+    }
+    if i == 0 && count != 1 {
+      return errors.New("ogg first page does not hold exactly the identification packet")
+    }
+  }
+  return errors.New("ogg header packets incomplete")
+}
+
+func (f *File) detectCodec() error {
+  ident := f.Headers[0]
+  switch {
+  case bytes.HasPrefix(ident, []byte("\x01vorbis")) && len(ident) >= 30:
+    f.Codec = Vorbis
+  case bytes.HasPrefix(ident, []byte("OpusHead")) && len(ident) >= 19:
+    f.Codec = Opus
+  case bytes.HasPrefix(ident, []byte("\x7fFLAC")):
+    return errors.New("ogg flac streams are not supported")
+  case bytes.HasPrefix(ident, []byte("Speex   ")):
+    return errors.New("ogg speex streams are not supported")
+  default:
+    return errors.New("unknown ogg codec")
+  }
+  return nil
+}
+
+func (f *File) headerCount() int {
+  if f.Codec == Vorbis {
+    return 3
+  }
+  return 2
+}
+
+// commentPrefix returns the magic in front of the comment packet
+func (f *File) commentPrefix() []byte {
+  if f.Codec == Vorbis {
+    return []byte("\x03vorbis")
+  }
+  return []byte("OpusTags")
+}
+
+// Comment decodes the comment header packet
+func (f *File) Comment() (*vorbis.Comment, error) {
+  prefix := f.commentPrefix()
+  packet := f.Headers[1]
+  if !bytes.HasPrefix(packet, prefix) {
+    return nil, errors.New("ogg comment header missing")
+  }
+  c, _, err := vorbis.Decode(packet[len(prefix):])
+  return c, err
+}
+
+// SetComment replaces the comment header packet; the framing bit of
+// vorbis and binary data behind opus comments are kept
+func (f *File) SetComment(c *vorbis.Comment) {
+  prefix := f.commentPrefix()
+  var trailer []byte
+  old := f.Headers[1]
+  if bytes.HasPrefix(old, prefix) {
+    if _, n, err := vorbis.Decode(old[len(prefix):]); err == nil {
+      trailer = old[len(prefix) + n:]
+    }
+  }
+  if f.Codec == Vorbis {
+    trailer = []byte{1}
+  } else if len(trailer) > 0 && trailer[0] & 1 == 0 {
+    // padding only, not worth keeping
+    trailer = nil
+  }
+
+  packet := append(append([]byte{}, prefix...), c.Encode()...)
+  f.Headers[1] = append(packet, trailer...)
+}
+
+func (f *File) Vorbis() VorbisInfo {
+  b := f.Headers[0]
+  return VorbisInfo{
+    Channels:       int(b[11]),
+    Samplerate:     int(binary.LittleEndian.Uint32(b[12:])),
+    BitrateMaximum: int(int32(binary.LittleEndian.Uint32(b[16:]))),
+    BitrateNominal: int(int32(binary.LittleEndian.Uint32(b[20:]))),
+    BitrateMinimum: int(int32(binary.LittleEndian.Uint32(b[24:]))),
+  }
+}
+
+func (f *File) Opus() OpusInfo {
+  b := f.Headers[0]
+  return OpusInfo{
+    Version:         int(b[8]),
+    Channels:        int(b[9]),
+    PreSkip:         int(binary.LittleEndian.Uint16(b[10:])),
+    InputSamplerate: int(binary.LittleEndian.Uint32(b[12:])),
+    OutputGain:      int(int16(binary.LittleEndian.Uint16(b[16:]))),
+    MappingFamily:   int(b[18]),
+  }
+}
+
+// Samples returns the number of samples of the stream taken from the
+// granule position of its last page, for opus at 48 kHz and without pre-skip
+func (f *File) Samples() int64 {
+  var last int64
+  for _, p := range f.Pages {
+    if p.Serial == f.Serial && p.Granule != NoGranule {
+      last = p.Granule
+    }
+  }
+  if f.Codec == Opus {
+    last -= int64(f.Opus().PreSkip)
+  }
+  if last < 0 {
+    return 0
+  }
+  return last
+}
+
+// HeaderEnd returns the offset of the first page behind the header pages
+func (f *File) HeaderEnd() int64 {
+  last := f.headerPages[len(f.headerPages) - 1]
+  return last.Offset + int64(last.Size())
+}
@@ -0,0 +1,165 @@
+package ogg
+
+import (
+  "bytes"
+  "encoding/binary"
+  "io/ioutil"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+import (
+  vorbis "github.com/elias-boemeke/taggo/format/vorbis"
+)
+
+
+
+const serial = 0x1234
+
+// stream returns an opus stream of the identification and comment
+// headers followed by audio packets on pages of their own
+func stream(audio [][]byte) []byte {
+  ident := []byte("OpusHead\x01\x02\x38\x01\x80\xbb\x00\x00\x00\x00\x00")
+  comment := append([]byte("OpusTags"), vorbis.NewComment("test").Encode()...)
+
+  first := Paginate([][]byte{ident}, serial, 0, 0)[0]
+  first.Flags = FlagFirst
+  pages := []*Page{first}
+  pages = append(pages, Paginate([][]byte{comment}, serial, 1, 0)...)
+  for i, packet := range audio {
+    pages = append(pages, Paginate([][]byte{packet}, serial, uint32(len(pages)),
+      int64(960 * (i + 1)))...)
+  }
+  pages[len(pages) - 1].Flags |= FlagLast
+
+  var b []byte
+  for _, p := range pages {
+    b = append(b, p.Encode()...)
+  }
+  return b
+}
+
+// checkPages verifies the checksums and sequence numbers of the pages
+// of b and returns the data of the pages behind the headers
+func checkPages(t *testing.T, b []byte, headerPages int) []byte {
+  var data []byte
+  for offset, sequence := int64(0), uint32(0); offset < int64(len(b)); sequence++ {
+    p, err := ReadPage(bytes.NewReader(b), offset, true)
+    if err != nil {
+      t.Fatal(err)
+    }
+    raw := append([]byte(nil), b[offset:offset + int64(p.Size())]...)
+    stored := binary.LittleEndian.Uint32(raw[22:])
+    binary.LittleEndian.PutUint32(raw[22:], 0)
+    if crc(raw) != stored {
+      t.Errorf("page %d has a wrong checksum", sequence)
+    }
+    if p.Sequence != sequence {
+      t.Errorf("page %d has sequence number %d", sequence, p.Sequence)
+    }
+    if int(sequence) >= headerPages {
+      data = append(data, p.Data...)
+    }
+    offset += int64(p.Size())
+  }
+  return data
+}
+
+func TestPaginate(t *testing.T) {
+  tests := []struct {
+    name      string
+    sizes     []int
+    pages     int
+    // lacing values of the last page
+    last      []byte
+    // the last page continues a packet
+    continued bool
+  }{
+    {"small", []int{10, 20}, 1, []byte{10, 20}, false},
+    {"multiple of 255", []int{510}, 1, []byte{255, 255, 0}, false},
+    {"spanning pages", []int{255 * 255 + 10}, 2, []byte{10}, true},
+    {"behind a full page", []int{255 * 254, 5}, 2, []byte{5}, false},
+  }
+  for _, tt := range tests {
+    var packets [][]byte
+    for _, n := range tt.sizes {
+      packets = append(packets, make([]byte, n))
+    }
+    pages := Paginate(packets, serial, 0, 0)
+    if len(pages) != tt.pages {
+      t.Errorf("%s: %d pages, want %d", tt.name, len(pages), tt.pages)
+      continue
+    }
+    last := pages[len(pages) - 1]
+    if !bytes.Equal(last.Segments, tt.last) {
+      t.Errorf("%s: last lacing values %v, want %v", tt.name, last.Segments, tt.last)
+    }
+    if (last.Flags & FlagContinued != 0) != tt.continued {
+      t.Errorf("%s: last page flags %d", tt.name, last.Flags)
+    }
+    for _, p := range pages {
+      if len(p.Segments) > 255 {
+        t.Errorf("%s: page of %d lacing values", tt.name, len(p.Segments))
+      }
+    }
+  }
+}
+
+func TestWriteFileRepaginates(t *testing.T) {
+  audio := [][]byte{[]byte("first audio packet"), []byte("second audio packet")}
+  tests := []struct {
+    name        string
+    title       int
+    // pages of the headers after writing
+    headerPages int
+  }{
+    {"same pages", 100, 2},
+    {"more pages", 100000, 3},
+    {"fewer pages", 10, 2},
+  }
+
+  path := filepath.Join(t.TempDir(), "a.opus")
+  if err := ioutil.WriteFile(path, stream(audio), 0644); err != nil {
+    t.Fatal(err)
+  }
+  for _, tt := range tests {
+    f, err := ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    c, err := f.Comment()
+    if err != nil {
+      t.Fatal(err)
+    }
+    c.Set("TITLE", strings.Repeat("x", tt.title))
+    f.SetComment(c)
+    if err := WriteFile(path, f); err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+
+    b, err := ioutil.ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    data := checkPages(t, b, tt.headerPages)
+    if !bytes.Equal(data, bytes.Join(audio, nil)) {
+      t.Errorf("%s: audio data changed", tt.name)
+    }
+    read, err := ReadFile(path)
+    if err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+    if len(read.headerPages) + 1 != tt.headerPages {
+      t.Errorf("%s: %d header pages, want %d", tt.name, len(read.headerPages) + 1,
+        tt.headerPages)
+    }
+    if read.Samples() != 2 * 960 - 0x138 {
+      t.Errorf("%s: %d samples", tt.name, read.Samples())
+    }
+    c, err = read.Comment()
+    if err != nil || c.First("TITLE") != strings.Repeat("x", tt.title) {
+      t.Errorf("%s: comment not read back: %v", tt.name, err)
+    }
+  }
+}
@@ -0,0 +1,184 @@
+package ogg
+
+import (
+  "encoding/binary"
+  "errors"
+  "io"
+)
+
+
+
+// page header flags
+const (
+  FlagContinued = 0x01
+  FlagFirst     = 0x02
+  FlagLast      = 0x04
+)
+
+const pageHeaderSize = 27
+
+// granule position of pages on which no packet ends
+const NoGranule = -1
+
+var ErrNotOgg = errors.New("not an ogg file")
+
+type Page struct {
+  // offset of the page in the file
+  Offset   int64
+  Flags    byte
+  Granule  int64
+  Serial   uint32
+  Sequence uint32
+  // lacing values
+  Segments []byte
+  // nil unless the page was read with its data
+  Data []byte
+}
+
+var crcTable = func() [256]uint32 {
+  var table [256]uint32
+  for i := range table {
+    r := uint32(i) << 24
+    for j := 0; j < 8; j++ {
+      if r & 0x80000000 != 0 {
+        r = r << 1 ^ 0x04c11db7
+      } else {
+        r <<= 1
+      }
+    }
+    table[i] = r
+  }
+  return table
+}()
+
+func crc(b []byte) uint32 {
+  var c uint32
+  for _, v := range b {
+    c = c << 8 ^ crcTable[byte(c >> 24) ^ v]
+  }
+  return c
+}
+
+// DataSize returns the size of the page data given by the lacing values
+func (p *Page) DataSize() int {
+  n := 0
+  for _, s := range p.Segments {
+    n += int(s)
+  }
+  return n
+}
+
+// Size returns the size of the page including its header
+func (p *Page) Size() int {
+  return pageHeaderSize + len(p.Segments) + p.DataSize()
+}
+
+// ReadPage reads the page at offset of r, its data only if withData is set
+func ReadPage(r io.ReaderAt, offset int64, withData bool) (*Page, error) {
+  header := make([]byte, pageHeaderSize)
+  if _, err := r.ReadAt(header, offset); err != nil {
+    if err == io.EOF {
+      return nil, io.ErrUnexpectedEOF
+    }
+    return nil, err
+  }
+  if string(header[0:4]) != "OggS" || header[4] != 0 {
+    return nil, ErrNotOgg
+  }
+  p := &Page{
+    Offset:   offset,
+    Flags:    header[5],
+    Granule:  int64(binary.LittleEndian.Uint64(header[6:])),
+    Serial:   binary.LittleEndian.Uint32(header[14:]),
+    Sequence: binary.LittleEndian.Uint32(header[18:]),
+    Segments: make([]byte, header[26]),
+  }
+  if _, err := r.ReadAt(p.Segments, offset + pageHeaderSize); err != nil {
+    return nil, io.ErrUnexpectedEOF
+  }
+  if withData {
+    p.Data = make([]byte, p.DataSize())
+    _, err := r.ReadAt(p.Data, offset + pageHeaderSize + int64(len(p.Segments)))
+    if err != nil {
+      return nil, io.ErrUnexpectedEOF
+    }
+  }
+  return p, nil
+}
+
+// Encode encodes the page with a freshly computed checksum
+func (p *Page) Encode() []byte {
+  b := make([]byte, pageHeaderSize, p.Size())
+  copy(b, "OggS")
+  b[5] = p.Flags
+  binary.LittleEndian.PutUint64(b[6:], uint64(p.Granule))
+  binary.LittleEndian.PutUint32(b[14:], p.Serial)
+  binary.LittleEndian.PutUint32(b[18:], p.Sequence)
+  b[26] = byte(len(p.Segments))
+  b = append(b, p.Segments...)
+  b = append(b, p.Data...)
+  binary.LittleEndian.PutUint32(b[22:], crc(b))
+  return b
+}
+
+// packets splits the data of the page at the lacing values, the last
+// packet is incomplete if the last lacing value is 255
+func (p *Page) packets() ([][]byte, bool) {
+  var packets [][]byte
+  pos, start := 0, 0
+  complete := true
+  for i, s := range p.Segments {
+    pos += int(s)
+    if s < 255 {
+      packets = append(packets, p.Data[start:pos])
+      start = pos
+    } else if i == len(p.Segments) - 1 {
+      packets = append(packets, p.Data[start:pos])
+      complete = false
+    }
+  }
+  return packets, complete
+}
+
+// Paginate puts packets into pages of the logical stream serial numbered
+// from sequence on, the last page ends with the last packet
+func Paginate(packets [][]byte, serial uint32, sequence uint32, granule int64) []*Page {
+  var pages []*Page
+  page := &Page{Serial: serial, Sequence: sequence, Granule: NoGranule}
+  flush := func(continued bool) {
+    pages = append(pages, page)
+    sequence++
+    page = &Page{Serial: serial, Sequence: sequence, Granule: NoGranule}
+    if continued {
+      page.Flags = FlagContinued
+    }
+  }
+
+  for _, packet := range packets {
+    if len(page.Segments) == 255 {
+      flush(false)
+    }
+    rest := packet
+    for {
+      if len(page.Segments) == 255 {
+        flush(true)
+      }
+      n := len(rest)
+      if n >= 255 {
+        n = 255
+      }
+      page.Segments = append(page.Segments, byte(n))
+      page.Data = append(page.Data, rest[:n]...)
+      rest = rest[n:]
+      // a packet of a multiple of 255 bytes ends with a zero lacing value
+      if n < 255 {
+        page.Granule = granule
+        break
+      }
+    }
+  }
+  if len(page.Segments) > 0 {
+    pages = append(pages, page)
+  }
+  return pages
+}
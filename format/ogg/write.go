@@ -0,0 +1,76 @@
+package ogg
+
+import (
+  "io"
+  "os"
+)
+
+import (
+  rewrite "github.com/elias-boemeke/taggo/format/rewrite"
+)
+
+
+
+// WriteFile writes the header packets after the identification to the ogg
+// file at path; they are paginated anew and if the number of pages changes,
+// the sequence numbers and checksums of all following pages of the stream
+// are updated, the audio packets themselves are copied unchanged
+func WriteFile(path string, f *File) error {
+  first := f.headerPages[0]
+  start := first.Offset
+  end := f.HeaderEnd()
+
+  // header pages end with granule position zero
+  pages := Paginate(f.Headers[1:], f.Serial, first.Sequence, 0)
+  var data []byte
+  for _, p := range pages {
+    data = append(data, p.Encode()...)
+  }
+  delta := int64(len(pages) - len(f.headerPages))
+
+  var err error
+  if delta == 0 {
+    err = rewrite.Replace(path, start, end, data)
+  } else {
+    err = rewrite.Rewrite(path, func(dst io.Writer, src *os.File) error {
+      if _, err := io.Copy(dst, io.NewSectionReader(src, 0, start)); err != nil {
+        return err
+      }
+      if _, err := dst.Write(data); err != nil {
+        return err
+      }
+      for _, p := range f.Pages {
+        if p.Offset < end {
+          continue
+        }
+        if p.Serial != f.Serial {
+          section := io.NewSectionReader(src, p.Offset, int64(p.Size()))
+          if _, err := io.Copy(dst, section); err != nil {
+            return err
+          }
+          continue
+        }
+        page, err := ReadPage(src, p.Offset, true)
+        if err != nil {
+          return err
+        }
+        page.Sequence = uint32(int64(page.Sequence) + delta)
+        if _, err := dst.Write(page.Encode()); err != nil {
+          return err
+        }
+      }
+      return nil
+    })
+  }
+  if err != nil {
+    return err
+  }
+
+  // read back to pick up the new page offsets
+  n, err := ReadFile(path)
+  if err != nil {
+    return err
+  }
+  *f = *n
+  return nil
+}
@@ -0,0 +1,92 @@
+package rewrite
+
+import (
+  "bufio"
+  "io"
+  "os"
+  "path/filepath"
+)
+
+
+
+// Replace replaces the bytes [start, end) of the file at path with data;
+// if the length does not change or the region is at the end of the file
+// the file is modified in place, otherwise it is copied to a temporary
+// file next to it which then replaces the original
+func Replace(path string, start int64, end int64, data []byte) error {
+  file, err := os.OpenFile(path, os.O_RDWR, 0)
+  if err != nil {
+    return err
+  }
+  info, err := file.Stat()
+  if err != nil {
+    file.Close()
+    return err
+  }
+
+  if int64(len(data)) == end - start || end == info.Size() {
+    _, err = file.WriteAt(data, start)
+    if err == nil && end == info.Size() {
+      err = file.Truncate(start + int64(len(data)))
+    }
+    if cerr := file.Close(); err == nil {
+      err = cerr
+    }
+    return err
+  }
+  file.Close()
+
+  return Rewrite(path, func(dst io.Writer, src *os.File) error {
+    return copyRegions(dst, src, start, end, data, info.Size())
+  })
+}
+
+// Rewrite writes a new version of the file at path with write, which
+// reads the original from src; the result is written to a temporary file
+// next to the original which replaces it when write succeeded
+func Rewrite(path string, write func(dst io.Writer, src *os.File) error) error {
+  file, err := os.Open(path)
+  if err != nil {
+    return err
+  }
+  defer file.Close()
+  info, err := file.Stat()
+  if err != nil {
+    return err
+  }
+
+  tmp, err := os.CreateTemp(filepath.Dir(path), "." + filepath.Base(path) + ".taggo-*")
+  if err != nil {
+    return err
+  }
+  // no-op after a successful rename
+  defer os.Remove(tmp.Name())
+
+  buffered := bufio.NewWriter(tmp)
+  err = write(buffered, file)
+  if err == nil {
+    err = buffered.Flush()
+  }
+  if err == nil {
+    err = tmp.Chmod(info.Mode().Perm())
+  }
+  if cerr := tmp.Close(); err == nil {
+    err = cerr
+  }
+  if err != nil {
+    return err
+  }
+  return os.Rename(tmp.Name(), path)
+}
+
+func copyRegions(dst io.Writer, src io.ReaderAt, start int64, end int64,
+    data []byte, size int64) error {
+  if _, err := io.Copy(dst, io.NewSectionReader(src, 0, start)); err != nil {
+    return err
+  }
+  if _, err := dst.Write(data); err != nil {
+    return err
+  }
+  _, err := io.Copy(dst, io.NewSectionReader(src, end, size - end))
+  return err
+}
@@ -0,0 +1,94 @@
+package riff
+
+import (
+  "encoding/binary"
+  "errors"
+  "math"
+)
+
+
+
+// text chunks of aiff files
+const (
+  AIFFName       = "NAME"
+  AIFFAuthor     = "AUTH"
+  AIFFAnnotation = "ANNO"
+  AIFFCopyright  = "(c) "
+)
+
+// the COMM chunk of an aiff file
+type Common struct {
+  Channels      int
+  Frames        int64
+  BitsPerSample int
+  Samplerate    float64
+  // compression type and name of AIFC files, NONE for aiff
+  Compression     string
+  CompressionName string
+}
+
+func (f *File) Common() (Common, error) {
+  c := f.Chunk("COMM")
+  if c == nil || len(c.Data) < 18 {
+    return Common{}, errors.New("aiff file has no valid COMM chunk")
+  }
+  b := c.Data
+  common := Common{
+    Channels:      int(binary.BigEndian.Uint16(b[0:])),
+    Frames:        int64(binary.BigEndian.Uint32(b[2:])),
+    BitsPerSample: int(binary.BigEndian.Uint16(b[6:])),
+    Samplerate:    extended(b[8:18]),
+    Compression:   "NONE",
+  }
+  if f.Type == "AIFC" && len(b) >= 22 {
+    common.Compression = string(b[18:22])
+    // pascal string
+    if len(b) > 22 && len(b) >= 23 + int(b[22]) {
+      common.CompressionName = decodeText(b[23:23 + int(b[22])])
+    }
+  }
+  return common, nil
+}
+
+// extended decodes an 80 bit IEEE 754 extended precision number
+func extended(b []byte) float64 {
+  exponent := int(binary.BigEndian.Uint16(b) & 0x7fff)
+  mantissa := binary.BigEndian.Uint64(b[2:])
+  if exponent == 0 && mantissa == 0 {
+    return 0
+  }
+  x := float64(mantissa) * math.Pow(2, float64(exponent - 16383 - 63))
+  if b[0] & 0x80 != 0 {
+    x = -x
+  }
+  return x
+}
+
+// Text returns the value of the first text chunk with the given id
+func (f *File) Text(id string) string {
+  if c := f.Chunk(id); c != nil {
+    return decodeText(c.Data)
+  }
+  return ""
+}
+
+// SetText replaces the text chunks with the given id,
+// an empty value removes them
+func (f *File) SetText(id string, value string) {
+  if value == "" {
+    f.RemoveChunk(id)
+    return
+  }
+  first := true
+  f.RemoveChunks(func(c *Chunk) bool {
+    if c.ID != id {
+      return false
+    }
+    if first {
+      first = false
+      return false
+    }
+    return true
+  })
+  f.SetChunk(id, []byte(value))
+}
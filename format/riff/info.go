@@ -0,0 +1,143 @@
+package riff
+
+import (
+  "bytes"
+  "encoding/binary"
+  "errors"
+  "unicode/utf8"
+)
+
+
+
+// ids of the INFO list used for the fields
+const (
+  InfoTitle      = "INAM"
+  InfoArtist     = "IART"
+  InfoAlbum      = "IPRD"
+  InfoComment    = "ICMT"
+  InfoGenre      = "IGNR"
+  InfoDate       = "ICRD"
+  InfoTrack      = "ITRK"
+  InfoCopyright  = "ICOP"
+  InfoTechnician = "ITCH"
+)
+
+type InfoField struct {
+  ID    string
+  Value string
+}
+
+// the LIST chunk of type INFO
+type Info struct {
+  Fields []InfoField
+}
+
+// DecodeInfo decodes the data of a LIST chunk of type INFO
+func DecodeInfo(b []byte) (*Info, error) {
+  if len(b) < 4 || string(b[0:4]) != "INFO" {
+    return nil, errors.New("list chunk is no INFO list")
+  }
+  info := &Info{}
+  for pos := 4; pos + 8 <= len(b); {
+    id := string(b[pos:pos + 4])
+    size := int(binary.LittleEndian.Uint32(b[pos + 4:]))
+    pos += 8
+    if pos + size > len(b) {
+      return nil, errors.New("INFO list truncated")
+    }
+    info.Fields = append(info.Fields, InfoField{id, decodeText(b[pos:pos + size])})
+    pos += size + size & 1
+  }
+  return info, nil
+}
+
+func (info *Info) Encode() []byte {
+  b := []byte("INFO")
+  for _, f := range info.Fields {
+    // values are terminated by a zero byte
+    value := append([]byte(f.Value), 0)
+    header := make([]byte, 8)
+    copy(header, f.ID)
+    binary.LittleEndian.PutUint32(header[4:], uint32(len(value)))
+    b = append(append(b, header...), value...)
+    if len(value) & 1 == 1 {
+      b = append(b, 0)
+    }
+  }
+  return b
+}
+
+// Get returns the value of the first field with the given id
+func (info *Info) Get(id string) string {
+  for _, f := range info.Fields {
+    if f.ID == id {
+      return f.Value
+    }
+  }
+  return ""
+}
+
+// Set replaces the fields with the given id, an empty value removes them
+func (info *Info) Set(id string, value string) {
+  var fields []InfoField
+  for _, f := range info.Fields {
+    if f.ID != id {
+      fields = append(fields, f)
+    } else if value != "" {
+      fields = append(fields, InfoField{id, value})
+      value = ""
+    }
+  }
+  if value != "" {
+    fields = append(fields, InfoField{id, value})
+  }
+  info.Fields = fields
+}
+
+// decodeText decodes a zero terminated string, which is UTF-8 if
+// valid and ISO-8859-1 otherwise
+func decodeText(b []byte) string {
+  if i := bytes.IndexByte(b, 0); i >= 0 {
+    b = b[:i]
+  }
+  if utf8.Valid(b) {
+    return string(b)
+  }
+  runes := make([]rune, len(b))
+  for i, c := range b {
+    runes[i] = rune(c)
+  }
+  return string(runes)
+}
+
+// isInfo tells if a chunk is a LIST of type INFO
+func isInfo(c *Chunk) bool {
+  return c.ID == "LIST" && len(c.Data) >= 4 && string(c.Data[0:4]) == "INFO"
+}
+
+// Info returns the INFO list of the file, nil if it has none
+func (f *File) Info() (*Info, error) {
+  for _, c := range f.Chunks {
+    if isInfo(c) {
+      return DecodeInfo(c.Data)
+    }
+  }
+  return nil, nil
+}
+
+// SetInfo replaces the INFO list, it is removed if info has no fields
+func (f *File) SetInfo(info *Info) {
+  if info == nil || len(info.Fields) == 0 {
+    f.RemoveChunks(isInfo)
+    return
+  }
+  data := info.Encode()
+  for _, c := range f.Chunks {
+    if isInfo(c) {
+      c.Data = data
+      c.Size = int64(len(data))
+      return
+    }
+  }
+  f.Chunks = append(f.Chunks, &Chunk{ID: "LIST", Offset: -1, Size: int64(len(data)), Data: data})
+}
@@ -0,0 +1,179 @@
+package riff
+
+import (
+  "bufio"
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "io"
+  "math"
+  "os"
+)
+
+
+
+// the layout of the samples in the audio chunk
+type PCM struct {
+  Samplerate int
+  Channels   int
+  // bytes taken by a sample, smaller samples are left-justified
+  Width int
+  Float bool
+  Order binary.ByteOrder
+  // 8 bit samples of wave files are unsigned
+  Unsigned bool
+  // the audio chunk starts with offset and block size (SSND of aiff)
+  ssnd bool
+}
+
+const (
+  formatPCM   = 0x0001
+  formatFloat = 0x0003
+)
+
+// PCM describes the samples of a wave or aiff file,
+// compressed formats give an error
+func (f *File) PCM() (PCM, error) {
+  if f.Form == "RIFF" {
+    format, err := f.WaveFormat()
+    if err != nil {
+      return PCM{}, err
+    }
+    tag := format.Tag
+    if tag == FormatExtensible {
+      tag = format.SubFormat
+    }
+    p := PCM{Samplerate: format.Samplerate, Channels: format.Channels, Order: binary.LittleEndian}
+    if format.Channels > 0 {
+      p.Width = format.BlockAlign / format.Channels
+    }
+    p.Float = tag == formatFloat
+    p.Unsigned = p.Width == 1
+    if (tag != formatPCM && !p.Float) || (p.Float && p.Width != 4 && p.Width != 8) {
+      return PCM{}, errors.New(fmt.Sprintf("wave format %s is not pcm", format.Name()))
+    }
+    return p, p.check()
+  }
+
+  common, err := f.Common()
+  if err != nil {
+    return PCM{}, err
+  }
+  p := PCM{
+    Samplerate: int(common.Samplerate),
+    Channels:   common.Channels,
+    Width:      (common.BitsPerSample + 7) / 8,
+    Order:      binary.BigEndian,
+    ssnd:       true,
+  }
+  switch common.Compression {
+  case "NONE", "twos":
+  case "sowt":
+    p.Order = binary.LittleEndian
+  case "fl32", "FL32":
+    p.Float, p.Width = true, 4
+  case "fl64", "FL64":
+    p.Float, p.Width = true, 8
+  default:
+    return PCM{}, errors.New(fmt.Sprintf("aiff compression '%s' is not supported", common.Compression))
+  }
+  return p, p.check()
+}
+
+func (p PCM) check() error {
+  if p.Channels <= 0 || p.Samplerate <= 0 || p.Width <= 0 || p.Width > 8 {
+    return errors.New(fmt.Sprintf("unsupported pcm layout: %d channel(s), %d Hz, %d byte samples",
+      p.Channels, p.Samplerate, p.Width))
+  }
+  return nil
+}
+
+// DecodeFile decodes the samples of the wave or aiff file at path and calls
+// block with up to a second of samples by channel, scaled to [-1, 1]
+func DecodeFile(path string, block func(samples [][]float64) error) (PCM, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return PCM{}, err
+  }
+  defer file.Close()
+  info, err := file.Stat()
+  if err != nil {
+    return PCM{}, err
+  }
+  f, err := Read(file, info.Size())
+  if err != nil {
+    return PCM{}, err
+  }
+  p, err := f.PCM()
+  if err != nil {
+    return p, err
+  }
+  audio := f.Audio()
+  if audio == nil {
+    return p, errors.New("file has no audio chunk")
+  }
+
+  start, size := audio.Offset + 8, audio.Size
+  if p.ssnd {
+    // the offset counts from behind offset and block size
+    header := make([]byte, 8)
+    if _, err := file.ReadAt(header, start); err != nil {
+      return p, err
+    }
+    skip := 8 + int64(binary.BigEndian.Uint32(header))
+    start, size = start + skip, size - skip
+    if size < 0 {
+      return p, errors.New("SSND offset exceeds the chunk")
+    }
+  }
+  r := bufio.NewReaderSize(io.NewSectionReader(file, start, size), 1 << 16)
+
+  frame := make([]byte, p.Width * p.Channels)
+  samples := make([][]float64, p.Channels)
+  for {
+    for c := range samples {
+      samples[c] = samples[c][:0]
+    }
+    for len(samples[0]) < p.Samplerate {
+      if _, err := io.ReadFull(r, frame); err == io.EOF || err == io.ErrUnexpectedEOF {
+        break
+      } else if err != nil {
+        return p, err
+      }
+      for c := range samples {
+        samples[c] = append(samples[c], p.sample(frame[c * p.Width:(c + 1) * p.Width]))
+      }
+    }
+    if len(samples[0]) == 0 {
+      return p, nil
+    }
+    if err := block(samples); err != nil {
+      return p, err
+    }
+  }
+}
+
+// sample decodes a sample of Width bytes
+func (p PCM) sample(b []byte) float64 {
+  if p.Float {
+    if p.Width == 4 {
+      return float64(math.Float32frombits(p.Order.Uint32(b)))
+    }
+    return math.Float64frombits(p.Order.Uint64(b))
+  }
+  var v uint64
+  for i := range b {
+    if p.Order == binary.BigEndian {
+      v = v << 8 | uint64(b[i])
+    } else {
+      v = v << 8 | uint64(b[len(b) - 1 - i])
+    }
+  }
+  bits := uint(8 * p.Width)
+  if p.Unsigned {
+    return (float64(v) - float64(uint64(1) << (bits - 1))) / float64(uint64(1) << (bits - 1))
+  }
+  // sign extension of the two's complement
+  x := int64(v << (64 - bits)) >> (64 - bits)
+  return float64(x) / float64(uint64(1) << (bits - 1))
+}
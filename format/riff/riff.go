@@ -0,0 +1,243 @@
+package riff
+
+import (
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+)
+
+import (
+  rewrite "github.com/elias-boemeke/taggo/format/rewrite"
+)
+
+
+
+var ErrNotRIFF = errors.New("not a riff or aiff file")
+
+type Chunk struct {
+  ID string
+  // position of the chunk header in the file when it was read
+  Offset int64
+  // size of the data without header and pad byte
+  Size int64
+  // nil for the audio chunk, which is not loaded
+  Data []byte
+}
+
+type File struct {
+  // RIFF (little endian) or FORM (big endian)
+  Form string
+  // WAVE, AIFF or AIFC
+  Type   string
+  Order  binary.ByteOrder
+  Chunks []*Chunk
+  Size   int64
+}
+
+// ids of the chunks holding the samples
+func isAudio(id string) bool {
+  return id == "data" || id == "SSND"
+}
+
+// padded returns the size of a chunk of size bytes including its
+// pad byte, chunks are aligned to even offsets
+func padded(size int64) int64 {
+  return size + size & 1
+}
+
+func ReadFile(path string) (*File, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+  info, err := file.Stat()
+  if err != nil {
+    return nil, err
+  }
+  return Read(file, info.Size())
+}
+
+func Read(r io.ReaderAt, size int64) (*File, error) {
+  header := make([]byte, 12)
+  if _, err := r.ReadAt(header, 0); err != nil {
+    return nil, ErrNotRIFF
+  }
+  f := &File{Form: string(header[0:4]), Type: string(header[8:12]), Size: size}
+  switch {
+  case f.Form == "RIFF" && f.Type == "WAVE":
+    f.Order = binary.LittleEndian
+  case f.Form == "FORM" && (f.Type == "AIFF" || f.Type == "AIFC"):
+    f.Order = binary.BigEndian
+  default:
+    return nil, ErrNotRIFF
+  }
+
+  // some writers leave the form size wrong, the file size is used instead
+  end := 8 + int64(f.Order.Uint32(header[4:]))
+  if end > size || end < 12 {
+    end = size
+  }
+  for offset := int64(12); offset + 8 <= end; {
+    if _, err := r.ReadAt(header[:8], offset); err != nil {
+      return nil, err
+    }
+    c := &Chunk{ID: string(header[0:4]), Offset: offset, Size: int64(f.Order.Uint32(header[4:]))}
+    if offset + 8 + c.Size > size {
+      if !isAudio(c.ID) {
+        return nil, errors.New(fmt.Sprintf("chunk '%s' at offset %d exceeds the file",
+          c.ID, offset))
+      }
+      // truncated recordings are still read
+      c.Size = size - offset - 8
+    }
+    if !isAudio(c.ID) {
+      c.Data = make([]byte, c.Size)
+      if _, err := r.ReadAt(c.Data, offset + 8); err != nil {
+        return nil, err
+      }
+    }
+    f.Chunks = append(f.Chunks, c)
+    offset += 8 + padded(c.Size)
+  }
+  return f, nil
+}
+
+// Chunk returns the first chunk with the given id or nil
+func (f *File) Chunk(id string) *Chunk {
+  for _, c := range f.Chunks {
+    if c.ID == id {
+      return c
+    }
+  }
+  return nil
+}
+
+// Audio returns the chunk holding the samples or nil
+func (f *File) Audio() *Chunk {
+  for _, c := range f.Chunks {
+    if isAudio(c.ID) {
+      return c
+    }
+  }
+  return nil
+}
+
+// SetChunk replaces the data of the first chunk with the given id,
+// the chunk is appended if the file has none
+func (f *File) SetChunk(id string, data []byte) {
+  if c := f.Chunk(id); c != nil {
+    c.Data = data
+    c.Size = int64(len(data))
+    return
+  }
+  f.Chunks = append(f.Chunks, &Chunk{ID: id, Offset: -1, Size: int64(len(data)), Data: data})
+}
+
+// RemoveChunks removes the chunks for which remove returns true
+func (f *File) RemoveChunks(remove func(*Chunk) bool) {
+  kept := f.Chunks[:0]
+  for _, c := range f.Chunks {
+    if isAudio(c.ID) || !remove(c) {
+      kept = append(kept, c)
+    }
+  }
+  f.Chunks = kept
+}
+
+// RemoveChunk removes all chunks with the given id
+func (f *File) RemoveChunk(id string) {
+  f.RemoveChunks(func(c *Chunk) bool {
+    return c.ID == id
+  })
+}
+
+func (f *File) encodeChunk(c *Chunk) []byte {
+  b := make([]byte, 8, 8 + padded(int64(len(c.Data))))
+  copy(b, c.ID)
+  f.Order.PutUint32(b[4:], uint32(len(c.Data)))
+  b = append(b, c.Data...)
+  if len(c.Data) & 1 == 1 {
+    b = append(b, 0)
+  }
+  return b
+}
+
+// WriteFile writes the chunks of f to the file at path; the audio chunk is
+// copied from the file, which is only rewritten if the audio has to move
+func WriteFile(path string, f *File) error {
+  var before, after []byte
+  audio := f.Audio()
+  seen := false
+  for _, c := range f.Chunks {
+    switch {
+    case c == audio:
+      seen = true
+    case seen:
+      after = append(after, f.encodeChunk(c)...)
+    default:
+      before = append(before, f.encodeChunk(c)...)
+    }
+  }
+
+  var audioSize int64
+  if audio != nil {
+    audioSize = 8 + padded(audio.Size)
+  }
+  total := 12 + int64(len(before)) + audioSize + int64(len(after))
+  if total - 8 > 0xffffffff {
+    return errors.New("riff file exceeds 4 GB")
+  }
+  header := make([]byte, 12)
+  copy(header, f.Form)
+  f.Order.PutUint32(header[4:], uint32(total - 8))
+  copy(header[8:], f.Type)
+  before = append(header, before...)
+
+  var err error
+  switch {
+  case audio == nil:
+    err = rewrite.Replace(path, 0, f.Size, append(before, after...))
+  case int64(len(before)) == audio.Offset:
+    // the audio stays in place, only the surrounding chunks are written
+    tail := audio.Offset + audioSize
+    if tail > f.Size {
+      // truncated audio chunk without its pad byte
+      after = append([]byte{0}, after...)
+      tail = f.Size
+    }
+    err = rewrite.Replace(path, tail, f.Size, after)
+    if err == nil {
+      err = rewrite.Replace(path, 0, audio.Offset, before)
+    }
+  default:
+    err = rewrite.Rewrite(path, func(dst io.Writer, src *os.File) error {
+      if _, err := dst.Write(before); err != nil {
+        return err
+      }
+      section := io.NewSectionReader(src, audio.Offset, 8 + audio.Size)
+      if _, err := io.Copy(dst, section); err != nil {
+        return err
+      }
+      if audio.Size & 1 == 1 {
+        if _, err := dst.Write([]byte{0}); err != nil {
+          return err
+        }
+      }
+      _, err := dst.Write(after)
+      return err
+    })
+  }
+  if err != nil {
+    return err
+  }
+  // read back to pick up the new offsets
+  n, err := ReadFile(path)
+  if err != nil {
+    return err
+  }
+  *f = *n
+  return nil
+}
@@ -0,0 +1,92 @@
+package riff
+
+import (
+  "bytes"
+  "encoding/binary"
+  "io/ioutil"
+  "path/filepath"
+  "testing"
+)
+
+
+
+// form returns a file of the given chunks, odd chunks are padded
+func form(order binary.ByteOrder, id string, typ string, chunks ...[]byte) []byte {
+  b := append([]byte(id + "    "), typ...)
+  for i := 0; i + 1 < len(chunks); i += 2 {
+    header := append([]byte(nil), chunks[i]...)
+    header = append(header, 0, 0, 0, 0)
+    order.PutUint32(header[4:], uint32(len(chunks[i + 1])))
+    b = append(b, header...)
+    b = append(b, chunks[i + 1]...)
+    if len(chunks[i + 1]) & 1 == 1 {
+      b = append(b, 0)
+    }
+  }
+  order.PutUint32(b[4:], uint32(len(b) - 8))
+  return b
+}
+
+func TestWriteFileAlignment(t *testing.T) {
+  samples := []byte("odd sample data")
+  tests := []struct {
+    name  string
+    order binary.ByteOrder
+    file  []byte
+    // chunk set and read back
+    id    string
+    data  []byte
+  }{
+    {"wave in place", binary.LittleEndian, form(binary.LittleEndian, "RIFF", "WAVE",
+      []byte("fmt "), make([]byte, 16), []byte("data"), samples, []byte("LIST"), []byte("INFOx")),
+      "LIST", []byte("INFOodd")},
+    {"wave grows", binary.LittleEndian, form(binary.LittleEndian, "RIFF", "WAVE",
+      []byte("fmt "), make([]byte, 16), []byte("LIST"), []byte("INFO"), []byte("data"), samples),
+      "LIST", []byte("INFO grown to an odd size")},
+    {"aiff", binary.BigEndian, form(binary.BigEndian, "FORM", "AIFF",
+      []byte("COMM"), make([]byte, 18), []byte("SSND"), samples),
+      "NAME", []byte("odd")},
+  }
+  for _, tt := range tests {
+    path := filepath.Join(t.TempDir(), "a")
+    if err := ioutil.WriteFile(path, tt.file, 0644); err != nil {
+      t.Fatal(err)
+    }
+    f, err := ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    f.SetChunk(tt.id, tt.data)
+    if err := WriteFile(path, f); err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+
+    b, err := ioutil.ReadFile(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if size := int64(tt.order.Uint32(b[4:])); size != int64(len(b)) - 8 {
+      t.Errorf("%s: form size %d in %d bytes", tt.name, size, len(b))
+    }
+    read, err := ReadFile(path)
+    if err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+    for _, c := range read.Chunks {
+      if c.Offset & 1 == 1 {
+        t.Errorf("%s: chunk '%s' at odd offset %d", tt.name, c.ID, c.Offset)
+      }
+    }
+    if len(b) & 1 == 1 {
+      t.Errorf("%s: file of odd size %d", tt.name, len(b))
+    }
+    audio := read.Audio()
+    if audio == nil || audio.Size != int64(len(samples)) ||
+        !bytes.Equal(b[audio.Offset + 8:audio.Offset + 8 + audio.Size], samples) {
+      t.Errorf("%s: audio data changed", tt.name)
+    }
+    if c := read.Chunk(tt.id); c == nil || !bytes.Equal(c.Data, tt.data) {
+      t.Errorf("%s: chunk '%s' not read back", tt.name, tt.id)
+    }
+  }
+}
@@ -0,0 +1,189 @@
+package riff
+
+import (
+  "bytes"
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "strings"
+)
+
+import (
+  id3v2 "github.com/elias-boemeke/taggo/format/id3v2"
+)
+
+
+
+// the fmt chunk of a wave file
+type Format struct {
+  Tag           uint16
+  Channels      int
+  Samplerate    int
+  ByteRate      int
+  BlockAlign    int
+  BitsPerSample int
+  // format tag of the sub format of WAVE_FORMAT_EXTENSIBLE
+  SubFormat uint16
+}
+
+const FormatExtensible = 0xfffe
+
+var formatNames = map[uint16]string{
+  0x0001: "PCM",
+  0x0002: "Microsoft ADPCM",
+  0x0003: "IEEE float",
+  0x0006: "A-law",
+  0x0007: "µ-law",
+  0x0011: "IMA ADPCM",
+  0x0050: "MPEG",
+  0x0055: "MPEG Layer 3",
+  0xfffe: "extensible",
+}
+
+// Name describes the format tag, for extensible formats the sub format
+func (f Format) Name() string {
+  tag := f.Tag
+  if tag == FormatExtensible && f.SubFormat != 0 {
+    tag = f.SubFormat
+  }
+  if name, ok := formatNames[tag]; ok {
+    return fmt.Sprintf("%s (0x%04x)", name, tag)
+  }
+  return fmt.Sprintf("0x%04x", tag)
+}
+
+// WaveFormat decodes the fmt chunk
+func (f *File) WaveFormat() (Format, error) {
+  c := f.Chunk("fmt ")
+  if c == nil || len(c.Data) < 16 {
+    return Format{}, errors.New("wave file has no valid fmt chunk")
+  }
+  b := c.Data
+  format := Format{
+    Tag:           binary.LittleEndian.Uint16(b[0:]),
+    Channels:      int(binary.LittleEndian.Uint16(b[2:])),
+    Samplerate:    int(binary.LittleEndian.Uint32(b[4:])),
+    ByteRate:      int(binary.LittleEndian.Uint32(b[8:])),
+    BlockAlign:    int(binary.LittleEndian.Uint16(b[12:])),
+    BitsPerSample: int(binary.LittleEndian.Uint16(b[14:])),
+  }
+  // the first two bytes of the sub format guid hold the format tag
+  if format.Tag == FormatExtensible && len(b) >= 26 {
+    format.SubFormat = binary.LittleEndian.Uint16(b[24:])
+  }
+  return format, nil
+}
+
+// the Broadcast Wave Format extension chunk
+type Bext struct {
+  Description         string
+  Originator          string
+  OriginatorReference string
+  // yyyy-mm-dd and hh:mm:ss
+  OriginationDate string
+  OriginationTime string
+  // samples since midnight
+  TimeReference uint64
+  Version       uint16
+  UMID          [64]byte
+  // loudness values of version 2 and the reserved bytes
+  Reserved      [190]byte
+  CodingHistory string
+}
+
+// size of the fixed part of the bext chunk
+const bextSize = 602
+
+func DecodeBext(b []byte) (*Bext, error) {
+  if len(b) < bextSize {
+    return nil, errors.New("bext chunk truncated")
+  }
+  bext := &Bext{
+    Description:         decodeText(b[0:256]),
+    Originator:          decodeText(b[256:288]),
+    OriginatorReference: decodeText(b[288:320]),
+    OriginationDate:     decodeText(b[320:330]),
+    OriginationTime:     decodeText(b[330:338]),
+    TimeReference:       binary.LittleEndian.Uint64(b[338:]),
+    Version:             binary.LittleEndian.Uint16(b[346:]),
+    CodingHistory:       strings.TrimRight(decodeText(b[bextSize:]), "\r\n"),
+  }
+  copy(bext.UMID[:], b[348:412])
+  copy(bext.Reserved[:], b[412:bextSize])
+  return bext, nil
+}
+
+func (bext *Bext) Encode() []byte {
+  b := make([]byte, bextSize)
+  copy(b[0:256], bext.Description)
+  copy(b[256:288], bext.Originator)
+  copy(b[288:320], bext.OriginatorReference)
+  copy(b[320:330], bext.OriginationDate)
+  copy(b[330:338], bext.OriginationTime)
+  binary.LittleEndian.PutUint64(b[338:], bext.TimeReference)
+  binary.LittleEndian.PutUint16(b[346:], bext.Version)
+  copy(b[348:412], bext.UMID[:])
+  copy(b[412:bextSize], bext.Reserved[:])
+  if bext.CodingHistory != "" {
+    b = append(b, bext.CodingHistory + "\r\n"...)
+  }
+  return b
+}
+
+// Bext returns the bext chunk of the file, nil if it has none
+func (f *File) Bext() (*Bext, error) {
+  c := f.Chunk("bext")
+  if c == nil {
+    return nil, nil
+  }
+  return DecodeBext(c.Data)
+}
+
+func (f *File) SetBext(bext *Bext) {
+  if bext == nil {
+    f.RemoveChunk("bext")
+    return
+  }
+  f.SetChunk("bext", bext.Encode())
+}
+
+// isID3 tells if a chunk holds an id3v2 tag, wave files use "id3 "
+// and sometimes "ID3 ", aiff files "ID3 "
+func isID3(c *Chunk) bool {
+  return c.ID == "id3 " || c.ID == "ID3 "
+}
+
+// ID3 returns the id3v2 tag of the file, nil if it has none
+func (f *File) ID3() (*id3v2.Tag, error) {
+  for _, c := range f.Chunks {
+    if isID3(c) {
+      return id3v2.Read(bytes.NewReader(c.Data))
+    }
+  }
+  return nil, nil
+}
+
+// SetID3 replaces the id3 chunk, it is removed if tag is nil
+func (f *File) SetID3(tag *id3v2.Tag) error {
+  if tag == nil {
+    f.RemoveChunks(isID3)
+    return nil
+  }
+  data, err := tag.Encode(0)
+  if err != nil {
+    return err
+  }
+  for _, c := range f.Chunks {
+    if isID3(c) {
+      c.Data = data
+      c.Size = int64(len(data))
+      return nil
+    }
+  }
+  id := "id3 "
+  if f.Form == "FORM" {
+    id = "ID3 "
+  }
+  f.Chunks = append(f.Chunks, &Chunk{ID: id, Offset: -1, Size: int64(len(data)), Data: data})
+  return nil
+}
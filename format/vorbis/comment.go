@@ -0,0 +1,160 @@
+package vorbis
+
+import (
+  "encoding/binary"
+  "errors"
+  "strings"
+)
+
+
+
+// Comment is a vorbis comment as used by Ogg Vorbis, Opus and FLAC;
+// keys are case-insensitive and may occur more than once
+type Comment struct {
+  Vendor string
+  Fields []Field
+}
+
+type Field struct {
+  Key   string
+  Value string
+}
+
+var errTruncated = errors.New("vorbis comment truncated")
+
+func NewComment(vendor string) *Comment {
+  return &Comment{Vendor: vendor}
+}
+
+// Decode decodes a comment without framing bit or packet header,
+// the number of bytes consumed is returned as well
+func Decode(b []byte) (*Comment, int, error) {
+  pos := 0
+  next := func() (string, error) {
+    if len(b) - pos < 4 {
+      return "", errTruncated
+    }
+    n := int(binary.LittleEndian.Uint32(b[pos:]))
+    pos += 4
+    if n < 0 || len(b) - pos < n {
+      return "", errTruncated
+    }
+    s := string(b[pos:pos + n])
+    pos += n
+    return s, nil
+  }
+
+  vendor, err := next()
+  if err != nil {
+    return nil, 0, err
+  }
+  c := &Comment{Vendor: vendor}
+  if len(b) - pos < 4 {
+    return nil, 0, errTruncated
+  }
+  count := int(binary.LittleEndian.Uint32(b[pos:]))
+  pos += 4
+  for i := 0; i < count; i++ {
+    s, err := next()
+    if err != nil {
+      return nil, 0, err
+    }
+    key, value := s, ""
+    if eq := strings.IndexByte(s, '='); eq >= 0 {
+      key, value = s[:eq], s[eq + 1:]
+    }
+    c.Fields = append(c.Fields, Field{key, value})
+  }
+  return c, pos, nil
+}
+
+// Encode encodes the comment without framing bit or packet header
+func (c *Comment) Encode() []byte {
+  b := make([]byte, 0)
+  put := func(s string) {
+    n := make([]byte, 4)
+    binary.LittleEndian.PutUint32(n, uint32(len(s)))
+    b = append(b, n...)
+    b = append(b, s...)
+  }
+  put(c.Vendor)
+  n := make([]byte, 4)
+  binary.LittleEndian.PutUint32(n, uint32(len(c.Fields)))
+  b = append(b, n...)
+  for _, f := range c.Fields {
+    put(f.Key + "=" + f.Value)
+  }
+  return b
+}
+
+// Get returns all values of key in order
+func (c *Comment) Get(key string) []string {
+  var values []string
+  for _, f := range c.Fields {
+    if strings.EqualFold(f.Key, key) {
+      values = append(values, f.Value)
+    }
+  }
+  return values
+}
+
+// First returns the first value of key or an empty string
+func (c *Comment) First(key string) string {
+  if values := c.Get(key); len(values) > 0 {
+    return values[0]
+  }
+  return ""
+}
+
+// Set replaces all values of key, the new values take the place of the
+// first old one; empty values are dropped
+func (c *Comment) Set(key string, values ...string) {
+  key = strings.ToUpper(key)
+  var fields []Field
+  inserted := false
+  insert := func() {
+    for _, v := range values {
+      if v != "" {
+        fields = append(fields, Field{key, v})
+      }
+    }
+    inserted = true
+  }
+  for _, f := range c.Fields {
+    if strings.EqualFold(f.Key, key) {
+      if !inserted {
+        insert()
+      }
+      continue
+    }
+    fields = append(fields, f)
+  }
+  if !inserted {
+    insert()
+  }
+  c.Fields = fields
+}
+
+// Add appends value to the values of key
+func (c *Comment) Add(key string, value string) {
+  c.Fields = append(c.Fields, Field{strings.ToUpper(key), value})
+}
+
+// Remove removes all values of key
+func (c *Comment) Remove(key string) {
+  c.Set(key)
+}
+
+// Keys returns the distinct keys in order of their first occurrence
+func (c *Comment) Keys() []string {
+  var keys []string
+  seen := make(map[string]bool)
+  for _, f := range c.Fields {
+    k := strings.ToUpper(f.Key)
+    if !seen[k] {
+      seen[k] = true
+      keys = append(keys, k)
+    }
+  }
+  return keys
+}
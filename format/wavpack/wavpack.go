@@ -0,0 +1,69 @@
+package wavpack
+
+import (
+  "encoding/binary"
+  "errors"
+  "io"
+  "time"
+)
+
+
+
+// stream properties of a WavPack file taken from its first block
+type Properties struct {
+  Version       int
+  Channels      int
+  Samplerate    int
+  BitsPerSample int
+  // zero if unknown
+  Samples  int64
+  Length   time.Duration
+  Lossless bool
+}
+
+var ErrNotWavPack = errors.New("not a wavpack file")
+
+// flags of the block header
+const (
+  flagMono   = 1 << 2
+  flagHybrid = 1 << 3
+  flagFloat  = 1 << 7
+)
+
+var samplerates = []int{
+  6000, 8000, 9600, 11025, 12000, 16000, 22050, 24000,
+  32000, 44100, 48000, 64000, 88200, 96000, 192000,
+}
+
+func ReadProperties(r io.ReaderAt) (Properties, error) {
+  var p Properties
+  b := make([]byte, 32)
+  if _, err := r.ReadAt(b, 0); err != nil || string(b[0:4]) != "wvpk" {
+    return p, ErrNotWavPack
+  }
+  p.Version = int(binary.LittleEndian.Uint16(b[8:]))
+  total := binary.LittleEndian.Uint32(b[12:])
+  flags := binary.LittleEndian.Uint32(b[24:])
+
+  p.BitsPerSample = int(flags & 3 + 1) * 8
+  if flags & flagFloat != 0 {
+    p.BitsPerSample = 32
+  }
+  p.Channels = 2
+  if flags & flagMono != 0 {
+    p.Channels = 1
+  }
+  p.Lossless = flags & flagHybrid == 0
+  // index 15 marks a custom rate, which is not supported here
+  if index := int(flags >> 23 & 0xf); index < len(samplerates) {
+    p.Samplerate = samplerates[index]
+  }
+
+  if total != 0xffffffff {
+    p.Samples = int64(total)
+  }
+  if p.Samplerate > 0 {
+    p.Length = time.Duration(float64(p.Samples) / float64(p.Samplerate) * float64(time.Second))
+  }
+  return p, nil
+}
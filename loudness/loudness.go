@@ -0,0 +1,289 @@
+package loudness
+
+import (
+  "math"
+)
+
+
+
+// loudness of the gain computed by ReplayGain 2.0 and by EBU R128,
+// the R128_* tags of opus files are relative to the latter
+const (
+  ReplayGainReference = -18.0
+  R128Reference       = -23.0
+)
+
+// gates of the integrated loudness in LUFS and LU
+const (
+  absoluteGate = -70.0
+  relativeGate = -10.0
+)
+
+// Meter measures the integrated loudness and the peaks of audio
+// as specified by ITU-R BS.1770-4, which EBU R128 is based on
+type Meter struct {
+  channels int
+  weights  []float64
+  filters  [][2]biquad
+  // samples of a 100 ms segment, four of them make a gating block
+  segmentSize int
+  segment     float64
+  filled      int
+  // mean square of each finished segment
+  segments []float64
+
+  samplePeak float64
+  truePeak   float64
+  // oversampling of the true peak, one per channel
+  oversamplers []*oversampler
+}
+
+// NewMeter returns a meter for audio of the given sample rate and channels
+func NewMeter(samplerate int, channels int) *Meter {
+  m := &Meter{channels: channels, segmentSize: samplerate / 10}
+  if m.segmentSize < 1 {
+    m.segmentSize = 1
+  }
+  shelf, highpass := kWeighting(float64(samplerate))
+  for c := 0; c < channels; c++ {
+    m.filters = append(m.filters, [2]biquad{shelf, highpass})
+    m.weights = append(m.weights, channelWeight(c, channels))
+    m.oversamplers = append(m.oversamplers, newOversampler(samplerate))
+  }
+  return m
+}
+
+// channelWeight follows the 5.1 layout L, R, C, LFE, Ls, Rs: the
+// low frequency channel is left out, the surround ones weigh +1.5 dB
+func channelWeight(c int, channels int) float64 {
+  if channels < 6 {
+    return 1
+  }
+  switch c {
+  case 3:
+    return 0
+  case 4, 5:
+    return 1.41
+  }
+  return 1
+}
+
+// Add measures samples given by channel, scaled to [-1, 1]
+func (m *Meter) Add(samples [][]float64) {
+  if len(samples) != m.channels {
+    return
+  }
+  for i := range samples[0] {
+    sum := 0.0
+    for c := 0; c < m.channels; c++ {
+      x := samples[c][i]
+      if a := math.Abs(x); a > m.samplePeak {
+        m.samplePeak = a
+      }
+      if p := m.oversamplers[c].peak(x); p > m.truePeak {
+        m.truePeak = p
+      }
+      y := m.filters[c][1].filter(m.filters[c][0].filter(x))
+      sum += m.weights[c] * y * y
+    }
+    m.segment += sum
+    m.filled++
+    if m.filled == m.segmentSize {
+      m.segments = append(m.segments, m.segment / float64(m.segmentSize))
+      m.segment, m.filled = 0, 0
+    }
+  }
+}
+
+// AddInt measures integer samples of the given bits
+func (m *Meter) AddInt(samples [][]int32, bits int) {
+  scale := 1 / float64(uint64(1) << uint(bits - 1))
+  converted := make([][]float64, len(samples))
+  for c, s := range samples {
+    converted[c] = make([]float64, len(s))
+    for i, x := range s {
+      converted[c][i] = float64(x) * scale
+    }
+  }
+  m.Add(converted)
+}
+
+// Loudness returns the integrated loudness in LUFS,
+// -Inf if the audio is silent or shorter than 400 ms
+func (m *Meter) Loudness() float64 {
+  return integrated(m.blocks())
+}
+
+// SamplePeak returns the largest absolute sample
+func (m *Meter) SamplePeak() float64 {
+  return m.samplePeak
+}
+
+// TruePeak returns the largest absolute value of the
+// oversampled signal, at least the sample peak
+func (m *Meter) TruePeak() float64 {
+  return math.Max(m.truePeak, m.samplePeak)
+}
+
+// AlbumLoudness returns the integrated loudness of the audio of all meters
+// as if played one after the other
+func AlbumLoudness(meters []*Meter) float64 {
+  var blocks []float64
+  for _, m := range meters {
+    blocks = append(blocks, m.blocks()...)
+  }
+  return integrated(blocks)
+}
+
+// blocks returns the mean square of the gating blocks of 400 ms,
+// which overlap by 75 percent
+func (m *Meter) blocks() []float64 {
+  var blocks []float64
+  for i := 0; i + 4 <= len(m.segments); i++ {
+    s := m.segments[i:i + 4]
+    blocks = append(blocks, (s[0] + s[1] + s[2] + s[3]) / 4)
+  }
+  return blocks
+}
+
+// integrated gates the blocks absolutely and relatively
+// to their loudness and returns the loudness of the rest
+func integrated(blocks []float64) float64 {
+  threshold := energyOf(absoluteGate)
+  mean := func() (float64, int) {
+    sum, n := 0.0, 0
+    for _, b := range blocks {
+      if b > threshold {
+        sum += b
+        n++
+      }
+    }
+    return sum, n
+  }
+  sum, n := mean()
+  if n == 0 {
+    return math.Inf(-1)
+  }
+  threshold = math.Max(threshold, energyOf(loudnessOf(sum / float64(n)) + relativeGate))
+  sum, n = mean()
+  if n == 0 {
+    return math.Inf(-1)
+  }
+  return loudnessOf(sum / float64(n))
+}
+
+func loudnessOf(energy float64) float64 {
+  return -0.691 + 10 * math.Log10(energy)
+}
+
+func energyOf(loudness float64) float64 {
+  return math.Pow(10, (loudness + 0.691) / 10)
+}
+
+// biquad is a second order filter in direct form I
+type biquad struct {
+  b0, b1, b2, a1, a2 float64
+  x1, x2, y1, y2     float64
+}
+
+func (f *biquad) filter(x float64) float64 {
+  y := f.b0 * x + f.b1 * f.x1 + f.b2 * f.x2 - f.a1 * f.y1 - f.a2 * f.y2
+  f.x2, f.x1 = f.x1, x
+  f.y2, f.y1 = f.y1, y
+  return y
+}
+
+// kWeighting returns the two stages of the K-weighting filter, a high
+// shelf modelling the head and a high pass, for any sample rate
+func kWeighting(samplerate float64) (biquad, biquad) {
+  f0, gain, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+  k := math.Tan(math.Pi * f0 / samplerate)
+  vh := math.Pow(10, gain / 20)
+  vb := math.Pow(vh, 0.4996667741545416)
+  a0 := 1 + k / q + k * k
+  shelf := biquad{
+    b0: (vh + vb * k / q + k * k) / a0,
+    b1: 2 * (k * k - vh) / a0,
+    b2: (vh - vb * k / q + k * k) / a0,
+    a1: 2 * (k * k - 1) / a0,
+    a2: (1 - k / q + k * k) / a0,
+  }
+
+  f0, q = 38.13547087602444, 0.5003270373238773
+  k = math.Tan(math.Pi * f0 / samplerate)
+  a0 = 1 + k / q + k * k
+  highpass := biquad{
+    b0: 1,
+    b1: -2,
+    b2: 1,
+    a1: 2 * (k * k - 1) / a0,
+    a2: (1 - k / q + k * k) / a0,
+  }
+  return shelf, highpass
+}
+
+// taps of each phase of the interpolation filter
+const phaseTaps = 12
+
+// oversampler interpolates a channel by a windowed sinc filter to find
+// peaks between the samples; four times below 96 kHz, twice below 192 kHz
+type oversampler struct {
+  phases  [][]float64
+  history []float64
+}
+
+func newOversampler(samplerate int) *oversampler {
+  factor := 4
+  if samplerate >= 192000 {
+    factor = 1
+  } else if samplerate >= 96000 {
+    factor = 2
+  }
+  o := &oversampler{history: make([]float64, phaseTaps)}
+  if factor == 1 {
+    return o
+  }
+  n := phaseTaps * factor
+  center := float64(n - 1) / 2
+  for p := 0; p < factor; p++ {
+    phase := make([]float64, phaseTaps)
+    sum := 0.0
+    for k := range phase {
+      t := (float64(k * factor + p) - center) / float64(factor)
+      window := 0.5 - 0.5 * math.Cos(2 * math.Pi * (float64(k * factor + p) + 0.5) / float64(n))
+      phase[k] = sinc(t) * window
+      sum += phase[k]
+    }
+    // each phase passes direct current unchanged
+    for k := range phase {
+      phase[k] /= sum
+    }
+    o.phases = append(o.phases, phase)
+  }
+  return o
+}
+
+func sinc(x float64) float64 {
+  if x == 0 {
+    return 1
+  }
+  return math.Sin(math.Pi * x) / (math.Pi * x)
+}
+
+// peak adds a sample and returns the largest absolute value
+// interpolated in front of it
+func (o *oversampler) peak(x float64) float64 {
+  copy(o.history[1:], o.history[:len(o.history) - 1])
+  o.history[0] = x
+  peak := 0.0
+  for _, phase := range o.phases {
+    y := 0.0
+    for k, h := range phase {
+      y += h * o.history[k]
+    }
+    if a := math.Abs(y); a > peak {
+      peak = a
+    }
+  }
+  return peak
+}
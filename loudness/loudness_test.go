@@ -0,0 +1,103 @@
+package loudness
+
+import (
+  "math"
+  "testing"
+)
+
+
+
+// sine returns seconds of a sine of the given frequency and peak level
+// in dBFS, phase in radians, in every channel
+func sine(samplerate int, channels int, frequency float64, level float64, phase float64,
+  seconds float64) [][]float64 {
+  amplitude := math.Pow(10, level / 20)
+  samples := make([][]float64, channels)
+  for c := range samples {
+    samples[c] = make([]float64, int(seconds * float64(samplerate)))
+    for i := range samples[c] {
+      samples[c][i] = amplitude * math.Sin(2 * math.Pi * frequency * float64(i) /
+        float64(samplerate) + phase)
+    }
+  }
+  return samples
+}
+
+func silence(samplerate int, channels int, seconds float64) [][]float64 {
+  return sine(samplerate, channels, 0, 0, 0, seconds)
+}
+
+func meter(samplerate int, channels int, audio ...[][]float64) *Meter {
+  m := NewMeter(samplerate, channels)
+  for _, a := range audio {
+    m.Add(a)
+  }
+  return m
+}
+
+func TestLoudness(t *testing.T) {
+  tests := []struct {
+    name string
+    m    *Meter
+    want float64
+  }{
+    // EBU Tech 3341, test case 1
+    {"stereo sine at 48 kHz", meter(48000, 2, sine(48000, 2, 1000, -23, 0, 20)), -23},
+    {"stereo sine at 44.1 kHz", meter(44100, 2, sine(44100, 2, 1000, -23, 0, 20)), -23},
+    {"mono sine", meter(48000, 1, sine(48000, 1, 1000, -20, 0, 10)), -23},
+    // EBU Tech 3341, test case 3: the quiet part is gated relatively
+    {"relative gate", meter(48000, 2, sine(48000, 2, 1000, -36, 0, 10),
+      sine(48000, 2, 1000, -23, 0, 60), sine(48000, 2, 1000, -36, 0, 10)), -23},
+    {"absolute gate", meter(48000, 2, silence(48000, 2, 10),
+      sine(48000, 2, 1000, -23, 0, 10)), -23},
+    // the low frequency channel is left out
+    {"5.1", meter(48000, 6, sine(48000, 6, 1000, -23, 0, 10)), -23 + 10 *
+      math.Log10((3 + 2 * 1.41) / 2)},
+  }
+  for _, tt := range tests {
+    if got := tt.m.Loudness(); math.Abs(got - tt.want) > 0.1 {
+      t.Errorf("%s: %.2f LUFS, want %.2f", tt.name, got, tt.want)
+    }
+  }
+
+  for name, m := range map[string]*Meter{
+    "silence":    meter(48000, 2, silence(48000, 2, 10)),
+    "too short":  meter(48000, 2, sine(48000, 2, 1000, -23, 0, 0.3)),
+    "no samples": NewMeter(48000, 2),
+  } {
+    if got := m.Loudness(); !math.IsInf(got, -1) {
+      t.Errorf("%s: %.2f LUFS", name, got)
+    }
+  }
+}
+
+func TestAlbumLoudness(t *testing.T) {
+  meters := []*Meter{
+    meter(48000, 2, sine(48000, 2, 1000, -20, 0, 10)),
+    meter(48000, 2, sine(48000, 2, 1000, -26, 0, 10)),
+    meter(48000, 2, silence(48000, 2, 10)),
+  }
+  // the mean energy of both tracks, silence is gated
+  want := 10 * math.Log10((math.Pow(10, -2.0) + math.Pow(10, -2.6)) / 2)
+  if got := AlbumLoudness(meters); math.Abs(got - want) > 0.1 {
+    t.Errorf("%.2f LUFS, want %.2f", got, want)
+  }
+}
+
+func TestPeaks(t *testing.T) {
+  // a quarter of the sample rate at 45 degrees samples the sine at
+  // 0.707 of its peak only
+  m := meter(48000, 2, sine(48000, 2, 12000, 0, math.Pi / 4, 1))
+  if got := m.SamplePeak(); math.Abs(got - math.Sqrt(0.5)) > 0.001 {
+    t.Errorf("sample peak %.3f", got)
+  }
+  if got := m.TruePeak(); math.Abs(got - 1) > 0.05 {
+    t.Errorf("true peak %.3f", got)
+  }
+
+  m = NewMeter(44100, 1)
+  m.AddInt([][]int32{{0, -16384, 8192}}, 16)
+  if got := m.SamplePeak(); got != 0.5 {
+    t.Errorf("sample peak of integers %.3f", got)
+  }
+}
@@ -0,0 +1,62 @@
+package loudness
+
+import (
+  "errors"
+  "fmt"
+)
+
+import (
+  detect "github.com/elias-boemeke/taggo/detect"
+  flac "github.com/elias-boemeke/taggo/format/flac"
+  riff "github.com/elias-boemeke/taggo/format/riff"
+)
+
+
+
+// ScanFile decodes the file at path and measures its audio;
+// wave, aiff and flac files are supported
+func ScanFile(path string) (*Meter, error) {
+  format, err := detect.Sniff(path)
+  if err != nil {
+    return nil, err
+  }
+
+  var m *Meter
+  switch format {
+  case "flac":
+    meta, err := flac.ReadFile(path)
+    if err != nil {
+      return nil, err
+    }
+    m = NewMeter(meta.Info.Samplerate, meta.Info.Channels)
+    _, err = flac.DecodeFile(path, func(samples [][]int32) error {
+      m.AddInt(samples, meta.Info.BitsPerSample)
+      return nil
+    })
+    if err != nil {
+      return nil, err
+    }
+
+  case "wav", "aiff":
+    file, err := riff.ReadFile(path)
+    if err != nil {
+      return nil, err
+    }
+    pcm, err := file.PCM()
+    if err != nil {
+      return nil, err
+    }
+    m = NewMeter(pcm.Samplerate, pcm.Channels)
+    if _, err := riff.DecodeFile(path, func(samples [][]float64) error {
+      m.Add(samples)
+      return nil
+    }); err != nil {
+      return nil, err
+    }
+
+  default:
+    return nil, errors.New(fmt.Sprintf("loudness of format '%s' can't be measured," +
+      " only wav, aiff and flac are decoded", format))
+  }
+  return m, nil
+}
@@ -0,0 +1,61 @@
+package loudness
+
+import (
+  "encoding/binary"
+  "io/ioutil"
+  "math"
+  "path/filepath"
+  "testing"
+)
+
+
+
+// wave returns a 16 bit wave file of the interleaved samples
+func wave(samplerate int, samples [][]float64) []byte {
+  channels := len(samples)
+  data := make([]byte, 0, 2 * channels * len(samples[0]))
+  for i := range samples[0] {
+    for c := range samples {
+      data = append(data, 0, 0)
+      binary.LittleEndian.PutUint16(data[len(data) - 2:], uint16(int16(samples[c][i] * 32767)))
+    }
+  }
+  fmtChunk := make([]byte, 16)
+  binary.LittleEndian.PutUint16(fmtChunk[0:], 1)
+  binary.LittleEndian.PutUint16(fmtChunk[2:], uint16(channels))
+  binary.LittleEndian.PutUint32(fmtChunk[4:], uint32(samplerate))
+  binary.LittleEndian.PutUint32(fmtChunk[8:], uint32(samplerate * channels * 2))
+  binary.LittleEndian.PutUint16(fmtChunk[12:], uint16(channels * 2))
+  binary.LittleEndian.PutUint16(fmtChunk[14:], 16)
+
+  b := []byte("RIFF\x00\x00\x00\x00WAVEfmt \x10\x00\x00\x00")
+  b = append(b, fmtChunk...)
+  b = append(b, "data\x00\x00\x00\x00"...)
+  binary.LittleEndian.PutUint32(b[len(b) - 4:], uint32(len(data)))
+  b = append(b, data...)
+  binary.LittleEndian.PutUint32(b[4:], uint32(len(b) - 8))
+  return b
+}
+
+func TestScanFile(t *testing.T) {
+  dir := t.TempDir()
+  path := filepath.Join(dir, "a.wav")
+  if err := ioutil.WriteFile(path, wave(48000, sine(48000, 2, 1000, -23, 0, 5)), 0644); err != nil {
+    t.Fatal(err)
+  }
+  m, err := ScanFile(path)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got := m.Loudness(); math.Abs(got + 23) > 0.1 {
+    t.Errorf("%.2f LUFS", got)
+  }
+
+  path = filepath.Join(dir, "a.ogg")
+  if err := ioutil.WriteFile(path, []byte("OggS\x00\x02"), 0644); err != nil {
+    t.Fatal(err)
+  }
+  if _, err := ScanFile(path); err == nil {
+    t.Error("ogg file scanned")
+  }
+}
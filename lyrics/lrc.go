@@ -0,0 +1,134 @@
+package lyrics
+
+import (
+  "fmt"
+  "regexp"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+)
+
+
+
+type Line struct {
+  Time time.Duration
+  Text string
+}
+
+// a metadata line like [ar:Artist]
+type Meta struct {
+  Key   string
+  Value string
+}
+
+type LRC struct {
+  Meta  []Meta
+  Lines []Line
+}
+
+// timestamps like [01:02.34], [01:02.345] or [01:02]
+var timestamp = regexp.MustCompile(`^\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+
+var metaLine = regexp.MustCompile(`^\[([a-zA-Z#]+):(.*)\]\s*$`)
+
+// IsLRC reports whether text holds at least one line with a timestamp
+func IsLRC(text string) bool {
+  for _, line := range splitLines(text) {
+    if timestamp.MatchString(strings.TrimSpace(line)) {
+      return true
+    }
+  }
+  return false
+}
+
+// Parse reads lrc text; a line may carry several timestamps, the lines
+// are sorted by time and shifted by the offset tag, which is consumed;
+// lines which are neither timed nor metadata are ignored
+func Parse(text string) *LRC {
+  l := &LRC{}
+  offset := time.Duration(0)
+  for _, line := range splitLines(text) {
+    line = strings.TrimSpace(line)
+    var times []time.Duration
+    for {
+      m := timestamp.FindStringSubmatch(line)
+      if m == nil {
+        break
+      }
+      times = append(times, parseTime(m))
+      line = line[len(m[0]):]
+    }
+    if len(times) > 0 {
+      for _, t := range times {
+        l.Lines = append(l.Lines, Line{t, strings.TrimSpace(line)})
+      }
+      continue
+    }
+    if m := metaLine.FindStringSubmatch(line); m != nil {
+      key, value := strings.ToLower(m[1]), strings.TrimSpace(m[2])
+      if key == "offset" {
+        // a positive offset shows the lines earlier
+        if ms, err := strconv.Atoi(strings.TrimPrefix(value, "+")); err == nil {
+          offset = time.Duration(ms) * time.Millisecond
+        }
+        continue
+      }
+      l.Meta = append(l.Meta, Meta{key, value})
+    }
+  }
+  for i := range l.Lines {
+    if l.Lines[i].Time -= offset; l.Lines[i].Time < 0 {
+      l.Lines[i].Time = 0
+    }
+  }
+  sort.SliceStable(l.Lines, func(i, j int) bool { return l.Lines[i].Time < l.Lines[j].Time })
+  return l
+}
+
+func parseTime(m []string) time.Duration {
+  min, _ := strconv.Atoi(m[1])
+  sec, _ := strconv.Atoi(m[2])
+  t := time.Duration(min) * time.Minute + time.Duration(sec) * time.Second
+  if m[3] != "" {
+    // fractions of one, two or three digits
+    frac, _ := strconv.Atoi(m[3])
+    for i := len(m[3]); i < 3; i++ {
+      frac *= 10
+    }
+    t += time.Duration(frac) * time.Millisecond
+  }
+  return t
+}
+
+// String formats the metadata followed by the lines with timestamps in
+// hundredths of a second
+func (l *LRC) String() string {
+  var b strings.Builder
+  for _, m := range l.Meta {
+    b.WriteString("[" + m.Key + ":" + m.Value + "]\n")
+  }
+  for _, line := range l.Lines {
+    b.WriteString(FormatTime(line.Time) + line.Text + "\n")
+  }
+  return b.String()
+}
+
+// FormatTime formats a timestamp like [01:02.34]
+func FormatTime(t time.Duration) string {
+  cs := int64(t / (10 * time.Millisecond))
+  return fmt.Sprintf("[%02d:%02d.%02d]", cs / 6000, cs / 100 % 60, cs % 100)
+}
+
+// Plain returns the text of the lines, one per line
+func Plain(lines []Line) string {
+  var text []string
+  for _, line := range lines {
+    text = append(text, line.Text)
+  }
+  return strings.Join(text, "\n")
+}
+
+func splitLines(text string) []string {
+  return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
@@ -0,0 +1,62 @@
+package lyrics
+
+import (
+  "reflect"
+  "testing"
+  "time"
+)
+
+
+
+func TestParse(t *testing.T) {
+  text := "[ar:Artist]\r\n" +
+    "[offset:+500]\n" +
+    "[00:12.34][01:00]Chorus\n" +
+    "[00:05.5] First line \n" +
+    "not timed\n" +
+    "[00:00.1]Shifted to the start\n"
+  want := &LRC{
+    Meta: []Meta{{"ar", "Artist"}},
+    Lines: []Line{
+      {0, "Shifted to the start"},
+      {5 * time.Second, "First line"},
+      {11840 * time.Millisecond, "Chorus"},
+      {59500 * time.Millisecond, "Chorus"},
+    },
+  }
+  if got := Parse(text); !reflect.DeepEqual(got, want) {
+    t.Errorf("parsed %+v, want %+v", got, want)
+  }
+}
+
+func TestString(t *testing.T) {
+  l := &LRC{
+    Meta:  []Meta{{"ti", "Title"}},
+    Lines: []Line{{1234 * time.Millisecond, "One"}, {61 * time.Minute, "Two"}},
+  }
+  want := "[ti:Title]\n[00:01.23]One\n[61:00.00]Two\n"
+  if got := l.String(); got != want {
+    t.Errorf("formatted %q, want %q", got, want)
+  }
+  if got := Parse(want); !reflect.DeepEqual(got.Lines, []Line{{1230 * time.Millisecond, "One"},
+      {61 * time.Minute, "Two"}}) {
+    t.Errorf("read back %+v", got.Lines)
+  }
+}
+
+func TestIsLRC(t *testing.T) {
+  tests := []struct {
+    text string
+    want bool
+  }{
+    {"[00:01.00]One", true},
+    {"One\n  [02:03]Two", true},
+    {"[ar:Artist]\nOne", false},
+    {"plain text", false},
+  }
+  for _, tt := range tests {
+    if got := IsLRC(tt.text); got != tt.want {
+      t.Errorf("IsLRC(%q) = %v", tt.text, got)
+    }
+  }
+}
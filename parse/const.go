@@ -3,23 +3,81 @@ package parse
 import (
   "errors"
   "fmt"
+  "path/filepath"
+  "regexp"
+  "strconv"
   "strings"
+  "sync"
 )
 
 
 
 var tags = [...]tagInfo {
-  {"l", "album",      "Album",      true,  false, showProd,   "set Album tag",   "clear Album tag"},
-  {"r", "artist",     "Artist",     true,  false, showProd,   "set Artist tag",  "clear Artist tag"},
-  {"b", "bitrate",    "Bitrate",    false, true,  showTech,   "", ""},
-  {"h", "channels",   "Channels",   false, true,  showTech,   "", ""},
-  {"c", "comment",    "Comment",    true,  false, showExtra,  "set Comment tag", "clear Comment tag"},
-  {"g", "genre",      "Genre",      true,  false, showExtra,  "set Genre tag",   "clear Genre tag"},
-  {"n", "length",     "Length",     false, false, showLength, "", ""},
-  {"s", "samplerate", "Samplerate", false, true,  showTech,   "", ""},
-  {"t", "title",      "Title",      true,  false, showProd,   "set Title tag",   "clear Title tag"},
-  {"k", "track",      "Track",      true,  true,  showProd,   "set Track tag",   "clear Track tag"},
-  {"y", "year",       "Year",       true,  true,  showExtra,  "set Year tag",    "clear Year tag"},
+  {"l", "album",           "Album",             true,  false, showProd,   "set Album tag",              "clear Album tag"},
+  {"a", "albumartist",     "Album Artist",      true,  false, showExtra,  "set Album Artist tag",       "clear Album Artist tag"},
+  {"A", "albumartistsort", "Album Artist Sort", true,  false, showMore,   "set Album Artist Sort tag",  "clear Album Artist Sort tag"},
+  {"v", "albumgain",       "Album Gain",        false, false, showTech,   "",                           ""},
+  {"V", "albumpeak",       "Album Peak",        false, false, showTech,   "",                           ""},
+  {"L", "albumsort",       "Album Sort",        true,  false, showMore,   "set Album Sort tag",         "clear Album Sort tag"},
+  {"r", "artist",          "Artist",            true,  false, showProd,   "set Artist tag",             "clear Artist tag"},
+  {"S", "artistsort",      "Artist Sort",       true,  false, showMore,   "set Artist Sort tag",        "clear Artist Sort tag"},
+  {"b", "bitrate",         "Bitrate",           false, true,  showTech,   "",                           ""},
+  {"B", "bpm",             "BPM",               true,  true,  showMore,   "set BPM tag",                "clear BPM tag"},
+  {"N", "catalog",         "Catalog Number",    true,  false, showMore,   "set Catalog Number tag",     "clear Catalog Number tag"},
+  {"h", "channels",        "Channels",          false, true,  showTech,   "",                           ""},
+  {"c", "comment",         "Comment",           true,  false, showExtra,  "set Comment tag",            "clear Comment tag"},
+  {"C", "compilation",     "Compilation",       true,  true,  showMore,   "set Compilation tag",        "clear Compilation tag"},
+  {"m", "composer",        "Composer",          true,  false, showExtra,  "set Composer tag",           "clear Composer tag"},
+  {"M", "composersort",    "Composer Sort",     true,  false, showMore,   "set Composer Sort tag",      "clear Composer Sort tag"},
+  {"o", "conductor",       "Conductor",         true,  false, showMore,   "set Conductor tag",          "clear Conductor tag"},
+  {"x", "copyright",       "Copyright",         true,  false, showMore,   "set Copyright tag",          "clear Copyright tag"},
+  {"Y", "date",            "Date",              true,  false, showMore,   "set Date tag",               "clear Date tag"},
+  {"d", "disc",            "Disc",              true,  true,  showExtra,  "set Disc tag",               "clear Disc tag"},
+  {"D", "disctotal",       "Disc Total",        true,  true,  showMore,   "set Disc Total tag",         "clear Disc Total tag"},
+  {"E", "encodedby",       "Encoded By",        true,  false, showMore,   "set Encoded By tag",         "clear Encoded By tag"},
+  {"g", "genre",           "Genre",             true,  false, showExtra,  "set Genre tag",              "clear Genre tag"},
+  {"G", "grouping",        "Grouping",          true,  false, showMore,   "set Grouping tag",           "clear Grouping tag"},
+  {"i", "isrc",            "ISRC",              true,  false, showMore,   "set ISRC tag",               "clear ISRC tag"},
+  {"n", "length",          "Length",            false, false, showLength, "",                           ""},
+  {"w", "lyricist",        "Lyricist",          true,  false, showMore,   "set Lyricist tag",           "clear Lyricist tag"},
+  {"O", "originaldate",    "Original Date",     true,  false, showMore,   "set Original Date tag",      "clear Original Date tag"},
+  {"p", "publisher",       "Publisher",         true,  false, showMore,   "set Publisher tag",          "clear Publisher tag"},
+  {"s", "samplerate",      "Samplerate",        false, true,  showTech,   "",                           ""},
+  {"t", "title",           "Title",             true,  false, showProd,   "set Title tag",              "clear Title tag"},
+  {"T", "titlesort",       "Title Sort",        true,  false, showMore,   "set Title Sort tag",         "clear Title Sort tag"},
+  {"k", "track",           "Track",             true,  true,  showProd,   "set Track tag",              "clear Track tag"},
+  {"u", "trackgain",       "Track Gain",        false, false, showTech,   "",                           ""},
+  {"U", "trackpeak",       "Track Peak",        false, false, showTech,   "",                           ""},
+  {"K", "tracktotal",      "Track Total",       true,  true,  showMore,   "set Track Total tag",        "clear Track Total tag"},
+  {"y", "year",            "Year",              true,  false, showExtra,  "set Year tag",               "clear Year tag"},
+}
+
+// tags holding a number and a total, given together like 3/12
+var pairTags = map[string]string{"disc": "disctotal", "track": "tracktotal"}
+
+// tags holding a date, the year is the date with its first part shown
+var dateTags = map[string]bool{"date": true, "originaldate": true, "year": true}
+
+// dates like 2021, 2021-03, 2021-03-05 and id3v2.4 timestamps like
+// 2021-03-05T10:20:30
+var dateSyntax = regexp.MustCompile(`^\d{4}(-(0[1-9]|1[0-2])(-(0[1-9]|[12]\d|3[01])` +
+  `(T([01]\d|2[0-3])(:[0-5]\d(:[0-5]\d)?)?)?)?)?$`)
+
+// picture types of id3v2 APIC frames in order of their number,
+// also used by flac, vorbis and apev2
+var PictureTypes = []string{"other", "icon", "other-icon", "front", "back",
+  "leaflet", "media", "lead-artist", "artist", "conductor", "band", "composer",
+  "lyricist", "location", "recording", "performance", "screen-capture", "fish",
+  "illustration", "band-logo", "publisher-logo"}
+
+// PictureType returns the index of a picture type name, -1 if unknown
+func PictureType(name string) int {
+  for i, t := range PictureTypes {
+    if t == name {
+      return i
+    }
+  }
+  return -1
 }
 
 // used for LogErrorAndDie to indicate if an
@@ -39,30 +97,37 @@ var showExtra = func(mode ShowMode) bool {
   return mode == Default || mode == Full
 }
 
+var showMore = func(mode ShowMode) bool {
+  return mode == Full
+}
+
 var showLength = func(mode ShowMode) bool {
   return mode == Simple || mode == Technical || mode == Full
 }
 
+// built lazily and only once, both are read concurrently by workers
 var info []*tagInfo
+var infoOnce sync.Once
 var shortToLong map[string]string
+var shortToLongOnce sync.Once
 
 func GetTagInfo() []*tagInfo {
-  if info == nil {
+  infoOnce.Do(func() {
     info = make([]*tagInfo, 0)
     for i := range tags {
       info = append(info, &tags[i])
     }
-  }
+  })
   return info
 }
 
 func GetShortToLongMap() map[string]string {
-  if len(shortToLong) == 0 {
+  shortToLongOnce.Do(func() {
     shortToLong = make(map[string]string)
     for _, t := range tags {
       shortToLong[t.Short] = t.Long
     }
-  }
+  })
   return shortToLong
 }
 
@@ -103,16 +168,8 @@ func getFlagDictionary() map[string]*flag {
     },
     finish: func(args []string, f *flag, options *Options,
         parseStatus map[string]*parseAction) ([]string, error) {
-      status := parseStatus["file"]
-      if *status == actionParse {
-        options.Filename = args[0]
-        *status = actionReparse
-        return nil, nil
-      } else {
-        // this error is a duplicate, remove duplication by refactoring
-        return nil, errors.New(fmt.Sprintf("attempting to use file '%s'," +
-          " but file is already set to '%s'", args[0], options.Filename))
-      }
+      addFile(args[0], options, parseStatus)
+      return nil, nil
     },
   }
 
@@ -123,7 +180,8 @@ func getFlagDictionary() map[string]*flag {
         pattern: "[MODE]",
         optional: true,
         restricted: true,
-        candidates: []string{"default", "simple", "technical", "full"},
+        candidates: []string{"default", "simple", "technical", "full", "layers",
+          "structure", "raw", "chapters"},
       },
     },
     finish: func(args []string, f *flag, options *Options,
@@ -145,6 +203,14 @@ func getFlagDictionary() map[string]*flag {
             mode = Technical
           case "full":
             mode = Full
+          case "layers":
+            mode = Layers
+          case "structure":
+            mode = Structure
+          case "raw":
+            mode = Raw
+          case "chapters":
+            mode = Chapters
           }
         }
         options.Show.Set = true
@@ -201,6 +267,604 @@ func getFlagDictionary() map[string]*flag {
     },
   }
 
+  // -R or --recursive
+  flags["recursive"] = &flag{
+    flagArgs: []flagArg{},
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("recursive", func() {
+        options.Walk.Recursive = true
+      }, parseStatus)
+    },
+  }
+
+  // --include and --exclude
+  for _, k := range []string{"include", "exclude"} {
+    // for closure capturing
+    key := k
+    flags[key] = &flag{
+      flagArgs: []flagArg{
+        flagArg{
+          pattern: "GLOB",
+        },
+      },
+      finish: func(args []string, f *flag, options *Options,
+          parseStatus map[string]*parseAction) ([]string, error) {
+        if _, err := filepath.Match(args[0], ""); err != nil {
+          return nil, errors.New(fmt.Sprintf("invalid pattern '%s' in option '--%s': %s",
+            args[0], key, err))
+        }
+        if key == "include" {
+          options.Walk.Include = append(options.Walk.Include, args[0])
+        } else {
+          options.Walk.Exclude = append(options.Walk.Exclude, args[0])
+        }
+        return nil, nil
+      },
+    }
+  }
+
+  // --max-depth
+  flags["max-depth"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "DEPTH",
+        integer: true,
+        condition: numberCondition{
+          description: "x > 0",
+          restriction: func(x int) bool { return x > 0 },
+        },
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("max-depth", func() {
+        options.Walk.MaxDepth, _ = strconv.Atoi(args[0])
+      }, parseStatus)
+    },
+  }
+
+  // -j or --jobs
+  flags["jobs"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "N",
+        integer: true,
+        condition: numberCondition{
+          description: "x > 0",
+          restriction: func(x int) bool { return x > 0 },
+        },
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("jobs", func() {
+        options.Jobs, _ = strconv.Atoi(args[0])
+      }, parseStatus)
+    },
+  }
+
+  // --separator
+  flags["separator"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "SEP",
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("separator", func() {
+        options.Show.Separator = args[0]
+      }, parseStatus)
+    },
+  }
+
+  // --backend
+  flags["backend"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "NAME",
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      // the name is checked against the available backends when opening files
+      return parseSwitch("backend", func() {
+        options.Backend = args[0]
+      }, parseStatus)
+    },
+  }
+
+  // --id3v2-version
+  flags["id3v2-version"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "VERSION",
+        restricted: true,
+        candidates: []string{"3", "4"},
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("id3v2-version", func() {
+        options.Write.ID3v2Version, _ = strconv.Atoi(args[0])
+      }, parseStatus)
+    },
+  }
+
+  // --id3
+  flags["id3"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "MODE",
+        restricted: true,
+        candidates: []string{"v2", "v1", "both", "no-v1"},
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("id3", func() {
+        options.Write.ID3Mode = args[0]
+      }, parseStatus)
+    },
+  }
+
+  // --padding
+  flags["padding"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "BYTES",
+        integer: true,
+        condition: numberCondition{
+          description: "x >= 0",
+          restriction: func(x int) bool { return x >= 0 },
+        },
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("padding", func() {
+        options.Write.Padding, _ = strconv.Atoi(args[0])
+      }, parseStatus)
+    },
+  }
+
+  // --follow-symlinks
+  flags["follow-symlinks"] = &flag{
+    flagArgs: []flagArg{},
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("follow-symlinks", func() {
+        options.Walk.FollowSymlinks = true
+      }, parseStatus)
+    },
+  }
+
+  // --sniff
+  flags["sniff"] = &flag{
+    flagArgs: []flagArg{},
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("sniff", func() {
+        options.Walk.Sniff = true
+      }, parseStatus)
+    },
+  }
+
+  // --detect
+  flags["detect"] = &flag{
+    flagArgs: []flagArg{},
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("detect", func() {
+        options.Detect = true
+      }, parseStatus)
+    },
+  }
+
+  // --fix-extension
+  flags["fix-extension"] = &flag{
+    flagArgs: []flagArg{},
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("fix-extension", func() {
+        options.FixExtension = true
+      }, parseStatus)
+    },
+  }
+
+  // --set
+  flags["set"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "KEY=VALUE",
+        syntax:  regexp.MustCompile(`^[^=]+=`),
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      // repeated keys collect their values
+      kv := strings.SplitN(args[0], "=", 2)
+      e := options.Raw.edit(kv[0])
+      e.Values = append(e.Values, kv[1])
+      return nil, nil
+    },
+  }
+
+  // --remove
+  flags["remove"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "KEY",
+        syntax:  regexp.MustCompile(`^[^=]+$`),
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      e := options.Raw.edit(args[0])
+      if len(e.Values) > 0 {
+        e.Values = nil
+        return []string{fmt.Sprintf("key '%s' is both set and removed, it is removed", args[0])}, nil
+      }
+      return nil, nil
+    },
+  }
+
+  // --get
+  flags["get"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "KEY",
+        syntax:  regexp.MustCompile(`^[^=]+$`),
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      options.Raw.Get = append(options.Raw.Get, args[0])
+      return nil, nil
+    },
+  }
+
+  // --pictures
+  flags["pictures"] = &flag{
+    flagArgs: []flagArg{},
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("pictures", func() {
+        options.Pictures.List = true
+      }, parseStatus)
+    },
+  }
+
+  // --extract-pictures
+  flags["extract-pictures"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "DIR",
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("extract-pictures", func() {
+        options.Pictures.Extract = args[0]
+      }, parseStatus)
+    },
+  }
+
+  // --add-picture
+  flags["add-picture"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "IMAGE",
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      options.Pictures.Add = append(options.Pictures.Add, args[0])
+      return nil, nil
+    },
+  }
+
+  // a picture is selected by its index as listed or its type
+  selector := flagArg{
+    pattern: "PIC",
+    syntax:  regexp.MustCompile(`^([1-9][0-9]*|` + strings.Join(PictureTypes, "|") + `)$`),
+  }
+
+  // --replace-picture
+  flags["replace-picture"] = &flag{
+    flagArgs: []flagArg{
+      selector,
+      flagArg{
+        pattern: "IMAGE",
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      options.Pictures.Replace = append(options.Pictures.Replace,
+        &PictureReplace{Selector: args[0], File: args[1]})
+      return nil, nil
+    },
+  }
+
+  // --remove-picture
+  flags["remove-picture"] = &flag{
+    flagArgs: []flagArg{selector},
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      options.Pictures.Remove = append(options.Pictures.Remove, args[0])
+      return nil, nil
+    },
+  }
+
+  // --picture-type
+  flags["picture-type"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "TYPE",
+        restricted: true,
+        candidates: PictureTypes,
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("picture-type", func() {
+        options.Pictures.Type = PictureType(args[0])
+      }, parseStatus)
+    },
+  }
+
+  // --picture-description
+  flags["picture-description"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "TEXT",
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("picture-description", func() {
+        options.Pictures.Description = args[0]
+      }, parseStatus)
+    },
+  }
+
+  // --normalise-pictures
+  flags["normalise-pictures"] = &flag{
+    flagArgs: []flagArg{},
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("normalise-pictures", func() {
+        options.Pictures.Normalise = true
+      }, parseStatus)
+    },
+  }
+
+  // --max-picture-size
+  flags["max-picture-size"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "PIXELS",
+        integer: true,
+        condition: numberCondition{
+          description: "x > 0",
+          restriction: func(x int) bool { return x > 0 },
+        },
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("max-picture-size", func() {
+        options.Pictures.MaxSize, _ = strconv.Atoi(args[0])
+      }, parseStatus)
+    },
+  }
+
+  // --jpeg-quality
+  flags["jpeg-quality"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "QUALITY",
+        integer: true,
+        condition: numberCondition{
+          description: "1 <= x <= 100",
+          restriction: func(x int) bool { return x >= 1 && x <= 100 },
+        },
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("jpeg-quality", func() {
+        options.Pictures.Quality, _ = strconv.Atoi(args[0])
+      }, parseStatus)
+    },
+  }
+
+  // --lyrics
+  flags["lyrics"] = &flag{
+    flagArgs: []flagArg{},
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("lyrics", func() {
+        options.Lyrics.Show = true
+      }, parseStatus)
+    },
+  }
+
+  // --lyrics-import
+  flags["lyrics-import"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "FILE",
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("lyrics-import", func() {
+        options.Lyrics.Import = args[0]
+      }, parseStatus)
+    },
+  }
+
+  // --lyrics-export
+  flags["lyrics-export"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "FILE",
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("lyrics-export", func() {
+        options.Lyrics.Export = args[0]
+      }, parseStatus)
+    },
+  }
+
+  // --clear-lyrics
+  flags["clear-lyrics"] = &flag{
+    flagArgs: []flagArg{},
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("clear-lyrics", func() {
+        options.Lyrics.Clear = true
+      }, parseStatus)
+    },
+  }
+
+  // --lyrics-language
+  flags["lyrics-language"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "LANG",
+        syntax:  regexp.MustCompile(`^[a-zA-Z]{3}$`),
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("lyrics-language", func() {
+        options.Lyrics.Language = strings.ToLower(args[0])
+      }, parseStatus)
+    },
+  }
+
+  // --lyrics-description
+  flags["lyrics-description"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "TEXT",
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("lyrics-description", func() {
+        options.Lyrics.Description = args[0]
+      }, parseStatus)
+    },
+  }
+
+  // --chapters-import
+  flags["chapters-import"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "FILE",
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("chapters-import", func() {
+        options.Chapters.Import = args[0]
+      }, parseStatus)
+    },
+  }
+
+  // --chapters-export
+  flags["chapters-export"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "FILE",
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("chapters-export", func() {
+        options.Chapters.Export = args[0]
+      }, parseStatus)
+    },
+  }
+
+  // --clear-chapters
+  flags["clear-chapters"] = &flag{
+    flagArgs: []flagArg{},
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("clear-chapters", func() {
+        options.Chapters.Clear = true
+      }, parseStatus)
+    },
+  }
+
+  // --number
+  flags["number"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern:    "ORDER",
+        restricted: true,
+        candidates: []string{"name", "tags"},
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("number", func() {
+        options.Number = args[0]
+      }, parseStatus)
+    },
+  }
+
+  // --checksum
+  flags["checksum"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern:    "ALGO",
+        restricted: true,
+        candidates: []string{"md5", "sha256"},
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("checksum", func() {
+        options.Checksum = args[0]
+      }, parseStatus)
+    },
+  }
+
+  // --verify
+  flags["verify"] = &flag{
+    flagArgs: []flagArg{
+      flagArg{
+        pattern: "MANIFEST",
+        syntax:  regexp.MustCompile(`(?i)\.(md5|sha256)$`),
+      },
+    },
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("verify", func() {
+        options.Verify = args[0]
+      }, parseStatus)
+    },
+  }
+
+  // --replaygain
+  flags["replaygain"] = &flag{
+    flagArgs: []flagArg{},
+    finish: func(args []string, f *flag, options *Options,
+        parseStatus map[string]*parseAction) ([]string, error) {
+      return parseSwitch("replaygain", func() {
+        options.ReplayGain = true
+      }, parseStatus)
+    },
+  }
+
   // tags
   for _, t := range(tags) {
     // for closure capturing
@@ -209,20 +873,42 @@ func getFlagDictionary() map[string]*flag {
     if t.Mutable {
       // set flag
       var fa []flagArg
-      var zeroval string
 
-      if t.Integer {
+      if _, ok := pairTags[t.Long]; ok {
+        fa = []flagArg{
+          flagArg{
+            pattern: "N[/TOTAL]",
+            syntax:  regexp.MustCompile(`^[1-9][0-9]*(/[1-9][0-9]*)?$`),
+          },
+        }
+
+      } else if t.Integer {
+        condition := numberCondition{
+          description: "x > 0",
+          restriction: func(x int) bool { return x > 0 },
+        }
+        // a flag, zero marks a file explicitly as no compilation
+        if t.Long == "compilation" {
+          condition = numberCondition{
+            description: "x = 0 or x = 1",
+            restriction: func(x int) bool { return x == 0 || x == 1 },
+          }
+        }
         fa = []flagArg{
           flagArg{
             pattern: strings.ToUpper(t.Long),
             integer: true,
-            condition: numberCondition{
-              description: "x > 0",
-              restriction: func(x int) bool { return x > 0 },
-            },
+            condition: condition,
+          },
+        }
+
+      } else if dateTags[t.Long] {
+        fa = []flagArg{
+          flagArg{
+            pattern: "YYYY[-MM[-DD]]",
+            syntax:  dateSyntax,
           },
         }
-        zeroval = "0"
 
       } else {
         fa = []flagArg{
@@ -230,7 +916,6 @@ func getFlagDictionary() map[string]*flag {
             pattern: strings.ToUpper(t.Long),
           },
         }
-        zeroval = ""
       }
 
       flags[t.Long] = &flag{
@@ -240,12 +925,34 @@ func getFlagDictionary() map[string]*flag {
           return parseTag(key, args[0], f, options, parseStatus)
         },
       }
+      // add and remove flags of text tags, repeatable
+      if !singleValued(t.Long) {
+        flags["add-" + t.Long] = &flag{
+          flagArgs: fa,
+          finish: func(args []string, f *flag, options *Options,
+              parseStatus map[string]*parseAction) ([]string, error) {
+            opt := options.Tags[key]
+            opt.Add = append(opt.Add, args[0])
+            return nil, nil
+          },
+        }
+        flags["remove-" + t.Long] = &flag{
+          flagArgs: fa,
+          finish: func(args []string, f *flag, options *Options,
+              parseStatus map[string]*parseAction) ([]string, error) {
+            opt := options.Tags[key]
+            opt.Remove = append(opt.Remove, args[0])
+            return nil, nil
+          },
+        }
+      }
       // clear flag
       flags["clear-" + t.Long] = &flag{
         flagArgs: []flagArg{},
         finish: func(args []string, f *flag, options *Options,
             parseStatus map[string]*parseAction) ([]string, error) {
-          return parseTag(key, zeroval, f, options, parseStatus)
+          // an empty value clears the tag
+          return parseTag(key, "", f, options, parseStatus)
         },
       }
     }
@@ -259,13 +966,7 @@ func getFlagDictionary() map[string]*flag {
       var warn []string
       for _, t := range(tags) {
         if t.Mutable {
-          var zeroval string
-          if t.Integer {
-            zeroval = "0"
-          } else {
-            zeroval = ""
-          }
-          w, err := parseTag(t.Long, zeroval, f, options, parseStatus)
+          w, err := parseTag(t.Long, "", f, options, parseStatus)
           if (err != nil) {
             return nil, err
           }
@@ -282,7 +983,14 @@ func getFlagDictionary() map[string]*flag {
 func getParseStatus() map[string]*parseAction {
 
   parseStatus := make(map[string]*parseAction)
-  extraKeys := []string{"help", "file", "show"}
+  extraKeys := []string{"help", "file", "show", "separator", "recursive",
+    "max-depth", "follow-symlinks", "sniff", "jobs", "backend", "id3v2-version",
+    "padding", "id3", "detect", "fix-extension", "pictures", "extract-pictures",
+    "picture-type", "picture-description", "normalise-pictures", "max-picture-size",
+    "jpeg-quality", "lyrics", "lyrics-import", "lyrics-export", "clear-lyrics",
+    "lyrics-language", "lyrics-description", "chapters-import", "chapters-export",
+    "clear-chapters", "number", "replaygain",
+    "checksum", "verify"}
 
   for _, k := range(extraKeys) {
     parseStatus[k] = new(parseAction)
@@ -310,6 +1018,55 @@ func getFlagkeyMap() map[string]string {
   keys["--show"] = keys["-s"]
 
   keys["--show-format"] = "show-format"
+  keys["--separator"] = "separator"
+
+  keys["-R"] = "recursive"
+  keys["--recursive"] = keys["-R"]
+
+  keys["-j"] = "jobs"
+  keys["--jobs"] = keys["-j"]
+
+  keys["--backend"] = "backend"
+  keys["--id3v2-version"] = "id3v2-version"
+  keys["--padding"] = "padding"
+  keys["--id3"] = "id3"
+
+  keys["--include"] = "include"
+  keys["--exclude"] = "exclude"
+  keys["--max-depth"] = "max-depth"
+  keys["--follow-symlinks"] = "follow-symlinks"
+  keys["--sniff"] = "sniff"
+
+  keys["--set"] = "set"
+  keys["--remove"] = "remove"
+  keys["--get"] = "get"
+
+  keys["--pictures"] = "pictures"
+  keys["--extract-pictures"] = "extract-pictures"
+  keys["--add-picture"] = "add-picture"
+  keys["--replace-picture"] = "replace-picture"
+  keys["--remove-picture"] = "remove-picture"
+  keys["--picture-type"] = "picture-type"
+  keys["--picture-description"] = "picture-description"
+  keys["--normalise-pictures"] = "normalise-pictures"
+  keys["--max-picture-size"] = "max-picture-size"
+  keys["--jpeg-quality"] = "jpeg-quality"
+  keys["--lyrics"] = "lyrics"
+  keys["--lyrics-import"] = "lyrics-import"
+  keys["--lyrics-export"] = "lyrics-export"
+  keys["--clear-lyrics"] = "clear-lyrics"
+  keys["--lyrics-language"] = "lyrics-language"
+  keys["--lyrics-description"] = "lyrics-description"
+  keys["--chapters-import"] = "chapters-import"
+  keys["--chapters-export"] = "chapters-export"
+  keys["--clear-chapters"] = "clear-chapters"
+  keys["--number"] = "number"
+  keys["--replaygain"] = "replaygain"
+  keys["--checksum"] = "checksum"
+  keys["--verify"] = "verify"
+
+  keys["--detect"] = "detect"
+  keys["--fix-extension"] = "fix-extension"
 
   // tags
   for _, t := range(tags) {
@@ -317,6 +1074,10 @@ func getFlagkeyMap() map[string]string {
       keys["-" + t.Short] = t.Long
       keys["--" + t.Long] = t.Long
       keys["--clear-" + t.Long] = "clear-" + t.Long
+      if !singleValued(t.Long) {
+        keys["--add-" + t.Long] = "add-" + t.Long
+        keys["--remove-" + t.Long] = "remove-" + t.Long
+      }
     }
   }
 
@@ -327,6 +1088,13 @@ func getFlagkeyMap() map[string]string {
 
 func newOptions() (*Options) {
   op := &Options{}
+  op.Jobs = 1
+  op.Write.Padding = -1
+  op.Show.Separator = "; "
+  op.Pictures.Type = -1
+  op.Pictures.MaxSize = 1000
+  op.Pictures.Quality = 85
+  op.Lyrics.Language = "eng"
   op.Tags = make(map[string]*tag)
 
   for _, t := range tags {
@@ -341,20 +1109,28 @@ func newOptions() (*Options) {
 func parseTag(key string, value string, f *flag, options *Options,
     parseStatus map[string]*parseAction) ([]string, error) {
   status := parseStatus[key]
+  opt := options.Tags[key]
   switch *status {
     // actionParse
   case actionParse:
-    opt := options.Tags[key]
     opt.Set = true
-    opt.Value = value
+    opt.Values = nil
+    if value != "" {
+      opt.Values = []string{value}
+    }
     *status = actionReparse
     return nil, nil
 
     // actionReparse
   case actionReparse:
+    // text tags collect their values
+    if value != "" && len(opt.Values) > 0 && !singleValued(key) {
+      opt.Values = append(opt.Values, value)
+      return nil, nil
+    }
     *status = actionIgnore
     return []string{fmt.Sprintf("tag '%s' already set, value remains" +
-      " '%s' (ignoring)", key, options.Tags[key].Value)}, nil
+      " '%s' (ignoring)", key, strings.Join(opt.Values, options.Show.Separator))}, nil
 
     // actionIgnore
   case actionIgnore:
@@ -365,3 +1141,38 @@ func parseTag(key string, value string, f *flag, options *Options,
   }
 }
 
+// singleValued reports whether tag key holds one value only,
+// which is the case for numbers and dates
+func singleValued(key string) bool {
+  for _, t := range tags {
+    if t.Long == key {
+      return t.Integer || dateTags[key]
+    }
+  }
+  return false
+}
+
+
+func parseSwitch(key string, apply func(),
+    parseStatus map[string]*parseAction) ([]string, error) {
+  status := parseStatus[key]
+  switch *status {
+    // actionParse
+  case actionParse:
+    apply()
+    *status = actionReparse
+    return nil, nil
+
+    // actionReparse
+  case actionReparse:
+    *status = actionIgnore
+    return []string{fmt.Sprintf("option '--%s' already given (ignoring)", key)}, nil
+
+    // actionIgnore
+  case actionIgnore:
+    return nil, nil
+
+  default:
+    panic("unreachable :(")
+  }
+}
@@ -3,6 +3,7 @@ package parse
 import (
   "errors"
   "fmt"
+  "regexp"
   "strconv"
   "strings"
 )
@@ -10,8 +11,30 @@ import (
 
 
 type Options struct {
-  Filename string
+  Files []string
   Show ShowOptions
+  Walk WalkOptions
+  Jobs int
+  Backend string
+  // report the format recognised by content
+  Detect bool
+  // rename files to the extension of their format
+  FixExtension bool
+  Write WriteOptions
+  Raw RawOptions
+  Pictures PictureOptions
+  Lyrics LyricsOptions
+  Chapters ChapterOptions
+  // order in which the files of each album are numbered (name or tags),
+  // empty for no numbering
+  Number string
+  // measure the loudness and write the ReplayGain tags
+  ReplayGain bool
+  // algorithm of the audio checksums printed, empty for none
+  Checksum string
+  // manifest of audio checksums the files are verified against,
+  // its files are taken if none are given
+  Verify string
   Tags map[string]*tag
 }
 
@@ -19,11 +42,103 @@ type ShowOptions struct {
   Set    bool
   Mode   ShowMode
   Format string
+  // joins the values of multi-valued tags
+  Separator string
 }
 
+type WalkOptions struct {
+  Recursive      bool
+  Include        []string
+  Exclude        []string
+  MaxDepth       int
+  FollowSymlinks bool
+  Sniff          bool
+}
+
+type WriteOptions struct {
+  // zero keeps the version of each file
+  ID3v2Version int
+  // negative selects the default of each format
+  Padding int
+  // id3 versions written to mp3 files, empty for automatic
+  ID3Mode string
+}
+
+// native keys addressed by --set, --remove and --get, a key may be
+// prefixed by the namespace of a tag format like id3v2:TXXX:SOURCE
+type RawOptions struct {
+  // in order of the first flag of each key
+  Set []*RawEdit
+  Get []string
+}
+
+type RawEdit struct {
+  Key string
+  // no values remove the key
+  Values []string
+}
+
+// embedded pictures; removals are applied first, then
+// replacements, then additions
+type PictureOptions struct {
+  // list the pictures of each file
+  List bool
+  // directory the pictures are extracted to
+  Extract string
+  // image files to embed
+  Add []string
+  Replace []*PictureReplace
+  // selectors, see PictureReplace
+  Remove []string
+  // index into PictureTypes of added pictures, -1 if not given
+  Type int
+  Description string
+  // drop duplicates, shrink to MaxSize pixels and recompress as jpeg
+  // of Quality where it saves bytes
+  Normalise bool
+  MaxSize int
+  Quality int
+}
+
+// lyrics files are lrc if they end in .lrc, else plain text; a file
+// given as extension like .lrc is the one next to each audio file
+type LyricsOptions struct {
+  // print the lyrics of each file
+  Show bool
+  Import string
+  Export string
+  // remove all lyrics before importing
+  Clear bool
+  // language and content descriptor of id3v2 lyrics
+  Language string
+  Description string
+}
+
+// chapter files are ffmpeg metadata if they start with ;FFMETADATA1,
+// else one chapter per line as start and title; exports end in .ffmeta
+// for ffmpeg metadata; a file given as extension is the one next to
+// each audio file
+type ChapterOptions struct {
+  Import string
+  Export string
+  // remove all chapters
+  Clear bool
+}
+
+type PictureReplace struct {
+  // 1 based index of a picture as listed or the name of a picture type
+  Selector string
+  File     string
+}
+
+// the edit of a tag; text tags may hold several values
 type tag struct {
   Set bool
-  Value string
+  // the values replacing those of the file, none clear the tag
+  Values []string
+  // values added to or removed from those of the file
+  Add    []string
+  Remove []string
 }
 
 type showFunc func(ShowMode) bool
@@ -34,6 +149,10 @@ const (
   Simple
   Technical
   Full
+  Layers
+  Structure
+  Raw
+  Chapters
   Custom
 )
 
@@ -74,6 +193,9 @@ type flagArg struct {
 
   restricted bool
   candidates []string
+
+  // arguments have to match syntax, pattern describes it
+  syntax *regexp.Regexp
 }
 
 func (fa *flagArg) validate(arg string) (error) {
@@ -89,6 +211,10 @@ func (fa *flagArg) validate(arg string) (error) {
     return nil
   }
 
+  if fa.syntax != nil && !fa.syntax.MatchString(arg) {
+    return errors.New(fmt.Sprintf("argument '%s' does not match pattern '%s'", arg, fa.pattern))
+  }
+
   if fa.restricted {
     found := false
     for _, v := range fa.candidates {
@@ -106,3 +232,91 @@ func (fa *flagArg) validate(arg string) (error) {
   return nil
 }
 
+
+// Edits reports whether any tag, native key or picture is
+// changed or writing was requested
+func (o *Options) Edits() bool {
+  for _, t := range o.Tags {
+    if t.Changes() {
+      return true
+    }
+  }
+  return len(o.Raw.Set) > 0 || o.Pictures.Changes() || o.Pictures.Normalise ||
+    o.Lyrics.Changes() || o.Chapters.Changes() || o.Write.Converts() || o.Number != "" ||
+    o.ReplayGain
+}
+
+// Changes reports whether pictures are added, replaced or removed
+func (p *PictureOptions) Changes() bool {
+  return len(p.Add) > 0 || len(p.Replace) > 0 || len(p.Remove) > 0
+}
+
+// Changes reports whether lyrics are imported or cleared
+func (l *LyricsOptions) Changes() bool {
+  return l.Import != "" || l.Clear
+}
+
+// Changes reports whether chapters are imported or cleared
+func (c *ChapterOptions) Changes() bool {
+  return c.Import != "" || c.Clear
+}
+
+// Changes reports whether the tag is set, added to or removed from
+func (t *tag) Changes() bool {
+  return t.Set || len(t.Add) > 0 || len(t.Remove) > 0
+}
+
+// Apply returns the values of the tag after the edit,
+// given the current values of a file
+func (t *tag) Apply(values []string) []string {
+  if t.Set {
+    values = t.Values
+  }
+  result := append([]string{}, values...)
+  for _, a := range t.Add {
+    if indexOf(result, a) < 0 {
+      result = append(result, a)
+    }
+  }
+  kept := result[:0]
+  for _, v := range result {
+    if indexOf(t.Remove, v) < 0 {
+      kept = append(kept, v)
+    }
+  }
+  return kept
+}
+
+func indexOf(values []string, value string) int {
+  for i, v := range values {
+    if v == value {
+      return i
+    }
+  }
+  return -1
+}
+
+// Displays reports whether anything is printed for each file
+func (o *Options) Displays() bool {
+  return o.Show.Set || len(o.Raw.Get) > 0 || o.Pictures.List ||
+    o.Pictures.Extract != "" || o.Pictures.Normalise || o.Lyrics.Show ||
+    o.Lyrics.Export != "" || o.Chapters.Export != ""
+}
+
+// edit returns the edit of key, creating it as needed
+func (ro *RawOptions) edit(key string) *RawEdit {
+  for _, e := range ro.Set {
+    if e.Key == key {
+      return e
+    }
+  }
+  e := &RawEdit{Key: key}
+  ro.Set = append(ro.Set, e)
+  return e
+}
+
+// Converts reports whether writing was requested
+// regardless of any tag being set
+func (wo *WriteOptions) Converts() bool {
+  return wo.ID3v2Version != 0 || wo.ID3Mode != ""
+}
@@ -33,6 +33,10 @@ func LogErrorAndDie(showManualReference bool, format string, args ...interface{}
   os.Exit(1)
 }
 
+func LogError(format string, args ...interface{}) {
+  fmt.Fprintln(os.Stderr, fmt.Sprintf(red("ERR") + " " + format, args...))
+}
+
 func LogWarning(message string) {
   fmt.Fprintln(os.Stderr, fmt.Sprintf(yellow("WARN") + " %s", message))
 }
@@ -66,17 +70,19 @@ func printManualPageOptionsAndExit() {
     " embedded into audio files\n" +
     "\n" +
     fat("Usage\n") +
-    "        " + "taggo [options...] <file>\n" +
+    "        " + "taggo [options...] <file>...\n" +
     "\n" +
     fat("Options\n")
 
   help += "      " + fat("set tag") + "\n"
   for _, t := range tags {
     if t.Mutable {
-      help += "        " +
-        fmt.Sprintf("%-28s", "-" + t.Short + ", --" + t.Long +
-        " " + flags[t.Long].flagArgs[0].pattern) +
-        t.Description + "\n"
+      usage := "-" + t.Short + ", --" + t.Long + " " + flags[t.Long].flagArgs[0].pattern
+      // long flags get the description on a line of its own
+      if len(usage) >= 28 {
+        usage += "\n        " + fmt.Sprintf("%-28s", "")
+      }
+      help += "        " + fmt.Sprintf("%-28s", usage) + t.Description + "\n"
     }
   }
   help += "\n"
@@ -92,6 +98,63 @@ func printManualPageOptionsAndExit() {
   help += "        " +
     fmt.Sprintf("%-28s", "--clear") + "clear all tags\n" +
     "\n"
+  help += "      " + fat("values of text tags") + "\n" +
+    "        " + fmt.Sprintf("%-28s", "--add-TAG VALUE") +
+    "add VALUE to the values of TAG, e.g.\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "--add-artist or --add-genre (repeatable)\n" +
+    "        " + fmt.Sprintf("%-28s", "--remove-TAG VALUE") +
+    "remove VALUE from the values of TAG (repeatable)\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "setting a text tag repeatedly gives it several\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "values, e.g. -r \"One\" -r \"Two\"\n" +
+    "\n"
+  help += "      " + fat("dates") + "\n" +
+    "        " + fmt.Sprintf("%-28s", "-Y, --date") +
+    "release date like 2021, 2021-03 or 2021-03-05,\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "an id3v2.4 timestamp like 2021-03-05T10:20 as well\n" +
+    "        " + fmt.Sprintf("%-28s", "-y, --year") +
+    "the same tag as Date, shown with its year only\n" +
+    "        " + fmt.Sprintf("%-28s", "-O, --originaldate") +
+    "original release date, not kept by matroska files\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "id3v2.3 drops a month given without day and\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "keeps the year of the original date only\n" +
+    "\n"
+  help += "      " + fat("numbers") + "\n" +
+    "        " + fmt.Sprintf("%-28s", "-k, --track N/TOTAL") +
+    "track and total at once, e.g. -k 3/12; likewise\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "-d, --disc N/TOTAL for disc and disc total\n" +
+    "        " + fmt.Sprintf("%-28s", "--number " +
+    flags["number"].flagArgs[0].pattern) +
+    "number the files of each album, ordered by file\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "name (name) or by disc and track tags (tags);\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "the files of a directory sharing the album tag form\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "an album, totals are set to the tracks of each disc\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "and the number of discs\n" +
+    "\n"
+  help += "      " + fat("loudness") + "\n" +
+    "        " + fmt.Sprintf("%-28s", "--replaygain") +
+    "measure the EBU R128 loudness and true peak of each\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "file and its album and write the ReplayGain tags,\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "gains refer to -18 LUFS; opus comments get the\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "R128 gains of -23 LUFS as well; albums are formed\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "like by --number; only wav, aiff and flac files\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "are decoded, other formats fail\n" +
+    "\n"
   help += "      " + fat("display tags") + " (see Presentation)\n" +
     "        " +
     fmt.Sprintf("%-28s", "-s, --show " +
@@ -101,6 +164,172 @@ func printManualPageOptionsAndExit() {
     fmt.Sprintf("%-28s", "--show-format " +
     flags["show-format"].flagArgs[0].pattern) +
     "display tags and custom text defined by format\n" +
+    "        " +
+    fmt.Sprintf("%-28s", "--separator " +
+    flags["separator"].flagArgs[0].pattern) +
+    "join several values of a tag by SEP (default '; ')\n" +
+    "\n"
+  help += "      " + fat("native keys") + "\n" +
+    "        " + fmt.Sprintf("%-28s", "--set " +
+    flags["set"].flagArgs[0].pattern) +
+    "set a native key of the tag, repeat for\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "several values, e.g. --set TXXX:SOURCE=web\n" +
+    "        " + fmt.Sprintf("%-28s", "--remove " +
+    flags["remove"].flagArgs[0].pattern) +
+    "remove a native key\n" +
+    "        " + fmt.Sprintf("%-28s", "--get " +
+    flags["get"].flagArgs[0].pattern) +
+    "print the values of a native key\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "a KEY may start with the namespace of a tag format:\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "id3v2, ape, vorbis, mp4, info, aiff, matroska, asf;\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "without it the main tag of the file is used\n" +
+    "\n"
+  help += "      " + fat("pictures") + "\n" +
+    "        " + fmt.Sprintf("%-28s", "--pictures") +
+    "list the embedded pictures with their index\n" +
+    "        " + fmt.Sprintf("%-28s", "--extract-pictures " +
+    flags["extract-pictures"].flagArgs[0].pattern) +
+    "write the pictures to DIR as NAME-INDEX-TYPE.EXT\n" +
+    "        " + fmt.Sprintf("%-28s", "--add-picture " +
+    flags["add-picture"].flagArgs[0].pattern) +
+    "embed a jpeg, png or gif image (repeatable)\n" +
+    "        " + fmt.Sprintf("%-28s", "--replace-picture " +
+    flags["replace-picture"].flagArgs[0].pattern + " " +
+    flags["replace-picture"].flagArgs[1].pattern) +
+    "replace the pictures selected by PIC with IMAGE,\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "PIC is an index or a type (repeatable)\n" +
+    "        " + fmt.Sprintf("%-28s", "--remove-picture " +
+    flags["remove-picture"].flagArgs[0].pattern) +
+    "remove the pictures selected by PIC (repeatable)\n" +
+    "        " + fmt.Sprintf("%-28s", "--picture-type " +
+    flags["picture-type"].flagArgs[0].pattern) +
+    "type of added and replacing pictures:\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "front (default), back, media, artist, ...\n" +
+    "        " + fmt.Sprintf("%-28s", "--picture-description " +
+    flags["picture-description"].flagArgs[0].pattern) +
+    "description of added and replacing pictures\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "mp4 files keep neither type nor description,\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "apev2 tags one picture per type\n" +
+    "        " + fmt.Sprintf("%-28s", "--normalise-pictures") +
+    "drop duplicate pictures, shrink the others and\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "recompress them as jpeg where that saves bytes\n" +
+    "        " + fmt.Sprintf("%-28s", "--max-picture-size " +
+    flags["max-picture-size"].flagArgs[0].pattern) +
+    "largest side of normalised pictures (default 1000)\n" +
+    "        " + fmt.Sprintf("%-28s", "--jpeg-quality " +
+    flags["jpeg-quality"].flagArgs[0].pattern) +
+    "quality of recompressed pictures (default 85)\n" +
+    "\n"
+  help += "      " + fat("lyrics") + "\n" +
+    "        " + fmt.Sprintf("%-28s", "--lyrics") +
+    "print the lyrics, synchronised ones as lrc\n" +
+    "        " + fmt.Sprintf("%-28s", "--lyrics-import " +
+    flags["lyrics-import"].flagArgs[0].pattern) +
+    "import lyrics from an lrc or text file; a FILE\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "like .lrc is the one next to each audio file\n" +
+    "        " + fmt.Sprintf("%-28s", "--lyrics-export " +
+    flags["lyrics-export"].flagArgs[0].pattern) +
+    "write the lyrics to FILE, as lrc if it ends in .lrc\n" +
+    "        " + fmt.Sprintf("%-28s", "--clear-lyrics") +
+    "remove all lyrics before importing\n" +
+    "        " + fmt.Sprintf("%-28s", "--lyrics-language " +
+    flags["lyrics-language"].flagArgs[0].pattern) +
+    "language of id3v2 lyrics (default eng)\n" +
+    "        " + fmt.Sprintf("%-28s", "--lyrics-description " +
+    flags["lyrics-description"].flagArgs[0].pattern) +
+    "content descriptor of id3v2 lyrics\n" +
+    "\n"
+  help += "      " + fat("chapters") + "\n" +
+    "        " + fmt.Sprintf("%-28s", "--chapters-import " +
+    flags["chapters-import"].flagArgs[0].pattern) +
+    "replace the chapters by those of FILE, lines of\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "start and title or ffmpeg metadata; a FILE like\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    ".txt is the one next to each audio file\n" +
+    "        " + fmt.Sprintf("%-28s", "--chapters-export " +
+    flags["chapters-export"].flagArgs[0].pattern) +
+    "write the chapters to FILE, as ffmpeg metadata\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "if it ends in .ffmeta\n" +
+    "        " + fmt.Sprintf("%-28s", "--clear-chapters") +
+    "remove all chapters\n" +
+    "\n"
+  help += "      " + fat("directories") + "\n" +
+    "        " + fmt.Sprintf("%-28s", "-R, --recursive") +
+    "descend into directories given as file\n" +
+    "        " + fmt.Sprintf("%-28s", "--include " +
+    flags["include"].flagArgs[0].pattern) +
+    "only take files matching GLOB (repeatable)\n" +
+    "        " + fmt.Sprintf("%-28s", "--exclude " +
+    flags["exclude"].flagArgs[0].pattern) +
+    "skip files and directories matching GLOB (repeatable)\n" +
+    "        " + fmt.Sprintf("%-28s", "--max-depth " +
+    flags["max-depth"].flagArgs[0].pattern) +
+    "descend at most DEPTH levels (1 = directory only)\n" +
+    "        " + fmt.Sprintf("%-28s", "--follow-symlinks") +
+    "follow symbolic links instead of skipping them\n" +
+    "        " + fmt.Sprintf("%-28s", "--sniff") +
+    "also take files without audio extension if their\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "content is recognised as audio\n" +
+    "\n"
+  help += "      " + fat("detection") + "\n" +
+    "        " + fmt.Sprintf("%-28s", "--detect") +
+    "report container and codec recognised by content\n" +
+    "        " + fmt.Sprintf("%-28s", "--fix-extension") +
+    "rename files whose extension does not match\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "their content\n" +
+    "\n"
+  help += "      " + fat("checksums") + "\n" +
+    "        " + fmt.Sprintf("%-28s", "--checksum " +
+    flags["checksum"].flagArgs[0].pattern) +
+    "print a checksum of the audio data of each file\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "like md5sum or sha256sum (md5|sha256); tags,\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "pictures and padding are left out, editing them\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "keeps the checksum\n" +
+    "        " + fmt.Sprintf("%-28s", "--verify " +
+    flags["verify"].flagArgs[0].pattern) +
+    "compare the audio checksums with a manifest of\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "such lines, its extension .md5 or .sha256 names\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "the algorithm and its paths are relative to the\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "working directory; without files all files of the\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "manifest are verified, listed files which are\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "missing fail\n" +
+    "\n"
+  help += "      " + fat("writing") + "\n" +
+    "        " + fmt.Sprintf("%-28s", "--id3v2-version " +
+    flags["id3v2-version"].flagArgs[0].pattern) +
+    "write id3v2 tags as version 2.3 or 2.4 (3|4)\n" +
+    "        " + fmt.Sprintf("%-28s", "--id3 " +
+    flags["id3"].flagArgs[0].pattern) +
+    "id3 versions written to mp3 files:\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "v2, v1, both or no-v1 (v2 and strip v1),\n" +
+    "        " + fmt.Sprintf("%-28s", "") +
+    "by default v2 and v1 if already present\n" +
+    "        " + fmt.Sprintf("%-28s", "--padding " +
+    flags["padding"].flagArgs[0].pattern) +
+    "reserve BYTES for future edits when a tag grows\n" +
     "\n"
   hps := flags["help"].flagArgs[0].candidates[0]
   hpe := flags["help"].flagArgs[0].candidates[1]
@@ -109,7 +338,13 @@ func printManualPageOptionsAndExit() {
     "        " + fmt.Sprintf("%-28s", "-h, --help [" + hps + "|" + hpe + "]") +
     "show help page\n" +
     "        " + fmt.Sprintf("%-28s", "-f, --file " + fpat) +
-    "explicitly take " + fpat + " as input file\n" +
+    "explicitly take " + fpat + " as input file (repeatable)\n" +
+    "        " + fmt.Sprintf("%-28s", "-j, --jobs " +
+    flags["jobs"].flagArgs[0].pattern) +
+    "process up to N files in parallel (default 1)\n" +
+    "        " + fmt.Sprintf("%-28s", "--backend " +
+    flags["backend"].flagArgs[0].pattern) +
+    "use backend NAME instead of choosing by file content\n" +
     "\n"
 
   help += fat("Presentation") + "\n" +
@@ -146,24 +381,63 @@ func printManualPageShowAndExit() {
     "\n" +
     "        available modes:  " +
     fat("default") + ", " + fat("simple") + ", " +
-    fat("technical") + ", " + fat("full") + "\n" +
+    fat("technical") + ", " + fat("full") + ", " + fat("layers") + ",\n" +
+    "                          " + fat("structure") + ", " + fat("raw") + ", " +
+    fat("chapters") + "\n" +
+    "\n" +
+    "        layers shows each tag of a file separately, e.g. the\n" +
+    "        id3v2 and the id3v1 tag of an mp3 file\n" +
+    "        structure lists the elements of the container with their\n" +
+    "        offset and size, e.g. the metadata blocks of a flac file\n" +
+    "        raw lists every native key and value of each tag like\n" +
+    "        id3v2:TXXX:SOURCE=web, the keys accepted by --set and --get\n" +
+    "        chapters lists the chapters with start and end, the last\n" +
+    "        one ending at the length of the file\n" +
     "\n" +
     "        there can only be one mode active at a time\n" +
     "        if you want a custom format use --show-format\n" +
+    "        several values of a tag are joined by '; ' or the\n" +
+    "        separator given by --separator\n" +
     "\n" +
-    "       tag        | shown by\n" +
-    "       ------------------------------------\n" +
-    "       Album      | default, simple, full\n" +
-    "       Artist     | default, simple, full\n" +
-    "       Bitrate    | technical, full\n" +
-    "       Channels   | technical, full\n" +
-    "       Comment    | default, full\n" +
-    "       Genre      | default, full\n" +
-    "       Length     | simple, technical, full\n" +
-    "       Samplerate | technical, full\n" +
-    "       Title      | default, simple, full\n" +
-    "       Track      | default, simple, full\n" +
-    "       Year       | default, full\n" +
+    "       tag               | shown by\n" +
+    "       --------------------------------------------\n" +
+    "       Album             | default, simple, full\n" +
+    "       Album Artist      | default, full\n" +
+    "       Album Artist Sort | full\n" +
+    "       Album Gain        | technical, full\n" +
+    "       Album Peak        | technical, full\n" +
+    "       Album Sort        | full\n" +
+    "       Artist            | default, simple, full\n" +
+    "       Artist Sort       | full\n" +
+    "       Bitrate           | technical, full\n" +
+    "       BPM               | full\n" +
+    "       Catalog Number    | full\n" +
+    "       Channels          | technical, full\n" +
+    "       Comment           | default, full\n" +
+    "       Compilation       | full\n" +
+    "       Composer          | default, full\n" +
+    "       Composer Sort     | full\n" +
+    "       Conductor         | full\n" +
+    "       Copyright         | full\n" +
+    "       Date              | full\n" +
+    "       Disc              | default, full\n" +
+    "       Disc Total        | full\n" +
+    "       Encoded By        | full\n" +
+    "       Genre             | default, full\n" +
+    "       Grouping          | full\n" +
+    "       ISRC              | full\n" +
+    "       Length            | simple, technical, full\n" +
+    "       Lyricist          | full\n" +
+    "       Original Date     | full\n" +
+    "       Publisher         | full\n" +
+    "       Samplerate        | technical, full\n" +
+    "       Title             | default, simple, full\n" +
+    "       Title Sort        | full\n" +
+    "       Track             | default, simple, full\n" +
+    "       Track Gain        | technical, full\n" +
+    "       Track Peak        | technical, full\n" +
+    "       Track Total       | full\n" +
+    "       Year              | default, full\n" +
     "\n" +
     "\n" +
     "      " + fmt.Sprintf("%-28s", "--show-format " +
@@ -175,18 +449,47 @@ func printManualPageShowAndExit() {
     "       escape | expands to\n" +
     "       -----------------------\n" +
     "       %l     | Album tag\n" +
+    "       %a     | Album Artist tag\n" +
+    "       %A     | Album Artist Sort tag\n" +
+    "       %v     | Album Gain tag\n" +
+    "       %V     | Album Peak tag\n" +
+    "       %L     | Album Sort tag\n" +
     "       %r     | Artist tag\n" +
+    "       %S     | Artist Sort tag\n" +
     "       %b     | Bitrate tag\n" +
+    "       %B     | BPM tag\n" +
+    "       %N     | Catalog Number tag\n" +
     "       %h     | Channels tag\n" +
     "       %c     | Comment tag\n" +
+    "       %C     | Compilation tag\n" +
+    "       %m     | Composer tag\n" +
+    "       %M     | Composer Sort tag\n" +
+    "       %o     | Conductor tag\n" +
+    "       %x     | Copyright tag\n" +
+    "       %Y     | Date tag\n" +
+    "       %d     | Disc tag\n" +
+    "       %D     | Disc Total tag\n" +
+    "       %E     | Encoded By tag\n" +
     "       %g     | Genre tag\n" +
+    "       %G     | Grouping tag\n" +
+    "       %i     | ISRC tag\n" +
     "       %n     | Length tag\n" +
+    "       %w     | Lyricist tag\n" +
+    "       %O     | Original Date tag\n" +
+    "       %p     | Publisher tag\n" +
     "       %s     | Samplerate tag\n" +
     "       %t     | Title tag\n" +
+    "       %T     | Title Sort tag\n" +
     "       %k     | Track tag\n" +
-    "       %y     | Year\n" +
+    "       %u     | Track Gain tag\n" +
+    "       %U     | Track Peak tag\n" +
+    "       %K     | Track Total tag\n" +
+    "       %y     | Year tag, the year of the Date tag\n" +
     "       %%     | literal %\n" +
     "\n" +
+    "        a width between % and the escape pads numbers with zeros,\n" +
+    "        e.g. %02k shows track 3 as 03\n" +
+    "\n" +
     "        after these escapes are resolved, the string is\n" +
     "        passed to strconv.Unquote such that you can use '\\n' and other escapes\n" +
     "\n" +
@@ -222,7 +525,65 @@ func printManualPageExamplesAndExit() {
     "        display tags using a custom given format\n" +
     "\n" +
     "      " + "taggo -f -dashfile -k 5\n" +
-    "        change track number tag of file '-dashfile' to 5"
+    "        change track number tag of file '-dashfile' to 5\n" +
+    "\n" +
+    "      " + "taggo *.mp3 -l \"The Album\" -s simple\n" +
+    "        change album tag of all mp3 files in the current directory\n" +
+    "        to 'The Album' and display each file in simple mode\n" +
+    "\n" +
+    "      " + "taggo -R music --include \"*.flac\" --exclude live -s\n" +
+    "        display tags of all flac files below directory 'music',\n" +
+    "        skipping everything inside directories named 'live'\n" +
+    "\n" +
+    "      " + "taggo test.flac -g Rock -g Pop --add-artist \"Guest\"\n" +
+    "        give file 'test.flac' the genres Rock and Pop and add\n" +
+    "        Guest to its artists\n" +
+    "\n" +
+    "      " + "taggo test.flac --set ARTIST=One --set ARTIST=Two -s raw\n" +
+    "        give file 'test.flac' two ARTIST comments and list\n" +
+    "        all native keys afterwards\n" +
+    "\n" +
+    "      " + "taggo test.mka --get matroska:30:ARTIST\n" +
+    "        print the ARTIST values of the track level of 'test.mka'\n" +
+    "\n" +
+    "      " + "taggo *.flac --remove-picture front --add-picture cover.jpg\n" +
+    "        replace the front covers of all flac files in the current\n" +
+    "        directory by the image 'cover.jpg'\n" +
+    "\n" +
+    "      " + "taggo test.mp3 --pictures --extract-pictures covers\n" +
+    "        list the pictures of 'test.mp3' and write them to\n" +
+    "        directory 'covers'\n" +
+    "\n" +
+    "      " + "taggo -R music --normalise-pictures --max-picture-size 600\n" +
+    "        shrink the pictures of all files below 'music' to at most\n" +
+    "        600 pixels and report the bytes saved\n" +
+    "\n" +
+    "      " + "taggo song.mp3 --lyrics-import song.lrc\n" +
+    "        store the lyrics of 'song.lrc' synchronised in 'song.mp3'\n" +
+    "\n" +
+    "      " + "taggo -R music --lyrics-import .lrc\n" +
+    "        import the lrc file next to each file below 'music'\n" +
+    "\n" +
+    "      " + "taggo book.m4b --chapters-import chapters.txt -s chapters\n" +
+    "        replace the chapters of 'book.m4b' and list them\n" +
+    "\n" +
+    "      " + "taggo remaster.flac -Y 2021-03-05 -O 1979-11-30 --show-format \"%y: %Y (%O)\"\n" +
+    "        set release and original release date of 'remaster.flac'\n" +
+    "        and display year and both dates\n" +
+    "\n" +
+    "      " + "taggo album/* --number name --show-format \"%02k/%K %t\"\n" +
+    "        number the files of 'album' by name, set the track totals\n" +
+    "        and display the padded track numbers\n" +
+    "\n" +
+    "      " + "taggo album/*.flac --replaygain --show-format \"%u %v %t\"\n" +
+    "        measure the loudness of the files of 'album' and display\n" +
+    "        track and album gain written to them\n" +
+    "\n" +
+    "      " + "taggo -R album --checksum sha256 > album/audio.sha256\n" +
+    "        store the checksums of the audio of the files of 'album'\n" +
+    "\n" +
+    "      " + "taggo --verify album/audio.sha256\n" +
+    "        check that the audio of the files of 'album' is unchanged"
 
   fmt.Println(help)
   os.Exit(0)
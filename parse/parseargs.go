@@ -3,6 +3,7 @@ package parse
 import (
   "errors"
   "fmt"
+  "strings"
 )
 
 
@@ -41,14 +42,33 @@ func ParseArgs(args []string) (*Options, error) {
     warnings = append(warnings, warn...)
   }
 
-  if *parseStatus["file"] == actionParse {
+  warnings = append(warnings, splitPairs(options)...)
+  if options.Number != "" {
+    for _, key := range []string{"track", "tracktotal"} {
+      if options.Tags[key].Changes() {
+        return nil, errors.New(fmt.Sprintf("option '--number' sets tag '%s'," +
+          " it can't be given as well", key))
+      }
+    }
+  }
+
+  // year and date are stored alike, the date is the more precise
+  if options.Tags["year"].Changes() && options.Tags["date"].Changes() {
+    warnings = append(warnings, "tags 'year' and 'date' share their storage, year is ignored")
+    *options.Tags["year"] = tag{}
+  }
+
+  if *parseStatus["file"] == actionParse && options.Verify == "" {
     return nil, errNoFile()
   }
 
-  if !options.Show.Set {
-    change := false
+  if !options.Displays() && !options.Detect && !options.FixExtension &&
+      options.Checksum == "" && options.Verify == "" {
+    change := len(options.Raw.Set) > 0 || options.Pictures.Changes() ||
+      options.Lyrics.Changes() || options.Chapters.Changes() || options.Number != "" ||
+      options.ReplayGain
     for _, tag := range options.Tags {
-      if tag.Set {
+      if tag.Changes() {
         change = true
         break
       }
@@ -113,18 +133,37 @@ func parseFile(arg string, options *Options,
       " --file option", arg))
   }
 
-  status := parseStatus["file"]
-  if *status == actionParse {
-    options.Filename = arg
-    *status = actionReparse
-
-  } else {
-    // this error is a duplicate, remove duplication by refactoring
-    return errors.New(fmt.Sprintf("attempting to use file '%s'," +
-      " but file is already set to '%s';" +
-      " (make sure you didn't missspell an option)", arg, options.Filename)), nil
-  }
+  addFile(arg, options, parseStatus)
 
   return nil, warnings
 }
 
+
+func addFile(fileName string, options *Options,
+  parseStatus map[string]*parseAction) {
+
+  options.Files = append(options.Files, fileName)
+  *parseStatus["file"] = actionReparse
+}
+
+// splitPairs moves the total of numbers given like 3/12 to the total tag,
+// unless that is given as well
+func splitPairs(options *Options) []string {
+  var warnings []string
+  for _, key := range []string{"track", "disc"} {
+    opt, total := options.Tags[key], pairTags[key]
+    if len(opt.Values) != 1 || !strings.Contains(opt.Values[0], "/") {
+      continue
+    }
+    parts := strings.SplitN(opt.Values[0], "/", 2)
+    opt.Values = []string{parts[0]}
+    if options.Tags[total].Changes() {
+      warnings = append(warnings, fmt.Sprintf("tag '%s' already set, total of" +
+        " %s '%s/%s' ignored", total, key, parts[0], parts[1]))
+      continue
+    }
+    options.Tags[total].Set = true
+    options.Tags[total].Values = []string{parts[1]}
+  }
+  return warnings
+}
@@ -0,0 +1,263 @@
+package parse
+
+import (
+  "reflect"
+  "testing"
+)
+
+
+
+func TestParseArgsFiles(t *testing.T) {
+  tests := []struct {
+    name  string
+    args  []string
+    files []string
+  }{
+    {"one file", []string{"a.mp3"}, []string{"a.mp3"}},
+    {"several files", []string{"a.mp3", "b.flac", "c.ogg"}, []string{"a.mp3", "b.flac", "c.ogg"}},
+    {"flags between files", []string{"a.mp3", "-t", "Title", "b.flac"}, []string{"a.mp3", "b.flac"}},
+    {"file flag", []string{"-f", "-a.mp3", "--file", "b.mp3"}, []string{"-a.mp3", "b.mp3"}},
+  }
+  for _, tt := range tests {
+    op, err := ParseArgs(tt.args)
+    if err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+    if !reflect.DeepEqual(op.Files, tt.files) {
+      t.Errorf("%s: files %q, want %q", tt.name, op.Files, tt.files)
+    }
+  }
+}
+
+func TestParseArgsShow(t *testing.T) {
+  tests := []struct {
+    name string
+    args []string
+    set  bool
+    mode ShowMode
+  }{
+    // files are shown unless they are changed
+    {"nothing given", []string{"a.mp3"}, true, Default},
+    {"tag set", []string{"-t", "Title", "a.mp3"}, false, Default},
+    {"mode", []string{"-s", "technical", "a.mp3"}, true, Technical},
+    {"mode and tag", []string{"-s", "full", "-t", "Title", "a.mp3"}, true, Full},
+    {"format", []string{"--show-format", "%t", "a.mp3"}, true, Custom},
+    {"detect", []string{"--detect", "a.mp3"}, false, Default},
+    {"checksum", []string{"--checksum", "md5", "a.mp3"}, false, Default},
+    // the files of a manifest are verified if none are given
+    {"verify", []string{"--verify", "album.md5"}, false, Default},
+  }
+  for _, tt := range tests {
+    op, err := ParseArgs(tt.args)
+    if err != nil {
+      t.Fatalf("%s: %s", tt.name, err)
+    }
+    if op.Show.Set != tt.set || op.Show.Set && op.Show.Mode != tt.mode {
+      t.Errorf("%s: show %+v", tt.name, op.Show)
+    }
+  }
+}
+
+func TestParseArgsTags(t *testing.T) {
+  op, err := ParseArgs([]string{"-r", "One", "--artist", "Two", "--add-genre", "Pop",
+    "--remove-genre", "Rock", "--clear-album", "--separator", " / ", "a.mp3"})
+  if err != nil {
+    t.Fatal(err)
+  }
+  tests := []struct {
+    key  string
+    want tag
+  }{
+    {"artist", tag{Set: true, Values: []string{"One", "Two"}}},
+    {"genre", tag{Add: []string{"Pop"}, Remove: []string{"Rock"}}},
+    {"album", tag{Set: true}},
+    {"title", tag{}},
+  }
+  for _, tt := range tests {
+    if got := op.Tags[tt.key]; !reflect.DeepEqual(*got, tt.want) {
+      t.Errorf("tag '%s' %+v, want %+v", tt.key, *got, tt.want)
+    }
+  }
+  if op.Show.Separator != " / " {
+    t.Errorf("separator '%s'", op.Show.Separator)
+  }
+  if got := op.Tags["genre"].Apply([]string{"Rock", "Jazz"}); !reflect.DeepEqual(got,
+      []string{"Jazz", "Pop"}) {
+    t.Errorf("genres %q", got)
+  }
+}
+
+func TestParseArgsDates(t *testing.T) {
+  op, err := ParseArgs([]string{"-Y", "2021-03-05T10:20", "-O", "1979-11", "-y", "1999",
+    "a.mp3"})
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got := op.Tags["date"].Values; !reflect.DeepEqual(got, []string{"2021-03-05T10:20"}) {
+    t.Errorf("date %q", got)
+  }
+  if got := op.Tags["originaldate"].Values; !reflect.DeepEqual(got, []string{"1979-11"}) {
+    t.Errorf("original date %q", got)
+  }
+  // year and date share their storage
+  if op.Tags["year"].Changes() {
+    t.Errorf("year %+v set along with the date", *op.Tags["year"])
+  }
+}
+
+func TestParseArgsNumbers(t *testing.T) {
+  tests := []struct {
+    args  []string
+    track []string
+    total []string
+  }{
+    {[]string{"-k", "3/12", "a.mp3"}, []string{"3"}, []string{"12"}},
+    {[]string{"-k", "3", "a.mp3"}, []string{"3"}, nil},
+    // a total given on its own wins
+    {[]string{"-k", "3/12", "-K", "10", "a.mp3"}, []string{"3"}, []string{"10"}},
+  }
+  for _, tt := range tests {
+    op, err := ParseArgs(tt.args)
+    if err != nil {
+      t.Fatalf("%q: %s", tt.args, err)
+    }
+    if got := op.Tags["track"].Values; !reflect.DeepEqual(got, tt.track) {
+      t.Errorf("%q: track %q", tt.args, got)
+    }
+    if got := op.Tags["tracktotal"].Values; !reflect.DeepEqual(got, tt.total) {
+      t.Errorf("%q: track total %q", tt.args, got)
+    }
+  }
+}
+
+func TestParseArgsWalk(t *testing.T) {
+  op, err := ParseArgs([]string{"-R", "--include", "*.mp3", "--include", "*.flac",
+    "--exclude", "live/*", "--max-depth", "2", "--follow-symlinks", "--sniff", "music"})
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := WalkOptions{
+    Recursive:      true,
+    Include:        []string{"*.mp3", "*.flac"},
+    Exclude:        []string{"live/*"},
+    MaxDepth:       2,
+    FollowSymlinks: true,
+    Sniff:          true,
+  }
+  if !reflect.DeepEqual(op.Walk, want) {
+    t.Errorf("walk options %+v, want %+v", op.Walk, want)
+  }
+}
+
+func TestParseArgsJobs(t *testing.T) {
+  tests := []struct {
+    args []string
+    jobs int
+  }{
+    {[]string{"a.mp3"}, 1},
+    {[]string{"-j", "4", "a.mp3"}, 4},
+    {[]string{"--jobs", "2", "a.mp3"}, 2},
+  }
+  for _, tt := range tests {
+    op, err := ParseArgs(tt.args)
+    if err != nil {
+      t.Fatalf("%q: %s", tt.args, err)
+    }
+    if op.Jobs != tt.jobs {
+      t.Errorf("%q: %d jobs, want %d", tt.args, op.Jobs, tt.jobs)
+    }
+  }
+}
+
+func TestParseArgsWrite(t *testing.T) {
+  op, err := ParseArgs([]string{"--id3v2-version", "3", "--padding", "0", "--id3", "both",
+    "a.mp3"})
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := WriteOptions{ID3v2Version: 3, Padding: 0, ID3Mode: "both"}
+  if op.Write != want {
+    t.Errorf("write options %+v, want %+v", op.Write, want)
+  }
+
+  op, err = ParseArgs([]string{"a.mp3"})
+  if err != nil {
+    t.Fatal(err)
+  }
+  if want := (WriteOptions{Padding: -1}); op.Write != want {
+    t.Errorf("default write options %+v, want %+v", op.Write, want)
+  }
+}
+
+func TestParseArgsRaw(t *testing.T) {
+  op, err := ParseArgs([]string{"--set", "TXXX:SOURCE=web", "--set", "comm=a=b",
+    "--set", "TXXX:SOURCE=cd", "--remove", "TCON", "--get", "id3v2:TIT2", "a.mp3"})
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := []*RawEdit{
+    {Key: "TXXX:SOURCE", Values: []string{"web", "cd"}},
+    {Key: "comm", Values: []string{"a=b"}},
+    {Key: "TCON"},
+  }
+  if !reflect.DeepEqual(op.Raw.Set, want) {
+    t.Errorf("raw edits %+v", op.Raw.Set)
+  }
+  if !reflect.DeepEqual(op.Raw.Get, []string{"id3v2:TIT2"}) {
+    t.Errorf("raw keys %q", op.Raw.Get)
+  }
+  if op.Show.Set {
+    t.Errorf("show set")
+  }
+}
+
+func TestParseArgsPictures(t *testing.T) {
+  op, err := ParseArgs([]string{"--add-picture", "front.jpg", "--picture-type", "back",
+    "--replace-picture", "2", "new.png", "--remove-picture", "front", "a.mp3"})
+  if err != nil {
+    t.Fatal(err)
+  }
+  p := op.Pictures
+  if !reflect.DeepEqual(p.Add, []string{"front.jpg"}) || p.Type != 4 ||
+      len(p.Replace) != 1 || *p.Replace[0] != (PictureReplace{Selector: "2", File: "new.png"}) ||
+      !reflect.DeepEqual(p.Remove, []string{"front"}) {
+    t.Errorf("picture options %+v", p)
+  }
+  op, err = ParseArgs([]string{"a.mp3"})
+  if err != nil {
+    t.Fatal(err)
+  }
+  if op.Pictures.Type != -1 {
+    t.Errorf("default picture type %d", op.Pictures.Type)
+  }
+}
+
+func TestParseArgsErrors(t *testing.T) {
+  tests := []struct {
+    name string
+    args []string
+  }{
+    {"no file", []string{"-t", "Title"}},
+    {"missing argument", []string{"a.mp3", "-t"}},
+    {"track not a number", []string{"-k", "three", "a.mp3"}},
+    {"track not positive", []string{"-k", "0", "a.mp3"}},
+    {"unknown id3 mode", []string{"--id3", "v3", "a.mp3"}},
+    {"set without value", []string{"--set", "TIT2", "a.mp3"}},
+    {"remove with value", []string{"--remove", "TIT2=x", "a.mp3"}},
+    {"unknown picture", []string{"--remove-picture", "cover", "a.mp3"}},
+    {"picture zero", []string{"--remove-picture", "0", "a.mp3"}},
+    {"jpeg quality too high", []string{"--jpeg-quality", "101", "a.mp3"}},
+    {"invalid month", []string{"-Y", "2021-13", "a.mp3"}},
+    {"short year", []string{"-y", "21", "a.mp3"}},
+    {"numbered track", []string{"--number", "name", "-k", "3", "a.mp3"}},
+    {"unknown numbering", []string{"--number", "size", "a.mp3"}},
+    {"unknown checksum", []string{"--checksum", "crc32", "a.mp3"}},
+    {"no jobs", []string{"-j", "0", "a.mp3"}},
+    {"negative depth", []string{"-R", "--max-depth", "-1", "music"}},
+  }
+  for _, tt := range tests {
+    if _, err := ParseArgs(tt.args); err == nil {
+      t.Errorf("%s: %q parsed", tt.name, tt.args)
+    }
+  }
+}
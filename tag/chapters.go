@@ -0,0 +1,133 @@
+package tag
+
+import (
+  "errors"
+  "fmt"
+  "io"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+)
+
+import (
+  backend "github.com/elias-boemeke/taggo/backend"
+  chapters "github.com/elias-boemeke/taggo/chapters"
+  parse "github.com/elias-boemeke/taggo/parse"
+)
+
+
+
+var errNoChapters = errors.New("chapters are not available for this file")
+
+// showChapters lists the chapters of file with start and end,
+// missing ends are computed against the length of the file
+func showChapters(out io.Writer, file backend.File) {
+  list, err := readChapters(file)
+  if err != nil {
+    fmt.Fprintln(out, err)
+    return
+  }
+  if len(list) == 0 {
+    fmt.Fprintln(out, "no chapters")
+    return
+  }
+
+  format := "%3s  %-12s  %-12s  %s"
+  fmt.Fprintln(out, fmt.Sprintf(format, "#", "start", "end", "title"))
+  for i, c := range list {
+    fmt.Fprintln(out, fmt.Sprintf(format, strconv.Itoa(i + 1), chapters.FormatTime(c.Start),
+      chapters.FormatTime(c.End), c.Title))
+  }
+}
+
+// readChapters returns the chapters of file sorted and with their end
+func readChapters(file backend.File) ([]chapters.Chapter, error) {
+  c, ok := file.(backend.Chaptered)
+  if !ok {
+    return nil, errNoChapters
+  }
+  list, err := c.Chapters()
+  if err != nil {
+    return nil, err
+  }
+  return chapters.Complete(list, file.Properties().Length), nil
+}
+
+// ExportChapters writes the chapters of file to a file which must not
+// exist, as ffmpeg metadata if it ends in .ffmeta, else as text
+func ExportChapters(out io.Writer, file backend.File, fileName string, name string) error {
+  list, err := readChapters(file)
+  if err != nil {
+    return err
+  }
+  if len(list) == 0 {
+    fmt.Fprintln(out, "no chapters to export")
+    return nil
+  }
+  path, _ := sidecarPath(fileName, name)
+  text := chapters.Text(list)
+  if isFFMetadataFile(path) {
+    text = chapters.FFMetadata(list)
+  }
+
+  w, err := os.OpenFile(path, os.O_WRONLY | os.O_CREATE | os.O_EXCL, 0644)
+  if os.IsExist(err) {
+    return errors.New(fmt.Sprintf("chapter file '%s' exists already", path))
+  } else if err != nil {
+    return err
+  }
+  _, err = w.Write([]byte(text))
+  if cerr := w.Close(); err == nil {
+    err = cerr
+  }
+  if err != nil {
+    return err
+  }
+  fmt.Fprintln(out, "exported " + path)
+  return nil
+}
+
+func isFFMetadataFile(path string) bool {
+  ext := strings.ToLower(filepath.Ext(path))
+  return ext == ".ffmeta" || ext == ".ffmetadata"
+}
+
+// editChapters clears the chapters of file or replaces them by those of
+// a chapter file; a file given by extension only which does not exist
+// is skipped
+func editChapters(file backend.File, fileName string, op *parse.ChapterOptions) error {
+  c, ok := file.(backend.Chaptered)
+  if !ok {
+    return errNoChapters
+  }
+  if op.Import == "" {
+    return c.SetChapters(nil)
+  }
+
+  path, sibling := sidecarPath(fileName, op.Import)
+  data, err := ioutil.ReadFile(path)
+  if sibling && os.IsNotExist(err) {
+    if op.Clear {
+      return c.SetChapters(nil)
+    }
+    return nil
+  } else if err != nil {
+    return err
+  }
+  text := strings.TrimPrefix(string(data), "\uFEFF")
+  var list []chapters.Chapter
+  if chapters.IsFFMetadata(text) {
+    list, err = chapters.ParseFFMetadata(text)
+  } else {
+    list, err = chapters.ParseText(text)
+  }
+  if err != nil {
+    return errors.New(fmt.Sprintf("chapter file '%s': %s", path, err))
+  }
+  if len(list) == 0 {
+    return errors.New(fmt.Sprintf("chapter file '%s' holds no chapters", path))
+  }
+  return c.SetChapters(chapters.Complete(list, file.Properties().Length))
+}
@@ -1,50 +1,209 @@
 package tag
 
 import (
+  "errors"
   "fmt"
+  "io"
+  "regexp"
   "strconv"
   "unicode/utf8"
 )
 
 import (
+  backend "github.com/elias-boemeke/taggo/backend"
+  detect "github.com/elias-boemeke/taggo/detect"
   parse "github.com/elias-boemeke/taggo/parse"
-  taglib "github.com/wtolson/go-taglib"
 )
 
 
 
-func ShowTags(file *taglib.File, showOpt *parse.ShowOptions) {
-  tagValues := tagValuesFromFile(file)
+func ShowTags(out io.Writer, file backend.File, showOpt *parse.ShowOptions) {
+  tagValues := tagValuesFromFile(file, showOpt.Separator)
   if showOpt.Mode == parse.Custom {
-    showTagsFromFormat(tagValues, showOpt.Format)
+    showTagsFromFormat(out, tagValues, showOpt.Format)
+  } else if showOpt.Mode == parse.Layers {
+    showLayers(out, file)
+  } else if showOpt.Mode == parse.Structure {
+    showStructure(out, file)
+  } else if showOpt.Mode == parse.Raw {
+    showRaw(out, file)
+  } else if showOpt.Mode == parse.Chapters {
+    showChapters(out, file)
   } else {
-    showTagsFromMode(tagValues, showOpt.Mode)
+    width := showTagsFromMode(out, tagValues, showOpt.Mode)
+    if showOpt.Mode == parse.Technical || showOpt.Mode == parse.Full {
+      showTechnical(out, file.Properties().Technical, width)
+    }
   }
 }
 
-func showTagsFromMode(tagValues map[string]string, mode parse.ShowMode) {
-  var width string
+func ShowHeader(out io.Writer, fileName string) {
+  fmt.Fprintln(out, "==> " + fileName + " <==")
+}
 
-  switch mode {
-  case parse.Default:
-    width = "7"
-  case parse.Simple:
-    width = "6"
-  case parse.Technical:
-    width = "10"
-  case parse.Full:
-    width = "10"
+// the format of a file as recognised by its content
+func ShowDetection(out io.Writer, fileName string, r detect.Report) {
+  if r.Format == "" {
+    fmt.Fprintln(out, fileName + ": unknown format")
+    return
+  }
+  line := fileName + ": " + r.Description
+  if r.ID3v2 {
+    line += " behind ID3v2 tag"
+  }
+  if !r.Matches {
+    line += fmt.Sprintf("; extension should be %s", r.Extension)
   }
+  fmt.Fprintln(out, line)
+}
 
+func showTagsFromMode(out io.Writer, tagValues map[string]string, mode parse.ShowMode) int {
+  var shown []string
   info := parse.GetTagInfo()
   for _, v := range info {
     if v.ShowCondition(mode) {
-      fmt.Println(fmt.Sprintf("%" + width + "s: %s", v.Name, tagValues[v.Long]))
+      shown = append(shown, v.Name)
+    }
+  }
+
+  width := nameWidth(shown)
+  for _, v := range info {
+    if v.ShowCondition(mode) {
+      fmt.Fprintln(out, fmt.Sprintf("%" + strconv.Itoa(width) + "s: %s", v.Name, tagValues[v.Long]))
+    }
+  }
+  return width
+}
+
+// nameWidth returns the length of the longest name
+func nameWidth(names []string) int {
+  width := 0
+  for _, n := range names {
+    if len(n) > width {
+      width = len(n)
+    }
+  }
+  return width
+}
+
+// the mutable tags each layer of file can hold, files with a single tag are
+// shown as one layer; fields beyond those of the default mode only if set
+func showLayers(out io.Writer, file backend.File) {
+  var layers []backend.Layer
+  layered := false
+  if l, ok := file.(backend.Layered); ok {
+    layers, layered = l.Layers(), true
+  } else {
+    layers = []backend.Layer{{Name: "Tag", Fields: file.Fields()}}
+  }
+  if len(layers) == 0 {
+    fmt.Fprintln(out, "no tags")
+  }
+
+  for _, layer := range layers {
+    fmt.Fprintln(out, "[" + layer.Name + "]")
+    var names, values []string
+    for _, v := range parse.GetTagInfo() {
+      value, held := layer.Fields[v.Long]
+      if !v.Mutable || layered && !held {
+        continue
+      }
+      if v.ShowCondition(parse.Default) || value != "" {
+        names = append(names, v.Name)
+        values = append(values, value)
+      }
+    }
+    width := strconv.Itoa(nameWidth(names))
+    for i, name := range names {
+      fmt.Fprintln(out, fmt.Sprintf("  %" + width + "s: %s", name, values[i]))
+    }
+  }
+}
+
+// the elements of the container in file order
+func showStructure(out io.Writer, file backend.File) {
+  s, ok := file.(backend.Structured)
+  if !ok {
+    fmt.Fprintln(out, "structure not available for this file")
+    return
+  }
+
+  fmt.Fprintln(out, fmt.Sprintf("%10s  %10s  %s", "offset", "size", "element"))
+  for _, e := range s.Structure() {
+    line := fmt.Sprintf("%10d  %10d  %s", e.Offset, e.Size, e.Name)
+    if e.Info != "" {
+      line += "  (" + e.Info + ")"
     }
+    fmt.Fprintln(out, line)
+    for _, c := range e.Children {
+      fmt.Fprintln(out, fmt.Sprintf("%24s  %s", "", c))
+    }
+  }
+}
+
+// every native key and value of file, prefixed by the namespace
+func showRaw(out io.Writer, file backend.File) {
+  r, ok := file.(backend.Raw)
+  if !ok {
+    fmt.Fprintln(out, "native keys not available for this file")
+    return
+  }
+  fields := r.RawFields()
+  if len(fields) == 0 {
+    fmt.Fprintln(out, "no tags")
+  }
+  for _, f := range fields {
+    fmt.Fprintln(out, f.Namespace + ":" + f.Key + "=" + f.Value)
   }
 }
 
-func showTagsFromFormat(tagValues map[string]string, format string) {
+// ShowValues prints the values of the native keys, one per line
+func ShowValues(out io.Writer, file backend.File, keys []string) error {
+  r, ok := file.(backend.Raw)
+  if !ok {
+    return errors.New("native keys are not available for this file")
+  }
+  for _, k := range keys {
+    namespace, key, err := backend.SplitRawKey(r, k)
+    if err != nil {
+      return err
+    }
+    values, err := r.RawValues(namespace, key)
+    if err != nil {
+      return err
+    }
+    for _, v := range values {
+      fmt.Fprintln(out, v)
+    }
+  }
+  return nil
+}
+
+// format specific properties, aligned to the widest name
+func showTechnical(out io.Writer, props []backend.Property, width int) {
+  for _, p := range props {
+    if len(p.Name) > width {
+      width = len(p.Name)
+    }
+  }
+  for _, p := range props {
+    fmt.Fprintln(out, fmt.Sprintf("%" + strconv.Itoa(width) + "s: %s", p.Name, p.Value))
+  }
+}
+
+// escapes of numbers padded with zeros to a width like %02k
+var paddedEscape = regexp.MustCompile(`^0([1-9][0-9]?)(.)`)
+
+// zeroPad pads a number with zeros to width, other values are kept
+func zeroPad(value string, width int) string {
+  n, err := strconv.Atoi(value)
+  if err != nil || n < 0 {
+    return value
+  }
+  return fmt.Sprintf("%0*d", width, n)
+}
+
+func showTagsFromFormat(out io.Writer, tagValues map[string]string, format string) {
   show := ""
   stl := parse.GetShortToLongMap()
 
@@ -55,6 +214,11 @@ func showTagsFromFormat(tagValues map[string]string, format string) {
       if i + w > len(format) - 1 {
         show += string(r)
 
+      } else if m := paddedEscape.FindStringSubmatch(format[i+w:]); m != nil && stl[m[2]] != "" {
+        width, _ := strconv.Atoi(m[1])
+        show += zeroPad(tagValues[stl[m[2]]], width)
+        w += len(m[0])
+
       } else {
         next, wNext := utf8.DecodeRuneInString(format[i+w:])
 
@@ -82,6 +246,6 @@ func showTagsFromFormat(tagValues map[string]string, format string) {
       "', make sure to escape quotes(\") and see" +
       " 'https://golang.org/pkg/strconv/#Unquote' for more information"
   }
-  fmt.Println(s)
+  fmt.Fprintln(out, s)
 }
 
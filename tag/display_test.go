@@ -0,0 +1,132 @@
+package tag
+
+import (
+  "bytes"
+  "strings"
+  "testing"
+  "time"
+)
+
+import (
+  backend "github.com/elias-boemeke/taggo/backend"
+  parse "github.com/elias-boemeke/taggo/parse"
+)
+
+
+
+func TestShowTags(t *testing.T) {
+  file := memory.Add("show/a.mp3", map[string]string{
+    "title":    "Title",
+    "artist":   "Artist",
+    "track":    "3",
+    "composer": "Composer",
+  }, backend.Properties{Length: 2 * time.Second, Bitrate: 128,
+    Technical: []backend.Property{{Name: "Codec", Value: "MPEG-1 Layer 3"}}})
+
+  tests := []struct {
+    name    string
+    show    parse.ShowOptions
+    want    []string
+    notWant []string
+  }{
+    {
+      name:    "default",
+      show:    parse.ShowOptions{Mode: parse.Default},
+      want:    []string{"  Title: Title", " Artist: Artist", "  Track: 3"},
+      notWant: []string{"Bitrate", "Codec"},
+    },
+    {
+      name:    "technical",
+      show:    parse.ShowOptions{Mode: parse.Technical},
+      want:    []string{"Bitrate: 128", "Length: 2s", "Codec: MPEG-1 Layer 3"},
+      notWant: []string{"Title"},
+    },
+    {
+      name: "custom",
+      show: parse.ShowOptions{Mode: parse.Custom, Format: `%r - %t (%02k)\t%%`},
+      want: []string{"Artist - Title (03)\t%"},
+    },
+    {
+      name:    "layers",
+      show:    parse.ShowOptions{Mode: parse.Layers},
+      want:    []string{"[Tag]", "Title: Title", "Track: 3", "Composer: Composer"},
+      notWant: []string{"Bitrate"},
+    },
+    {
+      name: "structure",
+      show: parse.ShowOptions{Mode: parse.Structure},
+      want: []string{"structure not available for this file"},
+    },
+    {
+      name: "raw",
+      show: parse.ShowOptions{Mode: parse.Raw},
+      want: []string{"native keys not available for this file"},
+    },
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      var out bytes.Buffer
+      ShowTags(&out, file, &tt.show)
+      for _, w := range tt.want {
+        if !strings.Contains(out.String(), w) {
+          t.Errorf("output lacks %q:\n%s", w, out.String())
+        }
+      }
+      for _, w := range tt.notWant {
+        if strings.Contains(out.String(), w) {
+          t.Errorf("output contains %q:\n%s", w, out.String())
+        }
+      }
+    })
+  }
+}
+
+// layered is a memory file with an id3v1 and an id3v2 layer
+type layered struct {
+  *backend.MemoryFile
+}
+
+func (l layered) Layers() []backend.Layer {
+  return []backend.Layer{
+    {Name: "ID3v2", Fields: map[string]string{"title": "Title", "composer": ""}},
+    {Name: "ID3v1", Fields: map[string]string{"title": "Title", "artist": ""}},
+  }
+}
+
+func TestShowLayers(t *testing.T) {
+  file := layered{memory.Add("show/layered.mp3", nil, backend.Properties{})}
+  var out bytes.Buffer
+  ShowTags(&out, file, &parse.ShowOptions{Mode: parse.Layers})
+  v2, v1 := out.String(), ""
+  if i := strings.Index(v2, "[ID3v1]"); i >= 0 {
+    v2, v1 = v2[:i], v2[i:]
+  } else {
+    t.Fatalf("output lacks the ID3v1 layer:\n%s", out.String())
+  }
+  if !strings.Contains(v2, "Composer:") || strings.Contains(v2, "Artist:") {
+    t.Errorf("ID3v2 layer lists other fields:\n%s", v2)
+  }
+  if !strings.Contains(v1, "Artist:") || strings.Contains(v1, "Composer:") {
+    t.Errorf("ID3v1 layer lists other fields:\n%s", v1)
+  }
+}
+
+func TestZeroPad(t *testing.T) {
+  tests := []struct {
+    value string
+    width int
+    want  string
+  }{
+    {"3", 2, "03"},
+    {"12", 2, "12"},
+    {"123", 2, "123"},
+    {"", 2, ""},
+    {"A", 3, "A"},
+  }
+  for _, tt := range tests {
+    if got := zeroPad(tt.value, tt.width); got != tt.want {
+      t.Errorf("zeroPad('%s', %d) = '%s', want '%s'", tt.value, tt.width, got, tt.want)
+    }
+  }
+}
@@ -0,0 +1,136 @@
+package tag
+
+import (
+  "errors"
+  "fmt"
+  "io"
+  "math"
+  "sync"
+)
+
+import (
+  backend "github.com/elias-boemeke/taggo/backend"
+  loudness "github.com/elias-boemeke/taggo/loudness"
+  parse "github.com/elias-boemeke/taggo/parse"
+)
+
+
+
+// Gain is the loudness of a file and its album measured by --replaygain,
+// loudness in LUFS and peaks as true peaks; Err is set if the file
+// couldn't be measured, it is left out of its album then
+type Gain struct {
+  Loudness      float64
+  SamplePeak    float64
+  TruePeak      float64
+  AlbumLoudness float64
+  AlbumPeak     float64
+  Err           error
+}
+
+// TrackGain returns the ReplayGain of the file in dB
+func (g *Gain) TrackGain() float64 {
+  return loudness.ReplayGainReference - g.Loudness
+}
+
+// AlbumGain returns the ReplayGain of the album in dB
+func (g *Gain) AlbumGain() float64 {
+  return loudness.ReplayGainReference - g.AlbumLoudness
+}
+
+// ScanAlbums measures the loudness of the files on jobs workers and of
+// their albums, grouped like by NumberAlbums; files which can't be read
+// are measured alone
+func ScanAlbums(files []string, op *parse.Options, jobs int) map[string]Gain {
+  meters := make([]*loudness.Meter, len(files))
+  errs := make([]error, len(files))
+  indices := make(chan int)
+  var wg sync.WaitGroup
+  for w := 0; w < jobs; w++ {
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      for i := range indices {
+        meters[i], errs[i] = loudness.ScanFile(files[i])
+      }
+    }()
+  }
+  for i := range files {
+    indices <- i
+  }
+  close(indices)
+  wg.Wait()
+
+  albums := make(map[string][]int)
+  for i, fileName := range files {
+    key := fileName
+    if file, err := ReadFile(fileName, op); err == nil {
+      key = albumKey(fileName, fieldValues(file), op)
+      file.Close()
+    }
+    if errs[i] == nil && math.IsInf(meters[i].Loudness(), -1) {
+      errs[i] = errors.New("audio is silent or shorter than 400 ms")
+    }
+    if errs[i] == nil {
+      albums[key] = append(albums[key], i)
+    }
+  }
+
+  gains := make(map[string]Gain)
+  for i, fileName := range files {
+    if errs[i] != nil {
+      gains[fileName] = Gain{Err: errs[i]}
+    }
+  }
+  for _, album := range albums {
+    var albumMeters []*loudness.Meter
+    peak := 0.0
+    for _, i := range album {
+      albumMeters = append(albumMeters, meters[i])
+      peak = math.Max(peak, meters[i].TruePeak())
+    }
+    albumLoudness := loudness.AlbumLoudness(albumMeters)
+    for _, i := range album {
+      m := meters[i]
+      gains[files[i]] = Gain{
+        Loudness:      m.Loudness(),
+        SamplePeak:    m.SamplePeak(),
+        TruePeak:      m.TruePeak(),
+        AlbumLoudness: albumLoudness,
+        AlbumPeak:     peak,
+      }
+    }
+  }
+  return gains
+}
+
+// setGain writes the ReplayGain tags of a file
+func setGain(file backend.File, g *Gain) error {
+  fields := []string{"trackgain", "trackpeak", "albumgain", "albumpeak"}
+  values := []string{
+    formatGain(g.TrackGain()), formatPeak(g.TruePeak),
+    formatGain(g.AlbumGain()), formatPeak(g.AlbumPeak),
+  }
+  for i, key := range fields {
+    if err := file.SetField(key, values[i]); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// ShowGain reports the measured loudness of a file
+func ShowGain(out io.Writer, fileName string, g *Gain) {
+  fmt.Fprintln(out, fmt.Sprintf("%s: %.2f LUFS, sample peak %s, true peak %s, track gain %s," +
+    " album gain %s", fileName, g.Loudness, formatPeak(g.SamplePeak), formatPeak(g.TruePeak),
+    formatGain(g.TrackGain()), formatGain(g.AlbumGain())))
+}
+
+// gains and peaks the way ReplayGain tags hold them
+func formatGain(gain float64) string {
+  return fmt.Sprintf("%.2f dB", gain)
+}
+
+func formatPeak(peak float64) string {
+  return fmt.Sprintf("%.6f", peak)
+}
@@ -0,0 +1,187 @@
+package tag
+
+import (
+  "errors"
+  "fmt"
+  "io"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strings"
+  "time"
+)
+
+import (
+  backend "github.com/elias-boemeke/taggo/backend"
+  lyrics "github.com/elias-boemeke/taggo/lyrics"
+  parse "github.com/elias-boemeke/taggo/parse"
+)
+
+
+
+var errNoLyrics = errors.New("lyrics are not available for this file")
+
+// ShowLyrics prints the lyrics of file, synchronised ones as lrc;
+// several lyrics are headed by their language and description
+func ShowLyrics(out io.Writer, file backend.File) error {
+  l, ok := file.(backend.Lyrical)
+  if !ok {
+    return errNoLyrics
+  }
+  list := l.Lyrics()
+  if len(list) == 0 {
+    fmt.Fprintln(out, "no lyrics")
+    return nil
+  }
+  for i, entry := range list {
+    if len(list) > 1 {
+      if i > 0 {
+        fmt.Fprintln(out)
+      }
+      fmt.Fprintln(out, "-- " + lyricsLabel(entry))
+    }
+    if len(entry.Lines) > 0 {
+      fmt.Fprint(out, (&lyrics.LRC{Lines: entry.Lines}).String())
+    } else {
+      fmt.Fprintln(out, entry.Text)
+    }
+  }
+  return nil
+}
+
+func lyricsLabel(l backend.Lyrics) string {
+  var parts []string
+  if l.Language != "" {
+    parts = append(parts, l.Language)
+  }
+  if l.Description != "" {
+    parts = append(parts, "'" + l.Description + "'")
+  }
+  if len(l.Lines) > 0 {
+    parts = append(parts, "synchronised")
+  }
+  if len(parts) == 0 {
+    return "lyrics"
+  }
+  return strings.Join(parts, ", ")
+}
+
+// ExportLyrics writes the lyrics of the language and description of the
+// options, else the first ones, to a file which must not exist; lrc files
+// get the synchronised lines behind artist, album, title and length
+func ExportLyrics(out io.Writer, file backend.File, fileName string,
+    op *parse.LyricsOptions) error {
+  l, ok := file.(backend.Lyrical)
+  if !ok {
+    return errNoLyrics
+  }
+  path, _ := sidecarPath(fileName, op.Export)
+  isLRC := strings.EqualFold(filepath.Ext(path), ".lrc")
+  entry, found := pickLyrics(l.Lyrics(), op)
+  switch {
+  case !found:
+    fmt.Fprintln(out, "no lyrics to export")
+    return nil
+  case isLRC && len(entry.Lines) == 0:
+    fmt.Fprintln(out, "no synchronised lyrics to export")
+    return nil
+  }
+
+  text := entry.Text
+  if len(entry.Lines) > 0 && !isLRC {
+    text = lyrics.Plain(entry.Lines)
+  }
+  if isLRC {
+    lrc := &lyrics.LRC{Lines: entry.Lines}
+    fields := file.Fields()
+    for _, m := range [][2]string{{"ar", "artist"}, {"al", "album"}, {"ti", "title"}} {
+      if v := fields[m[1]]; v != "" {
+        lrc.Meta = append(lrc.Meta, lyrics.Meta{Key: m[0], Value: v})
+      }
+    }
+    if length := file.Properties().Length; length > 0 {
+      s := int(length / time.Second)
+      lrc.Meta = append(lrc.Meta, lyrics.Meta{Key: "length",
+        Value: fmt.Sprintf("%02d:%02d", s / 60, s % 60)})
+    }
+    text = lrc.String()
+  } else if !strings.HasSuffix(text, "\n") {
+    text += "\n"
+  }
+
+  w, err := os.OpenFile(path, os.O_WRONLY | os.O_CREATE | os.O_EXCL, 0644)
+  if os.IsExist(err) {
+    return errors.New(fmt.Sprintf("lyrics file '%s' exists already", path))
+  } else if err != nil {
+    return err
+  }
+  _, err = w.Write([]byte(text))
+  if cerr := w.Close(); err == nil {
+    err = cerr
+  }
+  if err != nil {
+    return err
+  }
+  fmt.Fprintln(out, "exported " + path)
+  return nil
+}
+
+// pickLyrics returns the lyrics matching the options or the first ones
+func pickLyrics(list []backend.Lyrics, op *parse.LyricsOptions) (backend.Lyrics, bool) {
+  if len(list) == 0 {
+    return backend.Lyrics{}, false
+  }
+  for _, l := range list {
+    if matchesLyrics(l, op) {
+      return l, true
+    }
+  }
+  return list[0], true
+}
+
+// matchesLyrics compares language and description, lyrics of formats
+// without language match any
+func matchesLyrics(l backend.Lyrics, op *parse.LyricsOptions) bool {
+  return l.Description == op.Description &&
+    (l.Language == "" || strings.EqualFold(l.Language, op.Language))
+}
+
+// editLyrics clears the lyrics of file and imports a lyrics file, which
+// replaces those of the same language and description; a file given by
+// extension only which does not exist is skipped
+func editLyrics(file backend.File, fileName string, op *parse.LyricsOptions) error {
+  l, ok := file.(backend.Lyrical)
+  if !ok {
+    return errNoLyrics
+  }
+  var kept []backend.Lyrics
+  if !op.Clear {
+    kept = l.Lyrics()
+  }
+  if op.Import == "" {
+    return l.SetLyrics(kept)
+  }
+
+  path, sibling := sidecarPath(fileName, op.Import)
+  data, err := ioutil.ReadFile(path)
+  if sibling && os.IsNotExist(err) {
+    return l.SetLyrics(kept)
+  } else if err != nil {
+    return err
+  }
+  text := strings.TrimRight(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+  text = strings.TrimPrefix(text, "\uFEFF")
+  entry := backend.Lyrics{Language: op.Language, Description: op.Description, Text: text}
+  if lyrics.IsLRC(text) {
+    entry.Lines = lyrics.Parse(text).Lines
+    entry.Text = lyrics.Plain(entry.Lines)
+  }
+
+  var result []backend.Lyrics
+  for _, k := range kept {
+    if !matchesLyrics(k, op) {
+      result = append(result, k)
+    }
+  }
+  return l.SetLyrics(append(result, entry))
+}
@@ -0,0 +1,127 @@
+package tag
+
+import (
+  "path/filepath"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+import (
+  backend "github.com/elias-boemeke/taggo/backend"
+  parse "github.com/elias-boemeke/taggo/parse"
+)
+
+
+
+// Numbers are the track and disc numbers given to a file by --number,
+// a file without disc keeps disc and disc total
+type Numbers struct {
+  Track      int
+  TrackTotal int
+  Disc       int
+  DiscTotal  int
+}
+
+type albumFile struct {
+  name  string
+  disc  int
+  track int
+}
+
+// NumberAlbums numbers the files of each album, which are the files of a
+// directory sharing the album tag as it is after the edits; the files of
+// each disc are ordered by name or by their track tags and numbered from
+// one, the totals count the tracks of a disc and the discs of the album;
+// files which can't be read are left out, they fail when processed
+func NumberAlbums(files []string, op *parse.Options) map[string]Numbers {
+  albums := make(map[string][]albumFile)
+  var order []string
+  for _, fileName := range files {
+    file, err := ReadFile(fileName, op)
+    if err != nil {
+      continue
+    }
+    values := fieldValues(file)
+    file.Close()
+
+    key := albumKey(fileName, values, op)
+    if _, ok := albums[key]; !ok {
+      order = append(order, key)
+    }
+    f := albumFile{name: fileName}
+    f.disc, _ = strconv.Atoi(backend.FirstOf(op.Tags["disc"].Apply(values["disc"])))
+    f.track, _ = strconv.Atoi(backend.FirstOf(values["track"]))
+    albums[key] = append(albums[key], f)
+  }
+
+  numbers := make(map[string]Numbers)
+  for _, key := range order {
+    album := albums[key]
+    discs := 0
+    for _, f := range album {
+      if f.disc > discs {
+        discs = f.disc
+      }
+    }
+    // files without disc belong to the first one if others have a disc
+    for i := range album {
+      if album[i].disc == 0 && discs > 0 {
+        album[i].disc = 1
+      }
+    }
+    sort.SliceStable(album, func(i, j int) bool {
+      a, b := album[i], album[j]
+      if a.disc != b.disc {
+        return a.disc < b.disc
+      }
+      // files without track follow the numbered ones
+      if op.Number == "tags" && a.track != b.track {
+        return b.track == 0 || (a.track != 0 && a.track < b.track)
+      }
+      return filepath.Base(a.name) < filepath.Base(b.name)
+    })
+
+    tracks := make(map[int]int)
+    for _, f := range album {
+      tracks[f.disc]++
+    }
+    counted := make(map[int]int)
+    for _, f := range album {
+      counted[f.disc]++
+      n := Numbers{Track: counted[f.disc], TrackTotal: tracks[f.disc]}
+      if f.disc > 0 {
+        n.Disc, n.DiscTotal = f.disc, discs
+      }
+      numbers[f.name] = n
+    }
+  }
+  return numbers
+}
+
+// albumKey identifies the album of a file, its directory and
+// the album tag as it is after the edits
+func albumKey(fileName string, values map[string][]string, op *parse.Options) string {
+  album := strings.Join(op.Tags["album"].Apply(values["album"]), backend.ValueSeparator)
+  return filepath.Dir(fileName) + "\x00" + album
+}
+
+// setNumbers writes the numbers of a file, a zero disc is not written
+// and neither are fields the backend of the file can't hold
+func setNumbers(file backend.File, capabilities backend.Capabilities, n *Numbers) error {
+  fields := []string{"track", "tracktotal"}
+  values := []int{n.Track, n.TrackTotal}
+  if n.Disc > 0 {
+    fields = append(fields, "disc", "disctotal")
+    values = append(values, n.Disc, n.DiscTotal)
+  }
+  for i, key := range fields {
+    if !capabilities.CanWrite(key) {
+      continue
+    }
+    if err := file.SetField(key, strconv.Itoa(values[i])); err != nil {
+      return err
+    }
+  }
+  return nil
+}
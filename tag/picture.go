@@ -0,0 +1,248 @@
+package tag
+
+import (
+  "crypto/sha256"
+  "errors"
+  "fmt"
+  "io"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+)
+
+import (
+  backend "github.com/elias-boemeke/taggo/backend"
+  cover "github.com/elias-boemeke/taggo/cover"
+  parse "github.com/elias-boemeke/taggo/parse"
+)
+
+
+
+var errNoPictures = errors.New("pictures are not available for this file")
+
+// ShowPictures lists the pictures of file, their index is the one
+// used by --replace-picture and --remove-picture
+func ShowPictures(out io.Writer, file backend.File) error {
+  p, ok := file.(backend.Pictured)
+  if !ok {
+    return errNoPictures
+  }
+  pictures := p.Pictures()
+  if len(pictures) == 0 {
+    fmt.Fprintln(out, "no pictures")
+    return nil
+  }
+
+  format := "%3s  %-14s  %-10s  %10s  %10s  %s"
+  fmt.Fprintln(out, fmt.Sprintf(format, "#", "type", "mime", "bytes", "dimensions",
+    "description"))
+  for i, pic := range pictures {
+    dimensions := "unknown"
+    if _, w, h, err := backend.ImageInfo(pic.Data); err == nil {
+      dimensions = fmt.Sprintf("%dx%d", w, h)
+    }
+    fmt.Fprintln(out, fmt.Sprintf(format, strconv.Itoa(i + 1), pictureTypeName(pic.Type),
+      pic.MIME, strconv.Itoa(len(pic.Data)), dimensions, pic.Description))
+  }
+  return nil
+}
+
+// ExtractPictures writes the pictures of file to dir as NAME-INDEX-TYPE.EXT,
+// NAME being the file name without extension; existing files are not
+// overwritten
+func ExtractPictures(out io.Writer, file backend.File, fileName string, dir string) error {
+  p, ok := file.(backend.Pictured)
+  if !ok {
+    return errNoPictures
+  }
+  pictures := p.Pictures()
+  if len(pictures) == 0 {
+    return nil
+  }
+  if err := os.MkdirAll(dir, 0755); err != nil {
+    return err
+  }
+
+  base := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+  for i, pic := range pictures {
+    path := filepath.Join(dir, fmt.Sprintf("%s-%d-%s%s", base, i + 1,
+      pictureTypeName(pic.Type), backend.ExtensionOf(pic.MIME)))
+    w, err := os.OpenFile(path, os.O_WRONLY | os.O_CREATE | os.O_EXCL, 0644)
+    if os.IsExist(err) {
+      return errors.New(fmt.Sprintf("picture '%s' exists already", path))
+    } else if err != nil {
+      return err
+    }
+    _, err = w.Write(pic.Data)
+    if cerr := w.Close(); err == nil {
+      err = cerr
+    }
+    if err != nil {
+      return err
+    }
+    fmt.Fprintln(out, "extracted " + path)
+  }
+  return nil
+}
+
+// editPictures removes, replaces and adds the pictures of file;
+// selectors refer to the pictures as read, an index out of range
+// is an error while a type without pictures is not
+func editPictures(file backend.File, op *parse.PictureOptions) error {
+  p, ok := file.(backend.Pictured)
+  if !ok {
+    return errNoPictures
+  }
+  pictures := p.Pictures()
+
+  removed := make([]bool, len(pictures))
+  for _, s := range op.Remove {
+    matches, err := selectPictures(pictures, s)
+    if err != nil {
+      return err
+    }
+    for _, i := range matches {
+      removed[i] = true
+    }
+  }
+
+  var added []backend.Picture
+  for _, r := range op.Replace {
+    matches, err := selectPictures(pictures, r.Selector)
+    if err != nil {
+      return err
+    }
+    pic, err := loadPicture(r.File)
+    if err != nil {
+      return err
+    }
+    // a type nothing is found for gets a new picture
+    if len(matches) == 0 {
+      pic.Type = parse.PictureType(r.Selector)
+      added = append(added, withOptions(pic, op))
+    }
+    for _, i := range matches {
+      pic.Type, pic.Description = pictures[i].Type, pictures[i].Description
+      pictures[i] = withOptions(pic, op)
+    }
+  }
+
+  for _, name := range op.Add {
+    pic, err := loadPicture(name)
+    if err != nil {
+      return err
+    }
+    pic.Type = backend.PictureFront
+    added = append(added, withOptions(pic, op))
+  }
+
+  var result []backend.Picture
+  for i, pic := range pictures {
+    if !removed[i] {
+      result = append(result, pic)
+    }
+  }
+  return p.SetPictures(append(result, added...))
+}
+
+// NormalisePictures drops pictures whose content equals an earlier one and
+// shrinks the others with cover.Normalise, the file is saved if anything
+// changed; the savings are reported to out and the bytes saved returned
+func NormalisePictures(out io.Writer, file backend.File, op *parse.PictureOptions) (int64, error) {
+  p, ok := file.(backend.Pictured)
+  if !ok {
+    return 0, errNoPictures
+  }
+  pictures := p.Pictures()
+  if len(pictures) == 0 {
+    fmt.Fprintln(out, "no pictures")
+    return 0, nil
+  }
+
+  var before, after int64
+  var kept []backend.Picture
+  seen := make(map[[sha256.Size]byte]bool)
+  changed := false
+  for _, pic := range pictures {
+    before += int64(len(pic.Data))
+    sum := sha256.Sum256(pic.Data)
+    if seen[sum] {
+      changed = true
+      continue
+    }
+    seen[sum] = true
+    if data, mime, ok := cover.Normalise(pic.Data, op.MaxSize, op.Quality); ok {
+      // descriptions used as file names follow the new format
+      if old := backend.ExtensionOf(pic.MIME); strings.HasSuffix(pic.Description, old) {
+        pic.Description = strings.TrimSuffix(pic.Description, old) + backend.ExtensionOf(mime)
+      }
+      pic.Data, pic.MIME = data, mime
+      changed = true
+    }
+    after += int64(len(pic.Data))
+    kept = append(kept, pic)
+  }
+
+  fmt.Fprintln(out, fmt.Sprintf("%d of %d picture(s) kept, %d -> %d bytes, %d bytes saved",
+    len(kept), len(pictures), before, after, before - after))
+  if !changed {
+    return 0, nil
+  }
+  if err := p.SetPictures(kept); err != nil {
+    return 0, err
+  }
+  return before - after, file.Save()
+}
+
+// selectPictures returns the indices of the pictures matching
+// a 1 based index or the name of a picture type
+func selectPictures(pictures []backend.Picture, selector string) ([]int, error) {
+  if n, err := strconv.Atoi(selector); err == nil {
+    if n < 1 || n > len(pictures) {
+      return nil, errors.New(fmt.Sprintf("there is no picture %d, the file has %d",
+        n, len(pictures)))
+    }
+    return []int{n - 1}, nil
+  }
+  var matches []int
+  for i, pic := range pictures {
+    if pictureTypeName(pic.Type) == selector {
+      matches = append(matches, i)
+    }
+  }
+  return matches, nil
+}
+
+// withOptions applies --picture-type and --picture-description
+func withOptions(pic backend.Picture, op *parse.PictureOptions) backend.Picture {
+  if op.Type >= 0 {
+    pic.Type = op.Type
+  }
+  if op.Description != "" {
+    pic.Description = op.Description
+  }
+  return pic
+}
+
+// loadPicture reads a jpeg, png or gif image
+func loadPicture(name string) (backend.Picture, error) {
+  data, err := ioutil.ReadFile(name)
+  if err != nil {
+    return backend.Picture{}, err
+  }
+  mime, _, _, err := backend.ImageInfo(data)
+  if err != nil {
+    return backend.Picture{}, errors.New(fmt.Sprintf("'%s' is not a jpeg, png or gif image",
+      name))
+  }
+  return backend.Picture{MIME: mime, Data: data}, nil
+}
+
+func pictureTypeName(t int) string {
+  if t >= 0 && t < len(parse.PictureTypes) {
+    return parse.PictureTypes[t]
+  }
+  return strconv.Itoa(t)
+}
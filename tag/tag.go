@@ -1,84 +1,169 @@
 package tag
 
 import (
+  "errors"
+  "fmt"
+  "path/filepath"
+  "regexp"
   "strconv"
+  "strings"
 )
 
 import (
-  taglib "github.com/wtolson/go-taglib"
-)
-
-import (
+  backend "github.com/elias-boemeke/taggo/backend"
   parse "github.com/elias-boemeke/taggo/parse"
 )
 
 
 
-func ReadFile(fileName string) *taglib.File {
-  file, err := taglib.Read(fileName)
+// a file given like .lrc is the one next to each audio file
+var extensionOnly = regexp.MustCompile(`^\.[a-zA-Z0-9]+$`)
 
-  if err != nil {
-    parse.LogErrorAndDie(parse.NoRefManual, "unable to read file '%s': %s", fileName, err)
+// sidecarPath returns the file name belonging to fileName and whether
+// it was given by extension only
+func sidecarPath(fileName string, name string) (string, bool) {
+  if !extensionOnly.MatchString(name) {
+    return name, false
   }
+  return strings.TrimSuffix(fileName, filepath.Ext(fileName)) + name, true
+}
+
+// ReadFile opens fileName with the backend given in the options,
+// if there is none the backend is selected by the content of the file
+func ReadFile(fileName string, op *parse.Options) (backend.File, error) {
+  config := backend.Config{
+    ID3v2Version: byte(op.Write.ID3v2Version),
+    Padding:      op.Write.Padding,
+    ID3Mode:      op.Write.ID3Mode,
+    // normalised pictures are meant to make files smaller
+    Compact:      op.Pictures.Normalise,
+  }
+  file, err := backend.Open(fileName, op.Backend, config)
 
-  if file == nil {
-    parse.LogErrorAndDie(parse.NoRefManual, "unable to read file '%s'", fileName)
+  if err != nil {
+    return nil, errors.New(fmt.Sprintf("unable to read file '%s': %s", fileName, err))
   }
 
-  return file
+  return file, nil
 }
 
-func WriteTags(file *taglib.File, op *parse.Options) error {
-  forceInt := func(s string) int {
-    n, _ := strconv.Atoi(s)
-    return n
+// WriteTags applies the edits of the options to file at fileName, which
+// locates lyrics files given by extension; numbers are those given
+// by --number and gain is the one measured by --replaygain, nil if
+// not given
+func WriteTags(file backend.File, fileName string, op *parse.Options, numbers *Numbers,
+    gain *Gain) error {
+  // converting between tag versions is a change of its own
+  changed := op.Write.Converts()
+  // values as read, added to and removed from by the edits
+  current := fieldValues(file)
+  for _, t := range parse.GetTagInfo() {
+    opt, ok := op.Tags[t.Long]
+    if !ok || !opt.Changes() {
+      continue
+    }
+    values := opt.Apply(current[t.Long])
+    var err error
+    if m, ok := file.(backend.MultiValued); ok {
+      err = m.SetFieldValues(t.Long, values)
+    } else {
+      err = file.SetField(t.Long, strings.Join(values, backend.ValueSeparator))
+    }
+    if err != nil {
+      return err
+    }
+    changed = true
   }
 
-  if op.Tags["album"].Set {
-    file.SetAlbum(op.Tags["album"].Value)
-  }
-  if op.Tags["artist"].Set {
-    file.SetArtist(op.Tags["artist"].Value)
+  if numbers != nil {
+    b, err := backend.Choose(fileName, op.Backend)
+    if err != nil {
+      return err
+    }
+    if err := setNumbers(file, b.Capabilities(), numbers); err != nil {
+      return err
+    }
+    changed = true
   }
-  if op.Tags["comment"].Set {
-    file.SetComment(op.Tags["comment"].Value)
+
+  if gain != nil {
+    if err := setGain(file, gain); err != nil {
+      return err
+    }
+    changed = true
   }
-  if op.Tags["genre"].Set {
-    file.SetGenre(op.Tags["genre"].Value)
+
+  if op.Pictures.Changes() {
+    if err := editPictures(file, &op.Pictures); err != nil {
+      return err
+    }
+    changed = true
   }
-  if op.Tags["title"].Set {
-    file.SetTitle(op.Tags["title"].Value)
+
+  if op.Lyrics.Changes() {
+    if err := editLyrics(file, fileName, &op.Lyrics); err != nil {
+      return err
+    }
+    changed = true
   }
-  if op.Tags["track"].Set {
-    file.SetTrack(forceInt(op.Tags["track"].Value))
+
+  if op.Chapters.Changes() {
+    if err := editChapters(file, fileName, &op.Chapters); err != nil {
+      return err
+    }
+    changed = true
   }
-  if op.Tags["year"].Set {
-    file.SetYear(forceInt(op.Tags["year"].Value))
+
+  // native keys are set after the fields and take precedence
+  if len(op.Raw.Set) > 0 {
+    r, ok := file.(backend.Raw)
+    if !ok {
+      return errors.New("native keys are not available for this file")
+    }
+    for _, e := range op.Raw.Set {
+      namespace, key, err := backend.SplitRawKey(r, e.Key)
+      if err != nil {
+        return err
+      }
+      if err := r.SetRaw(namespace, key, e.Values); err != nil {
+        return err
+      }
+    }
+    changed = true
   }
 
-  err := file.Save()
-  return err
+  if !changed {
+    return nil
+  }
+  return file.Save()
 }
 
-func tagValuesFromFile(file *taglib.File) map[string]string {
-  values := make(map[string]string)
-  strHideZero := func(n int) string {
-    if n == 0 {
-      return ""
+// fieldValues returns the values of the fields of file,
+// files holding one value per field give lists of one
+func fieldValues(file backend.File) map[string][]string {
+  if m, ok := file.(backend.MultiValued); ok {
+    return m.FieldValues()
+  }
+  values := make(map[string][]string)
+  for k, v := range file.Fields() {
+    if v != "" {
+      values[k] = []string{v}
     }
-    return strconv.Itoa(n)
   }
-  values["album"]      = file.Album()
-  values["artist"]     = file.Artist()
-  values["bitrate"]    = strconv.Itoa(file.Bitrate())
-  values["channels"]   = strconv.Itoa(file.Channels())
-  values["comment"]    = file.Comment()
-  values["genre"]      = file.Genre()
-  values["length"]     = file.Length().String()
-  values["samplerate"] = strconv.Itoa(file.Samplerate())
-  values["title"]      = file.Title()
-  values["track"]      = strHideZero(file.Track())
-  values["year"]       = strHideZero(file.Year())
   return values
 }
 
+// tagValuesFromFile returns the fields of file, several values joined
+// by separator, and its properties
+func tagValuesFromFile(file backend.File, separator string) map[string]string {
+  values := make(map[string]string)
+  for k, v := range fieldValues(file) {
+    values[k] = strings.Join(v, separator)
+  }
+  props := file.Properties()
+  values["bitrate"]    = strconv.Itoa(props.Bitrate)
+  values["channels"]   = strconv.Itoa(props.Channels)
+  values["length"]     = props.Length.String()
+  values["samplerate"] = strconv.Itoa(props.Samplerate)
+  return values
+}
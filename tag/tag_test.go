@@ -0,0 +1,231 @@
+package tag
+
+import (
+  "reflect"
+  "testing"
+  "time"
+)
+
+import (
+  backend "github.com/elias-boemeke/taggo/backend"
+  parse "github.com/elias-boemeke/taggo/parse"
+)
+
+
+
+// the files of the tests are kept by a memory backend selected by name
+var memory = backend.NewMemory()
+
+// basic keeps files like memory but writes the basic fields only
+type basic struct {
+  *backend.Memory
+}
+
+func (basic) Name() string {
+  return "basic"
+}
+
+func (b basic) Capabilities() backend.Capabilities {
+  c := b.Memory.Capabilities()
+  c.Fields = backend.BasicFields
+  return c
+}
+
+func init() {
+  backend.Register(memory, 1000)
+  backend.Register(basic{memory}, 1001)
+}
+
+// options parses args for files of the memory backend
+func options(t *testing.T, args ...string) *parse.Options {
+  op, err := parse.ParseArgs(append(args, "--backend", "memory"))
+  if err != nil {
+    t.Fatalf("parsing %q failed: %s", args, err)
+  }
+  return op
+}
+
+func TestWriteTags(t *testing.T) {
+  tests := []struct {
+    name   string
+    values map[string]string
+    args   []string
+    want   map[string]string
+    saves  int
+  }{
+    {
+      name:   "set",
+      values: map[string]string{"title": "Old"},
+      args:   []string{"-t", "New", "-r", "Artist"},
+      want:   map[string]string{"title": "New", "artist": "Artist"},
+      saves:  1,
+    },
+    {
+      name:   "several values",
+      values: map[string]string{},
+      args:   []string{"-r", "One", "-r", "Two"},
+      want:   map[string]string{"artist": "One; Two"},
+      saves:  1,
+    },
+    {
+      name:   "add",
+      values: map[string]string{"genre": "Rock"},
+      args:   []string{"--add-genre", "Pop"},
+      want:   map[string]string{"genre": "Rock; Pop"},
+      saves:  1,
+    },
+    {
+      name:   "add and remove",
+      values: map[string]string{"genre": "Rock"},
+      args:   []string{"--add-genre", "Pop", "--add-genre", "Jazz", "--remove-genre", "Pop"},
+      want:   map[string]string{"genre": "Rock; Jazz"},
+      saves:  1,
+    },
+    {
+      name:   "clear",
+      values: map[string]string{"title": "Old", "album": "Album"},
+      args:   []string{"--clear-title"},
+      want:   map[string]string{"title": "", "album": "Album"},
+      saves:  1,
+    },
+    {
+      name:   "extended fields",
+      values: map[string]string{},
+      args:   []string{"-m", "Composer", "-B", "120", "--albumartist", "Various"},
+      want:   map[string]string{"composer": "Composer", "bpm": "120", "albumartist": "Various"},
+      saves:  1,
+    },
+    {
+      name:   "number with total",
+      values: map[string]string{},
+      args:   []string{"-k", "3/12"},
+      want:   map[string]string{"track": "3", "tracktotal": "12"},
+      saves:  1,
+    },
+    {
+      name:   "date",
+      values: map[string]string{"date": "1999"},
+      args:   []string{"-Y", "2021-03-05"},
+      want:   map[string]string{"date": "2021-03-05"},
+      saves:  1,
+    },
+    {
+      name:   "unchanged",
+      values: map[string]string{"title": "Old"},
+      args:   []string{"-s"},
+      want:   map[string]string{"title": "Old"},
+      saves:  0,
+    },
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      path := "write/" + tt.name + ".mp3"
+      memory.Add(path, tt.values, backend.Properties{})
+      op := options(t, append(tt.args, path)...)
+
+      file, err := ReadFile(path, op)
+      if err != nil {
+        t.Fatal(err)
+      }
+      if err := WriteTags(file, path, op, nil, nil); err != nil {
+        t.Fatal(err)
+      }
+      file.Close()
+
+      m := memory.Files[path]
+      if m.Saves != tt.saves {
+        t.Errorf("saved %d times, want %d", m.Saves, tt.saves)
+      }
+      saved := m.Saved()
+      for k, v := range tt.want {
+        if saved[k] != v {
+          t.Errorf("field '%s' is '%s', want '%s'", k, saved[k], v)
+        }
+      }
+    })
+  }
+}
+
+func TestWriteTagsNumbers(t *testing.T) {
+  tests := []struct {
+    backend string
+    want    map[string]string
+  }{
+    {"memory", map[string]string{"track": "2", "tracktotal": "5", "disc": "1", "disctotal": "2"}},
+    // disc and totals are left out where the backend can't write them
+    {"basic", map[string]string{"track": "2"}},
+  }
+  for _, tt := range tests {
+    path := "numbers/" + tt.backend + ".mp3"
+    memory.Add(path, map[string]string{"track": "9"}, backend.Properties{})
+    op := options(t, "--number", "name", path)
+    op.Backend = tt.backend
+    file, err := ReadFile(path, op)
+    if err != nil {
+      t.Fatal(err)
+    }
+    defer file.Close()
+
+    n := Numbers{Track: 2, TrackTotal: 5, Disc: 1, DiscTotal: 2}
+    if err := WriteTags(file, path, op, &n, nil); err != nil {
+      t.Fatalf("%s: %s", tt.backend, err)
+    }
+    if saved := memory.Files[path].Saved(); !reflect.DeepEqual(saved, tt.want) {
+      t.Errorf("%s: saved %v, want %v", tt.backend, saved, tt.want)
+    }
+  }
+}
+
+func TestNumberAlbums(t *testing.T) {
+  files := []string{"album/c.mp3", "album/a.mp3", "album/b.mp3", "other/a.mp3"}
+  for _, f := range files {
+    memory.Add(f, map[string]string{"album": "Album"}, backend.Properties{})
+  }
+  op := options(t, append([]string{"--number", "name"}, files...)...)
+
+  numbers := NumberAlbums(files, op)
+  want := map[string]Numbers{
+    "album/a.mp3": {Track: 1, TrackTotal: 3},
+    "album/b.mp3": {Track: 2, TrackTotal: 3},
+    "album/c.mp3": {Track: 3, TrackTotal: 3},
+    "other/a.mp3": {Track: 1, TrackTotal: 1},
+  }
+  if !reflect.DeepEqual(numbers, want) {
+    t.Errorf("numbers %v, want %v", numbers, want)
+  }
+}
+
+func TestFieldValues(t *testing.T) {
+  file := memory.Add("values/a.mp3", map[string]string{
+    "title":  "Title",
+    "artist": "One; Two",
+    "album":  "",
+  }, backend.Properties{Length: 90 * time.Second, Bitrate: 320})
+
+  want := map[string][]string{
+    "title":  {"Title"},
+    "artist": {"One; Two"},
+  }
+  if values := fieldValues(file); !reflect.DeepEqual(values, want) {
+    t.Errorf("values %v, want %v", values, want)
+  }
+  values := tagValuesFromFile(file, " / ")
+  if values["artist"] != "One; Two" || values["bitrate"] != "320" || values["length"] != "1m30s" {
+    t.Errorf("values %v", values)
+  }
+}
+
+func TestWriteTagsRaw(t *testing.T) {
+  path := "raw/a.mp3"
+  memory.Add(path, map[string]string{}, backend.Properties{})
+  op := options(t, "--set", "TIT2=Title", path)
+  file, err := ReadFile(path, op)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer file.Close()
+  if err := WriteTags(file, path, op, nil, nil); err == nil || memory.Files[path].Saves != 0 {
+    t.Errorf("native keys written to a file without them: %v", err)
+  }
+}
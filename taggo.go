@@ -1,12 +1,20 @@
 package main
 
 import (
+  "bytes"
+  "errors"
+  "fmt"
+  "io"
   "os"
+  "sync/atomic"
 )
 
 import (
+  checksum  "github.com/elias-boemeke/taggo/checksum"
+  detect  "github.com/elias-boemeke/taggo/detect"
   parse  "github.com/elias-boemeke/taggo/parse"
   tag  "github.com/elias-boemeke/taggo/tag"
+  walk  "github.com/elias-boemeke/taggo/walk"
 )
 
 
@@ -17,72 +25,425 @@ func main() {
     parse.LogErrorAndDie(parse.RefManual, "parsing of arguments failed: %s", err)
   }
 
-  fileName := options.Filename
-  file := tag.ReadFile(fileName)
+  var manifest *checksum.Manifest
+  if options.Verify != "" {
+    manifest, err = checksum.ReadManifest(options.Verify)
+    if err != nil {
+      parse.LogErrorAndDie(parse.NoRefManual, "unable to read manifest: %s", err)
+    }
+  }
+
+  files := walk.Collect(options.Files, &options.Walk)
+  if manifest != nil && len(options.Files) == 0 {
+    files = manifest.Files
+  }
+  if len(files) == 0 {
+    parse.LogErrorAndDie(parse.NoRefManual, "no audio files found")
+  }
+
+  // albums are numbered and measured before any file is written
+  var numbers map[string]tag.Numbers
+  if options.Number != "" {
+    numbers = tag.NumberAlbums(files, options)
+  }
+  var gains map[string]tag.Gain
+  if options.ReplayGain {
+    gains = tag.ScanAlbums(files, options, options.Jobs)
+  }
+
+  errs := processFiles(files, options, numbers, gains, manifest)
+  if options.Pictures.Normalise && len(files) > 1 {
+    fmt.Println(fmt.Sprintf("\n%d bytes saved in total", savedBytes))
+  }
+
+  for _, err := range errs {
+    parse.LogError("%s", err)
+  }
+  var missing []string
+  if manifest != nil {
+    missing = manifest.Missing(files)
+  }
+  for _, fileName := range missing {
+    parse.LogError("file '%s' of the manifest is missing", fileName)
+  }
+  if len(errs) > 0 {
+    parse.LogErrorAndDie(parse.NoRefManual, "%d of %d file(s) failed",
+      len(errs), len(files))
+  }
+  if len(missing) > 0 {
+    parse.LogErrorAndDie(parse.NoRefManual, "%d file(s) of the manifest missing", len(missing))
+  }
+}
+
+// bytes saved by --normalise-pictures over all files
+var savedBytes int64
+
+type result struct {
+  output bytes.Buffer
+  err    error
+  done   chan struct{}
+}
+
+// processFiles runs processFile on a pool of options.Jobs workers;
+// output is printed in the order of files as soon as it is available
+// and errors are returned in the same order
+func processFiles(files []string, options *parse.Options, numbers map[string]tag.Numbers,
+    gains map[string]tag.Gain, manifest *checksum.Manifest) []error {
+  multiple := len(files) > 1
+  results := make([]*result, len(files))
+  for i := range results {
+    results[i] = &result{done: make(chan struct{})}
+  }
+
+  jobs := make(chan int)
+  for w := 0; w < options.Jobs; w++ {
+    go func() {
+      for i := range jobs {
+        r := results[i]
+        if multiple && options.Displays() && i > 0 {
+          fmt.Fprintln(&r.output)
+        }
+        var n *tag.Numbers
+        if x, ok := numbers[files[i]]; ok {
+          n = &x
+        }
+        var g *tag.Gain
+        if x, ok := gains[files[i]]; ok {
+          g = &x
+        }
+        r.err = recoverFile(files[i], func() error {
+          return processFile(&r.output, files[i], options, multiple, n, g, manifest)
+        })
+        close(r.done)
+      }
+    }()
+  }
+  go func() {
+    for i := range files {
+      jobs <- i
+    }
+    close(jobs)
+  }()
+
+  var errs []error
+  for _, r := range results {
+    <-r.done
+    os.Stdout.Write(r.output.Bytes())
+    if r.err != nil {
+      errs = append(errs, r.err)
+    }
+  }
+  return errs
+}
+
+// recoverFile runs process, reporting a panic as the error of the file
+// so that a corrupt file doesn't stop the others
+func recoverFile(fileName string, process func() error) (err error) {
+  defer func() {
+    if p := recover(); p != nil {
+      err = errors.New(fmt.Sprintf("failed to process file '%s': %v", fileName, p))
+    }
+  }()
+  return process()
+}
+
+func processFile(out io.Writer, fileName string, options *parse.Options, header bool,
+    numbers *tag.Numbers, gain *tag.Gain, manifest *checksum.Manifest) error {
+  if gain != nil && gain.Err != nil {
+    return errors.New(fmt.Sprintf("failed to measure loudness of file '%s': %s", fileName, gain.Err))
+  }
+  if options.Detect || options.FixExtension {
+    report, err := detect.Inspect(fileName)
+    if err != nil {
+      return err
+    }
+    if options.Detect {
+      tag.ShowDetection(out, fileName, report)
+    }
+    if options.FixExtension {
+      fixed, err := detect.FixExtension(fileName, report)
+      if err != nil {
+        return err
+      }
+      if fixed != fileName {
+        fmt.Fprintln(out, fmt.Sprintf("renamed '%s' to '%s'", fileName, fixed))
+        fileName = fixed
+      }
+    }
+    if !options.Displays() && !options.Edits() && options.Checksum == "" && manifest == nil {
+      return nil
+    }
+  }
+
+  if options.Checksum != "" {
+    sum, err := checksum.Sum(fileName, options.Checksum)
+    if err != nil {
+      return errors.New(fmt.Sprintf("failed to checksum audio of file '%s': %s", fileName, err))
+    }
+    fmt.Fprintln(out, checksum.Line(sum, fileName))
+  }
+  if manifest != nil {
+    if err := manifest.Verify(fileName); err != nil {
+      return errors.New(fmt.Sprintf("failed to verify audio of file '%s': %s", fileName, err))
+    }
+    fmt.Fprintln(out, fileName + ": OK")
+  }
+  if (options.Checksum != "" || manifest != nil) && !options.Displays() && !options.Edits() {
+    return nil
+  }
+
+  file, err := tag.ReadFile(fileName, options)
+  if err != nil {
+    return err
+  }
   defer file.Close()
 
-  err = tag.WriteTags(file, options)
+  err = tag.WriteTags(file, fileName, options, numbers, gain)
   if err != nil {
-    parse.LogErrorAndDie(parse.NoRefManual, "failed to write tags: %s", err)
+    return errors.New(fmt.Sprintf("failed to write tags of file '%s': %s", fileName, err))
   }
 
+  if header && options.Displays() {
+    tag.ShowHeader(out, fileName)
+  }
+  if options.Pictures.Normalise {
+    saved, err := tag.NormalisePictures(out, file, &options.Pictures)
+    if err != nil {
+      return errors.New(fmt.Sprintf("failed to normalise pictures of file '%s': %s", fileName, err))
+    }
+    atomic.AddInt64(&savedBytes, saved)
+  }
+  if gain != nil {
+    tag.ShowGain(out, fileName, gain)
+  }
   if options.Show.Set {
-    tag.ShowTags(file, &options.Show)
+    tag.ShowTags(out, file, &options.Show)
+  }
+  if options.Pictures.List {
+    if err := tag.ShowPictures(out, file); err != nil {
+      return errors.New(fmt.Sprintf("failed to list pictures of file '%s': %s", fileName, err))
+    }
+  }
+  if options.Pictures.Extract != "" {
+    err := tag.ExtractPictures(out, file, fileName, options.Pictures.Extract)
+    if err != nil {
+      return errors.New(fmt.Sprintf("failed to extract pictures of file '%s': %s", fileName, err))
+    }
+  }
+  if options.Lyrics.Show {
+    if err := tag.ShowLyrics(out, file); err != nil {
+      return errors.New(fmt.Sprintf("failed to show lyrics of file '%s': %s", fileName, err))
+    }
+  }
+  if options.Lyrics.Export != "" {
+    if err := tag.ExportLyrics(out, file, fileName, &options.Lyrics); err != nil {
+      return errors.New(fmt.Sprintf("failed to export lyrics of file '%s': %s", fileName, err))
+    }
+  }
+  if options.Chapters.Export != "" {
+    if err := tag.ExportChapters(out, file, fileName, options.Chapters.Export); err != nil {
+      return errors.New(fmt.Sprintf("failed to export chapters of file '%s': %s", fileName, err))
+    }
+  }
+  if len(options.Raw.Get) > 0 {
+    if err := tag.ShowValues(out, file, options.Raw.Get); err != nil {
+      return errors.New(fmt.Sprintf("failed to get keys of file '%s': %s", fileName, err))
+    }
   }
+  return nil
 }
 
 /*
 -------------------------
   Available Tags
 -------------------------
-  Album       ~   string
-  Artist      ~   string
-  Bitrate         int
-  Channels        int
-  Comment     ~   string
-  Genre       ~   string
-  Length          time.Duration
-  Samplerate      int
-  Title       ~   string
-  Track       ~   int
-  Year        ~   int
+  Album                ~   string
+  Album Artist         ~   string
+  Album Artist Sort    ~   string
+  Album Gain               string
+  Album Peak               string
+  Album Sort           ~   string
+  Artist               ~   string
+  Artist Sort          ~   string
+  Bitrate                  int
+  BPM                  ~   int
+  Catalog Number       ~   string
+  Channels                 int
+  Comment              ~   string
+  Compilation          ~   int
+  Composer             ~   string
+  Composer Sort        ~   string
+  Conductor            ~   string
+  Copyright            ~   string
+  Date                 ~   date
+  Disc                 ~   int
+  Disc Total           ~   int
+  Encoded By           ~   string
+  Genre                ~   string
+  Grouping             ~   string
+  ISRC                 ~   string
+  Length                   time.Duration
+  Lyricist             ~   string
+  Original Date        ~   date
+  Publisher            ~   string
+  Samplerate               int
+  Title                ~   string
+  Title Sort           ~   string
+  Track                ~   int
+  Track Gain               string
+  Track Peak               string
+  Track Total          ~   int
+  Year                 ~   date
 -------------------------
    Escapes
 -------------------------
   %l : Album
+  %a : Album Artist
+  %A : Album Artist Sort
+  %v : Album Gain
+  %V : Album Peak
+  %L : Album Sort
   %r : Artist
+  %S : Artist Sort
   %b : Bitrate
+  %B : BPM
+  %N : Catalog Number
   %h : Channels
   %c : Comment
+  %C : Compilation
+  %m : Composer
+  %M : Composer Sort
+  %o : Conductor
+  %x : Copyright
+  %Y : Date
+  %d : Disc
+  %D : Disc Total
+  %E : Encoded By
   %g : Genre
+  %G : Grouping
+  %i : ISRC
   %n : Length
+  %w : Lyricist
+  %O : Original Date
+  %p : Publisher
   %s : Samplerate
   %t : Title
+  %T : Title Sort
   %k : Track
+  %u : Track Gain
+  %U : Track Peak
+  %K : Track Total
   %y : Year
   %% : %
+  %0Nx : escape x padded with zeros to width N, e.g. %02k
 -------------------------
    Flags
 -------------------------
-  -h or --help        print help (this message) and exit, additional parameters: show, examples
-  -f or --file        set the file for reading and editing (flag can be omitted)
-  -s or --show        mode of printing tags, leaving out mode defaults to show mode default
-  --show-format       custom format for printing tags
-  -l or --album       set Album tag
-  -r or --artist      set Artist tag
-  -c or --comment     set Comment tag
-  -g or --genre       set Genre tag
-  -t or --title       set Title tag
-  -k or --track       set Track tag
-  -y or --year        set Year tag
-  --clear-album       clear Album tag
-  --clear-artist      clear Artist tag
-  --clear-comment     clear Comment tag
-  --clear-genre       clear Genre tag
-  --clear-title       clear Title tag
-  --clear-track       clear Track tag
-  --clear-year        clear Year tag
-  --clear             clear all tags
+  -h or --help              print help (this message) and exit, additional parameters: show, examples
+  -f or --file              add a file for reading and editing (flag can be omitted, repeatable)
+  -s or --show              mode of printing tags, leaving out mode defaults to show mode default
+  --show-format             custom format for printing tags
+  --separator               separator joining several values of a tag
+  -l or --album             set Album tag
+  -a or --albumartist       set Album Artist tag
+  -A or --albumartistsort   set Album Artist Sort tag
+  -L or --albumsort         set Album Sort tag
+  -r or --artist            set Artist tag
+  -S or --artistsort        set Artist Sort tag
+  -B or --bpm               set BPM tag
+  -N or --catalog           set Catalog Number tag
+  -c or --comment           set Comment tag
+  -C or --compilation       set Compilation tag
+  -m or --composer          set Composer tag
+  -M or --composersort      set Composer Sort tag
+  -o or --conductor         set Conductor tag
+  -x or --copyright         set Copyright tag
+  -Y or --date              set Date tag
+  -d or --disc              set Disc tag, N/TOTAL sets Disc Total as well
+  -D or --disctotal         set Disc Total tag
+  -E or --encodedby         set Encoded By tag
+  -g or --genre             set Genre tag
+  -G or --grouping          set Grouping tag
+  -i or --isrc              set ISRC tag
+  -w or --lyricist          set Lyricist tag
+  -O or --originaldate      set Original Date tag
+  -p or --publisher         set Publisher tag
+  -t or --title             set Title tag
+  -T or --titlesort         set Title Sort tag
+  -k or --track             set Track tag, N/TOTAL sets Track Total as well
+  -K or --tracktotal        set Track Total tag
+  -y or --year              set Year tag
+  --clear-album             clear Album tag
+  --clear-albumartist       clear Album Artist tag
+  --clear-albumartistsort   clear Album Artist Sort tag
+  --clear-albumsort         clear Album Sort tag
+  --clear-artist            clear Artist tag
+  --clear-artistsort        clear Artist Sort tag
+  --clear-bpm               clear BPM tag
+  --clear-catalog           clear Catalog Number tag
+  --clear-comment           clear Comment tag
+  --clear-compilation       clear Compilation tag
+  --clear-composer          clear Composer tag
+  --clear-composersort      clear Composer Sort tag
+  --clear-conductor         clear Conductor tag
+  --clear-copyright         clear Copyright tag
+  --clear-date              clear Date tag
+  --clear-disc              clear Disc tag
+  --clear-disctotal         clear Disc Total tag
+  --clear-encodedby         clear Encoded By tag
+  --clear-genre             clear Genre tag
+  --clear-grouping          clear Grouping tag
+  --clear-isrc              clear ISRC tag
+  --clear-lyricist          clear Lyricist tag
+  --clear-originaldate      clear Original Date tag
+  --clear-publisher         clear Publisher tag
+  --clear-title             clear Title tag
+  --clear-titlesort         clear Title Sort tag
+  --clear-track             clear Track tag
+  --clear-tracktotal        clear Track Total tag
+  --clear-year              clear Year tag
+  --clear                   clear all tags
+  --add-TAG                 add a value to a text tag (repeatable)
+  --remove-TAG              remove a value from a text tag (repeatable)
+  -R or --recursive         descend into directories
+  --include                 only take files matching glob (repeatable)
+  --exclude                 skip files and directories matching glob (repeatable)
+  --max-depth               limit the depth of directory traversal
+  --follow-symlinks         follow symbolic links
+  --sniff                   recognise audio files by content as well
+  -j or --jobs              number of files processed in parallel
+  --backend                 name of the backend used to read and write files
+  --id3v2-version           id3v2 version written (3 or 4)
+  --padding                 bytes reserved for future edits when a tag grows
+  --id3                     id3 versions written to mp3 files (v2, v1, both, no-v1)
+  --set                     set a native key, repeatable for several values
+  --remove                  remove a native key
+  --get                     print the values of a native key
+  --pictures                list the embedded pictures
+  --extract-pictures        write the embedded pictures to a directory
+  --add-picture             embed an image file (repeatable)
+  --replace-picture         replace pictures by index or type with an image file (repeatable)
+  --remove-picture          remove pictures by index or type (repeatable)
+  --picture-type            type of added and replacing pictures
+  --picture-description     description of added and replacing pictures
+  --normalise-pictures      shrink, recompress and deduplicate the embedded pictures
+  --max-picture-size        largest side in pixels of normalised pictures
+  --jpeg-quality            quality of recompressed jpeg pictures
+  --lyrics                  print the lyrics
+  --lyrics-import           import lyrics from an lrc or text file
+  --lyrics-export           export lyrics to an lrc or text file
+  --clear-lyrics            remove all lyrics
+  --lyrics-language         language of imported and exported id3v2 lyrics
+  --lyrics-description      content descriptor of imported and exported id3v2 lyrics
+  --chapters-import         import chapters from a text or ffmetadata file
+  --chapters-export         export chapters to a text or ffmetadata file
+  --clear-chapters          remove all chapters
+  --number                  number the files of each album by name or by track tags
+  --replaygain              measure the loudness of each file and album and write ReplayGain tags
+  --checksum                print a checksum of the audio data of each file (md5, sha256)
+  --verify                  verify the audio checksums of a manifest (.md5, .sha256)
+  --detect                  report the real container and codec of each file
+  --fix-extension           rename files whose extension does not match their content
 -------------------------
 */
 
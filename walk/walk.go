@@ -0,0 +1,134 @@
+package walk
+
+import (
+  "fmt"
+  "os"
+  "path/filepath"
+  "strings"
+)
+
+import (
+  detect "github.com/elias-boemeke/taggo/detect"
+  parse "github.com/elias-boemeke/taggo/parse"
+)
+
+
+
+type walker struct {
+  op      *parse.WalkOptions
+  files   []string
+  visited map[string]bool
+}
+
+// Collect expands the paths given on the command line to the list of files
+// to process; without the recursive option paths are returned as they are
+func Collect(paths []string, op *parse.WalkOptions) []string {
+  if !op.Recursive {
+    return paths
+  }
+
+  w := &walker{op: op, visited: make(map[string]bool)}
+  for _, path := range paths {
+    info, err := os.Stat(path)
+    if err != nil || !info.IsDir() {
+      // explicitly given files are never filtered,
+      // errors are reported when the file is read
+      w.files = append(w.files, path)
+      continue
+    }
+    w.enter(path, path, 1)
+  }
+  return w.files
+}
+
+func (w *walker) enter(root string, dir string, depth int) {
+  real, err := filepath.EvalSymlinks(dir)
+  if err != nil {
+    real = dir
+  }
+  if w.visited[real] {
+    parse.LogWarning(fmt.Sprintf("skipping directory '%s', already visited" +
+      " (symbolic link loop?)", dir))
+    return
+  }
+  w.visited[real] = true
+
+  entries, err := os.ReadDir(dir)
+  if err != nil {
+    parse.LogWarning(fmt.Sprintf("skipping unreadable directory '%s': %s", dir, err))
+    return
+  }
+
+  for _, e := range entries {
+    path := filepath.Join(dir, e.Name())
+    rel, err := filepath.Rel(root, path)
+    if err != nil {
+      rel = e.Name()
+    }
+    rel = filepath.ToSlash(rel)
+
+    if strings.HasPrefix(e.Name(), ".") {
+      parse.LogWarning(fmt.Sprintf("skipping hidden file '%s'", path))
+      continue
+    }
+    if matchAny(w.op.Exclude, rel) {
+      continue
+    }
+
+    mode := e.Type()
+    if mode & os.ModeSymlink != 0 {
+      if !w.op.FollowSymlinks {
+        continue
+      }
+      info, err := os.Stat(path)
+      if err != nil {
+        parse.LogWarning(fmt.Sprintf("skipping broken symbolic link '%s': %s", path, err))
+        continue
+      }
+      mode = info.Mode().Type()
+    }
+
+    if mode.IsDir() {
+      if w.op.MaxDepth == 0 || depth < w.op.MaxDepth {
+        w.enter(root, path, depth + 1)
+      }
+    } else if mode.IsRegular() {
+      if len(w.op.Include) > 0 && !matchAny(w.op.Include, rel) {
+        continue
+      }
+      if w.isAudio(path) {
+        w.files = append(w.files, path)
+      }
+    }
+  }
+}
+
+func (w *walker) isAudio(path string) bool {
+  if detect.ByExtension(path) != "" {
+    return true
+  }
+  if !w.op.Sniff {
+    return false
+  }
+  format, err := detect.Sniff(path)
+  if err != nil {
+    parse.LogWarning(fmt.Sprintf("skipping unreadable file '%s': %s", path, err))
+    return false
+  }
+  return format != ""
+}
+
+// patterns containing a slash are matched against the path relative
+// to the directory given on the command line, others against the base name
+func matchAny(patterns []string, rel string) bool {
+  for _, p := range patterns {
+    subject := rel
+    if !strings.Contains(p, "/") {
+      subject = filepath.Base(rel)
+    }
+    if ok, _ := filepath.Match(p, subject); ok {
+      return true
+    }
+  }
+  return false
+}
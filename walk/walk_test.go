@@ -0,0 +1,90 @@
+package walk
+
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "reflect"
+  "sort"
+  "testing"
+)
+
+import (
+  parse "github.com/elias-boemeke/taggo/parse"
+)
+
+
+
+// tree creates the files below a temporary directory and returns it
+func tree(t *testing.T, files map[string]string) string {
+  root := t.TempDir()
+  for name, content := range files {
+    path := filepath.Join(root, filepath.FromSlash(name))
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+      t.Fatal(err)
+    }
+    if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+      t.Fatal(err)
+    }
+  }
+  return root
+}
+
+func TestCollect(t *testing.T) {
+  root := tree(t, map[string]string{
+    "a.mp3":            "",
+    "notes.txt":        "",
+    "flac":             "fLaC",
+    ".hidden/b.mp3":    "",
+    "disc1/c.flac":     "",
+    "disc1/d.ogg":      "",
+    "disc1/deep/e.mp3": "",
+    "live/f.mp3":       "",
+  })
+  tests := []struct {
+    name string
+    op   parse.WalkOptions
+    want []string
+  }{
+    {"all", parse.WalkOptions{},
+      []string{"a.mp3", "disc1/c.flac", "disc1/d.ogg", "disc1/deep/e.mp3", "live/f.mp3"}},
+    {"sniffed", parse.WalkOptions{Sniff: true},
+      []string{"a.mp3", "disc1/c.flac", "disc1/d.ogg", "disc1/deep/e.mp3", "flac", "live/f.mp3"}},
+    {"include", parse.WalkOptions{Include: []string{"*.mp3"}},
+      []string{"a.mp3", "disc1/deep/e.mp3", "live/f.mp3"}},
+    {"exclude directory", parse.WalkOptions{Exclude: []string{"live"}},
+      []string{"a.mp3", "disc1/c.flac", "disc1/d.ogg", "disc1/deep/e.mp3"}},
+    {"exclude relative path", parse.WalkOptions{Exclude: []string{"disc1/*.ogg"}},
+      []string{"a.mp3", "disc1/c.flac", "disc1/deep/e.mp3", "live/f.mp3"}},
+    {"max depth", parse.WalkOptions{MaxDepth: 2},
+      []string{"a.mp3", "disc1/c.flac", "disc1/d.ogg", "live/f.mp3"}},
+  }
+  for _, tt := range tests {
+    tt.op.Recursive = true
+    var got []string
+    for _, path := range Collect([]string{root}, &tt.op) {
+      rel, err := filepath.Rel(root, path)
+      if err != nil {
+        t.Fatal(err)
+      }
+      got = append(got, filepath.ToSlash(rel))
+    }
+    sort.Strings(got)
+    if !reflect.DeepEqual(got, tt.want) {
+      t.Errorf("%s: collected %q, want %q", tt.name, got, tt.want)
+    }
+  }
+}
+
+func TestCollectFiles(t *testing.T) {
+  root := tree(t, map[string]string{"notes.txt": ""})
+  // given files are kept even if they are no audio or don't exist
+  paths := []string{filepath.Join(root, "notes.txt"), filepath.Join(root, "missing.mp3")}
+  if got := Collect(paths, &parse.WalkOptions{Recursive: true}); !reflect.DeepEqual(got, paths) {
+    t.Errorf("collected %q", got)
+  }
+  dir := []string{root}
+  if got := Collect(dir, &parse.WalkOptions{}); !reflect.DeepEqual(got, dir) {
+    t.Errorf("collected %q without recursion", got)
+  }
+}